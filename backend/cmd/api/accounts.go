@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var errAccountArchived = errors.New("account is archived")
+
+type CreateAccountPayload struct {
+	Name           string `json:"name" validate:"required,max=100"`
+	Currency       string `json:"currency" validate:"omitempty,len=3,uppercase"`
+	OpeningBalance int64  `json:"opening_balance"`
+}
+
+type UpdateAccountPayload struct {
+	Name           *string `json:"name" validate:"omitempty,max=100"`
+	Currency       *string `json:"currency" validate:"omitempty,len=3,uppercase"`
+	OpeningBalance *int64  `json:"opening_balance"`
+	Archived       *bool   `json:"archived"`
+}
+
+// NetWorthResponse holds the balance of every account, the totals per
+// currency and the grand total in the base currency. Accounts in a currency
+// without a known rate are missing from Total and have their currency listed
+// in MissingRates.
+type NetWorthResponse struct {
+	Accounts     []store.AccountBalance `json:"accounts"`
+	Totals       map[string]int64       `json:"totals"`
+	BaseCurrency string                 `json:"base_currency"`
+	Total        int64                  `json:"total"`
+	MissingRates []string               `json:"missing_rates"`
+}
+
+func (app *application) createAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	account := &store.Account{
+		Name:           payload.Name,
+		Currency:       payload.Currency,
+		OpeningBalance: payload.OpeningBalance,
+	}
+	if account.Currency == "" {
+		account.Currency = store.BaseCurrency
+	}
+
+	ctx := r.Context()
+	if err := app.store.Accounts.Create(ctx, account); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, account); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) indexAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	accounts, err := app.store.Accounts.Index(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, accounts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, account); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromCtx(r)
+
+	var payload UpdateAccountPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		account.Name = *payload.Name
+	}
+	if payload.Currency != nil {
+		account.Currency = *payload.Currency
+	}
+	if payload.OpeningBalance != nil {
+		account.OpeningBalance = *payload.OpeningBalance
+	}
+	if payload.Archived != nil {
+		account.Archived = *payload.Archived
+	}
+
+	if err := app.store.Accounts.Update(r.Context(), account); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict), errors.Is(err, store.ErrCurrencyInUse), errors.Is(err, store.ErrLocked):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, account); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account := getAccountFromCtx(r)
+
+	ctx := r.Context()
+	if err := app.store.Accounts.Delete(ctx, account.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, errors.New("account still has transactions, archive it instead"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getNetWorthHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	balances, err := app.store.Accounts.GetBalances(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// amounts in different currencies are only added together once
+	// converted
+	response := NetWorthResponse{
+		Accounts:     balances,
+		Totals:       map[string]int64{},
+		BaseCurrency: store.BaseCurrency,
+		MissingRates: []string{},
+	}
+	for _, balance := range balances {
+		response.Totals[balance.Currency] += balance.Balance
+		switch {
+		case balance.ConvertedBalance != nil:
+			response.Total += *balance.ConvertedBalance
+		case !slices.Contains(response.MissingRates, balance.Currency):
+			response.MissingRates = append(response.MissingRates, balance.Currency)
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// resolveAccount loads the account a new transaction is booked on, falling
+// back to the default account when none is given.
+func (app *application) resolveAccount(ctx context.Context, accountID *int64) (*store.Account, error) {
+	if accountID == nil || *accountID == 0 {
+		return app.store.Accounts.GetDefault(ctx)
+	}
+
+	account, err := app.store.Accounts.GetByID(ctx, *accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.Archived {
+		return nil, errAccountArchived
+	}
+
+	return account, nil
+}
+
+// checkCurrency rejects a currency given for a transaction that differs from
+// the currency of its account. Transactions are always booked in their
+// account's currency so that running balances never mix currencies.
+func checkCurrency(currency, accountCurrency string) error {
+	if currency != "" && currency != accountCurrency {
+		return fmt.Errorf("currency %s does not match the account currency %s", currency, accountCurrency)
+	}
+	return nil
+}
+
+func (app *application) accountResolveError(w http.ResponseWriter, r *http.Request, err error) {
+	app.payloadError(w, r, accountLookupError(err))
+}
+
+// accountLookupError marks the errors of resolveAccount that come from the
+// request rather than the server.
+func accountLookupError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return invalidPayload(errors.New("account not found"))
+	case errors.Is(err, errAccountArchived):
+		return invalidPayload(err)
+	default:
+		return err
+	}
+}
+
+func (app *application) accountContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "accountID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		account, err := app.store.Accounts.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, accountCtx, account)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getAccountFromCtx(r *http.Request) *store.Account {
+	account, _ := r.Context().Value(accountCtx).(*store.Account)
+	return account
+}
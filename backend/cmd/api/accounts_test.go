@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockAccountStore struct {
+	accounts []store.Account
+	account  *store.Account
+	balances []store.AccountBalance
+	err      error
+}
+
+func (m *MockAccountStore) Create(ctx context.Context, account *store.Account) error {
+	if m.err != nil {
+		return m.err
+	}
+	account.ID = 1
+	return nil
+}
+
+func (m *MockAccountStore) Index(ctx context.Context) ([]store.Account, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.accounts, nil
+}
+
+func (m *MockAccountStore) GetByID(ctx context.Context, id int64) (*store.Account, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.account == nil {
+		return nil, store.ErrNotFound
+	}
+	return m.account, nil
+}
+
+func (m *MockAccountStore) GetDefault(ctx context.Context) (*store.Account, error) {
+	return m.GetByID(ctx, 0)
+}
+
+func (m *MockAccountStore) Update(ctx context.Context, account *store.Account) error {
+	return m.err
+}
+
+func (m *MockAccountStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+func (m *MockAccountStore) GetBalances(ctx context.Context) ([]store.AccountBalance, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.balances, nil
+}
+
+type AccountsTestSuite struct {
+	suite.Suite
+	app *application
+}
+
+func (suite *AccountsTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.app = &application{config: cfg, store: store.NewStorage(nil)}
+}
+
+func (suite *AccountsTestSuite) TestIndexAccountsHandler_Success() {
+	mockStore := &MockAccountStore{
+		accounts: []store.Account{
+			{ID: 1, Name: "Cash", Currency: "IDR"},
+			{ID: 2, Name: "GoPay", Currency: "IDR"},
+		},
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/accounts", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexAccountsHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data []store.Account `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data, 2)
+	assert.Equal(suite.T(), "GoPay", response.Data[1].Name)
+}
+
+func (suite *AccountsTestSuite) TestCreateAccountHandler_Success() {
+	mockStore := &MockAccountStore{}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	requestBody := CreateAccountPayload{
+		Name:           "BCA",
+		OpeningBalance: 500000,
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createAccountHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data store.Account `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "BCA", response.Data.Name)
+	assert.Equal(suite.T(), "IDR", response.Data.Currency)
+	assert.Equal(suite.T(), int64(500000), response.Data.OpeningBalance)
+}
+
+func (suite *AccountsTestSuite) TestCreateAccountHandler_InvalidCurrency() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: &MockAccountStore{},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	requestBody := CreateAccountPayload{
+		Name:     "Wise",
+		Currency: "usd",
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createAccountHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *AccountsTestSuite) TestCreateAccountHandler_DuplicateName() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: &MockAccountStore{err: store.ErrConflict},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	requestBody := CreateAccountPayload{
+		Name: "Cash",
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createAccountHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *AccountsTestSuite) TestUpdateAccountHandler_Archive() {
+	account := &store.Account{ID: 1, Name: "Cash", Currency: "IDR"}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: &MockAccountStore{account: account},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodPatch, "/accounts/1", bytes.NewReader([]byte(`{"archived": true}`)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), accountCtx, account))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateAccountHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.True(suite.T(), account.Archived)
+}
+
+func (suite *AccountsTestSuite) TestUpdateAccountHandler_CurrencyInUse() {
+	account := &store.Account{ID: 1, Name: "Cash", Currency: "IDR"}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: &MockAccountStore{account: account, err: store.ErrCurrencyInUse},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodPatch, "/accounts/1", bytes.NewReader([]byte(`{"currency": "USD"}`)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), accountCtx, account))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateAccountHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *AccountsTestSuite) TestUpdateAccountHandler_OpeningBalanceReconciled() {
+	account := &store.Account{ID: 1, Name: "Cash", Currency: "IDR", OpeningBalance: 100000}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: &MockAccountStore{account: account, err: store.ErrLocked},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodPatch, "/accounts/1", bytes.NewReader([]byte(`{"opening_balance": 150000}`)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), accountCtx, account))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateAccountHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *AccountsTestSuite) TestDeleteAccountHandler_HasTransactions() {
+	account := &store.Account{ID: 1, Name: "Cash", Currency: "IDR"}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: &MockAccountStore{account: account, err: store.ErrConflict},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodDelete, "/accounts/1", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), accountCtx, account))
+
+	rr := httptest.NewRecorder()
+	suite.app.deleteAccountHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *AccountsTestSuite) TestGetNetWorthHandler_TotalsPerCurrency() {
+	mockStore := &MockAccountStore{
+		balances: []store.AccountBalance{
+			{ID: 1, Name: "Cash", Currency: "IDR", Balance: 150000},
+			{ID: 2, Name: "BCA", Currency: "IDR", Balance: 2500000},
+			{ID: 3, Name: "Wise", Currency: "USD", Balance: 120},
+		},
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/accounts/net_worth", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.getNetWorthHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data NetWorthResponse `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data.Accounts, 3)
+	assert.Equal(suite.T(), int64(2650000), response.Data.Totals["IDR"])
+	assert.Equal(suite.T(), int64(120), response.Data.Totals["USD"])
+}
+
+func (suite *AccountsTestSuite) TestGetNetWorthHandler_StoreError() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Accounts: &MockAccountStore{err: errors.New("database error")},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/accounts/net_worth", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.getNetWorthHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, rr.Code)
+}
+
+func TestAccountsTestSuite(t *testing.T) {
+	suite.Run(t, new(AccountsTestSuite))
+}
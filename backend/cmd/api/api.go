@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"mime"
 	"net/http"
 	"time"
 
@@ -9,14 +10,18 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/blob"
 	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/pukuri/expenses/backend/internal/suggest"
 	"golang.org/x/oauth2"
 )
 
 type application struct {
 	config      *config.Config
 	store       store.Storage
+	blobs       blob.Store
 	oauthConfig *oauth2.Config
+	suggestions suggest.Classifier
 }
 
 func (app *application) mount() http.Handler {
@@ -24,9 +29,9 @@ func (app *application) mount() http.Handler {
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://*", "https://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key", "If-Match"},
+		ExposedHeaders:   []string{"Link", "Idempotent-Replayed", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -36,56 +41,237 @@ func (app *application) mount() http.Handler {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	r.Use(middleware.Timeout(10 * time.Second))
-
 	r.Route("/api", func(r chi.Router) {
-		r.Route("/auth", func(r chi.Router) {
-			r.Get("/google", app.googleAuth)
-			r.Get("/google/callback", app.googleCallback)
-			r.Post("/logout", app.googleLogout)
-			r.Get("/logged_user", app.googleLoggedUser)
-		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(requestTimeout))
 
-		r.Get("/health", app.healthCheckHandler)
+			r.Route("/auth", func(r chi.Router) {
+				r.Get("/google", app.googleAuth)
+				r.Get("/google/callback", app.googleCallback)
+				r.Post("/logout", app.googleLogout)
+				r.Get("/logged_user", app.googleLoggedUser)
+			})
+
+			r.Get("/health", app.healthCheckHandler)
+		})
 
 		r.Route("/v1", func(r chi.Router) {
 			r.Use(app.authenticationMiddleware)
 
-			r.Route("/transactions", func(r chi.Router) {
-				r.Post("/", app.createTransactionHandler)
-				r.Get("/", app.indexTransactionHandler)
+			r.Group(func(r chi.Router) {
+				r.Use(app.requestTimeoutMiddleware)
+				r.Use(app.idempotencyMiddleware)
 
-				r.Route("/{transactionID}", func(r chi.Router) {
-					r.Use(app.transactionContextMiddleware)
+				r.Route("/transactions", func(r chi.Router) {
+					r.Post("/", app.createTransactionHandler)
+					r.Get("/", app.indexTransactionHandler)
+					r.Post("/quick", app.quickTransactionHandler)
+					r.Get("/{transactionID}/history", app.transactionHistoryHandler)
 
-					r.Get("/", app.getTransactionHandler)
-					r.Delete("/", app.deleteTransactionHandler)
-					r.Patch("/", app.updateTransactionHandler)
+					r.Route("/{transactionID}", func(r chi.Router) {
+						r.Use(app.transactionContextMiddleware)
+
+						r.Get("/", app.getTransactionHandler)
+						r.Delete("/", app.deleteTransactionHandler)
+						r.Patch("/", app.updateTransactionHandler)
+						r.Get("/attachments", app.indexTransactionAttachmentsHandler)
+						r.Post("/attachments", app.uploadTransactionAttachmentHandler)
+					})
 				})
-			})
 
-			r.Get("/expenses_by_month", app.getExpensesByMonthHandler)
-			r.Get("/expenses_by_months", app.getExpensesByMonthsHandler)
-			r.Get("/expenses_by_month_category", app.getExpensesByMonthCategoryHandler)
-			r.Get("/expenses_last_30_days", app.getExpensesLast30DaysHandler)
-			r.Get("/balance_by_date", app.getBalanceByDateHandler)
+				r.Get("/expenses_by_month", app.getExpensesByMonthHandler)
+				r.Get("/expenses_by_months", app.getExpensesByMonthsHandler)
+				r.Get("/expenses_by_month_category", app.getExpensesByMonthCategoryHandler)
+				r.Get("/expenses_by_tag", app.getExpensesByTagHandler)
+				r.Get("/expenses_last_30_days", app.getExpensesLast30DaysHandler)
+				r.Get("/balance_by_date", app.getBalanceByDateHandler)
+				r.Get("/savings_rate", app.getSavingsRateHandler)
+				r.Get("/search", app.searchHandler)
+
+				r.Route("/accounts", func(r chi.Router) {
+					r.Post("/", app.createAccountHandler)
+					r.Get("/", app.indexAccountsHandler)
+					r.Get("/net_worth", app.getNetWorthHandler)
+
+					r.Route("/{accountID}", func(r chi.Router) {
+						r.Use(app.accountContextMiddleware)
+
+						r.Get("/", app.getAccountHandler)
+						r.Patch("/", app.updateAccountHandler)
+						r.Delete("/", app.deleteAccountHandler)
+					})
+				})
+
+				r.Route("/categories", func(r chi.Router) {
+					r.Post("/", app.createCategoryHandler)
+					r.Get("/", app.indexCategoryHandler)
+					r.Get("/suggest", app.suggestCategoriesHandler)
+
+					r.Route("/{categoryID}", func(r chi.Router) {
+						r.Use(app.categoryContextMiddleware)
+
+						r.Get("/", app.getCategoryHandler)
+						r.Delete("/", app.deleteCategoryHandler)
+					})
+				})
+
+				r.Route("/tags", func(r chi.Router) {
+					r.Post("/", app.createTagHandler)
+					r.Get("/", app.indexTagsHandler)
+
+					r.Route("/{tagID}", func(r chi.Router) {
+						r.Use(app.tagContextMiddleware)
 
-			r.Route("/categories", func(r chi.Router) {
-				r.Post("/", app.createCategoryHandler)
-				r.Get("/", app.indexCategoryHandler)
+						r.Get("/", app.getTagHandler)
+						r.Patch("/", app.updateTagHandler)
+						r.Delete("/", app.deleteTagHandler)
+					})
+				})
+
+				r.Route("/recurring", func(r chi.Router) {
+					r.Post("/", app.createRecurringRuleHandler)
+					r.Get("/", app.indexRecurringRulesHandler)
+
+					r.Route("/{ruleID}", func(r chi.Router) {
+						r.Use(app.recurringRuleContextMiddleware)
+
+						r.Get("/", app.getRecurringRuleHandler)
+						r.Patch("/", app.updateRecurringRuleHandler)
+						r.Delete("/", app.deleteRecurringRuleHandler)
+						r.Get("/preview", app.previewRecurringRuleHandler)
+					})
+				})
+
+				r.Route("/rules", func(r chi.Router) {
+					r.Post("/", app.createCategorizationRuleHandler)
+					r.Get("/", app.indexCategorizationRulesHandler)
+
+					r.Route("/{ruleID}", func(r chi.Router) {
+						r.Use(app.categorizationRuleContextMiddleware)
+
+						r.Get("/", app.getCategorizationRuleHandler)
+						r.Patch("/", app.updateCategorizationRuleHandler)
+						r.Delete("/", app.deleteCategorizationRuleHandler)
+					})
+				})
+
+				r.Route("/exchange_rates", func(r chi.Router) {
+					r.Post("/", app.createExchangeRatesHandler)
+					r.Get("/", app.indexExchangeRatesHandler)
+					r.Post("/import", app.importExchangeRatesHandler)
+
+					r.Route("/{rateID}", func(r chi.Router) {
+						r.Use(app.exchangeRateContextMiddleware)
+
+						r.Get("/", app.getExchangeRateHandler)
+						r.Delete("/", app.deleteExchangeRateHandler)
+					})
+				})
+
+				r.Route("/import_profiles", func(r chi.Router) {
+					r.Post("/", app.createImportProfileHandler)
+					r.Get("/", app.indexImportProfilesHandler)
+
+					r.Route("/{profileID}", func(r chi.Router) {
+						r.Use(app.importProfileContextMiddleware)
+
+						r.Get("/", app.getImportProfileHandler)
+						r.Patch("/", app.updateImportProfileHandler)
+						r.Delete("/", app.deleteImportProfileHandler)
+					})
+				})
+
+				r.Route("/trash", func(r chi.Router) {
+					r.Get("/", app.indexTrashHandler)
+					r.Post("/{type}/{id}/restore", app.restoreTrashItemHandler)
+				})
+
+				r.Route("/reconciliations", func(r chi.Router) {
+					r.Post("/", app.createReconciliationHandler)
+					r.Get("/", app.indexReconciliationsHandler)
+
+					r.Route("/{reconciliationID}", func(r chi.Router) {
+						r.Use(app.reconciliationContextMiddleware)
+
+						r.Get("/", app.getReconciliationHandler)
+						r.Delete("/", app.deleteReconciliationHandler)
+						r.Post("/cleared", app.clearTransactionsHandler)
+						r.Post("/complete", app.completeReconciliationHandler)
+					})
+				})
+
+				r.Route("/payees", func(r chi.Router) {
+					r.Post("/", app.createPayeeHandler)
+					r.Get("/", app.indexPayeesHandler)
+					r.Get("/top", app.topPayeesHandler)
+
+					r.Route("/{payeeID}", func(r chi.Router) {
+						r.Use(app.payeeContextMiddleware)
+
+						r.Get("/", app.getPayeeHandler)
+						r.Patch("/", app.updatePayeeHandler)
+						r.Delete("/", app.deletePayeeHandler)
+						r.Post("/merge", app.mergePayeesHandler)
+					})
+				})
+
+				r.Get("/audit", app.indexAuditHandler)
+
+				r.Route("/attachments/{attachmentID}", func(r chi.Router) {
+					r.Use(app.attachmentContextMiddleware)
+
+					r.Get("/", app.downloadAttachmentHandler)
+					r.Get("/thumbnail", app.downloadAttachmentThumbnailHandler)
+					r.Delete("/", app.deleteAttachmentHandler)
+				})
+
+				r.Route("/events", func(r chi.Router) {
+					r.Post("/", app.createEventHandler)
+					r.Get("/", app.indexEventsHandler)
+
+					r.Route("/{eventID}", func(r chi.Router) {
+						r.Use(app.eventContextMiddleware)
+
+						r.Get("/", app.getEventHandler)
+						r.Delete("/", app.deleteEventHandler)
+						r.Get("/expenses", app.getEventExpensesHandler)
+						r.Post("/expenses", app.createEventExpenseHandler)
+
+						r.Route("/expenses/{expenseID}", func(r chi.Router) {
+							r.Use(app.eventExpenseContextMiddleware)
+
+							r.Get("/attachments", app.indexEventExpenseAttachmentsHandler)
+							r.Post("/attachments", app.uploadEventExpenseAttachmentHandler)
+						})
+					})
+				})
 			})
 
-			r.Route("/events", func(r chi.Router) {
-				r.Post("/", app.createEventHandler)
-				r.Get("/", app.indexEventsHandler)
+			// imports, batches, exports, rule runs and ledger checks go
+			// through whole files, hundreds of operations or the whole
+			// ledger, which takes longer than other requests are allowed to.
+			// The deadlines are extended before the idempotency key reads
+			// the body.
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.Timeout(longRequestTimeout))
+				r.Use(app.extendDeadlinesMiddleware)
+				r.Use(app.idempotencyMiddleware)
+
+				r.Post("/imports", app.createImportHandler)
+				r.Post("/transactions/batch", app.batchTransactionsHandler)
+				r.Post("/rules/apply", app.applyRulesHandler)
 
-				r.Route("/{eventID}", func(r chi.Router) {
-					r.Use(app.eventContextMiddleware)
+				r.Route("/exports", func(r chi.Router) {
+					r.Get("/transactions", app.exportTransactionsHandler)
+					r.Get("/events", app.exportEventsHandler)
+					r.Get("/journal", app.exportJournalHandler)
+				})
+
+				r.Route("/admin/ledger", func(r chi.Router) {
+					r.Use(app.adminMiddleware)
 
-					r.Get("/", app.getEventHandler)
-					r.Delete("/", app.deleteEventHandler)
-					r.Get("/expenses", app.getEventExpensesHandler)
-					r.Post("/expenses", app.createEventExpenseHandler)
+					r.Get("/", app.checkLedgerHandler)
+					r.Post("/fix", app.fixLedgerHandler)
 				})
 			})
 		})
@@ -94,6 +280,47 @@ func (app *application) mount() http.Handler {
 	return r
 }
 
+const (
+	// requestTimeout bounds the handling of most requests.
+	requestTimeout = 10 * time.Second
+	// longRequestTimeout bounds the requests that read or write whole files,
+	// which are also let past the server's read and write timeouts.
+	longRequestTimeout = 5 * time.Minute
+)
+
+// extendDeadlinesMiddleware lets the request read its body and write its
+// response for up to longRequestTimeout instead of the server's ReadTimeout
+// and WriteTimeout.
+func (app *application) extendDeadlinesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(longRequestTimeout)
+		rc := http.NewResponseController(w)
+		// writers without a connection, such as httptest's, do not support
+		// deadlines and need none
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestTimeoutMiddleware bounds a request by requestTimeout, except for
+// multipart uploads such as attachments and rate files, which are given
+// longRequestTimeout to arrive and be stored.
+func (app *application) requestTimeoutMiddleware(next http.Handler) http.Handler {
+	short := middleware.Timeout(requestTimeout)(next)
+	long := middleware.Timeout(longRequestTimeout)(app.extendDeadlinesMiddleware(next))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if r.Method == http.MethodPost && mediaType == "multipart/form-data" {
+			long.ServeHTTP(w, r)
+			return
+		}
+		short.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) run(mux http.Handler) error {
 	srv := &http.Server{
 		Addr:         app.config.Addr,
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type APITestSuite struct {
+	suite.Suite
+	app *application
+}
+
+func (suite *APITestSuite) SetupTest() {
+	suite.app = &application{}
+}
+
+// deadline returns how long the request timeout middleware gives a request.
+func (suite *APITestSuite) deadline(method, contentType string) time.Duration {
+	var remaining time.Duration
+	handler := suite.app.requestTimeoutMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		assert.True(suite.T(), ok)
+		remaining = time.Until(deadline)
+	}))
+
+	req := httptest.NewRequest(method, "/api/v1/attachments", strings.NewReader(""))
+	req.Header.Set("Content-Type", contentType)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	return remaining
+}
+
+func (suite *APITestSuite) TestRequestTimeoutMiddleware() {
+	upload := suite.deadline(http.MethodPost, "multipart/form-data; boundary=x")
+	assert.Greater(suite.T(), upload, requestTimeout)
+	assert.LessOrEqual(suite.T(), upload, longRequestTimeout)
+
+	assert.LessOrEqual(suite.T(), suite.deadline(http.MethodPost, "application/json"), requestTimeout)
+	assert.LessOrEqual(suite.T(), suite.deadline(http.MethodGet, "multipart/form-data; boundary=x"), requestTimeout)
+}
+
+func TestAPITestSuite(t *testing.T) {
+	suite.Run(t, new(APITestSuite))
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/blob"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// attachmentContentTypes lists the accepted uploads, detected from the file
+// content rather than the client-supplied header.
+var attachmentContentTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"image/heic",
+	"application/pdf",
+}
+
+// thumbnailContentTypes are the images makeThumbnail can decode.
+var thumbnailContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+var errUnsupportedAttachment = errors.New("unsupported file type, upload an image or a PDF")
+
+func (app *application) uploadTransactionAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	transaction := getTransactionFromCtx(r)
+
+	attachment := &store.Attachment{TransactionID: &transaction.ID}
+	app.uploadAttachment(w, r, attachment, fmt.Sprintf("transactions/%d", transaction.ID))
+}
+
+func (app *application) indexTransactionAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	transaction := getTransactionFromCtx(r)
+
+	attachments, err := app.store.Attachments.IndexByTransaction(r.Context(), transaction.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, attachments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) uploadEventExpenseAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	expense := getEventExpenseFromCtx(r)
+
+	attachment := &store.Attachment{EventExpenseID: &expense.ID}
+	app.uploadAttachment(w, r, attachment, fmt.Sprintf("event_expenses/%d", expense.ID))
+}
+
+func (app *application) indexEventExpenseAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	expense := getEventExpenseFromCtx(r)
+
+	attachments, err := app.store.Attachments.IndexByEventExpense(r.Context(), expense.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, attachments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// uploadAttachment stores the "file" part of a multipart request under
+// keyPrefix, together with a thumbnail for images, and records it.
+func (app *application) uploadAttachment(w http.ResponseWriter, r *http.Request, attachment *store.Attachment, keyPrefix string) {
+	maxSize := app.config.Attachments.MaxSize
+	// leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		app.badRequest(w, r, fmt.Errorf("file is larger than %d bytes", maxSize))
+		return
+	}
+
+	contentType, extension, err := detectAttachmentType(file)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	key, err := newBlobKey(keyPrefix, extension)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.blobs.Put(ctx, key, file); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	attachment.FileName = attachmentFileName(header)
+	attachment.ContentType = contentType
+	attachment.Size = header.Size
+	attachment.StorageKey = key
+
+	if thumbnailContentTypes[contentType] {
+		// a missing thumbnail never fails the upload
+		if thumbnailKey, err := app.storeThumbnail(ctx, file, key); err != nil {
+			log.Printf("thumbnail for %s: %s", key, err)
+		} else {
+			attachment.ThumbnailKey = sql.NullString{String: thumbnailKey, Valid: true}
+		}
+	}
+
+	if err := app.store.Attachments.Create(ctx, attachment); err != nil {
+		app.deleteBlobs(ctx, attachment)
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, attachment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// detectAttachmentType sniffs the content type and file extension of an
+// upload and rewinds it.
+func detectAttachmentType(file multipart.File) (string, string, error) {
+	detected, err := mimetype.DetectReader(file)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	for _, contentType := range attachmentContentTypes {
+		if detected.Is(contentType) {
+			return contentType, detected.Extension(), nil
+		}
+	}
+
+	return "", "", errUnsupportedAttachment
+}
+
+func (app *application) storeThumbnail(ctx context.Context, file multipart.File, key string) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	thumbnail, err := makeThumbnail(file)
+	if err != nil {
+		return "", err
+	}
+
+	thumbnailKey := key + ".thumb.jpg"
+	if err := app.blobs.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		return "", err
+	}
+
+	return thumbnailKey, nil
+}
+
+func (app *application) downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment := getAttachmentFromCtx(r)
+
+	app.serveBlob(w, r, attachment.StorageKey, attachment.ContentType, attachment.FileName)
+}
+
+func (app *application) downloadAttachmentThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	attachment := getAttachmentFromCtx(r)
+	if !attachment.ThumbnailKey.Valid {
+		app.notFound(w, r, errors.New("attachment has no thumbnail"))
+		return
+	}
+
+	name := strings.TrimSuffix(attachment.FileName, filepath.Ext(attachment.FileName)) + ".jpg"
+	app.serveBlob(w, r, attachment.ThumbnailKey.String, "image/jpeg", name)
+}
+
+func (app *application) serveBlob(w http.ResponseWriter, r *http.Request, key, contentType, fileName string) {
+	content, err := app.blobs.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound):
+			app.notFound(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("download %s: %s", key, err)
+	}
+}
+
+func (app *application) deleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment := getAttachmentFromCtx(r)
+
+	ctx := r.Context()
+	if err := app.store.Attachments.Delete(ctx, attachment.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.deleteBlobs(ctx, attachment)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteBlobs removes the stored content of an attachment. Failures only
+// leave orphaned files behind, so they are logged rather than returned.
+func (app *application) deleteBlobs(ctx context.Context, attachment *store.Attachment) {
+	keys := []string{attachment.StorageKey}
+	if attachment.ThumbnailKey.Valid {
+		keys = append(keys, attachment.ThumbnailKey.String)
+	}
+
+	for _, key := range keys {
+		if err := app.blobs.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			log.Printf("delete blob %s: %s", key, err)
+		}
+	}
+}
+
+func newBlobKey(prefix, extension string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return prefix + "/" + hex.EncodeToString(b) + extension, nil
+}
+
+// attachmentFileName keeps the base name of the uploaded file for display
+// and downloads; it is never used as a storage path.
+func attachmentFileName(header *multipart.FileHeader) string {
+	name := filepath.Base(strings.ReplaceAll(header.Filename, `\`, "/"))
+	if name == "." || name == "/" {
+		name = "attachment"
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[len(runes)-255:])
+	}
+	return name
+}
+
+func (app *application) attachmentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "attachmentID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		attachment, err := app.store.Attachments.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, attachmentCtx, attachment)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getAttachmentFromCtx(r *http.Request) *store.Attachment {
+	attachment, _ := r.Context().Value(attachmentCtx).(*store.Attachment)
+	return attachment
+}
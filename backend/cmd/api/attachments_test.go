@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/blob"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockAttachmentStore struct {
+	attachments []store.Attachment
+	created     *store.Attachment
+	deleted     int64
+	err         error
+}
+
+func (m *MockAttachmentStore) Create(ctx context.Context, attachment *store.Attachment) error {
+	if m.err != nil {
+		return m.err
+	}
+	attachment.ID = 1
+	attachment.HasThumbnail = attachment.ThumbnailKey.Valid
+	m.created = attachment
+	return nil
+}
+
+func (m *MockAttachmentStore) GetByID(ctx context.Context, id int64) (*store.Attachment, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockAttachmentStore) IndexByTransaction(ctx context.Context, transactionID int64) ([]store.Attachment, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.attachments, nil
+}
+
+func (m *MockAttachmentStore) IndexByEventExpense(ctx context.Context, eventExpenseID int64) ([]store.Attachment, error) {
+	return m.IndexByTransaction(ctx, eventExpenseID)
+}
+
+func (m *MockAttachmentStore) Delete(ctx context.Context, id int64) error {
+	m.deleted = id
+	return m.err
+}
+
+type AttachmentsTestSuite struct {
+	suite.Suite
+	app *application
+}
+
+func (suite *AttachmentsTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr:        "0.0.0.0",
+		Env:         "test",
+		Attachments: config.AttachmentsConfig{MaxSize: 1 << 20},
+	}
+
+	blobs, err := blob.NewLocalStore(suite.T().TempDir())
+	assert.NoError(suite.T(), err)
+
+	suite.app = &application{config: cfg, store: store.NewStorage(nil), blobs: blobs}
+}
+
+func newUploadRequest(name string, content []byte) (*http.Request, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/transactions/1/attachments", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req, nil
+}
+
+func testPNG(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: 200, A: 0xff})
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func (suite *AttachmentsTestSuite) TestUploadTransactionAttachment_ImageWithThumbnail() {
+	attachments := &MockAttachmentStore{}
+	suite.app.store = store.Storage{Attachments: attachments}
+
+	req, err := newUploadRequest("../../receipt.png", testPNG(800, 600))
+	assert.NoError(suite.T(), err)
+	transaction := &store.Transaction{ID: 1}
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, transaction))
+
+	rr := httptest.NewRecorder()
+	suite.app.uploadTransactionAttachmentHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data store.Attachment `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "receipt.png", response.Data.FileName)
+	assert.Equal(suite.T(), "image/png", response.Data.ContentType)
+	assert.True(suite.T(), response.Data.HasThumbnail)
+	assert.Equal(suite.T(), int64(1), *response.Data.TransactionID)
+
+	// the thumbnail is a JPEG scaled to the longest side
+	thumbnail, err := suite.app.blobs.Get(context.Background(), attachments.created.ThumbnailKey.String)
+	assert.NoError(suite.T(), err)
+	defer thumbnail.Close()
+	config, format, err := image.DecodeConfig(thumbnail)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "jpeg", format)
+	assert.Equal(suite.T(), thumbnailMaxSide, config.Width)
+	assert.Equal(suite.T(), 240, config.Height)
+}
+
+func (suite *AttachmentsTestSuite) TestUploadTransactionAttachment_PDF() {
+	attachments := &MockAttachmentStore{}
+	suite.app.store = store.Storage{Attachments: attachments}
+
+	// the client-supplied name does not decide the type
+	req, err := newUploadRequest("invoice.txt", []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj\n<<>>\nendobj\n"))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, &store.Transaction{ID: 1}))
+
+	rr := httptest.NewRecorder()
+	suite.app.uploadTransactionAttachmentHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	assert.Equal(suite.T(), "application/pdf", attachments.created.ContentType)
+	assert.False(suite.T(), attachments.created.ThumbnailKey.Valid)
+}
+
+func (suite *AttachmentsTestSuite) TestUploadTransactionAttachment_UnsupportedType() {
+	suite.app.store = store.Storage{Attachments: &MockAttachmentStore{}}
+
+	req, err := newUploadRequest("receipt.jpg", []byte("#!/bin/sh\necho hello\n"))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, &store.Transaction{ID: 1}))
+
+	rr := httptest.NewRecorder()
+	suite.app.uploadTransactionAttachmentHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *AttachmentsTestSuite) TestUploadTransactionAttachment_TooLarge() {
+	suite.app.store = store.Storage{Attachments: &MockAttachmentStore{}}
+	suite.app.config.Attachments.MaxSize = 100
+
+	req, err := newUploadRequest("receipt.png", testPNG(200, 200))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, &store.Transaction{ID: 1}))
+
+	rr := httptest.NewRecorder()
+	suite.app.uploadTransactionAttachmentHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *AttachmentsTestSuite) TestDownloadAttachment() {
+	ctx := context.Background()
+	err := suite.app.blobs.Put(ctx, "transactions/1/abc.pdf", bytes.NewReader([]byte("%PDF-1.4")))
+	assert.NoError(suite.T(), err)
+
+	attachment := &store.Attachment{
+		ID:          1,
+		FileName:    "invoice march.pdf",
+		ContentType: "application/pdf",
+		StorageKey:  "transactions/1/abc.pdf",
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/attachments/1", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), attachmentCtx, attachment))
+
+	rr := httptest.NewRecorder()
+	suite.app.downloadAttachmentHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), `attachment; filename="invoice march.pdf"`, rr.Header().Get("Content-Disposition"))
+	assert.Equal(suite.T(), "%PDF-1.4", rr.Body.String())
+}
+
+func (suite *AttachmentsTestSuite) TestDownloadAttachmentThumbnail_Missing() {
+	attachment := &store.Attachment{ID: 1, StorageKey: "transactions/1/abc.pdf"}
+
+	req, err := http.NewRequest(http.MethodGet, "/attachments/1/thumbnail", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), attachmentCtx, attachment))
+
+	rr := httptest.NewRecorder()
+	suite.app.downloadAttachmentThumbnailHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusNotFound, rr.Code)
+}
+
+func (suite *AttachmentsTestSuite) TestDeleteAttachment_RemovesBlobs() {
+	attachments := &MockAttachmentStore{}
+	suite.app.store = store.Storage{Attachments: attachments}
+
+	ctx := context.Background()
+	assert.NoError(suite.T(), suite.app.blobs.Put(ctx, "transactions/1/abc.png", bytes.NewReader([]byte("png"))))
+	assert.NoError(suite.T(), suite.app.blobs.Put(ctx, "transactions/1/abc.png.thumb.jpg", bytes.NewReader([]byte("jpg"))))
+
+	attachment := &store.Attachment{
+		ID:           7,
+		StorageKey:   "transactions/1/abc.png",
+		ThumbnailKey: sql.NullString{String: "transactions/1/abc.png.thumb.jpg", Valid: true},
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, "/attachments/7", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), attachmentCtx, attachment))
+
+	rr := httptest.NewRecorder()
+	suite.app.deleteAttachmentHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusNoContent, rr.Code)
+	assert.Equal(suite.T(), int64(7), attachments.deleted)
+
+	_, err = suite.app.blobs.Get(ctx, attachment.StorageKey)
+	assert.ErrorIs(suite.T(), err, blob.ErrNotFound)
+	_, err = suite.app.blobs.Get(ctx, attachment.ThumbnailKey.String)
+	assert.ErrorIs(suite.T(), err, blob.ErrNotFound)
+}
+
+func TestAttachmentsTestSuite(t *testing.T) {
+	suite.Run(t, new(AttachmentsTestSuite))
+}
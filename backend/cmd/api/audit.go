@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// indexAuditHandler lists the recorded changes, newest first. It accepts
+// entity, entity_id, action, user_id, request_id, an inclusive from/to date
+// range, limit and cursor.
+func (app *application) indexAuditHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	app.auditResponse(w, r, filter)
+}
+
+// transactionHistoryHandler lists the changes made to one transaction,
+// newest first. It reads the id itself rather than going through the
+// transaction context, so that the history of a deleted transaction can
+// still be looked up.
+func (app *application) transactionHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "transactionID"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	filter.Entity = store.AuditTransaction
+	filter.EntityID = id
+
+	app.auditResponse(w, r, filter)
+}
+
+func (app *application) auditResponse(w http.ResponseWriter, r *http.Request, filter store.AuditFilter) {
+	entries, next, err := app.store.Audit.Index(r.Context(), filter)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var nextCursor *string
+	if next != nil {
+		cursor := strconv.FormatInt(*next, 10)
+		nextCursor = &cursor
+	}
+
+	if err := app.paginatedJSONResponse(w, http.StatusOK, entries, nextCursor); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func parseAuditFilter(r *http.Request) (store.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := store.AuditFilter{
+		Action:    query.Get("action"),
+		RequestID: query.Get("request_id"),
+		From:      query.Get("from"),
+		To:        query.Get("to"),
+	}
+
+	if entity := query.Get("entity"); entity != "" {
+		if !store.IsAuditEntity(entity) {
+			return filter, fmt.Errorf("invalid entity %q", entity)
+		}
+		filter.Entity = entity
+	}
+
+	for name, value := range map[string]string{"from": filter.From, "to": filter.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return filter, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", name, value)
+		}
+	}
+
+	for name, target := range map[string]*int64{
+		"entity_id": &filter.EntityID,
+		"user_id":   &filter.UserID,
+		"cursor":    &filter.Before,
+	} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id < 1 {
+			return filter, fmt.Errorf("invalid %s %q", name, value)
+		}
+		*target = id
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > store.MaxAuditLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", store.MaxAuditLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockAuditStore struct {
+	entries []store.AuditEntry
+	next    *int64
+	filter  store.AuditFilter
+	err     error
+}
+
+func (m *MockAuditStore) Index(ctx context.Context, filter store.AuditFilter) ([]store.AuditEntry, *int64, error) {
+	m.filter = filter
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	return m.entries, m.next, nil
+}
+
+type AuditTestSuite struct {
+	suite.Suite
+	app   *application
+	audit *MockAuditStore
+}
+
+func (suite *AuditTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	userID := int64(1)
+	next := int64(41)
+	suite.audit = &MockAuditStore{
+		entries: []store.AuditEntry{
+			{
+				ID:        42,
+				Entity:    store.AuditTransaction,
+				EntityID:  7,
+				Action:    store.AuditUpdate,
+				Before:    json.RawMessage(`{"id":7,"amount":150000}`),
+				After:     json.RawMessage(`{"id":7,"amount":186000}`),
+				UserID:    &userID,
+				RequestID: "host/abc-000001",
+				CreatedAt: "2024-03-02T10:00:00Z",
+			},
+		},
+		next: &next,
+	}
+	suite.app = &application{config: cfg, store: store.Storage{Audit: suite.audit}}
+}
+
+func (suite *AuditTestSuite) TestIndexAuditHandler() {
+	req, err := http.NewRequest(http.MethodGet, "/audit?entity=transaction&action=update&user_id=1&request_id=host/abc-000001&from=2024-03-01&to=2024-03-31&limit=1&cursor=50", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexAuditHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), store.AuditFilter{
+		Entity:    store.AuditTransaction,
+		Action:    store.AuditUpdate,
+		UserID:    1,
+		RequestID: "host/abc-000001",
+		From:      "2024-03-01",
+		To:        "2024-03-31",
+		Limit:     1,
+		Before:    50,
+	}, suite.audit.filter)
+
+	var response struct {
+		Data       []store.AuditEntry `json:"data"`
+		NextCursor *string            `json:"next_cursor"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), response.Data, 1) {
+		assert.JSONEq(suite.T(), `{"id":7,"amount":150000}`, string(response.Data[0].Before))
+		assert.Equal(suite.T(), int64(1), *response.Data[0].UserID)
+	}
+	if assert.NotNil(suite.T(), response.NextCursor) {
+		assert.Equal(suite.T(), "41", *response.NextCursor)
+	}
+}
+
+func (suite *AuditTestSuite) TestIndexAuditHandler_InvalidFilter() {
+	for _, query := range []string{
+		"entity=user",
+		"entity_id=abc",
+		"user_id=0",
+		"from=03/01/2024",
+		"limit=501",
+		"cursor=next",
+	} {
+		req, err := http.NewRequest(http.MethodGet, "/audit?"+query, nil)
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.indexAuditHandler(rr, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func (suite *AuditTestSuite) TestIndexAuditHandler_StoreError() {
+	suite.audit.err = errors.New("database error")
+
+	req, err := http.NewRequest(http.MethodGet, "/audit", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexAuditHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, rr.Code)
+}
+
+func (suite *AuditTestSuite) TestTransactionHistoryHandler() {
+	suite.audit.next = nil
+
+	req, err := http.NewRequest(http.MethodGet, "/transactions/7/history?entity=account", nil)
+	assert.NoError(suite.T(), err)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("transactionID", "7")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	suite.app.transactionHistoryHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	// the history is always the transaction's own
+	assert.Equal(suite.T(), store.AuditTransaction, suite.audit.filter.Entity)
+	assert.Equal(suite.T(), int64(7), suite.audit.filter.EntityID)
+
+	var response struct {
+		NextCursor *string `json:"next_cursor"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), response.NextCursor)
+}
+
+func TestAuditTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
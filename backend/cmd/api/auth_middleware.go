@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/pukuri/expenses/backend/internal/store"
 )
 
 func (app *application) authenticationMiddleware(next http.Handler) http.Handler {
@@ -35,8 +39,33 @@ func (app *application) authenticationMiddleware(next http.Handler) http.Handler
 		}
 
 		ctx = context.WithValue(r.Context(), authenticatedUser, user)
+		// changes made during the request are attributed to the user
+		ctx = store.WithActor(ctx, store.Actor{UserID: user.ID, RequestID: middleware.GetReqID(ctx)})
 		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// adminMiddleware only lets through the users whose email is in
+// config.AdminEmails.
+func (app *application) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getAuthenticatedUserFromCtx(r)
+		if user == nil || !app.isAdmin(user) {
+			app.forbidden(w, r, errors.New("user is not an admin"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) isAdmin(user *store.User) bool {
+	for _, email := range app.config.AdminEmails {
+		if email != "" && strings.EqualFold(strings.TrimSpace(email), user.Email) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/pukuri/expenses/backend/internal/store"
 )
 
 type CreateCategoryPayload struct {
 	Name  string `json:"name" validate:"required"`
 	Color string `json:"color" validate:"required"`
+	Kind  string `json:"kind" validate:"omitempty,oneof=expense income transfer adjustment"`
 }
 
 func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
@@ -26,6 +31,7 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 	category := &store.Category{
 		Name:  payload.Name,
 		Color: payload.Color,
+		Kind:  payload.Kind,
 	}
 
 	ctx := r.Context()
@@ -54,3 +60,62 @@ func (app *application) indexCategoryHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 }
+
+func (app *application) getCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category := getCategoryFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, category); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deleteCategoryHandler moves the category to the trash, from where it can be
+// restored until it is purged.
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category := getCategoryFromCtx(r)
+
+	ctx := r.Context()
+	if err := app.store.Categories.Delete(ctx, category.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFound(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) categoryContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "categoryID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		category, err := app.store.Categories.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, categoryCtx, category)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCategoryFromCtx(r *http.Request) *store.Category {
+	category, _ := r.Context().Value(categoryCtx).(*store.Category)
+	return category
+}
@@ -17,6 +17,7 @@ import (
 
 type MockCategoryStore struct {
 	categories []store.Category
+	deleted    []int64
 	err        error
 }
 
@@ -31,6 +32,26 @@ func (m *MockCategoryStore) Index(ctx context.Context) ([]store.Category, error)
 	return m.categories, nil
 }
 
+func (m *MockCategoryStore) GetByID(ctx context.Context, id int64) (*store.Category, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for i := range m.categories {
+		if m.categories[i].ID == id {
+			return &m.categories[i], nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockCategoryStore) Delete(ctx context.Context, id int64) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
 type CategoriesTestSuite struct {
 	suite.Suite
 	app *application
@@ -236,6 +257,28 @@ func (suite *CategoriesTestSuite) TestCreateCategoryHandler_StoreError() {
 	assert.Equal(suite.T(), "the server encountered a problem", response["error"])
 }
 
+func (suite *CategoriesTestSuite) TestDeleteCategoryHandler() {
+	mockStore := &MockCategoryStore{
+		categories: []store.Category{{ID: 3, Name: "Subscriptions", Color: "#E50914"}},
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Categories: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodDelete, "/categories/3", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), categoryCtx, &mockStore.categories[0]))
+
+	rr := httptest.NewRecorder()
+	suite.app.deleteCategoryHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusNoContent, rr.Code)
+	assert.Equal(suite.T(), []int64{3}, mockStore.deleted)
+}
+
 func TestCategoriesTestSuite(t *testing.T) {
 	suite.Run(t, new(CategoriesTestSuite))
 }
@@ -9,15 +9,26 @@ import (
 type contextKey string
 
 const (
-	authenticatedUser contextKey = "authenticatedUser"
-	transactionCtx    contextKey = "transaction"
-	eventCtx          contextKey = "event"
+	authenticatedUser     contextKey = "authenticatedUser"
+	transactionCtx        contextKey = "transaction"
+	eventCtx              contextKey = "event"
+	accountCtx            contextKey = "account"
+	tagCtx                contextKey = "tag"
+	eventExpenseCtx       contextKey = "eventExpense"
+	attachmentCtx         contextKey = "attachment"
+	recurringRuleCtx      contextKey = "recurringRule"
+	importProfileCtx      contextKey = "importProfile"
+	categorizationRuleCtx contextKey = "categorizationRule"
+	exchangeRateCtx       contextKey = "exchangeRate"
+	categoryCtx           contextKey = "category"
+	reconciliationCtx     contextKey = "reconciliation"
+	payeeCtx              contextKey = "payee"
 )
 
-// func getAuthenticatedUserFromCtx(r *http.Request) *store.User {
-// 	user, _ := r.Context().Value(authenticatedUser).(*store.User)
-// 	return user
-// }
+func getAuthenticatedUserFromCtx(r *http.Request) *store.User {
+	user, _ := r.Context().Value(authenticatedUser).(*store.User)
+	return user
+}
 
 func getTransactionFromCtx(r *http.Request) *store.Transaction {
 	transaction, _ := r.Context().Value(transactionCtx).(*store.Transaction)
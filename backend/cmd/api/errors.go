@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
 )
@@ -24,3 +25,52 @@ func (app *application) forbidden(w http.ResponseWriter, r *http.Request, err er
 	log.Printf("forbidden: found: %s path: %s error: %s", r.Method, r.URL.Path, err)
 	writeJSONError(w, http.StatusForbidden, "access denied, only authorized account allowed.")
 }
+
+func (app *application) conflict(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("conflict: %s path: %s error: %s", r.Method, r.URL.Path, err)
+	writeJSONError(w, http.StatusConflict, err.Error())
+}
+
+func (app *application) unprocessableEntity(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("unprocessable entity: %s path: %s error: %s", r.Method, r.URL.Path, err)
+	writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+}
+
+func (app *application) preconditionFailed(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("precondition failed: %s path: %s error: %s", r.Method, r.URL.Path, err)
+	writeJSONError(w, http.StatusPreconditionFailed, "the resource was changed since it was read")
+}
+
+func (app *application) preconditionRequired(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("precondition required: %s path: %s error: %s", r.Method, r.URL.Path, err)
+	writeJSONError(w, http.StatusPreconditionRequired, err.Error())
+}
+
+// invalidPayloadError is an error caused by the request rather than the
+// server, reported by payloadError as a bad request.
+type invalidPayloadError struct {
+	err error
+}
+
+func (e invalidPayloadError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidPayloadError) Unwrap() error {
+	return e.err
+}
+
+func invalidPayload(err error) error {
+	return invalidPayloadError{err}
+}
+
+// payloadError reports an error of processing a request payload as a bad
+// request when it is an invalidPayloadError, and as a server error otherwise.
+func (app *application) payloadError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid invalidPayloadError
+	if errors.As(err, &invalid) {
+		app.badRequest(w, r, err)
+		return
+	}
+	app.internalServerError(w, r, err)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// versionETag is the entity tag of a resource at the given version.
+func versionETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// checkIfMatch reports whether a request may change a resource that is at
+// the given version, and writes the error response when it may not. A
+// missing If-Match header lets the request through unless the config
+// requires one.
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, version int64) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if app.config.RequireIfMatch {
+			app.preconditionRequired(w, r, errors.New("the If-Match header is required"))
+			return false
+		}
+		return true
+	}
+
+	etag := versionETag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		// weak tags never match, as If-Match uses the strong comparison
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	app.preconditionFailed(w, r, fmt.Errorf("If-Match %s does not match the current version %s", header, etag))
+	return false
+}
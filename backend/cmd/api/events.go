@@ -19,6 +19,7 @@ type CreateEventPayload struct {
 type CreateEventExpensePayload struct {
 	Amount      int64  `json:"amount" validate:"required"`
 	Description string `json:"description" validate:"required"`
+	Currency    string `json:"currency" validate:"omitempty,len=3,uppercase"`
 }
 
 func (app *application) createEventHandler(w http.ResponseWriter, r *http.Request) {
@@ -54,6 +55,7 @@ func (app *application) createEventHandler(w http.ResponseWriter, r *http.Reques
 func (app *application) getEventHandler(w http.ResponseWriter, r *http.Request) {
 	event := getEventFromCtx(r)
 
+	w.Header().Set("ETag", versionETag(event.Version))
 	if err := app.jsonResponse(w, http.StatusOK, event); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -108,12 +110,22 @@ func (app *application) createEventExpenseHandler(w http.ResponseWriter, r *http
 	expense := &store.EventExpense{
 		EventID:     event.ID,
 		Amount:      payload.Amount,
+		Currency:    payload.Currency,
 		Description: payload.Description,
 	}
+	if expense.Currency == "" {
+		expense.Currency = store.BaseCurrency
+	}
 
 	ctx := r.Context()
 	if err := app.store.Events.CreateExpense(ctx, expense); err != nil {
-		app.internalServerError(w, r, err)
+		switch {
+		// trashed after it was read above
+		case errors.Is(err, store.ErrNotFound):
+			app.notFound(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
@@ -125,10 +137,21 @@ func (app *application) createEventExpenseHandler(w http.ResponseWriter, r *http
 
 func (app *application) deleteEventHandler(w http.ResponseWriter, r *http.Request) {
 	event := getEventFromCtx(r)
+	if !app.checkIfMatch(w, r, event.Version) {
+		return
+	}
 
 	ctx := r.Context()
-	if err := app.store.Events.Delete(ctx, event.ID); err != nil {
-		app.internalServerError(w, r, err)
+	if err := app.store.Events.Delete(ctx, event.ID, event.Version); err != nil {
+		switch {
+		// changed by someone else after it was read above
+		case errors.Is(err, store.ErrVersionMismatch):
+			app.preconditionFailed(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFound(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
 
@@ -164,4 +187,40 @@ func (app *application) eventContextMiddleware(next http.Handler) http.Handler {
 func getEventFromCtx(r *http.Request) *store.Event {
 	event, _ := r.Context().Value(eventCtx).(*store.Event)
 	return event
-}
\ No newline at end of file
+}
+
+// eventExpenseContextMiddleware loads the expense named in the URL, which
+// must belong to the event loaded by eventContextMiddleware.
+func (app *application) eventExpenseContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "expenseID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		expense, err := app.store.Events.GetExpenseByID(ctx, id)
+		if err == nil && expense.EventID != getEventFromCtx(r).ID {
+			err = store.ErrNotFound
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, eventExpenseCtx, expense)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getEventExpenseFromCtx(r *http.Request) *store.EventExpense {
+	expense, _ := r.Context().Value(eventExpenseCtx).(*store.EventExpense)
+	return expense
+}
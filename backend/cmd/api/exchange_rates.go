@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+type ExchangeRatePayload struct {
+	Currency     string  `json:"currency" validate:"required,len=3,uppercase"`
+	BaseCurrency string  `json:"base_currency" validate:"omitempty,len=3,uppercase"`
+	Date         string  `json:"date" validate:"required,datetime=2006-01-02"`
+	Rate         float64 `json:"rate" validate:"gt=0"`
+}
+
+type CreateExchangeRatesPayload struct {
+	Rates []ExchangeRatePayload `json:"rates" validate:"required,min=1,max=10000,dive"`
+}
+
+// createExchangeRatesHandler saves a batch of rates. A rate without a base
+// currency is quoted in the configured base currency, and a rate already
+// known for the same pair and date is replaced.
+func (app *application) createExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateExchangeRatesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	rates := make([]store.ExchangeRate, 0, len(payload.Rates))
+	for i, rate := range payload.Rates {
+		if rate.BaseCurrency == "" {
+			rate.BaseCurrency = store.BaseCurrency
+		}
+		if err := importer.ValidateCurrencyPair(rate.Currency, rate.BaseCurrency); err != nil {
+			app.badRequest(w, r, fmt.Errorf("rates[%d]: %w", i, err))
+			return
+		}
+		rates = append(rates, store.ExchangeRate{
+			Currency:     rate.Currency,
+			BaseCurrency: rate.BaseCurrency,
+			Date:         rate.Date,
+			Rate:         rate.Rate,
+		})
+	}
+
+	app.saveExchangeRates(w, r, rates)
+}
+
+// importExchangeRatesHandler saves the rates of an uploaded CSV file in the
+// "file" form field; see importer.ParseExchangeRates for its layout.
+func (app *application) importExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxImportSize {
+		app.badRequest(w, r, fmt.Errorf("file is larger than %d bytes", maxImportSize))
+		return
+	}
+
+	rates, err := importer.ParseExchangeRates(file, store.BaseCurrency)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	app.saveExchangeRates(w, r, rates)
+}
+
+func (app *application) saveExchangeRates(w http.ResponseWriter, r *http.Request, rates []store.ExchangeRate) {
+	if err := app.store.ExchangeRates.Upsert(r.Context(), rates); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, rates); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// indexExchangeRatesHandler lists the known rates, newest first, optionally
+// narrowed by currency, base_currency and an inclusive from/to date range.
+func (app *application) indexExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := store.ExchangeRateFilter{
+		Currency:     query.Get("currency"),
+		BaseCurrency: query.Get("base_currency"),
+		From:         query.Get("from"),
+		To:           query.Get("to"),
+	}
+	for name, value := range map[string]string{"from": filter.From, "to": filter.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid %s date %q", name, value))
+			return
+		}
+	}
+
+	rates, err := app.store.ExchangeRates.Index(r.Context(), filter)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rates); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	rate := getExchangeRateFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, rate); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	rate := getExchangeRateFromCtx(r)
+
+	if err := app.store.ExchangeRates.Delete(r.Context(), rate.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFound(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) exchangeRateContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "rateID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		rate, err := app.store.ExchangeRates.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, exchangeRateCtx, rate)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getExchangeRateFromCtx(r *http.Request) *store.ExchangeRate {
+	rate, _ := r.Context().Value(exchangeRateCtx).(*store.ExchangeRate)
+	return rate
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockExchangeRateStore struct {
+	rates  []store.ExchangeRate
+	filter store.ExchangeRateFilter
+	err    error
+}
+
+func (m *MockExchangeRateStore) Upsert(ctx context.Context, rates []store.ExchangeRate) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.rates = rates
+	return nil
+}
+
+func (m *MockExchangeRateStore) Index(ctx context.Context, filter store.ExchangeRateFilter) ([]store.ExchangeRate, error) {
+	m.filter = filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.rates, nil
+}
+
+func (m *MockExchangeRateStore) GetByID(ctx context.Context, id int64) (*store.ExchangeRate, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for i := range m.rates {
+		if m.rates[i].ID == id {
+			return &m.rates[i], nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockExchangeRateStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+type ExchangeRatesTestSuite struct {
+	suite.Suite
+	app   *application
+	rates *MockExchangeRateStore
+}
+
+func (suite *ExchangeRatesTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.rates = &MockExchangeRateStore{}
+	suite.app = &application{config: cfg, store: store.Storage{ExchangeRates: suite.rates}}
+}
+
+func (suite *ExchangeRatesTestSuite) TestCreateExchangeRatesHandler() {
+	body := `{"rates": [{"currency": "USD", "date": "2024-01-02", "rate": 15500}, {"currency": "SGD", "base_currency": "USD", "date": "2024-01-02", "rate": 0.75}]}`
+	req, err := http.NewRequest(http.MethodPost, "/exchange_rates", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createExchangeRatesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if !assert.Len(suite.T(), suite.rates.rates, 2) {
+		return
+	}
+	assert.Equal(suite.T(), store.BaseCurrency, suite.rates.rates[0].BaseCurrency)
+	assert.Equal(suite.T(), 15500.0, suite.rates.rates[0].Rate)
+	assert.Equal(suite.T(), "USD", suite.rates.rates[1].BaseCurrency)
+}
+
+func (suite *ExchangeRatesTestSuite) TestCreateExchangeRatesHandler_Invalid() {
+	for _, body := range []string{
+		`{"rates": []}`,
+		`{"rates": [{"currency": "usd", "date": "2024-01-02", "rate": 15500}]}`,
+		`{"rates": [{"currency": "USD", "date": "02/01/2024", "rate": 15500}]}`,
+		`{"rates": [{"currency": "USD", "date": "2024-01-02", "rate": 0}]}`,
+		`{"rates": [{"currency": "IDR", "date": "2024-01-02", "rate": 1}]}`,
+	} {
+		req, err := http.NewRequest(http.MethodPost, "/exchange_rates", bytes.NewReader([]byte(body)))
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.createExchangeRatesHandler(rr, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+	assert.Empty(suite.T(), suite.rates.rates)
+}
+
+func (suite *ExchangeRatesTestSuite) TestImportExchangeRatesHandler() {
+	req, err := newUploadRequest("rates.csv", []byte("date,currency,rate\n2024-01-02,usd,15500\n2024-01-03,EUR,17000.5\n"))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.importExchangeRatesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if !assert.Len(suite.T(), suite.rates.rates, 2) {
+		return
+	}
+	assert.Equal(suite.T(), "USD", suite.rates.rates[0].Currency)
+	assert.Equal(suite.T(), 17000.5, suite.rates.rates[1].Rate)
+}
+
+func (suite *ExchangeRatesTestSuite) TestIndexExchangeRatesHandler_Filters() {
+	req, err := http.NewRequest(http.MethodGet, "/exchange_rates?currency=USD&from=2024-01-01&to=2024-01-31", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexExchangeRatesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), store.ExchangeRateFilter{Currency: "USD", From: "2024-01-01", To: "2024-01-31"}, suite.rates.filter)
+
+	req, err = http.NewRequest(http.MethodGet, "/exchange_rates?from=01-01-2024", nil)
+	assert.NoError(suite.T(), err)
+
+	rr = httptest.NewRecorder()
+	suite.app.indexExchangeRatesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func TestExchangeRatesTestSuite(t *testing.T) {
+	suite.Run(t, new(ExchangeRatesTestSuite))
+}
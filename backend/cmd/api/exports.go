@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/export"
+	"github.com/pukuri/expenses/backend/internal/journal"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var transactionExportColumns = []string{
+	"id", "date", "account", "description", "kind", "category", "amount", "running_balance", "tags", "event_id", "event",
+	"currency", "converted_amount",
+}
+
+var eventExportColumns = []string{
+	"event_id", "event", "event_description", "event_date", "expense_id", "expense_description", "expense_amount", "expense_created_at",
+	"expense_currency", "expense_converted_amount",
+}
+
+// exportTransactionsHandler streams every transaction matching the listing
+// filters of parseTransactionFilter, oldest first unless sort=desc, as
+// format=csv, xlsx or json.
+func (app *application) exportTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := parseExportFormat(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	// exports are not paginated
+	filter.Limit = 0
+	filter.After = nil
+	if r.URL.Query().Get("sort") == "" {
+		filter.Ascending = true
+	}
+
+	app.streamExport(w, r, format, "transactions", transactionExportColumns, func(writer export.Writer) error {
+		return app.store.Transactions.Export(r.Context(), filter, func(transaction *store.TransactionExport) error {
+			return writer.Write(transaction, transactionExportRow(transaction))
+		})
+	})
+}
+
+// exportEventsHandler streams all events with their expenses. Tabular
+// formats have one row per expense.
+func (app *application) exportEventsHandler(w http.ResponseWriter, r *http.Request) {
+	format, err := parseExportFormat(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	app.streamExport(w, r, format, "events", eventExportColumns, func(writer export.Writer) error {
+		return app.store.Events.Export(r.Context(), func(event *store.EventExport) error {
+			return writer.Write(event, eventExportRows(event)...)
+		})
+	})
+}
+
+// exportJournalHandler streams the accounts, categories and the transactions
+// matching the listing filters as a plain-text accounting journal,
+// format=beancount (the default) or format=ledger.
+func (app *application) exportJournalHandler(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = journal.FormatBeancount
+	}
+	if !journal.IsValidFormat(format) {
+		app.badRequest(w, r, fmt.Errorf("unsupported journal format %q, expected beancount or ledger", format))
+		return
+	}
+
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	// journals are read in date order
+	filter.Limit = 0
+	filter.After = nil
+	filter.Ascending = true
+
+	ctx := r.Context()
+	accounts, categories, err := app.journalAccounts(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	extension := "beancount"
+	if format == journal.FormatLedger {
+		extension = "journal"
+	}
+	fileName := fmt.Sprintf("ledger-%s.%s", time.Now().Format("20060102"), extension)
+
+	app.streamDownload(w, r, fileName, "text/plain; charset=utf-8", func(out io.Writer) error {
+		writer, err := journal.NewWriter(out, format, accounts, categories)
+		if err != nil {
+			return err
+		}
+		if err := app.store.Transactions.Export(ctx, filter, writer.WriteTransaction); err != nil {
+			return err
+		}
+		return writer.Flush()
+	})
+}
+
+func parseExportFormat(r *http.Request) (string, error) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if !export.IsValidFormat(format) {
+		return "", fmt.Errorf("%w, got %q", export.ErrUnsupportedFormat, format)
+	}
+	return format, nil
+}
+
+// streamExport writes an export in one of the export package formats as a
+// file download.
+func (app *application) streamExport(w http.ResponseWriter, r *http.Request, format, name string, columns []string, fn func(export.Writer) error) {
+	fileName := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102"), format)
+	app.streamDownload(w, r, fileName, export.ContentType(format), func(out io.Writer) error {
+		writer, err := export.NewWriter(format, out, name, columns)
+		if err != nil {
+			return err
+		}
+		if err := fn(writer); err != nil {
+			return err
+		}
+		return writer.Close()
+	})
+}
+
+// streamDownload writes a file download. Once the first bytes are sent the
+// status can no longer change, so a later failure only cuts the download
+// short and is logged.
+func (app *application) streamDownload(w http.ResponseWriter, r *http.Request, fileName, contentType string, fn func(io.Writer) error) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+
+	sent := &countingWriter{w: w}
+	buffered := bufio.NewWriterSize(sent, 32<<10)
+
+	err := fn(buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		return
+	}
+
+	if sent.n == 0 {
+		w.Header().Del("Content-Disposition")
+		app.internalServerError(w, r, err)
+		return
+	}
+	log.Printf("export aborted: %s path: %s error: %s", r.Method, r.URL.Path, err)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func transactionExportRow(transaction *store.TransactionExport) []any {
+	var eventID any
+	if transaction.EventID != nil {
+		eventID = *transaction.EventID
+	}
+
+	return []any{
+		transaction.ID,
+		transaction.Date,
+		transaction.AccountName,
+		transaction.Description,
+		transaction.Kind,
+		transaction.CategoryName,
+		transaction.Amount,
+		transaction.RunningBalance,
+		strings.Join(transaction.Tags, ", "),
+		eventID,
+		transaction.EventName,
+		transaction.Currency,
+		optionalAmount(transaction.ConvertedAmount),
+	}
+}
+
+func eventExportRows(event *store.EventExport) [][]any {
+	columns := []any{event.ID, event.Name, event.Description, event.Date}
+	if len(event.Expenses) == 0 {
+		return [][]any{append(columns, nil, nil, nil, nil, nil, nil)}
+	}
+
+	rows := make([][]any, 0, len(event.Expenses))
+	for _, expense := range event.Expenses {
+		row := append(append([]any{}, columns...), expense.ID, expense.Description, expense.Amount, expense.CreatedAt,
+			expense.Currency, optionalAmount(expense.ConvertedAmount))
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// optionalAmount leaves the cell of an amount that could not be converted
+// empty.
+func optionalAmount(amount *int64) any {
+	if amount == nil {
+		return nil
+	}
+	return *amount
+}
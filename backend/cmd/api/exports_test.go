@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockEventStore struct {
+	*store.EventStore
+	exports []store.EventExport
+	err     error
+}
+
+func (m *MockEventStore) Export(ctx context.Context, fn func(*store.EventExport) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	for i := range m.exports {
+		if err := fn(&m.exports[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ExportsTestSuite struct {
+	suite.Suite
+	app          *application
+	transactions *MockTransactionStore
+}
+
+func (suite *ExportsTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	eventID, categoryID, converted := int64(3), int64(5), int64(350000)
+	suite.transactions = &MockTransactionStore{exports: []store.TransactionExport{
+		{
+			ID:              1,
+			Date:            time.Date(2024, 1, 3, 9, 30, 0, 0, time.UTC),
+			AccountID:       1,
+			AccountName:     "BCA",
+			Description:     "Dinner, Bali",
+			Kind:            store.KindExpense,
+			CategoryID:      &categoryID,
+			CategoryName:    "Food",
+			Amount:          350000,
+			RunningBalance:  1650000,
+			Tags:            []string{"holiday", "shared"},
+			EventID:         &eventID,
+			EventName:       "Bali trip",
+			Currency:        "IDR",
+			ConvertedAmount: &converted,
+		},
+		{
+			ID:             2,
+			Date:           time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+			AccountID:      1,
+			AccountName:    "BCA",
+			Description:    "Salary",
+			Kind:           store.KindIncome,
+			Amount:         -15000000,
+			RunningBalance: 16650000,
+			Currency:       "USD",
+		},
+	}}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Transactions: suite.transactions,
+		Accounts:     &MockAccountStore{accounts: []store.Account{{ID: 1, Name: "BCA", Currency: "IDR"}}},
+		Categories:   &MockCategoryStore{categories: []store.Category{{ID: 5, Name: "Food", Kind: store.KindExpense}}},
+		Events: &MockEventStore{exports: []store.EventExport{
+			{ID: 3, Name: "Bali trip", Date: "2024-01-02", Expenses: []store.EventExpense{
+				{ID: 7, EventID: 3, Amount: 500000, Currency: "IDR", ConvertedAmount: &converted, Description: "Villa"},
+				{ID: 8, EventID: 3, Amount: 120000, Currency: "USD", Description: "Scooter"},
+			}},
+			{ID: 4, Name: "Wedding", Date: "2024-03-09", Expenses: []store.EventExpense{}},
+		}},
+	}}
+}
+
+func (suite *ExportsTestSuite) TestExportTransactions_CSV() {
+	req, err := http.NewRequest(http.MethodGet, "/exports/transactions?format=csv&from=2024-01-01&to=2024-12-31", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.exportTransactionsHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(suite.T(), rr.Header().Get("Content-Disposition"), "attachment; filename=transactions-")
+	assert.Equal(suite.T(), "id,date,account,description,kind,category,amount,running_balance,tags,event_id,event,currency,converted_amount\n"+
+		"1,2024-01-03T09:30:00Z,BCA,\"Dinner, Bali\",expense,Food,350000,1650000,\"holiday, shared\",3,Bali trip,IDR,350000\n"+
+		"2,2024-01-25T00:00:00Z,BCA,Salary,income,,-15000000,16650000,,,,USD,\n", rr.Body.String())
+
+	filter := suite.transactions.filter
+	assert.Equal(suite.T(), "2024-01-01", filter.From)
+	assert.Equal(suite.T(), "2024-12-31", filter.To)
+	assert.True(suite.T(), filter.Ascending)
+	assert.Zero(suite.T(), filter.Limit)
+}
+
+func (suite *ExportsTestSuite) TestExportTransactions_JSON() {
+	req, err := http.NewRequest(http.MethodGet, "/exports/transactions?format=json&sort=desc", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.exportTransactionsHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var transactions []store.TransactionExport
+	err = json.Unmarshal(rr.Body.Bytes(), &transactions)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), transactions, 2)
+	assert.Equal(suite.T(), "Bali trip", transactions[0].EventName)
+	assert.False(suite.T(), suite.transactions.filter.Ascending)
+}
+
+func (suite *ExportsTestSuite) TestExportTransactions_InvalidParams() {
+	for _, url := range []string{
+		"/exports/transactions?format=pdf",
+		"/exports/transactions?from=01-01-2024",
+	} {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.exportTransactionsHandler(rr, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, url)
+	}
+}
+
+func (suite *ExportsTestSuite) TestExportTransactions_StoreError() {
+	suite.transactions.err = errors.New("database error")
+
+	req, err := http.NewRequest(http.MethodGet, "/exports/transactions?format=xlsx", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.exportTransactionsHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, rr.Code)
+	assert.Empty(suite.T(), rr.Header().Get("Content-Disposition"))
+}
+
+func (suite *ExportsTestSuite) TestExportJournal() {
+	req, err := http.NewRequest(http.MethodGet, "/exports/journal?format=ledger&account_id=1", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.exportJournalHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Contains(suite.T(), rr.Header().Get("Content-Disposition"), ".journal")
+	assert.Contains(suite.T(), rr.Body.String(), "account Expenses:Food\n")
+	assert.Contains(suite.T(), rr.Body.String(), "2024-01-03 * Dinner, Bali\n")
+	assert.Contains(suite.T(), rr.Body.String(), "  Income:Uncategorized                      -15000000 IDR\n")
+	assert.True(suite.T(), suite.transactions.filter.Ascending)
+}
+
+func (suite *ExportsTestSuite) TestExportJournal_InvalidFormat() {
+	req, err := http.NewRequest(http.MethodGet, "/exports/journal?format=csv", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.exportJournalHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *ExportsTestSuite) TestExportEvents_CSV() {
+	req, err := http.NewRequest(http.MethodGet, "/exports/events", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.exportEventsHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "event_id,event,event_description,event_date,expense_id,expense_description,expense_amount,expense_created_at,expense_currency,expense_converted_amount\n"+
+		"3,Bali trip,,2024-01-02,7,Villa,500000,,IDR,350000\n"+
+		"3,Bali trip,,2024-01-02,8,Scooter,120000,,USD,\n"+
+		"4,Wedding,,2024-03-09,,,,,,\n", rr.Body.String())
+}
+
+func (suite *ExportsTestSuite) TestExportEvents_JSON() {
+	req, err := http.NewRequest(http.MethodGet, "/exports/events?format=json", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.exportEventsHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var events []store.EventExport
+	err = json.Unmarshal(rr.Body.Bytes(), &events)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), events, 2)
+	assert.Len(suite.T(), events[0].Expenses, 2)
+	assert.Empty(suite.T(), events[1].Expenses)
+}
+
+func TestExportsTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportsTestSuite))
+}
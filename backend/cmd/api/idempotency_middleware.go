@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+	maxIdempotencyKeyLen = 255
+)
+
+// idempotencyMiddleware makes POST requests that carry an Idempotency-Key
+// header safe to retry. The first request with a key is handled as usual and
+// its response saved; a retry with the same key and body gets that response
+// replayed instead of being handled again, while reusing the key for a
+// different body is rejected. Server errors are not saved, so a request that
+// failed can be retried with the same key.
+func (app *application) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLen {
+			app.badRequest(w, r, fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLen))
+			return
+		}
+
+		// uploads are the largest bodies, the handlers enforce their own limits
+		maxBytes := max(maxImportSize, app.config.Attachments.MaxSize) + 1<<20
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		user := getAuthenticatedUserFromCtx(r)
+		ctx := r.Context()
+		saved, err := app.store.Idempotency.Begin(ctx, user.ID, key, requestHash(r, body), app.config.IdempotencyKeyTTL, app.config.IdempotencyKeyLease)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrKeyReused):
+				app.unprocessableEntity(w, r, err)
+			case errors.Is(err, store.ErrConflict):
+				app.conflict(w, r, errors.New("a request with this idempotency key is still being processed"))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		if saved != nil {
+			if saved.ContentType != "" {
+				w.Header().Set("Content-Type", saved.ContentType)
+			}
+			w.Header().Set(replayedHeader, "true")
+			w.WriteHeader(saved.StatusCode)
+			w.Write(saved.Body)
+			return
+		}
+
+		// the outcome is saved even when the client has gone away, as that is
+		// exactly when it will retry
+		ctx = context.WithoutCancel(ctx)
+		release := func() {
+			if err := app.store.Idempotency.Release(ctx, user.ID, key); err != nil {
+				log.Printf("releasing idempotency key %q: %s", key, err)
+			}
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		response := store.IdempotentResponse{
+			StatusCode:  rec.status,
+			ContentType: rec.Header().Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		}
+		if err := app.store.Idempotency.Complete(ctx, user.ID, key, response); err != nil {
+			log.Printf("saving response of idempotency key %q: %s", key, err)
+		}
+	})
+}
+
+// requestHash identifies a request by its method, path and body. The
+// boundary of a multipart body is left out because clients pick a new one
+// for every attempt.
+func requestHash(r *http.Request, body []byte) string {
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && params["boundary"] != "" {
+		body = bytes.ReplaceAll(body, []byte(params["boundary"]), nil)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
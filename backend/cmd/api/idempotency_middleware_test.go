@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type idempotencyEntry struct {
+	hash     string
+	response *store.IdempotentResponse
+}
+
+// MockIdempotencyStore keeps keys in memory with the semantics of the real
+// store.
+type MockIdempotencyStore struct {
+	entries map[string]*idempotencyEntry
+}
+
+func (m *MockIdempotencyStore) Begin(ctx context.Context, userID int64, key, requestHash string, ttl, lease time.Duration) (*store.IdempotentResponse, error) {
+	entry, ok := m.entries[key]
+	switch {
+	case !ok:
+		m.entries[key] = &idempotencyEntry{hash: requestHash}
+		return nil, nil
+	case entry.hash != requestHash:
+		return nil, store.ErrKeyReused
+	case entry.response == nil:
+		return nil, store.ErrConflict
+	default:
+		return entry.response, nil
+	}
+}
+
+func (m *MockIdempotencyStore) Complete(ctx context.Context, userID int64, key string, response store.IdempotentResponse) error {
+	m.entries[key].response = &response
+	return nil
+}
+
+func (m *MockIdempotencyStore) Release(ctx context.Context, userID int64, key string) error {
+	delete(m.entries, key)
+	return nil
+}
+
+type IdempotencyTestSuite struct {
+	suite.Suite
+	app     *application
+	keys    *MockIdempotencyStore
+	calls   int
+	status  int
+	handler http.Handler
+}
+
+func (suite *IdempotencyTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr:                "0.0.0.0",
+		Env:                 "test",
+		IdempotencyKeyTTL:   24 * time.Hour,
+		IdempotencyKeyLease: 10 * time.Minute,
+	}
+	suite.keys = &MockIdempotencyStore{entries: map[string]*idempotencyEntry{}}
+	suite.app = &application{config: cfg, store: store.Storage{Idempotency: suite.keys}}
+	suite.calls = 0
+	suite.status = http.StatusCreated
+	suite.handler = suite.app.idempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.calls++
+		body, _ := io.ReadAll(r.Body)
+		writeJSON(w, suite.status, map[string]any{"call": suite.calls, "body": string(body)})
+	}))
+}
+
+func (suite *IdempotencyTestSuite) send(method, key, contentType string, body []byte) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, "/transactions", bytes.NewReader(body))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", contentType)
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), authenticatedUser, &store.User{ID: 1}))
+
+	rr := httptest.NewRecorder()
+	suite.handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func (suite *IdempotencyTestSuite) TestReplaysResponse() {
+	body := []byte(`{"amount":50000}`)
+
+	first := suite.send(http.MethodPost, "key-1", "application/json", body)
+	retry := suite.send(http.MethodPost, "key-1", "application/json", body)
+
+	assert.Equal(suite.T(), 1, suite.calls)
+	assert.Equal(suite.T(), http.StatusCreated, first.Code)
+	assert.Equal(suite.T(), http.StatusCreated, retry.Code)
+	assert.Equal(suite.T(), first.Body.String(), retry.Body.String())
+	assert.Equal(suite.T(), "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(suite.T(), "true", retry.Header().Get(replayedHeader))
+	assert.Empty(suite.T(), first.Header().Get(replayedHeader))
+}
+
+func (suite *IdempotencyTestSuite) TestKeyReusedWithDifferentBody() {
+	suite.send(http.MethodPost, "key-1", "application/json", []byte(`{"amount":50000}`))
+	rr := suite.send(http.MethodPost, "key-1", "application/json", []byte(`{"amount":60000}`))
+
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestKeyStillInProgress() {
+	body := []byte(`{"amount":50000}`)
+	suite.keys.entries["key-1"] = &idempotencyEntry{hash: requestHash(httptest.NewRequest(http.MethodPost, "/transactions", nil), body)}
+
+	rr := suite.send(http.MethodPost, "key-1", "application/json", body)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+	assert.Equal(suite.T(), 0, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestServerErrorIsNotSaved() {
+	body := []byte(`{"amount":50000}`)
+
+	suite.status = http.StatusInternalServerError
+	suite.send(http.MethodPost, "key-1", "application/json", body)
+	assert.Empty(suite.T(), suite.keys.entries)
+
+	suite.status = http.StatusCreated
+	rr := suite.send(http.MethodPost, "key-1", "application/json", body)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	assert.Equal(suite.T(), 2, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestPanicReleasesKey() {
+	suite.handler = suite.app.idempotencyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	assert.Panics(suite.T(), func() {
+		suite.send(http.MethodPost, "key-1", "application/json", []byte(`{}`))
+	})
+	assert.Empty(suite.T(), suite.keys.entries)
+}
+
+func (suite *IdempotencyTestSuite) TestIgnoresMultipartBoundary() {
+	upload := func() (string, []byte) {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", "receipt.csv")
+		part.Write([]byte("date,amount\n2024-03-01,50000\n"))
+		writer.Close()
+		return writer.FormDataContentType(), buf.Bytes()
+	}
+
+	contentType, body := upload()
+	suite.send(http.MethodPost, "key-1", contentType, body)
+	contentType, body = upload()
+	rr := suite.send(http.MethodPost, "key-1", contentType, body)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	assert.Equal(suite.T(), "true", rr.Header().Get(replayedHeader))
+	assert.Equal(suite.T(), 1, suite.calls)
+}
+
+func (suite *IdempotencyTestSuite) TestWithoutKey() {
+	body := []byte(`{"amount":50000}`)
+	suite.send(http.MethodPost, "", "application/json", body)
+	suite.send(http.MethodPost, "", "application/json", body)
+	suite.send(http.MethodPatch, "key-1", "application/json", body)
+	suite.send(http.MethodPatch, "key-1", "application/json", body)
+
+	assert.Equal(suite.T(), 4, suite.calls)
+	assert.Empty(suite.T(), suite.keys.entries)
+}
+
+func (suite *IdempotencyTestSuite) TestKeyTooLong() {
+	rr := suite.send(http.MethodPost, string(bytes.Repeat([]byte("k"), maxIdempotencyKeyLen+1)), "application/json", []byte(`{}`))
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), 0, suite.calls)
+}
+
+func TestIdempotencyTestSuite(t *testing.T) {
+	suite.Run(t, new(IdempotencyTestSuite))
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/journal"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+const maxImportSize = 5 << 20
+
+type CreateImportProfilePayload struct {
+	Name              string `json:"name" validate:"required,max=100"`
+	Delimiter         string `json:"delimiter" validate:"omitempty,max=1"`
+	HasHeader         *bool  `json:"has_header"`
+	SkipRows          int    `json:"skip_rows" validate:"gte=0,lte=100"`
+	DateColumn        string `json:"date_column" validate:"required,max=100"`
+	DateFormat        string `json:"date_format" validate:"required,max=50"`
+	DescriptionColumn string `json:"description_column" validate:"required,max=100"`
+	AmountColumn      string `json:"amount_column" validate:"max=100"`
+	DebitColumn       string `json:"debit_column" validate:"max=100"`
+	CreditColumn      string `json:"credit_column" validate:"max=100"`
+	AmountSign        string `json:"amount_sign" validate:"required,oneof=negative_expense positive_expense debit_credit"`
+	DecimalSeparator  string `json:"decimal_separator" validate:"omitempty,max=1"`
+	ThousandSeparator string `json:"thousand_separator" validate:"omitempty,max=1"`
+}
+
+type UpdateImportProfilePayload struct {
+	Name              *string `json:"name" validate:"omitempty,max=100"`
+	Delimiter         *string `json:"delimiter" validate:"omitempty,max=1"`
+	HasHeader         *bool   `json:"has_header"`
+	SkipRows          *int    `json:"skip_rows" validate:"omitempty,gte=0,lte=100"`
+	DateColumn        *string `json:"date_column" validate:"omitempty,max=100"`
+	DateFormat        *string `json:"date_format" validate:"omitempty,max=50"`
+	DescriptionColumn *string `json:"description_column" validate:"omitempty,max=100"`
+	AmountColumn      *string `json:"amount_column" validate:"omitempty,max=100"`
+	DebitColumn       *string `json:"debit_column" validate:"omitempty,max=100"`
+	CreditColumn      *string `json:"credit_column" validate:"omitempty,max=100"`
+	AmountSign        *string `json:"amount_sign" validate:"omitempty,oneof=negative_expense positive_expense debit_credit"`
+	DecimalSeparator  *string `json:"decimal_separator" validate:"omitempty,max=1"`
+	ThousandSeparator *string `json:"thousand_separator" validate:"omitempty,max=1"`
+}
+
+// ImportResult is the outcome of an import. A dry run only parses the file;
+// otherwise Transactions holds the created transactions. Duplicates counts
+// valid rows that were already booked and are skipped.
+type ImportResult struct {
+	DryRun       bool                 `json:"dry_run"`
+	Format       string               `json:"format"`
+	Total        int                  `json:"total"`
+	Valid        int                  `json:"valid"`
+	Invalid      int                  `json:"invalid"`
+	Duplicates   int                  `json:"duplicates"`
+	Rows         []importer.Row       `json:"rows"`
+	Transactions []*store.Transaction `json:"transactions,omitempty"`
+}
+
+func (app *application) createImportProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateImportProfilePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	profile := &store.ImportProfile{
+		Name:              payload.Name,
+		Delimiter:         payload.Delimiter,
+		HasHeader:         true,
+		SkipRows:          payload.SkipRows,
+		DateColumn:        payload.DateColumn,
+		DateFormat:        payload.DateFormat,
+		DescriptionColumn: payload.DescriptionColumn,
+		AmountColumn:      payload.AmountColumn,
+		DebitColumn:       payload.DebitColumn,
+		CreditColumn:      payload.CreditColumn,
+		AmountSign:        payload.AmountSign,
+		DecimalSeparator:  payload.DecimalSeparator,
+		ThousandSeparator: payload.ThousandSeparator,
+	}
+	if payload.HasHeader != nil {
+		profile.HasHeader = *payload.HasHeader
+	}
+	if profile.Delimiter == "" {
+		profile.Delimiter = ","
+	}
+
+	if err := importer.ValidateProfile(profile); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.ImportProfiles.Create(ctx, profile); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, profile); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) indexImportProfilesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	profiles, err := app.store.ImportProfiles.Index(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, profiles); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getImportProfileHandler(w http.ResponseWriter, r *http.Request) {
+	profile := getImportProfileFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, profile); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateImportProfileHandler(w http.ResponseWriter, r *http.Request) {
+	profile := getImportProfileFromCtx(r)
+
+	var payload UpdateImportProfilePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setString(&profile.Name, payload.Name)
+	setString(&profile.Delimiter, payload.Delimiter)
+	setString(&profile.DateColumn, payload.DateColumn)
+	setString(&profile.DateFormat, payload.DateFormat)
+	setString(&profile.DescriptionColumn, payload.DescriptionColumn)
+	setString(&profile.AmountColumn, payload.AmountColumn)
+	setString(&profile.DebitColumn, payload.DebitColumn)
+	setString(&profile.CreditColumn, payload.CreditColumn)
+	setString(&profile.AmountSign, payload.AmountSign)
+	setString(&profile.DecimalSeparator, payload.DecimalSeparator)
+	setString(&profile.ThousandSeparator, payload.ThousandSeparator)
+	if payload.HasHeader != nil {
+		profile.HasHeader = *payload.HasHeader
+	}
+	if payload.SkipRows != nil {
+		profile.SkipRows = *payload.SkipRows
+	}
+	if profile.Delimiter == "" {
+		profile.Delimiter = ","
+	}
+
+	if err := importer.ValidateProfile(profile); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := app.store.ImportProfiles.Update(r.Context(), profile); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, profile); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteImportProfileHandler(w http.ResponseWriter, r *http.Request) {
+	profile := getImportProfileFromCtx(r)
+
+	ctx := r.Context()
+	if err := app.store.ImportProfiles.Delete(ctx, profile.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createImportHandler parses an uploaded CSV, OFX/QFX or QIF statement, or a
+// beancount or ledger journal. CSV files need a saved profile. Statements are
+// booked on account_id or the default account, while journals name the
+// account of every entry. Categorization rules fill in rows without a
+// category, and rows already booked on their account are flagged as
+// duplicates. With dry_run=true only the parsed rows are returned;
+// otherwise the new rows are booked in one database transaction, and a file
+// with any invalid row is rejected as a whole.
+func (app *application) createImportHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize+1<<20)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	dryRun := false
+	if value := r.FormValue("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid dry_run %q", value))
+			return
+		}
+		dryRun = parsed
+	}
+
+	var accountID *int64
+	if value := r.FormValue("account_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, fmt.Errorf("invalid account_id %q", value))
+			return
+		}
+		accountID = &id
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxImportSize {
+		app.badRequest(w, r, fmt.Errorf("file is larger than %d bytes", maxImportSize))
+		return
+	}
+
+	ctx := r.Context()
+	account, err := app.resolveAccount(ctx, accountID)
+	if err != nil {
+		app.accountResolveError(w, r, err)
+		return
+	}
+
+	format := importFormat(r.FormValue("format"), header.Filename)
+	var rows []importer.Row
+	switch format {
+	case "csv":
+		var profile *store.ImportProfile
+		if profile, err = app.importProfileFromForm(r); err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequest(w, r, errors.New("import profile not found"))
+			case errors.Is(err, errProfileRequired):
+				app.badRequest(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		rows, err = importer.ParseCSV(file, profile, account.Currency)
+	case "ofx", "qfx":
+		rows, err = importer.ParseOFX(file, account.Currency)
+	case "qif":
+		rows, err = importer.ParseQIF(file, r.FormValue("date_format"), account.Currency)
+	case "journal", "beancount", "bean", "ledger", "hledger":
+		var accounts []store.Account
+		var categories []store.Category
+		if accounts, categories, err = app.journalAccounts(ctx); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		rows, err = journal.Import(file, accounts, categories)
+	default:
+		app.badRequest(w, r, fmt.Errorf("unsupported import format %q, expected csv, ofx, qfx, qif or journal", format))
+		return
+	}
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	for i := range rows {
+		if rows[i].AccountID == 0 {
+			rows[i].AccountID = account.ID
+		}
+	}
+	if err := app.assignPayees(ctx, rows); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if err := app.categorizeRows(ctx, rows); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	app.suggestRows(rows)
+	if err := app.markDuplicates(ctx, rows); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	result := ImportResult{DryRun: dryRun, Format: format, Total: len(rows), Rows: rows}
+	for _, row := range rows {
+		switch {
+		case !row.Valid():
+			result.Invalid++
+		case row.Duplicate:
+			result.Valid++
+			result.Duplicates++
+		default:
+			result.Valid++
+		}
+	}
+
+	if dryRun {
+		if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if result.Invalid > 0 || result.Total == 0 {
+		if err := app.jsonResponse(w, http.StatusUnprocessableEntity, result); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	transactions := []*store.Transaction{}
+	for _, row := range rows {
+		if row.Duplicate {
+			continue
+		}
+		transaction := &store.Transaction{
+			AccountID:   row.AccountID,
+			Amount:      row.Amount,
+			Description: row.Description,
+			Kind:        row.Kind,
+			Date:        row.Date.Format(time.RFC3339),
+			Splits:      row.Splits,
+			Tags:        tagsFromIDs(row.TagIDs),
+		}
+		if row.CategoryID != nil {
+			transaction.CategoryID = sql.NullInt64{Int64: *row.CategoryID, Valid: true}
+		}
+		if row.PayeeID != nil {
+			transaction.PayeeID = sql.NullInt64{Int64: *row.PayeeID, Valid: true}
+		}
+		if row.ExternalID != "" {
+			transaction.ExternalID = sql.NullString{String: row.ExternalID, Valid: true}
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	// re-importing a statement that is already fully booked changes nothing
+	status := http.StatusOK
+	if len(transactions) > 0 {
+		if err := app.store.Transactions.CreateBatch(ctx, transactions); err != nil {
+			switch {
+			// a category or payee picked for a row was deleted meanwhile
+			case errors.Is(err, store.ErrInvalidReference):
+				app.badRequest(w, r, err)
+			case errors.Is(err, store.ErrConflict), errors.Is(err, store.ErrLocked):
+				app.conflict(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		status = http.StatusCreated
+	}
+	for _, transaction := range transactions {
+		app.learnTransaction(transaction)
+	}
+	result.Transactions = transactions
+
+	if err := app.jsonResponse(w, status, result); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// journalAccounts loads the accounts and categories that journal account
+// names refer to.
+func (app *application) journalAccounts(ctx context.Context) ([]store.Account, []store.Category, error) {
+	accounts, err := app.store.Accounts.Index(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	categories, err := app.store.Categories.Index(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return accounts, categories, nil
+}
+
+// markDuplicates flags rows already booked, comparing the rows of each
+// account with that account's transactions.
+func (app *application) markDuplicates(ctx context.Context, rows []importer.Row) error {
+	byAccount := map[int64][]int{}
+	accountIDs := []int64{}
+	for i, row := range rows {
+		if _, ok := byAccount[row.AccountID]; !ok {
+			accountIDs = append(accountIDs, row.AccountID)
+		}
+		byAccount[row.AccountID] = append(byAccount[row.AccountID], i)
+	}
+
+	for _, accountID := range accountIDs {
+		indexes := byAccount[accountID]
+		group := make([]importer.Row, len(indexes))
+		for i, index := range indexes {
+			group[i] = rows[index]
+		}
+
+		from, to, ok := importer.DateRange(group)
+		if !ok {
+			continue
+		}
+		existing, err := app.store.Transactions.GetFingerprints(ctx, accountID, from, to, importer.ExternalIDs(group))
+		if err != nil {
+			return err
+		}
+		importer.MarkDuplicates(group, existing)
+
+		for i, index := range indexes {
+			rows[index] = group[i]
+		}
+	}
+
+	return nil
+}
+
+var errProfileRequired = errors.New("profile_id is required for csv imports")
+
+func (app *application) importProfileFromForm(r *http.Request) (*store.ImportProfile, error) {
+	profileID, err := strconv.ParseInt(r.FormValue("profile_id"), 10, 64)
+	if err != nil {
+		return nil, errProfileRequired
+	}
+
+	return app.store.ImportProfiles.GetByID(r.Context(), profileID)
+}
+
+// importFormat returns the requested format, falling back to the extension
+// of the uploaded file name.
+func importFormat(format, fileName string) string {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(fileName), ".")
+	}
+	return strings.ToLower(format)
+}
+
+func (app *application) importProfileContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "profileID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		profile, err := app.store.ImportProfiles.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, importProfileCtx, profile)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getImportProfileFromCtx(r *http.Request) *store.ImportProfile {
+	profile, _ := r.Context().Value(importProfileCtx).(*store.ImportProfile)
+	return profile
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockImportProfileStore struct {
+	profiles []store.ImportProfile
+	profile  *store.ImportProfile
+	err      error
+}
+
+func (m *MockImportProfileStore) Create(ctx context.Context, profile *store.ImportProfile) error {
+	if m.err != nil {
+		return m.err
+	}
+	profile.ID = 1
+	return nil
+}
+
+func (m *MockImportProfileStore) Index(ctx context.Context) ([]store.ImportProfile, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.profiles, nil
+}
+
+func (m *MockImportProfileStore) GetByID(ctx context.Context, id int64) (*store.ImportProfile, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.profile == nil {
+		return nil, store.ErrNotFound
+	}
+	return m.profile, nil
+}
+
+func (m *MockImportProfileStore) Update(ctx context.Context, profile *store.ImportProfile) error {
+	return m.err
+}
+
+func (m *MockImportProfileStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+type ImportsTestSuite struct {
+	suite.Suite
+	app          *application
+	transactions *MockTransactionStore
+}
+
+func (suite *ImportsTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.transactions = &MockTransactionStore{}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Transactions: suite.transactions,
+		Accounts: &MockAccountStore{
+			account:  &store.Account{ID: 2, Name: "BCA", Currency: "IDR"},
+			accounts: []store.Account{{ID: 2, Name: "BCA", Currency: "IDR"}, {ID: 3, Name: "GoPay", Currency: "IDR"}},
+		},
+		Categories:          &MockCategoryStore{categories: []store.Category{{ID: 5, Name: "Groceries", Kind: store.KindExpense}}},
+		CategorizationRules: &MockCategorizationRuleStore{},
+		Payees:              &MockPayeeStore{},
+		ImportProfiles: &MockImportProfileStore{profile: &store.ImportProfile{
+			ID:                1,
+			Name:              "BCA",
+			HasHeader:         true,
+			DateColumn:        "Date",
+			DateFormat:        "DD/MM/YYYY",
+			DescriptionColumn: "Description",
+			AmountColumn:      "Amount",
+			AmountSign:        store.AmountSignNegativeExpense,
+			DecimalSeparator:  ",",
+			ThousandSeparator: ".",
+		}},
+	}}
+}
+
+func newImportRequest(fileName string, fields map[string]string, content string) (*http.Request, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/imports", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req, nil
+}
+
+const importFile = "Date,Description,Amount\n" +
+	"02/01/2024,Alfamart,\"-58.900\"\n" +
+	"25/01/2024,Salary,\"15.000.000\"\n"
+
+func (suite *ImportsTestSuite) TestCreateImport_DryRun() {
+	req, err := newImportRequest("mutasi.csv", map[string]string{"profile_id": "1", "dry_run": "true"}, importFile+"31/02/2024,Typo,-1.000\n")
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data ImportResult `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), response.Data.DryRun)
+	assert.Equal(suite.T(), 3, response.Data.Total)
+	assert.Equal(suite.T(), 2, response.Data.Valid)
+	assert.Equal(suite.T(), 1, response.Data.Invalid)
+	assert.NotEmpty(suite.T(), response.Data.Rows[2].Errors)
+	assert.Empty(suite.T(), suite.transactions.transactionList)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_Commit() {
+	req, err := newImportRequest("mutasi.csv", map[string]string{"profile_id": "1"}, importFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if !assert.Len(suite.T(), suite.transactions.transactionList, 2) {
+		return
+	}
+
+	expense := suite.transactions.transactionList[0]
+	assert.Equal(suite.T(), int64(2), expense.AccountID)
+	assert.Equal(suite.T(), int64(58900), expense.Amount)
+	assert.Equal(suite.T(), store.KindExpense, expense.Kind)
+
+	income := suite.transactions.transactionList[1]
+	assert.Equal(suite.T(), int64(-15000000), income.Amount)
+	assert.Equal(suite.T(), store.KindIncome, income.Kind)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_AssignsPayees() {
+	suite.app.store.Payees = &MockPayeeStore{payees: []store.Payee{{ID: 4, Name: "Alfamart"}}}
+	req, err := newImportRequest("mutasi.csv", map[string]string{"profile_id": "1"}, importFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if !assert.Len(suite.T(), suite.transactions.transactionList, 2) {
+		return
+	}
+	assert.Equal(suite.T(), sql.NullInt64{Int64: 4, Valid: true}, suite.transactions.transactionList[0].PayeeID)
+	assert.False(suite.T(), suite.transactions.transactionList[1].PayeeID.Valid)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_DeletedCategory() {
+	// the category picked for a row went to the trash before the commit
+	suite.transactions.createBatchErr = store.ErrInvalidReference
+	req, err := newImportRequest("mutasi.csv", map[string]string{"profile_id": "1"}, importFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_CommitRejectsInvalidRows() {
+	req, err := newImportRequest("mutasi.csv", map[string]string{"profile_id": "1"}, importFile+"03/01/2024,,-1.000\n")
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, rr.Code)
+	assert.Empty(suite.T(), suite.transactions.transactionList)
+
+	var response struct {
+		Data ImportResult `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, response.Data.Invalid)
+	assert.Equal(suite.T(), []string{"description is missing"}, response.Data.Rows[2].Errors)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_UnknownProfile() {
+	suite.app.store.ImportProfiles = &MockImportProfileStore{}
+
+	req, err := newImportRequest("mutasi.csv", map[string]string{"profile_id": "9"}, importFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_CSVNeedsProfile() {
+	req, err := newImportRequest("mutasi.csv", map[string]string{}, importFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+const ofxFile = `OFXHEADER:100
+
+<OFX>
+<BANKTRANLIST>
+<STMTTRN>
+<DTPOSTED>20240102
+<TRNAMT>-58900
+<FITID>F1
+<NAME>Alfamart
+</STMTTRN>
+<STMTTRN>
+<DTPOSTED>20240125
+<TRNAMT>15000000
+<FITID>F2
+<NAME>Salary
+</STMTTRN>
+</BANKTRANLIST>
+</OFX>
+`
+
+func (suite *ImportsTestSuite) TestCreateImport_OFXSkipsDuplicates() {
+	suite.transactions.fingerprints = []store.TransactionFingerprint{
+		{ID: 7, ExternalID: "F1", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: 58900, Description: "Alfamart"},
+	}
+
+	req, err := newImportRequest("statement.OFX", map[string]string{"account_id": "2"}, ofxFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data ImportResult `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "ofx", response.Data.Format)
+	assert.Equal(suite.T(), 1, response.Data.Duplicates)
+	assert.Equal(suite.T(), int64(7), response.Data.Rows[0].DuplicateOf)
+
+	if !assert.Len(suite.T(), suite.transactions.transactionList, 1) {
+		return
+	}
+	assert.Equal(suite.T(), "F2", suite.transactions.transactionList[0].ExternalID.String)
+	assert.Equal(suite.T(), int64(-15000000), suite.transactions.transactionList[0].Amount)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_AppliesRules() {
+	groceries := int64(5)
+	suite.app.store.CategorizationRules = &MockCategorizationRuleStore{rules: []store.CategorizationRule{
+		{ID: 1, DescriptionContains: "alfamart", CategoryID: &groceries, TagIDs: []int64{3}, RenameTo: "Alfamart groceries"},
+	}}
+
+	req, err := newImportRequest("statement.ofx", map[string]string{}, ofxFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	transactions := suite.transactions.transactionList
+	if !assert.Len(suite.T(), transactions, 2) {
+		return
+	}
+	assert.Equal(suite.T(), "Alfamart groceries", transactions[0].Description)
+	assert.Equal(suite.T(), groceries, transactions[0].CategoryID.Int64)
+	assert.Equal(suite.T(), []store.Tag{{ID: 3}}, transactions[0].Tags)
+	assert.False(suite.T(), transactions[1].CategoryID.Valid)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_AllDuplicates() {
+	suite.transactions.fingerprints = []store.TransactionFingerprint{
+		{ID: 7, ExternalID: "F1"},
+		{ID: 8, ExternalID: "F2"},
+	}
+
+	req, err := newImportRequest("statement.qfx", map[string]string{}, ofxFile)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Empty(suite.T(), suite.transactions.transactionList)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_Journal() {
+	file := "2024-01-05 * \"Superindo\"\n" +
+		"  external_id: \"S-1\"\n" +
+		"  Assets:BCA         -250000 IDR\n" +
+		"  Expenses:Groceries  250000 IDR\n" +
+		"\n" +
+		"2024-01-06 * \"Top up\"\n" +
+		"  Assets:GoPay        100000 IDR\n" +
+		"  Equity:Transfers\n"
+
+	req, err := newImportRequest("ledger.beancount", map[string]string{}, file)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	transactions := suite.transactions.transactionList
+	if !assert.Len(suite.T(), transactions, 2) {
+		return
+	}
+	assert.Equal(suite.T(), int64(2), transactions[0].AccountID)
+	assert.Equal(suite.T(), int64(250000), transactions[0].Amount)
+	assert.Equal(suite.T(), int64(5), transactions[0].CategoryID.Int64)
+	assert.Equal(suite.T(), "S-1", transactions[0].ExternalID.String)
+	assert.Equal(suite.T(), int64(3), transactions[1].AccountID)
+	assert.Equal(suite.T(), int64(-100000), transactions[1].Amount)
+	assert.Equal(suite.T(), store.KindTransfer, transactions[1].Kind)
+}
+
+func (suite *ImportsTestSuite) TestCreateImport_UnsupportedFormat() {
+	req, err := newImportRequest("statement.pdf", map[string]string{}, "%PDF-1.4")
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *ImportsTestSuite) TestCreateImportProfileHandler_InvalidMapping() {
+	for _, body := range []string{
+		`{"name": "GoPay", "date_column": "Date", "date_format": "YYYY-MM-DD", "description_column": "Note", "amount_sign": "debit_credit", "debit_column": "Out"}`,
+		`{"name": "GoPay", "date_column": "Date", "date_format": "YYYY-MM-DD", "description_column": "Note", "amount_column": "Amount", "amount_sign": "reversed"}`,
+		`{"name": "GoPay", "has_header": false, "date_column": "Date", "date_format": "YYYY-MM-DD", "description_column": "2", "amount_column": "3", "amount_sign": "positive_expense"}`,
+	} {
+		req, err := http.NewRequest(http.MethodPost, "/import_profiles", bytes.NewReader([]byte(body)))
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.createImportProfileHandler(rr, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func (suite *ImportsTestSuite) TestCreateImportProfileHandler_Success() {
+	body := `{"name": "GoPay", "date_column": "Date", "date_format": "YYYY-MM-DD HH:mm", "description_column": "Note", "amount_column": "Amount", "amount_sign": "positive_expense"}`
+	req, err := http.NewRequest(http.MethodPost, "/import_profiles", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createImportProfileHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data store.ImportProfile `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), response.Data.ID)
+	assert.True(suite.T(), response.Data.HasHeader)
+	assert.Equal(suite.T(), ",", response.Data.Delimiter)
+}
+
+func TestImportsTestSuite(t *testing.T) {
+	suite.Run(t, new(ImportsTestSuite))
+}
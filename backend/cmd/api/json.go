@@ -71,3 +71,14 @@ func (app *application) jsonResponse(w http.ResponseWriter, status int, data any
 
 	return writeJSON(w, status, &envelope{Data: data})
 }
+
+// paginatedJSONResponse wraps a page of results together with the opaque
+// cursor of the next page, which is null on the last page.
+func (app *application) paginatedJSONResponse(w http.ResponseWriter, status int, data any, nextCursor *string) error {
+	type envelope struct {
+		Data       any     `json:"data"`
+		NextCursor *string `json:"next_cursor"`
+	}
+
+	return writeJSON(w, status, &envelope{Data: data, NextCursor: nextCursor})
+}
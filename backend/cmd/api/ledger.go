@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+)
+
+// checkLedgerHandler reports the transactions whose stored running balance
+// disagrees with the recomputed one, without changing anything.
+func (app *application) checkLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	app.ledgerReport(w, r, false)
+}
+
+// fixLedgerHandler rewrites the drifting running balances in one database
+// transaction and reports the corrections made, which the audit log
+// attributes to the admin making the request.
+func (app *application) fixLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	app.ledgerReport(w, r, true)
+}
+
+func (app *application) ledgerReport(w http.ResponseWriter, r *http.Request, fix bool) {
+	report, err := app.store.Ledger.Check(r.Context(), fix)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, report); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockLedgerStore struct {
+	report *store.LedgerReport
+	fixed  bool
+	err    error
+}
+
+func (m *MockLedgerStore) Check(ctx context.Context, fix bool) (*store.LedgerReport, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.fixed = fix
+	report := *m.report
+	report.Fixed = fix
+	return &report, nil
+}
+
+type LedgerTestSuite struct {
+	suite.Suite
+	app    *application
+	ledger *MockLedgerStore
+}
+
+func (suite *LedgerTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.ledger = &MockLedgerStore{report: &store.LedgerReport{
+		Checked: 42,
+		Drifts: []store.LedgerDrift{
+			{TransactionID: 7, AccountID: 1, Date: "2023-01-05T00:00:00Z", Stored: 100, Expected: 120},
+		},
+		Accounts: []store.LedgerAccountSummary{
+			{AccountID: 1, Drifts: 1, From: "2023-01-05T00:00:00Z", LargestDifference: 20},
+		},
+	}}
+	suite.app = &application{config: cfg, store: store.Storage{Ledger: suite.ledger}}
+}
+
+func (suite *LedgerTestSuite) report(method string, handler http.HandlerFunc) (*httptest.ResponseRecorder, store.LedgerReport) {
+	req, err := http.NewRequest(method, "/admin/ledger", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+
+	var response struct {
+		Data store.LedgerReport `json:"data"`
+	}
+	if rr.Code == http.StatusOK {
+		assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &response))
+	}
+	return rr, response.Data
+}
+
+func (suite *LedgerTestSuite) TestCheckLedger() {
+	rr, report := suite.report(http.MethodGet, suite.app.checkLedgerHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.False(suite.T(), suite.ledger.fixed)
+	assert.False(suite.T(), report.Fixed)
+	assert.Equal(suite.T(), int64(42), report.Checked)
+	assert.Len(suite.T(), report.Drifts, 1)
+	assert.Len(suite.T(), report.Accounts, 1)
+}
+
+func (suite *LedgerTestSuite) TestFixLedger() {
+	rr, report := suite.report(http.MethodPost, suite.app.fixLedgerHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.True(suite.T(), suite.ledger.fixed)
+	assert.True(suite.T(), report.Fixed)
+}
+
+func (suite *LedgerTestSuite) TestCheckLedger_Error() {
+	suite.ledger.err = errors.New("connection refused")
+	rr, _ := suite.report(http.MethodGet, suite.app.checkLedgerHandler)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, rr.Code)
+}
+
+func (suite *LedgerTestSuite) TestAdminMiddleware() {
+	suite.app.config.AdminEmails = []string{"Admin@example.com"}
+	handler := suite.app.adminMiddleware(http.HandlerFunc(suite.app.checkLedgerHandler))
+
+	tests := []struct {
+		name     string
+		user     *store.User
+		expected int
+	}{
+		{"admin", &store.User{ID: 1, Email: "admin@example.com"}, http.StatusOK},
+		{"other user", &store.User{ID: 2, Email: "user@example.com"}, http.StatusForbidden},
+		{"no user", nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			req, err := http.NewRequest(http.MethodGet, "/admin/ledger", nil)
+			assert.NoError(suite.T(), err)
+			if tt.user != nil {
+				req = req.WithContext(context.WithValue(req.Context(), authenticatedUser, tt.user))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(suite.T(), tt.expected, rr.Code)
+		})
+	}
+}
+
+func (suite *LedgerTestSuite) TestAdminMiddleware_NoAdmins() {
+	handler := suite.app.adminMiddleware(http.HandlerFunc(suite.app.checkLedgerHandler))
+
+	req, err := http.NewRequest(http.MethodGet, "/admin/ledger", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), authenticatedUser, &store.User{ID: 1, Email: "admin@example.com"}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(suite.T(), http.StatusForbidden, rr.Code)
+}
+
+func TestLedgerTestSuite(t *testing.T) {
+	suite.Run(t, new(LedgerTestSuite))
+}
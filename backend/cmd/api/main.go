@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
+	// quick entry dates are read in a named zone, which the runtime image
+	// does not ship the database for
+	_ "time/tzdata"
 
 	_ "github.com/lib/pq"
 	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/blob"
 	"github.com/pukuri/expenses/backend/internal/db"
+	"github.com/pukuri/expenses/backend/internal/recurring"
 	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/pukuri/expenses/backend/internal/suggest"
+	"github.com/pukuri/expenses/backend/internal/trash"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
@@ -19,6 +27,8 @@ func main() {
 		log.Fatal(err)
 	}
 
+	store.BaseCurrency = cfg.BaseCurrency
+
 	db, err := db.New(cfg)
 	if err != nil {
 		log.Panic(err)
@@ -26,6 +36,11 @@ func main() {
 	defer db.Close()
 	log.Println("database connection pool established")
 
+	blobs, err := blob.NewLocalStore(cfg.Attachments.Dir)
+	if err != nil {
+		log.Panic(err)
+	}
+
 	oauthConfig := &oauth2.Config{
 		ClientID:     cfg.Google.ClientID,
 		ClientSecret: cfg.Google.ClientSecret,
@@ -40,9 +55,16 @@ func main() {
 	app := &application{
 		config:      cfg,
 		store:       store.NewStorage(db),
+		blobs:       blobs,
 		oauthConfig: oauthConfig,
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go recurring.NewWorker(app.store, cfg.RecurringInterval).Run(ctx)
+	go suggest.NewTrainer(app.store, &app.suggestions, cfg.SuggestRetrainInterval).Run(ctx)
+	go trash.NewPurger(app.store, app.blobs, cfg.TrashRetention, cfg.TrashPurgeInterval).Run(ctx)
+
 	mux := app.mount()
 	log.Fatal(app.run(mux))
 }
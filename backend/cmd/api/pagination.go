@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+type cursorPayload struct {
+	Date time.Time `json:"d"`
+	ID   int64     `json:"i"`
+}
+
+// encodeCursor turns a store cursor into an opaque token for clients. It
+// returns nil when there is no next page.
+func encodeCursor(cursor *store.TransactionCursor) *string {
+	if cursor == nil {
+		return nil
+	}
+
+	b, _ := json.Marshal(cursorPayload{Date: cursor.Date, ID: cursor.ID})
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return &token
+}
+
+func decodeCursor(token string) (*store.TransactionCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(b, &payload); err != nil || payload.ID == 0 {
+		return nil, errInvalidCursor
+	}
+
+	return &store.TransactionCursor{Date: payload.Date, ID: payload.ID}, nil
+}
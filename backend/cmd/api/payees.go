@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/payee"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+type CreatePayeePayload struct {
+	Name    string   `json:"name" validate:"required,max=255"`
+	Aliases []string `json:"aliases" validate:"max=50,dive,required,max=255"`
+}
+
+type UpdatePayeePayload struct {
+	Name    *string   `json:"name" validate:"omitempty,min=1,max=255"`
+	Aliases *[]string `json:"aliases" validate:"omitempty,max=50,dive,required,max=255"`
+}
+
+// MergePayeesPayload names the payees merged into the payee of the request.
+type MergePayeesPayload struct {
+	PayeeIDs []int64 `json:"payee_ids" validate:"required,min=1,max=50"`
+}
+
+var errPayeeTaken = errors.New("the payee name or one of its aliases is already taken")
+
+func (app *application) createPayeeHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreatePayeePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	payee := &store.Payee{
+		Name:    payload.Name,
+		Aliases: normalizeAliases(payload.Aliases),
+	}
+
+	if err := app.store.Payees.Create(r.Context(), payee); err != nil {
+		app.payeeError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, payee); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) indexPayeesHandler(w http.ResponseWriter, r *http.Request) {
+	payees, err := app.store.Payees.Index(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, payees); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getPayeeHandler(w http.ResponseWriter, r *http.Request) {
+	payee := getPayeeFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, payee); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// updatePayeeHandler renames the payee and, when aliases are given, replaces
+// all of its aliases.
+func (app *application) updatePayeeHandler(w http.ResponseWriter, r *http.Request) {
+	payee := getPayeeFromCtx(r)
+
+	var payload UpdatePayeePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		payee.Name = *payload.Name
+	}
+	if payload.Aliases != nil {
+		payee.Aliases = normalizeAliases(*payload.Aliases)
+	}
+
+	if err := app.store.Payees.Update(r.Context(), payee); err != nil {
+		app.payeeError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, payee); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// deletePayeeHandler deletes the payee. Its transactions are kept without a
+// payee, which is refused while any of them is reconciled.
+func (app *application) deletePayeeHandler(w http.ResponseWriter, r *http.Request) {
+	payee := getPayeeFromCtx(r)
+
+	if err := app.store.Payees.Delete(r.Context(), payee.ID); err != nil {
+		app.payeeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mergePayeesHandler folds duplicate payees into the payee of the request:
+// their transactions move over, and their names and aliases become its
+// aliases so that future imports match it. Payees with reconciled
+// transactions cannot be merged away.
+func (app *application) mergePayeesHandler(w http.ResponseWriter, r *http.Request) {
+	target := getPayeeFromCtx(r)
+
+	var payload MergePayeesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if slices.Contains(payload.PayeeIDs, target.ID) {
+		app.badRequest(w, r, errors.New("a payee cannot be merged into itself"))
+		return
+	}
+
+	ctx := r.Context()
+	aliases := target.Aliases
+	for _, id := range payload.PayeeIDs {
+		source, err := app.store.Payees.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequest(w, r, fmt.Errorf("payee %d does not exist", id))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+		aliases = append(aliases, source.Name)
+		aliases = append(aliases, source.Aliases...)
+	}
+	target.Aliases = normalizeAliases(aliases)
+
+	if err := app.store.Payees.Merge(ctx, target, payload.PayeeIDs); err != nil {
+		app.payeeError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, target); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// topPayeesHandler reports the payees with the largest totals. It takes the
+// optional query parameters from and to (inclusive, YYYY-MM-DD), kind
+// (defaulting to expenses) and limit.
+func (app *application) topPayeesHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parsePayeeReportFilter(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	totals, err := app.store.Payees.Top(r.Context(), filter)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, totals); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func parsePayeeReportFilter(r *http.Request) (store.PayeeReportFilter, error) {
+	query := r.URL.Query()
+	filter := store.PayeeReportFilter{
+		From:  query.Get("from"),
+		To:    query.Get("to"),
+		Limit: store.DefaultPayeeReportLimit,
+	}
+
+	for param, value := range map[string]string{"from": filter.From, "to": filter.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return filter, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", param, value)
+		}
+	}
+	if filter.From != "" && filter.To != "" && filter.From > filter.To {
+		return filter, errors.New("from must not be after to")
+	}
+
+	kind, err := kindFromQuery(r)
+	if err != nil {
+		return filter, err
+	}
+	filter.Kind = kind
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > store.MaxPayeeReportLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", store.MaxPayeeReportLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// normalizeAliases normalizes the aliases the way descriptions are matched,
+// dropping those left empty and duplicates.
+func normalizeAliases(aliases []string) []string {
+	normalized := []string{}
+	for _, alias := range aliases {
+		alias = payee.Normalize(alias)
+		if alias != "" && !slices.Contains(normalized, alias) {
+			normalized = append(normalized, alias)
+		}
+	}
+	return normalized
+}
+
+// assignPayees sets the payee of imported rows from the aliases of the known
+// payees. It runs before the rules rename the rows, as aliases are taken from
+// the descriptions banks print.
+func (app *application) assignPayees(ctx context.Context, rows []importer.Row) error {
+	payees, err := app.store.Payees.Index(ctx)
+	if err != nil {
+		return err
+	}
+
+	matcher := payee.New(payees)
+	for i := range rows {
+		if rows[i].Valid() && rows[i].PayeeID == nil {
+			rows[i].PayeeID = matcher.Match(rows[i].Description)
+		}
+	}
+
+	return nil
+}
+
+func (app *application) payeeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		app.conflict(w, r, errPayeeTaken)
+	case errors.Is(err, store.ErrNotFound):
+		app.notFound(w, r, err)
+	case errors.Is(err, store.ErrLocked):
+		app.conflict(w, r, errors.New("the payee has reconciled transactions"))
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) payeeContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "payeeID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		payee, err := app.store.Payees.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, payeeCtx, payee)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getPayeeFromCtx(r *http.Request) *store.Payee {
+	payee, _ := r.Context().Value(payeeCtx).(*store.Payee)
+	return payee
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockPayeeStore struct {
+	payees    []store.Payee
+	created   *store.Payee
+	updated   *store.Payee
+	mergedIDs []int64
+	filter    store.PayeeReportFilter
+	err       error
+}
+
+func (m *MockPayeeStore) Create(ctx context.Context, payee *store.Payee) error {
+	if m.err != nil {
+		return m.err
+	}
+	payee.ID = 1
+	m.created = payee
+	return nil
+}
+
+func (m *MockPayeeStore) Index(ctx context.Context) ([]store.Payee, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.payees, nil
+}
+
+func (m *MockPayeeStore) GetByID(ctx context.Context, id int64) (*store.Payee, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, payee := range m.payees {
+		if payee.ID == id {
+			return &payee, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockPayeeStore) Update(ctx context.Context, payee *store.Payee) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.updated = payee
+	return nil
+}
+
+func (m *MockPayeeStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+func (m *MockPayeeStore) Merge(ctx context.Context, target *store.Payee, sourceIDs []int64) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.updated = target
+	m.mergedIDs = sourceIDs
+	return nil
+}
+
+func (m *MockPayeeStore) Top(ctx context.Context, filter store.PayeeReportFilter) ([]store.PayeeTotal, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.filter = filter
+	return []store.PayeeTotal{{ID: 1, Name: "Indomaret", Transactions: 3, Amount: 150000}}, nil
+}
+
+type PayeesTestSuite struct {
+	suite.Suite
+	app    *application
+	payees *MockPayeeStore
+	payee  *store.Payee
+}
+
+func (suite *PayeesTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.payee = &store.Payee{ID: 1, Name: "Indomaret", Aliases: []string{"idm"}}
+	suite.payees = &MockPayeeStore{payees: []store.Payee{
+		*suite.payee,
+		{ID: 2, Name: "Indomaret Point", Aliases: []string{"indomaret pt"}},
+		{ID: 3, Name: "INDOMARET KEMANG"},
+	}}
+	suite.app = &application{config: cfg, store: store.Storage{Payees: suite.payees}}
+}
+
+func (suite *PayeesTestSuite) request(method, target, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), payeeCtx, suite.payee))
+
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	return rr
+}
+
+func (suite *PayeesTestSuite) TestCreatePayee() {
+	rr := suite.request(http.MethodPost, "/payees", `{"name": "Starbucks", "aliases": ["SBUX*", "Starbucks Coffee", "sbux", " - "]}`, suite.app.createPayeeHandler)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if assert.NotNil(suite.T(), suite.payees.created) {
+		assert.Equal(suite.T(), "Starbucks", suite.payees.created.Name)
+		assert.Equal(suite.T(), []string{"sbux", "starbucks coffee"}, suite.payees.created.Aliases)
+	}
+}
+
+func (suite *PayeesTestSuite) TestCreatePayee_Invalid() {
+	for _, body := range []string{
+		`{"aliases": ["sbux"]}`,
+		`{"name": "Starbucks", "aliases": [""]}`,
+	} {
+		rr := suite.request(http.MethodPost, "/payees", body, suite.app.createPayeeHandler)
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+	assert.Nil(suite.T(), suite.payees.created)
+
+	suite.payees.err = store.ErrConflict
+	rr := suite.request(http.MethodPost, "/payees", `{"name": "Indomaret"}`, suite.app.createPayeeHandler)
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *PayeesTestSuite) TestUpdatePayee() {
+	rr := suite.request(http.MethodPatch, "/payees/1", `{"aliases": ["IDM", "Indomaret-Kemang"]}`, suite.app.updatePayeeHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	if assert.NotNil(suite.T(), suite.payees.updated) {
+		assert.Equal(suite.T(), "Indomaret", suite.payees.updated.Name)
+		assert.Equal(suite.T(), []string{"idm", "indomaret kemang"}, suite.payees.updated.Aliases)
+	}
+
+	rr = suite.request(http.MethodPatch, "/payees/1", `{"name": ""}`, suite.app.updatePayeeHandler)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *PayeesTestSuite) TestMergePayees() {
+	rr := suite.request(http.MethodPost, "/payees/1/merge", `{"payee_ids": [2, 3]}`, suite.app.mergePayeesHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), []int64{2, 3}, suite.payees.mergedIDs)
+	if assert.NotNil(suite.T(), suite.payees.updated) {
+		assert.Equal(suite.T(), []string{"idm", "indomaret point", "indomaret pt", "indomaret kemang"}, suite.payees.updated.Aliases)
+	}
+}
+
+func (suite *PayeesTestSuite) TestMergePayees_Invalid() {
+	for _, body := range []string{
+		`{"payee_ids": []}`,
+		`{"payee_ids": [1, 2]}`,
+		`{"payee_ids": [9]}`,
+	} {
+		rr := suite.request(http.MethodPost, "/payees/1/merge", body, suite.app.mergePayeesHandler)
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+	assert.Nil(suite.T(), suite.payees.mergedIDs)
+}
+
+func (suite *PayeesTestSuite) TestDeletePayee_Locked() {
+	suite.payees.err = store.ErrLocked
+	rr := suite.request(http.MethodDelete, "/payees/1", "", suite.app.deletePayeeHandler)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *PayeesTestSuite) TestTopPayees() {
+	rr := suite.request(http.MethodGet, "/payees/top?from=2024-01-01&to=2024-03-31&kind=income&limit=5", "", suite.app.topPayeesHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), store.PayeeReportFilter{From: "2024-01-01", To: "2024-03-31", Kind: store.KindIncome, Limit: 5}, suite.payees.filter)
+
+	rr = suite.request(http.MethodGet, "/payees/top", "", suite.app.topPayeesHandler)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), store.PayeeReportFilter{Kind: store.KindExpense, Limit: store.DefaultPayeeReportLimit}, suite.payees.filter)
+}
+
+func (suite *PayeesTestSuite) TestTopPayees_Invalid() {
+	for _, query := range []string{
+		"?from=01/01/2024",
+		"?from=2024-03-01&to=2024-01-31",
+		"?kind=gift",
+		"?limit=0",
+		"?limit=101",
+	} {
+		rr := suite.request(http.MethodGet, "/payees/top"+query, "", suite.app.topPayeesHandler)
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestPayeesTestSuite(t *testing.T) {
+	suite.Run(t, new(PayeesTestSuite))
+}
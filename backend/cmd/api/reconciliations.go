@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+const reconciliationAdjustmentDescription = "Reconciliation adjustment"
+
+type CreateReconciliationPayload struct {
+	AccountID        *int64 `json:"account_id" validate:"required"`
+	StatementDate    string `json:"statement_date" validate:"required,datetime=2006-01-02"`
+	StatementBalance int64  `json:"statement_balance"`
+}
+
+type ClearTransactionsPayload struct {
+	TransactionIDs []int64 `json:"transaction_ids" validate:"required,min=1,max=500"`
+	Cleared        bool    `json:"cleared"`
+}
+
+// CompleteReconciliationPayload completes a reconciliation. Adjust books a
+// balance adjustment for a remaining difference instead of refusing to
+// complete.
+type CompleteReconciliationPayload struct {
+	Adjust bool `json:"adjust"`
+}
+
+// ReconciliationResponse is a reconciliation together with where the ledger
+// stands at the statement date. Balance is the ledger balance, ClearedBalance
+// counts only the cleared and reconciled transactions, and Difference is what
+// the statement balance is above the ledger balance.
+type ReconciliationResponse struct {
+	*store.Reconciliation
+	Balance        int64                      `json:"balance"`
+	ClearedBalance int64                      `json:"cleared_balance"`
+	Difference     int64                      `json:"difference"`
+	Transactions   []store.ReconciliationItem `json:"transactions"`
+}
+
+// createReconciliationHandler starts reconciling an account against a
+// statement. Only accounts in BaseCurrency can be reconciled, as ledger
+// balances are compared in BaseCurrency.
+func (app *application) createReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateReconciliationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	account, err := app.resolveAccount(ctx, payload.AccountID)
+	if err != nil {
+		app.accountResolveError(w, r, err)
+		return
+	}
+	if account.Currency != store.BaseCurrency {
+		app.badRequest(w, r, fmt.Errorf("only accounts in %s can be reconciled", store.BaseCurrency))
+		return
+	}
+
+	reconciliation := &store.Reconciliation{
+		AccountID:        account.ID,
+		StatementDate:    payload.StatementDate,
+		StatementBalance: payload.StatementBalance,
+	}
+	if err := app.store.Reconciliations.Create(ctx, reconciliation); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, errors.New("the account already has an open reconciliation"))
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	app.reconciliationResponse(w, r, http.StatusCreated, reconciliation)
+}
+
+func (app *application) indexReconciliationsHandler(w http.ResponseWriter, r *http.Request) {
+	var accountID int64
+	if param := r.URL.Query().Get("account_id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		accountID = id
+	}
+
+	reconciliations, err := app.store.Reconciliations.Index(r.Context(), accountID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, reconciliations); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	app.reconciliationResponse(w, r, http.StatusOK, getReconciliationFromCtx(r))
+}
+
+// clearTransactionsHandler marks transactions of the reconciliation as
+// cleared, or back as uncleared.
+func (app *application) clearTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	reconciliation := getReconciliationFromCtx(r)
+
+	var payload ClearTransactionsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err := app.store.Reconciliations.SetCleared(r.Context(), reconciliation, payload.TransactionIDs, payload.Cleared)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, errors.New("transactions must be unreconciled transactions of the account up to the statement date"))
+		default:
+			app.reconciliationError(w, r, err)
+		}
+		return
+	}
+
+	app.reconciliationResponse(w, r, http.StatusOK, reconciliation)
+}
+
+// completeReconciliationHandler reconciles the cleared transactions, which
+// locks them from further changes. It is refused while the ledger balance
+// differs from the statement balance, unless the payload asks for the
+// difference to be booked as a balance adjustment.
+func (app *application) completeReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	reconciliation := getReconciliationFromCtx(r)
+
+	var payload CompleteReconciliationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	// the store works out the amount of the adjustment once the account is
+	// locked, and books it only when there is a difference
+	var adjustment *store.Transaction
+	if payload.Adjust {
+		adjustment = &store.Transaction{
+			AccountID:   reconciliation.AccountID,
+			Description: reconciliationAdjustmentDescription,
+			Kind:        store.KindAdjustment,
+			Date:        reconciliation.StatementDate,
+		}
+	}
+
+	if err := app.store.Reconciliations.Complete(r.Context(), reconciliation, adjustment); err != nil {
+		var differenceErr *store.BalanceDifferenceError
+		switch {
+		case errors.As(err, &differenceErr):
+			app.unprocessableEntity(w, r, differenceErr)
+		default:
+			app.reconciliationError(w, r, err)
+		}
+		return
+	}
+
+	app.reconciliationResponse(w, r, http.StatusOK, reconciliation)
+}
+
+// deleteReconciliationHandler abandons an open reconciliation.
+func (app *application) deleteReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	reconciliation := getReconciliationFromCtx(r)
+
+	if err := app.store.Reconciliations.Delete(r.Context(), reconciliation.ID); err != nil {
+		app.reconciliationError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reconciliationResponse writes the reconciliation with the ledger balance
+// at its statement date and the transactions still to be reconciled.
+func (app *application) reconciliationResponse(w http.ResponseWriter, r *http.Request, status int, reconciliation *store.Reconciliation) {
+	ctx := r.Context()
+	balance, err := app.store.Transactions.GetBalanceByDate(ctx, reconciliation.StatementDate, reconciliation.AccountID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	items, err := app.store.Reconciliations.Items(ctx, reconciliation)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := ReconciliationResponse{
+		Reconciliation: reconciliation,
+		Balance:        balance,
+		ClearedBalance: balance,
+		Difference:     reconciliation.StatementBalance - balance,
+		Transactions:   items,
+	}
+	for _, item := range items {
+		if item.Status == store.StatusUncleared {
+			response.ClearedBalance += item.Amount
+		}
+	}
+
+	if err := app.jsonResponse(w, status, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) reconciliationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		app.conflict(w, r, errors.New("the reconciliation is already completed"))
+	case errors.Is(err, store.ErrNotFound):
+		app.notFound(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) reconciliationContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "reconciliationID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		reconciliation, err := app.store.Reconciliations.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, reconciliationCtx, reconciliation)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getReconciliationFromCtx(r *http.Request) *store.Reconciliation {
+	reconciliation, _ := r.Context().Value(reconciliationCtx).(*store.Reconciliation)
+	return reconciliation
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockReconciliationStore struct {
+	reconciliations []store.Reconciliation
+	items           []store.ReconciliationItem
+	cleared         []int64
+	adjustment      *store.Transaction
+	difference      int64
+	completed       bool
+	err             error
+}
+
+func (m *MockReconciliationStore) Create(ctx context.Context, reconciliation *store.Reconciliation) error {
+	if m.err != nil {
+		return m.err
+	}
+	reconciliation.ID = 1
+	reconciliation.Status = store.ReconciliationOpen
+	m.reconciliations = append(m.reconciliations, *reconciliation)
+	return nil
+}
+
+func (m *MockReconciliationStore) Index(ctx context.Context, accountID int64) ([]store.Reconciliation, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.reconciliations, nil
+}
+
+func (m *MockReconciliationStore) GetByID(ctx context.Context, id int64) (*store.Reconciliation, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, reconciliation := range m.reconciliations {
+		if reconciliation.ID == id {
+			return &reconciliation, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (m *MockReconciliationStore) Items(ctx context.Context, reconciliation *store.Reconciliation) ([]store.ReconciliationItem, error) {
+	return m.items, nil
+}
+
+func (m *MockReconciliationStore) SetCleared(ctx context.Context, reconciliation *store.Reconciliation, ids []int64, cleared bool) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.cleared = append(m.cleared, ids...)
+	return nil
+}
+
+func (m *MockReconciliationStore) Complete(ctx context.Context, reconciliation *store.Reconciliation, adjustment *store.Transaction) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.difference != 0 {
+		if adjustment == nil {
+			return &store.BalanceDifferenceError{Difference: m.difference}
+		}
+		adjustment.Amount = -m.difference
+		m.adjustment = adjustment
+	}
+	m.completed = true
+	reconciliation.Status = store.ReconciliationCompleted
+	return nil
+}
+
+func (m *MockReconciliationStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+type ReconciliationsTestSuite struct {
+	suite.Suite
+	app             *application
+	reconciliations *MockReconciliationStore
+	transactions    *MockTransactionStore
+	reconciliation  *store.Reconciliation
+}
+
+func (suite *ReconciliationsTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.reconciliation = &store.Reconciliation{
+		ID:               1,
+		AccountID:        1,
+		StatementDate:    "2024-03-31T00:00:00Z",
+		StatementBalance: 1000000,
+		Status:           store.ReconciliationOpen,
+	}
+	suite.reconciliations = &MockReconciliationStore{
+		difference: 75000,
+		items: []store.ReconciliationItem{
+			{ID: 10, Amount: 50000, Status: store.StatusCleared},
+			{ID: 11, Amount: 25000, Status: store.StatusUncleared},
+			{ID: 12, Amount: -100000, Status: store.StatusUncleared},
+		},
+	}
+	suite.transactions = &MockTransactionStore{balanceByDate: 925000}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Transactions:    suite.transactions,
+		Accounts:        &MockAccountStore{account: &store.Account{ID: 1, Name: "BCA", Currency: "IDR"}},
+		Reconciliations: suite.reconciliations,
+	}}
+}
+
+func (suite *ReconciliationsTestSuite) request(method string, body string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, "/reconciliations/1", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), reconciliationCtx, suite.reconciliation))
+
+	rr := httptest.NewRecorder()
+	handler(rr, req)
+	return rr
+}
+
+func (suite *ReconciliationsTestSuite) TestCreateReconciliation() {
+	rr := suite.request(http.MethodPost, `{"account_id": 1, "statement_date": "2024-03-31", "statement_balance": 1000000}`, suite.app.createReconciliationHandler)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if assert.Len(suite.T(), suite.reconciliations.reconciliations, 1) {
+		assert.Equal(suite.T(), "2024-03-31", suite.reconciliations.reconciliations[0].StatementDate)
+		assert.Equal(suite.T(), int64(1000000), suite.reconciliations.reconciliations[0].StatementBalance)
+	}
+}
+
+func (suite *ReconciliationsTestSuite) TestCreateReconciliation_Invalid() {
+	for _, body := range []string{
+		`{"statement_date": "2024-03-31", "statement_balance": 1000000}`,
+		`{"account_id": 1, "statement_date": "31/03/2024"}`,
+	} {
+		rr := suite.request(http.MethodPost, body, suite.app.createReconciliationHandler)
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+
+	suite.app.store.Accounts = &MockAccountStore{account: &store.Account{ID: 2, Name: "Wise", Currency: "USD"}}
+	rr := suite.request(http.MethodPost, `{"account_id": 2, "statement_date": "2024-03-31"}`, suite.app.createReconciliationHandler)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+
+	assert.Empty(suite.T(), suite.reconciliations.reconciliations)
+}
+
+func (suite *ReconciliationsTestSuite) TestCreateReconciliation_AlreadyOpen() {
+	suite.reconciliations.err = store.ErrConflict
+	rr := suite.request(http.MethodPost, `{"account_id": 1, "statement_date": "2024-03-31"}`, suite.app.createReconciliationHandler)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *ReconciliationsTestSuite) TestGetReconciliation() {
+	rr := suite.request(http.MethodGet, "", suite.app.getReconciliationHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data ReconciliationResponse `json:"data"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(925000), response.Data.Balance)
+	assert.Equal(suite.T(), int64(850000), response.Data.ClearedBalance)
+	assert.Equal(suite.T(), int64(75000), response.Data.Difference)
+	assert.Len(suite.T(), response.Data.Transactions, 3)
+	assert.Equal(suite.T(), int64(1), response.Data.ID)
+}
+
+func (suite *ReconciliationsTestSuite) TestClearTransactions() {
+	rr := suite.request(http.MethodPost, `{"transaction_ids": [11, 12], "cleared": true}`, suite.app.clearTransactionsHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), []int64{11, 12}, suite.reconciliations.cleared)
+
+	rr = suite.request(http.MethodPost, `{"transaction_ids": [], "cleared": true}`, suite.app.clearTransactionsHandler)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+
+	suite.reconciliations.err = store.ErrInvalidReference
+	rr = suite.request(http.MethodPost, `{"transaction_ids": [99], "cleared": true}`, suite.app.clearTransactionsHandler)
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+
+	suite.reconciliations.err = store.ErrConflict
+	rr = suite.request(http.MethodPost, `{"transaction_ids": [11], "cleared": false}`, suite.app.clearTransactionsHandler)
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *ReconciliationsTestSuite) TestCompleteReconciliation_Difference() {
+	rr := suite.request(http.MethodPost, `{}`, suite.app.completeReconciliationHandler)
+
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, rr.Code)
+	assert.False(suite.T(), suite.reconciliations.completed)
+}
+
+func (suite *ReconciliationsTestSuite) TestCompleteReconciliation_Adjust() {
+	rr := suite.request(http.MethodPost, `{"adjust": true}`, suite.app.completeReconciliationHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.True(suite.T(), suite.reconciliations.completed)
+	if assert.NotNil(suite.T(), suite.reconciliations.adjustment) {
+		adjustment := suite.reconciliations.adjustment
+		assert.Equal(suite.T(), int64(-75000), adjustment.Amount)
+		assert.Equal(suite.T(), store.KindAdjustment, adjustment.Kind)
+		assert.Equal(suite.T(), int64(1), adjustment.AccountID)
+		assert.Equal(suite.T(), suite.reconciliation.StatementDate, adjustment.Date)
+	}
+}
+
+func (suite *ReconciliationsTestSuite) TestCompleteReconciliation_Balanced() {
+	suite.reconciliations.difference = 0
+	rr := suite.request(http.MethodPost, `{}`, suite.app.completeReconciliationHandler)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.True(suite.T(), suite.reconciliations.completed)
+	assert.Nil(suite.T(), suite.reconciliations.adjustment)
+}
+
+func (suite *ReconciliationsTestSuite) TestCompleteReconciliation_AlreadyCompleted() {
+	suite.reconciliations.err = store.ErrConflict
+	rr := suite.request(http.MethodPost, `{}`, suite.app.completeReconciliationHandler)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func TestReconciliationsTestSuite(t *testing.T) {
+	suite.Run(t, new(ReconciliationsTestSuite))
+}
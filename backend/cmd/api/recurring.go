@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/recurring"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+const (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 50
+)
+
+type CreateRecurringRulePayload struct {
+	AccountID   *int64  `json:"account_id"`
+	CategoryID  *int64  `json:"category_id"`
+	Amount      int64   `json:"amount" validate:"required"`
+	Description string  `json:"description" validate:"required,max=255"`
+	Kind        string  `json:"kind" validate:"omitempty,oneof=expense income transfer adjustment"`
+	RRule       string  `json:"rrule" validate:"required,max=255"`
+	StartDate   string  `json:"start_date" validate:"required"`
+	EndDate     *string `json:"end_date"`
+}
+
+// UpdateRecurringRulePayload changes the given fields only. An empty
+// end_date removes the end of the schedule.
+type UpdateRecurringRulePayload struct {
+	AccountID   *int64         `json:"account_id"`
+	CategoryID  *NullableInt64 `json:"category_id"`
+	Amount      *int64         `json:"amount"`
+	Description *string        `json:"description" validate:"omitempty,max=255"`
+	Kind        *string        `json:"kind" validate:"omitempty,oneof=expense income transfer adjustment"`
+	RRule       *string        `json:"rrule" validate:"omitempty,max=255"`
+	StartDate   *string        `json:"start_date"`
+	EndDate     *string        `json:"end_date"`
+}
+
+func (app *application) createRecurringRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateRecurringRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	account, err := app.resolveAccount(r.Context(), payload.AccountID)
+	if err != nil {
+		app.accountResolveError(w, r, err)
+		return
+	}
+
+	rule := &store.RecurringRule{
+		AccountID:   account.ID,
+		Amount:      payload.Amount,
+		Description: payload.Description,
+		Kind:        payload.Kind,
+		RRule:       payload.RRule,
+	}
+	if payload.CategoryID != nil && *payload.CategoryID != 0 {
+		rule.CategoryID = payload.CategoryID
+	}
+	if rule.StartDate, err = parseRuleDate("start_date", payload.StartDate); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+	if payload.EndDate != nil && *payload.EndDate != "" {
+		endDate, err := parseRuleDate("end_date", *payload.EndDate)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		rule.EndDate = &endDate
+	}
+
+	// occurrences between the start date and today are booked by the worker
+	if rule.NextRun, err = recurring.FirstRun(rule, rule.StartDate); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.RecurringRules.Create(ctx, rule); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, rule); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) indexRecurringRulesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rules, err := app.store.RecurringRules.Index(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rules); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getRecurringRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRecurringRuleFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, rule); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateRecurringRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRecurringRuleFromCtx(r)
+
+	var payload UpdateRecurringRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if payload.AccountID != nil && *payload.AccountID != rule.AccountID {
+		account, err := app.resolveAccount(r.Context(), payload.AccountID)
+		if err != nil {
+			app.accountResolveError(w, r, err)
+			return
+		}
+		rule.AccountID = account.ID
+	}
+	if payload.CategoryID != nil {
+		rule.CategoryID = nil
+		if payload.CategoryID.Valid {
+			rule.CategoryID = &payload.CategoryID.Int64
+		}
+	}
+	if payload.Amount != nil {
+		rule.Amount = *payload.Amount
+	}
+	if payload.Description != nil {
+		rule.Description = *payload.Description
+	}
+	if payload.Kind != nil {
+		rule.Kind = *payload.Kind
+	}
+
+	rescheduled := false
+	if payload.RRule != nil {
+		rule.RRule = *payload.RRule
+		rescheduled = true
+	}
+	if payload.StartDate != nil {
+		startDate, err := parseRuleDate("start_date", *payload.StartDate)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		rule.StartDate = startDate
+		rescheduled = true
+	}
+	if payload.EndDate != nil {
+		rule.EndDate = nil
+		if *payload.EndDate != "" {
+			endDate, err := parseRuleDate("end_date", *payload.EndDate)
+			if err != nil {
+				app.badRequest(w, r, err)
+				return
+			}
+			rule.EndDate = &endDate
+		}
+		rescheduled = true
+	}
+
+	if rescheduled {
+		// continue from where the old schedule stood so that booked
+		// occurrences are neither repeated nor backfilled
+		from := rule.StartDate
+		switch {
+		case rule.NextRun != nil && rule.NextRun.After(from):
+			from = *rule.NextRun
+		case rule.NextRun == nil && time.Now().After(from):
+			from = time.Now()
+		}
+
+		nextRun, err := recurring.FirstRun(rule, from)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		rule.NextRun = nextRun
+	}
+
+	if err := app.store.RecurringRules.Update(r.Context(), rule); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rule); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteRecurringRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRecurringRuleFromCtx(r)
+
+	ctx := r.Context()
+	if err := app.store.RecurringRules.Delete(ctx, rule.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// previewRecurringRuleHandler lists the next occurrences that the worker
+// will book, starting at the rule's next run.
+func (app *application) previewRecurringRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getRecurringRuleFromCtx(r)
+
+	count := defaultPreviewCount
+	if value := r.URL.Query().Get("count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPreviewCount {
+			app.badRequest(w, r, fmt.Errorf("count must be between 1 and %d", maxPreviewCount))
+			return
+		}
+		count = n
+	}
+
+	occurrences := []time.Time{}
+	if rule.NextRun != nil {
+		schedule, err := recurring.ScheduleFor(rule)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		occurrences = append(occurrences, schedule.Take(*rule.NextRun, count)...)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, occurrences); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// parseRuleDate accepts a calendar date (YYYY-MM-DD, midnight local time) or
+// an RFC 3339 timestamp.
+func parseRuleDate(field, value string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD or RFC 3339", field, value)
+}
+
+func (app *application) recurringRuleContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "ruleID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		rule, err := app.store.RecurringRules.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, recurringRuleCtx, rule)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getRecurringRuleFromCtx(r *http.Request) *store.RecurringRule {
+	rule, _ := r.Context().Value(recurringRuleCtx).(*store.RecurringRule)
+	return rule
+}
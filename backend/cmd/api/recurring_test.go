@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockRecurringRuleStore struct {
+	rules   []store.RecurringRule
+	rule    *store.RecurringRule
+	updated *store.RecurringRule
+	err     error
+}
+
+func (m *MockRecurringRuleStore) Create(ctx context.Context, rule *store.RecurringRule) error {
+	if m.err != nil {
+		return m.err
+	}
+	rule.ID = 1
+	return nil
+}
+
+func (m *MockRecurringRuleStore) Index(ctx context.Context) ([]store.RecurringRule, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.rules, nil
+}
+
+func (m *MockRecurringRuleStore) Due(ctx context.Context, now time.Time) ([]store.RecurringRule, error) {
+	return m.Index(ctx)
+}
+
+func (m *MockRecurringRuleStore) GetByID(ctx context.Context, id int64) (*store.RecurringRule, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.rule == nil {
+		return nil, store.ErrNotFound
+	}
+	return m.rule, nil
+}
+
+func (m *MockRecurringRuleStore) Update(ctx context.Context, rule *store.RecurringRule) error {
+	m.updated = rule
+	return m.err
+}
+
+func (m *MockRecurringRuleStore) SetNextRun(ctx context.Context, id int64, nextRun *time.Time) error {
+	return m.err
+}
+
+func (m *MockRecurringRuleStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+type RecurringTestSuite struct {
+	suite.Suite
+	app *application
+}
+
+func (suite *RecurringTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.app = &application{config: cfg, store: store.NewStorage(nil)}
+}
+
+func (suite *RecurringTestSuite) TestCreateRecurringRuleHandler_Success() {
+	suite.app.store = store.Storage{
+		RecurringRules: &MockRecurringRuleStore{},
+		Accounts:       &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
+	}
+
+	requestBody := CreateRecurringRulePayload{
+		Amount:      3500000,
+		Description: "Rent",
+		RRule:       "FREQ=MONTHLY;BYMONTHDAY=1",
+		StartDate:   "2024-01-15T00:00:00Z",
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/recurring", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createRecurringRuleHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data store.RecurringRule `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), response.Data.AccountID)
+	assert.True(suite.T(), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Equal(*response.Data.NextRun))
+}
+
+func (suite *RecurringTestSuite) TestCreateRecurringRuleHandler_InvalidInput() {
+	suite.app.store = store.Storage{
+		RecurringRules: &MockRecurringRuleStore{},
+		Accounts:       &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
+	}
+
+	for _, body := range []string{
+		`{"amount": 55000, "description": "Spotify", "rrule": "FREQ=HOURLY", "start_date": "2024-01-01"}`,
+		`{"amount": 55000, "description": "Spotify", "rrule": "FREQ=MONTHLY", "start_date": "01/01/2024"}`,
+		`{"amount": 55000, "description": "Spotify", "start_date": "2024-01-01"}`,
+	} {
+		req, err := http.NewRequest(http.MethodPost, "/recurring", bytes.NewReader([]byte(body)))
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.createRecurringRuleHandler(rr, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func (suite *RecurringTestSuite) TestPreviewRecurringRuleHandler() {
+	nextRun := time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)
+	rule := &store.RecurringRule{
+		ID:        1,
+		RRule:     "FREQ=MONTHLY;BYMONTHDAY=25",
+		StartDate: time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+		NextRun:   &nextRun,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/recurring/1/preview?count=3", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), recurringRuleCtx, rule))
+
+	rr := httptest.NewRecorder()
+	suite.app.previewRecurringRuleHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data []time.Time `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data, 3)
+	assert.True(suite.T(), nextRun.Equal(response.Data[0]))
+	assert.True(suite.T(), time.Date(2024, 5, 25, 0, 0, 0, 0, time.UTC).Equal(response.Data[2]))
+
+	req, err = http.NewRequest(http.MethodGet, "/recurring/1/preview?count=500", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), recurringRuleCtx, rule))
+
+	rr = httptest.NewRecorder()
+	suite.app.previewRecurringRuleHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *RecurringTestSuite) TestUpdateRecurringRuleHandler_Reschedule() {
+	nextRun := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	rule := &store.RecurringRule{
+		ID:          1,
+		AccountID:   1,
+		Amount:      3500000,
+		Description: "Rent",
+		RRule:       "FREQ=MONTHLY;BYMONTHDAY=1",
+		StartDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		NextRun:     &nextRun,
+	}
+	mockStore := &MockRecurringRuleStore{rule: rule}
+	suite.app.store = store.Storage{RecurringRules: mockStore}
+
+	req, err := http.NewRequest(http.MethodPatch, "/recurring/1", bytes.NewReader([]byte(`{"rrule": "FREQ=MONTHLY;BYMONTHDAY=15"}`)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), recurringRuleCtx, rule))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateRecurringRuleHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	// the schedule continues after the last booked occurrence
+	assert.Equal(suite.T(), time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), *mockStore.updated.NextRun)
+}
+
+func TestRecurringTestSuite(t *testing.T) {
+	suite.Run(t, new(RecurringTestSuite))
+}
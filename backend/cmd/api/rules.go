@@ -0,0 +1,528 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/categorize"
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/pukuri/expenses/backend/internal/suggest"
+)
+
+type CreateCategorizationRulePayload struct {
+	Name                string  `json:"name" validate:"required,max=100"`
+	Priority            int     `json:"priority"`
+	Enabled             *bool   `json:"enabled"`
+	DescriptionContains string  `json:"description_contains" validate:"max=255"`
+	DescriptionPattern  string  `json:"description_pattern" validate:"max=255"`
+	MinAmount           *int64  `json:"min_amount" validate:"omitempty,gte=0"`
+	MaxAmount           *int64  `json:"max_amount" validate:"omitempty,gte=0"`
+	AccountID           *int64  `json:"account_id"`
+	CategoryID          *int64  `json:"category_id"`
+	TagIDs              []int64 `json:"tag_ids"`
+	RenameTo            string  `json:"rename_to" validate:"max=255"`
+}
+
+// UpdateCategorizationRulePayload changes the given fields only. A null
+// account, category or amount bound removes it.
+type UpdateCategorizationRulePayload struct {
+	Name                *string        `json:"name" validate:"omitempty,max=100"`
+	Priority            *int           `json:"priority"`
+	Enabled             *bool          `json:"enabled"`
+	DescriptionContains *string        `json:"description_contains" validate:"omitempty,max=255"`
+	DescriptionPattern  *string        `json:"description_pattern" validate:"omitempty,max=255"`
+	MinAmount           *NullableInt64 `json:"min_amount"`
+	MaxAmount           *NullableInt64 `json:"max_amount"`
+	AccountID           *NullableInt64 `json:"account_id"`
+	CategoryID          *NullableInt64 `json:"category_id"`
+	TagIDs              *[]int64       `json:"tag_ids"`
+	RenameTo            *string        `json:"rename_to" validate:"omitempty,max=255"`
+}
+
+// ApplyRulesPayload selects the booked transactions to re-run the rules on.
+// Categorized transactions are only changed with overwrite; split
+// transactions never are.
+type ApplyRulesPayload struct {
+	From      string `json:"from" validate:"required,datetime=2006-01-02"`
+	To        string `json:"to" validate:"required,datetime=2006-01-02"`
+	AccountID *int64 `json:"account_id"`
+	Overwrite bool   `json:"overwrite"`
+	DryRun    bool   `json:"dry_run"`
+}
+
+// ApplyRulesResult lists the changes of a retroactive rule run. Scanned
+// counts the transactions in the date range, and Skipped the ids of
+// transactions left alone because they were edited, reconciled or trashed
+// while the run was worked out.
+type ApplyRulesResult struct {
+	DryRun  bool               `json:"dry_run"`
+	Scanned int                `json:"scanned"`
+	Changes []store.RuleChange `json:"changes"`
+	Skipped []int64            `json:"skipped"`
+}
+
+func (app *application) createCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateCategorizationRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	rule := &store.CategorizationRule{
+		Name:                payload.Name,
+		Priority:            payload.Priority,
+		Enabled:             payload.Enabled == nil || *payload.Enabled,
+		DescriptionContains: payload.DescriptionContains,
+		DescriptionPattern:  payload.DescriptionPattern,
+		MinAmount:           payload.MinAmount,
+		MaxAmount:           payload.MaxAmount,
+		TagIDs:              uniqueIDs(payload.TagIDs),
+		RenameTo:            payload.RenameTo,
+	}
+	if payload.AccountID != nil && *payload.AccountID != 0 {
+		rule.AccountID = payload.AccountID
+	}
+	if payload.CategoryID != nil && *payload.CategoryID != 0 {
+		rule.CategoryID = payload.CategoryID
+	}
+
+	if err := categorize.Validate(rule); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.checkRuleTags(ctx, rule.TagIDs); err != nil {
+		app.payloadError(w, r, err)
+		return
+	}
+
+	if err := app.store.CategorizationRules.Create(ctx, rule); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, rule); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) indexCategorizationRulesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	rules, err := app.store.CategorizationRules.Index(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rules); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getCategorizationRuleFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, rule); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getCategorizationRuleFromCtx(r)
+
+	var payload UpdateCategorizationRulePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setNullable := func(dst **int64, src *NullableInt64) {
+		if src != nil {
+			*dst = nil
+			if src.Valid {
+				value := src.Int64
+				*dst = &value
+			}
+		}
+	}
+	setString(&rule.Name, payload.Name)
+	setString(&rule.DescriptionContains, payload.DescriptionContains)
+	setString(&rule.DescriptionPattern, payload.DescriptionPattern)
+	setString(&rule.RenameTo, payload.RenameTo)
+	setNullable(&rule.MinAmount, payload.MinAmount)
+	setNullable(&rule.MaxAmount, payload.MaxAmount)
+	setNullable(&rule.AccountID, payload.AccountID)
+	setNullable(&rule.CategoryID, payload.CategoryID)
+	if payload.Priority != nil {
+		rule.Priority = *payload.Priority
+	}
+	if payload.Enabled != nil {
+		rule.Enabled = *payload.Enabled
+	}
+	if payload.TagIDs != nil {
+		rule.TagIDs = uniqueIDs(*payload.TagIDs)
+	}
+
+	if rule.Name == "" {
+		app.badRequest(w, r, errors.New("name must not be empty"))
+		return
+	}
+	if err := categorize.Validate(rule); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.checkRuleTags(ctx, rule.TagIDs); err != nil {
+		app.payloadError(w, r, err)
+		return
+	}
+
+	if err := app.store.CategorizationRules.Update(ctx, rule); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFound(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, rule); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := getCategorizationRuleFromCtx(r)
+
+	ctx := r.Context()
+	if err := app.store.CategorizationRules.Delete(ctx, rule.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFound(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyRulesHandler re-runs the enabled rules over the transactions of a
+// date range, leaving reconciled transactions alone. With dry_run=true it
+// only returns the changes it would make; otherwise they are saved in one
+// database transaction, skipping the transactions that changed meanwhile.
+func (app *application) applyRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var payload ApplyRulesPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	engine, err := app.categorizer(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Tags.Index(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	tagIDs := map[string]int64{}
+	for _, tag := range tags {
+		tagIDs[tag.Name] = tag.ID
+	}
+
+	filter := store.TransactionFilter{From: payload.From, To: payload.To, Ascending: true}
+	if payload.AccountID != nil {
+		filter.AccountID = *payload.AccountID
+	}
+
+	result := ApplyRulesResult{DryRun: payload.DryRun, Changes: []store.RuleChange{}, Skipped: []int64{}}
+	// the suggestion examples each change replaces, by transaction
+	type examples struct {
+		forget, learn *suggest.Example
+	}
+	learned := map[int64]examples{}
+	err = app.store.Transactions.Export(ctx, filter, func(transaction *store.TransactionExport) error {
+		result.Scanned++
+		if transaction.Status == store.StatusReconciled {
+			return nil
+		}
+		if len(transaction.Splits) > 0 || (transaction.CategoryID != nil && !payload.Overwrite) {
+			return nil
+		}
+
+		change, ok := ruleChange(engine, transaction, tagIDs)
+		if !ok {
+			return nil
+		}
+		result.Changes = append(result.Changes, change)
+
+		var e examples
+		if example, ok := suggest.ExampleOf(transaction.Kind, change.OldDescription, transaction.Amount, change.OldCategoryID, false); ok {
+			e.forget = &example
+		}
+		if example, ok := suggest.ExampleOf(transaction.Kind, change.Description, transaction.Amount, change.CategoryID, false); ok {
+			e.learn = &example
+		}
+		learned[change.TransactionID] = e
+		return nil
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !payload.DryRun && len(result.Changes) > 0 {
+		skipped, err := app.store.Transactions.ApplyRuleChanges(ctx, result.Changes)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrInvalidReference):
+				app.badRequest(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		result.Skipped = skipped
+		result.Changes = slices.DeleteFunc(result.Changes, func(change store.RuleChange) bool {
+			return slices.Contains(skipped, change.TransactionID)
+		})
+
+		for _, change := range result.Changes {
+			e := learned[change.TransactionID]
+			if e.forget != nil {
+				app.suggestions.Forget(*e.forget)
+			}
+			if e.learn != nil {
+				app.suggestions.Learn(*e.learn)
+			}
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, result); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// ruleChange runs the rules on a booked transaction and reports what they
+// would change. A category is only replaced, never removed.
+func ruleChange(engine *categorize.Engine, transaction *store.TransactionExport, tagIDs map[string]int64) (store.RuleChange, bool) {
+	result := engine.Apply(categorize.Input{
+		AccountID:   transaction.AccountID,
+		Amount:      transaction.Amount,
+		Description: transaction.Description,
+		Kind:        transaction.Kind,
+	})
+	if !result.Matched() {
+		return store.RuleChange{}, false
+	}
+
+	change := store.RuleChange{
+		TransactionID:  transaction.ID,
+		Version:        transaction.Version,
+		Date:           transaction.Date,
+		RuleIDs:        result.RuleIDs,
+		OldCategoryID:  transaction.CategoryID,
+		CategoryID:     transaction.CategoryID,
+		OldDescription: transaction.Description,
+		Description:    result.Description,
+		AddedTagIDs:    []int64{},
+	}
+	if result.CategoryID != nil {
+		change.CategoryID = result.CategoryID
+	}
+
+	existing := []int64{}
+	for _, name := range transaction.Tags {
+		existing = append(existing, tagIDs[name])
+	}
+	for _, tagID := range result.TagIDs {
+		if !slices.Contains(existing, tagID) {
+			change.AddedTagIDs = append(change.AddedTagIDs, tagID)
+		}
+	}
+
+	categoryChanged := (change.CategoryID == nil) != (change.OldCategoryID == nil) ||
+		(change.CategoryID != nil && *change.CategoryID != *change.OldCategoryID)
+	if !categoryChanged && change.Description == change.OldDescription && len(change.AddedTagIDs) == 0 {
+		return store.RuleChange{}, false
+	}
+	return change, true
+}
+
+// checkRuleTags fails when a rule refers to a tag that does not exist. Rules
+// keep their tags as an array, which no foreign key guards.
+func (app *application) checkRuleTags(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tags, err := app.store.Tags.Index(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(tags, func(tag store.Tag) bool { return tag.ID == id }) {
+			return invalidPayload(fmt.Errorf("tag %d not found", id))
+		}
+	}
+	return nil
+}
+
+// categorizer loads the enabled rules.
+func (app *application) categorizer(ctx context.Context) (*categorize.Engine, error) {
+	rules, err := app.store.CategorizationRules.Enabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return categorize.New(rules)
+}
+
+// categorizeTransaction applies the rules to a new transaction that has no
+// category and no splits. Tags set by the rules are added to the given ones.
+func (app *application) categorizeTransaction(ctx context.Context, transaction *store.Transaction) error {
+	if transaction.CategoryID.Valid || len(transaction.Splits) > 0 {
+		return nil
+	}
+
+	engine, err := app.categorizer(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := engine.Apply(categorize.Input{
+		AccountID:   transaction.AccountID,
+		Amount:      transaction.Amount,
+		Description: transaction.Description,
+		Kind:        transaction.Kind,
+	})
+	if result.CategoryID != nil {
+		transaction.CategoryID.Int64, transaction.CategoryID.Valid = *result.CategoryID, true
+	}
+	transaction.Description = result.Description
+	for _, tagID := range result.TagIDs {
+		if !slices.ContainsFunc(transaction.Tags, func(tag store.Tag) bool { return tag.ID == tagID }) {
+			transaction.Tags = append(transaction.Tags, store.Tag{ID: tagID})
+		}
+	}
+
+	return nil
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	unique := []int64{}
+	for _, id := range ids {
+		if id != 0 && !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func (app *application) categorizationRuleContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "ruleID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		rule, err := app.store.CategorizationRules.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, categorizationRuleCtx, rule)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCategorizationRuleFromCtx(r *http.Request) *store.CategorizationRule {
+	rule, _ := r.Context().Value(categorizationRuleCtx).(*store.CategorizationRule)
+	return rule
+}
+
+// categorizeRows applies the rules to imported rows without a category or
+// splits, before they are checked for duplicates, so that renamed rows
+// match the renamed transactions of an earlier import.
+func (app *application) categorizeRows(ctx context.Context, rows []importer.Row) error {
+	engine, err := app.categorizer(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if !row.Valid() || row.CategoryID != nil || len(row.Splits) > 0 {
+			continue
+		}
+
+		result := engine.Apply(categorize.Input{
+			AccountID:   row.AccountID,
+			Amount:      row.Amount,
+			Description: row.Description,
+			Kind:        row.Kind,
+		})
+		row.CategoryID = result.CategoryID
+		row.Description = result.Description
+		row.TagIDs = result.TagIDs
+	}
+
+	return nil
+}
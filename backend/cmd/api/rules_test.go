@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockCategorizationRuleStore struct {
+	rules   []store.CategorizationRule
+	rule    *store.CategorizationRule
+	created *store.CategorizationRule
+	err     error
+}
+
+func (m *MockCategorizationRuleStore) Create(ctx context.Context, rule *store.CategorizationRule) error {
+	if m.err != nil {
+		return m.err
+	}
+	rule.ID = 1
+	m.created = rule
+	return nil
+}
+
+func (m *MockCategorizationRuleStore) Index(ctx context.Context) ([]store.CategorizationRule, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.rules, nil
+}
+
+func (m *MockCategorizationRuleStore) Enabled(ctx context.Context) ([]store.CategorizationRule, error) {
+	return m.Index(ctx)
+}
+
+func (m *MockCategorizationRuleStore) GetByID(ctx context.Context, id int64) (*store.CategorizationRule, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.rule == nil {
+		return nil, store.ErrNotFound
+	}
+	return m.rule, nil
+}
+
+func (m *MockCategorizationRuleStore) Update(ctx context.Context, rule *store.CategorizationRule) error {
+	return m.err
+}
+
+func (m *MockCategorizationRuleStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+type RulesTestSuite struct {
+	suite.Suite
+	app          *application
+	transactions *MockTransactionStore
+}
+
+func (suite *RulesTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	food, subscriptions := int64(4), int64(9)
+	suite.transactions = &MockTransactionStore{}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Transactions: suite.transactions,
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
+		Tags:         &MockTagStore{tags: []store.Tag{{ID: 2, Name: "online"}, {ID: 3, Name: "coffee"}}},
+		CategorizationRules: &MockCategorizationRuleStore{rules: []store.CategorizationRule{
+			{ID: 1, Name: "Netflix", Enabled: true, DescriptionContains: "netflix", CategoryID: &subscriptions, TagIDs: []int64{2}, RenameTo: "Netflix"},
+			{ID: 2, Name: "GrabFood", Enabled: true, DescriptionPattern: `^GRAB\*? ?FOOD`, CategoryID: &food},
+		}},
+	}}
+}
+
+func (suite *RulesTestSuite) TestCreateCategorizationRuleHandler() {
+	rules := &MockCategorizationRuleStore{}
+	suite.app.store.CategorizationRules = rules
+
+	body := `{"name": "Coffee", "description_contains": "starbucks", "max_amount": 150000, "category_id": 4, "tag_ids": [3, 3]}`
+	req, err := http.NewRequest(http.MethodPost, "/rules", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createCategorizationRuleHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if !assert.NotNil(suite.T(), rules.created) {
+		return
+	}
+	assert.True(suite.T(), rules.created.Enabled)
+	assert.Equal(suite.T(), []int64{3}, rules.created.TagIDs)
+	assert.Equal(suite.T(), int64(150000), *rules.created.MaxAmount)
+}
+
+func (suite *RulesTestSuite) TestCreateCategorizationRuleHandler_Invalid() {
+	for _, body := range []string{
+		`{"name": "No condition", "category_id": 4}`,
+		`{"name": "No action", "description_contains": "starbucks"}`,
+		`{"name": "Bad pattern", "description_pattern": "(", "category_id": 4}`,
+		`{"name": "Bad range", "min_amount": 10, "max_amount": 5, "category_id": 4}`,
+		`{"description_contains": "starbucks", "category_id": 4}`,
+		`{"name": "Unknown tag", "description_contains": "starbucks", "tag_ids": [99]}`,
+	} {
+		req, err := http.NewRequest(http.MethodPost, "/rules", bytes.NewReader([]byte(body)))
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.createCategorizationRuleHandler(rr, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func (suite *RulesTestSuite) TestUpdateCategorizationRuleHandler_UnknownTag() {
+	subscriptions := int64(9)
+	rule := &store.CategorizationRule{ID: 1, Name: "Netflix", Enabled: true, DescriptionContains: "netflix", CategoryID: &subscriptions}
+
+	req, err := http.NewRequest(http.MethodPatch, "/rules/1", bytes.NewReader([]byte(`{"tag_ids": [2, 99]}`)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), categorizationRuleCtx, rule))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateCategorizationRuleHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *RulesTestSuite) TestCreateTransactionHandler_AppliesRules() {
+	body := `{"amount": 186000, "description": "NETFLIX.COM 8831", "date": "2024-03-01T00:00:00Z", "tags": [7]}`
+	req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data store.Transaction `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Netflix", response.Data.Description)
+	assert.Equal(suite.T(), int64(9), response.Data.CategoryID.Int64)
+	assert.Equal(suite.T(), []store.Tag{{ID: 7}, {ID: 2}}, response.Data.Tags)
+}
+
+func (suite *RulesTestSuite) TestCreateTransactionHandler_KeepsGivenCategory() {
+	body := `{"amount": 186000, "description": "NETFLIX.COM 8831", "date": "2024-03-01T00:00:00Z", "category_id": 1}`
+	req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data store.Transaction `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "NETFLIX.COM 8831", response.Data.Description)
+	assert.Equal(suite.T(), int64(1), response.Data.CategoryID.Int64)
+}
+
+func (suite *RulesTestSuite) TestApplyRulesHandler() {
+	other := int64(1)
+	suite.transactions.exports = []store.TransactionExport{
+		{ID: 10, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), AccountID: 1, Description: "NETFLIX.COM 8831", Kind: store.KindExpense, Amount: 186000},
+		{ID: 11, Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), AccountID: 1, Description: "GRAB* FOOD", Kind: store.KindExpense, Amount: 54000, CategoryID: &other},
+		{ID: 12, Date: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), AccountID: 1, Description: "Netflix", Kind: store.KindExpense, Amount: 186000, CategoryID: &other, Tags: []string{"online"}},
+		{ID: 13, Date: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), AccountID: 1, Description: "Indomaret", Kind: store.KindExpense, Amount: 20000},
+	}
+
+	body := `{"from": "2024-03-01", "to": "2024-03-31", "dry_run": true}`
+	req, err := http.NewRequest(http.MethodPost, "/rules/apply", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.applyRulesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data ApplyRulesResult `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), response.Data.DryRun)
+	assert.Equal(suite.T(), 4, response.Data.Scanned)
+	if !assert.Len(suite.T(), response.Data.Changes, 1) {
+		return
+	}
+	change := response.Data.Changes[0]
+	assert.Equal(suite.T(), int64(10), change.TransactionID)
+	assert.Nil(suite.T(), change.OldCategoryID)
+	assert.Equal(suite.T(), int64(9), *change.CategoryID)
+	assert.Equal(suite.T(), "Netflix", change.Description)
+	assert.Equal(suite.T(), []int64{2}, change.AddedTagIDs)
+	assert.Empty(suite.T(), suite.transactions.ruleChanges)
+	assert.Equal(suite.T(), "2024-03-01", suite.transactions.filter.From)
+
+	// overwriting categorized transactions, for real this time
+	body = `{"from": "2024-03-01", "to": "2024-03-31", "overwrite": true}`
+	req, err = http.NewRequest(http.MethodPost, "/rules/apply", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr = httptest.NewRecorder()
+	suite.app.applyRulesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	if !assert.Len(suite.T(), suite.transactions.ruleChanges, 3) {
+		return
+	}
+	assert.Equal(suite.T(), int64(11), suite.transactions.ruleChanges[1].TransactionID)
+	assert.Equal(suite.T(), int64(4), *suite.transactions.ruleChanges[1].CategoryID)
+	assert.Empty(suite.T(), suite.transactions.ruleChanges[2].AddedTagIDs)
+}
+
+func (suite *RulesTestSuite) TestApplyRulesHandler_Skipped() {
+	suite.transactions.exports = []store.TransactionExport{
+		{ID: 10, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), AccountID: 1, Description: "NETFLIX.COM 8831", Kind: store.KindExpense, Amount: 186000, Version: 3},
+		{ID: 11, Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), AccountID: 1, Description: "NETFLIX.COM 9012", Kind: store.KindExpense, Amount: 186000, Version: 1},
+	}
+	suite.transactions.skippedRuleChanges = []int64{11}
+
+	body := `{"from": "2024-03-01", "to": "2024-03-31"}`
+	req, err := http.NewRequest(http.MethodPost, "/rules/apply", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.applyRulesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	if assert.Len(suite.T(), suite.transactions.ruleChanges, 2) {
+		assert.Equal(suite.T(), int64(3), suite.transactions.ruleChanges[0].Version)
+	}
+
+	var response struct {
+		Data ApplyRulesResult `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []int64{11}, response.Data.Skipped)
+	if assert.Len(suite.T(), response.Data.Changes, 1) {
+		assert.Equal(suite.T(), int64(10), response.Data.Changes[0].TransactionID)
+	}
+}
+
+func (suite *RulesTestSuite) TestApplyRulesHandler_InvalidRange() {
+	req, err := http.NewRequest(http.MethodPost, "/rules/apply", bytes.NewReader([]byte(`{"from": "03/01/2024", "to": "2024-03-31"}`)))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.applyRulesHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func TestRulesTestSuite(t *testing.T) {
+	suite.Run(t, new(RulesTestSuite))
+}
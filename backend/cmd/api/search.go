@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	term := strings.TrimSpace(r.URL.Query().Get("q"))
+	if term == "" {
+		app.badRequest(w, r, errors.New("query parameter q is required"))
+		return
+	}
+
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			app.badRequest(w, r, errors.New("limit must be between 1 and 100"))
+			return
+		}
+		limit = parsed
+	}
+
+	ctx := r.Context()
+	hits, err := app.store.Search.Find(ctx, term, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, hits); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockSearchStore struct {
+	hits  []store.SearchHit
+	term  string
+	limit int
+	err   error
+}
+
+func (m *MockSearchStore) Find(ctx context.Context, term string, limit int) ([]store.SearchHit, error) {
+	m.term = term
+	m.limit = limit
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.hits, nil
+}
+
+type SearchTestSuite struct {
+	suite.Suite
+	app *application
+}
+
+func (suite *SearchTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.app = &application{config: cfg, store: store.NewStorage(nil)}
+}
+
+func (suite *SearchTestSuite) TestSearchHandler_Success() {
+	mockStore := &MockSearchStore{
+		hits: []store.SearchHit{
+			{Type: store.SearchHitTransaction, ID: 7, Description: "Tokopedia order", Snippet: "<mark>Tokopedia</mark> order", Rank: 0.9},
+			{Type: store.SearchHitEventExpense, ID: 3, EventID: 2, EventName: "Bali", Description: "Tokopedia souvenir", Rank: 0.4},
+		},
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Search: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/search?q=tokopedia", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.searchHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "tokopedia", mockStore.term)
+	assert.Equal(suite.T(), defaultSearchLimit, mockStore.limit)
+
+	var response struct {
+		Data []store.SearchHit `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data, 2)
+	assert.Equal(suite.T(), "<mark>Tokopedia</mark> order", response.Data[0].Snippet)
+	assert.Equal(suite.T(), int64(2), response.Data[1].EventID)
+}
+
+func (suite *SearchTestSuite) TestSearchHandler_MissingQuery() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Search: &MockSearchStore{},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/search?q=%20", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.searchHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *SearchTestSuite) TestSearchHandler_InvalidLimit() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Search: &MockSearchStore{},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/search?q=kopi&limit=1000", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.searchHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *SearchTestSuite) TestSearchHandler_StoreError() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Search: &MockSearchStore{err: errors.New("database error")},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/search?q=kopi", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.searchHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusInternalServerError, rr.Code)
+}
+
+func TestSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(SearchTestSuite))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/pukuri/expenses/backend/internal/suggest"
+)
+
+type CategorySuggestion struct {
+	CategoryID int64   `json:"category_id"`
+	Name       string  `json:"name"`
+	Color      string  `json:"color"`
+	Kind       string  `json:"kind"`
+	Confidence float64 `json:"confidence"`
+}
+
+// suggestCategoriesHandler ranks the categories a transaction with the given
+// description and amount most likely belongs to, as learned from the
+// categorized transactions booked so far.
+func (app *application) suggestCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	description := query.Get("description")
+
+	var amount int64
+	if param := query.Get("amount"); param != "" {
+		value, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		amount = value
+	}
+
+	limit := suggest.DefaultLimit
+	if param := query.Get("limit"); param != "" {
+		value, err := strconv.Atoi(param)
+		if err != nil || value < 1 || value > 20 {
+			app.badRequest(w, r, errors.New("limit must be between 1 and 20"))
+			return
+		}
+		limit = value
+	}
+
+	ctx := r.Context()
+	categories, err := app.store.Categories.Index(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	byID := map[int64]store.Category{}
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	// the model may still know categories deleted since it was trained
+	response := []CategorySuggestion{}
+	for _, suggestion := range app.suggestions.Suggest(description, amount, math.MaxInt) {
+		category, ok := byID[suggestion.CategoryID]
+		if !ok {
+			continue
+		}
+		response = append(response, CategorySuggestion{
+			CategoryID: category.ID,
+			Name:       category.Name,
+			Color:      category.Color,
+			Kind:       category.Kind,
+			Confidence: suggestion.Confidence,
+		})
+		if len(response) == limit {
+			break
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// learnTransaction teaches the classifier a booked transaction.
+func (app *application) learnTransaction(transaction *store.Transaction) {
+	if example, ok := transactionExample(transaction); ok {
+		app.suggestions.Learn(example)
+	}
+}
+
+// forgetTransaction makes the classifier forget a transaction that is being
+// changed or deleted.
+func (app *application) forgetTransaction(transaction *store.Transaction) {
+	if example, ok := transactionExample(transaction); ok {
+		app.suggestions.Forget(example)
+	}
+}
+
+func transactionExample(transaction *store.Transaction) (suggest.Example, bool) {
+	var categoryID *int64
+	if transaction.CategoryID.Valid {
+		categoryID = &transaction.CategoryID.Int64
+	}
+	return suggest.ExampleOf(transaction.Kind, transaction.Description, transaction.Amount, categoryID, len(transaction.Splits) > 0)
+}
+
+// suggestRows attaches category suggestions to the import rows that are still
+// uncategorized once the rules have run.
+func (app *application) suggestRows(rows []importer.Row) {
+	for i := range rows {
+		row := &rows[i]
+		if !row.Valid() || row.CategoryID != nil || len(row.Splits) > 0 {
+			continue
+		}
+		if row.Kind != store.KindExpense && row.Kind != store.KindIncome {
+			continue
+		}
+		row.Suggestions = app.suggestions.Suggest(row.Description, row.Amount, suggest.DefaultLimit)
+	}
+}
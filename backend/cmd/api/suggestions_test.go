@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/pukuri/expenses/backend/internal/suggest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SuggestionsTestSuite struct {
+	suite.Suite
+	app *application
+}
+
+func (suite *SuggestionsTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Transactions: &MockTransactionStore{},
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
+		Categories: &MockCategoryStore{categories: []store.Category{
+			{ID: 1, Name: "Food", Color: "#FF5733", Kind: store.KindExpense},
+			{ID: 2, Name: "Transport", Color: "#33FF57", Kind: store.KindExpense},
+		}},
+		CategorizationRules: &MockCategorizationRuleStore{},
+	}}
+	suite.app.suggestions.Train([]suggest.Example{
+		{Description: "GrabFood Nasi Goreng", Amount: 45000, CategoryID: 1},
+		{Description: "GrabFood martabak", Amount: 60000, CategoryID: 1},
+		{Description: "Grab bike", Amount: 25000, CategoryID: 2},
+		// a category deleted since training
+		{Description: "GrabFood", Amount: 50000, CategoryID: 3},
+	})
+}
+
+func (suite *SuggestionsTestSuite) suggest(query string) (int, []CategorySuggestion) {
+	req, err := http.NewRequest(http.MethodGet, "/categories/suggest?"+query, nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.suggestCategoriesHandler(rr, req)
+
+	var response struct {
+		Data []CategorySuggestion `json:"data"`
+	}
+	if rr.Code == http.StatusOK {
+		assert.NoError(suite.T(), json.Unmarshal(rr.Body.Bytes(), &response))
+	}
+	return rr.Code, response.Data
+}
+
+func (suite *SuggestionsTestSuite) TestSuggestCategoriesHandler() {
+	code, suggestions := suite.suggest("description=GRABFOOD+ayam&amount=50000")
+
+	assert.Equal(suite.T(), http.StatusOK, code)
+	if !assert.Len(suite.T(), suggestions, 2) {
+		return
+	}
+	assert.Equal(suite.T(), int64(1), suggestions[0].CategoryID)
+	assert.Equal(suite.T(), "Food", suggestions[0].Name)
+	assert.Equal(suite.T(), "#FF5733", suggestions[0].Color)
+	assert.Greater(suite.T(), suggestions[0].Confidence, suggestions[1].Confidence)
+
+	_, suggestions = suite.suggest("description=GRABFOOD&amount=50000&limit=1")
+	assert.Len(suite.T(), suggestions, 1)
+
+	code, suggestions = suite.suggest("description=pharmacy")
+	assert.Equal(suite.T(), http.StatusOK, code)
+	assert.Empty(suite.T(), suggestions)
+}
+
+func (suite *SuggestionsTestSuite) TestSuggestCategoriesHandler_InvalidQuery() {
+	for _, query := range []string{"description=grab&amount=ten", "description=grab&limit=0"} {
+		code, _ := suite.suggest(query)
+		assert.Equal(suite.T(), http.StatusBadRequest, code, query)
+	}
+}
+
+func (suite *SuggestionsTestSuite) TestCreateTransactionHandler_Learns() {
+	body := `{"amount": 95000, "description": "Apotek Kimia Farma", "date": "2024-03-01T00:00:00Z", "category_id": 2, "kind": "expense"}`
+	for range 2 {
+		req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(body)))
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.createTransactionHandler(rr, req)
+		assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	}
+
+	_, suggestions := suite.suggest("description=apotek&amount=90000&limit=1")
+	if assert.Len(suite.T(), suggestions, 1) {
+		assert.Equal(suite.T(), int64(2), suggestions[0].CategoryID)
+	}
+}
+
+func TestSuggestionsTestSuite(t *testing.T) {
+	suite.Run(t, new(SuggestionsTestSuite))
+}
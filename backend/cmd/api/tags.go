@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+type CreateTagPayload struct {
+	Name  string `json:"name" validate:"required,max=50"`
+	Color string `json:"color" validate:"omitempty,max=20"`
+}
+
+type UpdateTagPayload struct {
+	Name  *string `json:"name" validate:"omitempty,max=50"`
+	Color *string `json:"color" validate:"omitempty,max=20"`
+}
+
+func (app *application) createTagHandler(w http.ResponseWriter, r *http.Request) {
+	var payload CreateTagPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	tag := &store.Tag{
+		Name:  payload.Name,
+		Color: payload.Color,
+	}
+
+	ctx := r.Context()
+	if err := app.store.Tags.Create(ctx, tag); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusCreated, tag); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) indexTagsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	tags, err := app.store.Tags.Index(ctx)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) getTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromCtx(r)
+
+	if err := app.jsonResponse(w, http.StatusOK, tag); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) updateTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromCtx(r)
+
+	var payload UpdateTagPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if payload.Name != nil {
+		tag.Name = *payload.Name
+	}
+	if payload.Color != nil {
+		tag.Color = *payload.Color
+	}
+
+	if err := app.store.Tags.Update(r.Context(), tag); err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tag); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func (app *application) deleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	tag := getTagFromCtx(r)
+
+	ctx := r.Context()
+	if err := app.store.Tags.Delete(ctx, tag.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) getExpensesByTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	date := r.URL.Query().Get("date")
+	kind, err := kindFromQuery(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Transactions.GetExpensesByMonthTag(ctx, kind, date)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func tagsFromIDs(ids []int64) []store.Tag {
+	tags := make([]store.Tag, 0, len(ids))
+	for _, id := range ids {
+		tags = append(tags, store.Tag{ID: id})
+	}
+	return tags
+}
+
+func (app *application) tagContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idParam := chi.URLParam(r, "tagID")
+		id, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		ctx := r.Context()
+
+		tag, err := app.store.Tags.GetByID(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFound(w, r, err)
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx = context.WithValue(ctx, tagCtx, tag)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getTagFromCtx(r *http.Request) *store.Tag {
+	tag, _ := r.Context().Value(tagCtx).(*store.Tag)
+	return tag
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockTagStore struct {
+	tags []store.Tag
+	tag  *store.Tag
+	err  error
+}
+
+func (m *MockTagStore) Create(ctx context.Context, tag *store.Tag) error {
+	if m.err != nil {
+		return m.err
+	}
+	tag.ID = 1
+	return nil
+}
+
+func (m *MockTagStore) Index(ctx context.Context) ([]store.Tag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.tags, nil
+}
+
+func (m *MockTagStore) GetByID(ctx context.Context, id int64) (*store.Tag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.tag == nil {
+		return nil, store.ErrNotFound
+	}
+	return m.tag, nil
+}
+
+func (m *MockTagStore) Update(ctx context.Context, tag *store.Tag) error {
+	return m.err
+}
+
+func (m *MockTagStore) Delete(ctx context.Context, id int64) error {
+	return m.err
+}
+
+type TagsTestSuite struct {
+	suite.Suite
+	app *application
+}
+
+func (suite *TagsTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.app = &application{config: cfg, store: store.NewStorage(nil)}
+}
+
+func (suite *TagsTestSuite) TestIndexTagsHandler_Success() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Tags: &MockTagStore{
+			tags: []store.Tag{
+				{ID: 1, Name: "bali-trip", Color: "#0ea5e9"},
+				{ID: 2, Name: "reimbursable", Color: "#666"},
+			},
+		},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/tags", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexTagsHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data []store.Tag `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data, 2)
+	assert.Equal(suite.T(), "reimbursable", response.Data[1].Name)
+}
+
+func (suite *TagsTestSuite) TestCreateTagHandler_Success() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Tags: &MockTagStore{},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	jsonBody, err := json.Marshal(CreateTagPayload{Name: "bali-trip"})
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/tags", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createTagHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+}
+
+func (suite *TagsTestSuite) TestCreateTagHandler_DuplicateName() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Tags: &MockTagStore{err: store.ErrConflict},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	jsonBody, err := json.Marshal(CreateTagPayload{Name: "bali-trip"})
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/tags", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createTagHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *TagsTestSuite) TestUpdateTagHandler_Rename() {
+	tag := &store.Tag{ID: 1, Name: "bali", Color: "#666"}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Tags: &MockTagStore{tag: tag},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodPatch, "/tags/1", bytes.NewReader([]byte(`{"name": "bali-trip"}`)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), tagCtx, tag))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateTagHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), "bali-trip", tag.Name)
+	assert.Equal(suite.T(), "#666", tag.Color)
+}
+
+func (suite *TagsTestSuite) TestGetExpensesByTagHandler_Success() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{
+			expensesByMonthTag: []store.TagReturnValue{
+				{ID: 1, Name: "bali-trip", Color: "#0ea5e9", Amount: 3500000},
+			},
+		},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/expenses_by_tag?date=2023-08-01", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.getExpensesByTagHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data []store.TagReturnValue `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data, 1)
+	assert.Equal(suite.T(), int64(3500000), response.Data[0].Amount)
+}
+
+func TestTagsTestSuite(t *testing.T) {
+	suite.Run(t, new(TagsTestSuite))
+}
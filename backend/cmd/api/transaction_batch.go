@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// BatchOperationPayload is one step of a batch: a create with the new
+// transaction, an update with the changes to transaction ID, or a delete of
+// transaction ID. Version, when given, must be the current version of the
+// transaction, like an If-Match header.
+type BatchOperationPayload struct {
+	Op          string                    `json:"op" validate:"required,oneof=create update delete"`
+	ID          int64                     `json:"id" validate:"required_unless=Op create"`
+	Version     *int64                    `json:"version"`
+	Transaction *CreateTransactionPayload `json:"transaction" validate:"required_if=Op create"`
+	Changes     *UpdateTransactionPayload `json:"changes" validate:"required_if=Op update"`
+}
+
+type BatchTransactionsPayload struct {
+	Operations []BatchOperationPayload `json:"operations" validate:"required,min=1,max=500,dive"`
+}
+
+// BatchOperationResult is the outcome of one step of a batch. Transaction
+// is left out for deletes.
+type BatchOperationResult struct {
+	Op          string             `json:"op"`
+	ID          int64              `json:"id"`
+	Transaction *store.Transaction `json:"transaction,omitempty"`
+}
+
+// batchTransactionsHandler creates, updates and deletes many transactions
+// at once. Every operation is checked before anything is saved, and then
+// all of them are saved in one database transaction, so a failing operation
+// leaves everything as it was. Errors name the index of the operation.
+func (app *application) batchTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	var payload BatchTransactionsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	operations := make([]store.TransactionOperation, len(payload.Operations))
+	// the classifier unlearns updated and deleted transactions as they were
+	previous := make([]*store.Transaction, len(payload.Operations))
+	seen := map[int64]bool{}
+	for i := range payload.Operations {
+		op := &payload.Operations[i]
+		if op.Op != store.BatchCreate {
+			if seen[op.ID] {
+				app.badRequest(w, r, fmt.Errorf("operation %d: transaction %d appears more than once", i, op.ID))
+				return
+			}
+			seen[op.ID] = true
+		}
+
+		transaction, old, err := app.prepareBatchOperation(ctx, op)
+		if err != nil {
+			app.batchError(w, r, fmt.Errorf("operation %d: %w", i, err))
+			return
+		}
+
+		operations[i] = store.TransactionOperation{Action: op.Op, Transaction: transaction}
+		previous[i] = old
+	}
+
+	if err := app.store.Transactions.Batch(ctx, operations); err != nil {
+		app.batchError(w, r, err)
+		return
+	}
+
+	results := make([]BatchOperationResult, len(operations))
+	for i, op := range operations {
+		if previous[i] != nil {
+			app.forgetTransaction(previous[i])
+		}
+
+		results[i] = BatchOperationResult{Op: op.Action, ID: op.Transaction.ID}
+		if op.Action != store.BatchDelete {
+			app.learnTransaction(op.Transaction)
+			results[i].Transaction = op.Transaction
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// prepareBatchOperation returns the transaction an operation will save or
+// delete and, for updates and deletes, a copy of it as it is now.
+func (app *application) prepareBatchOperation(ctx context.Context, op *BatchOperationPayload) (*store.Transaction, *store.Transaction, error) {
+	if op.Op == store.BatchCreate {
+		transaction, err := app.newTransaction(ctx, op.Transaction)
+		return transaction, nil, err
+	}
+
+	transaction, err := app.store.Transactions.GetById(ctx, op.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, invalidPayload(fmt.Errorf("transaction %d not found", op.ID))
+		}
+		return nil, nil, err
+	}
+	if op.Version != nil && *op.Version != transaction.Version {
+		return nil, nil, store.ErrVersionMismatch
+	}
+
+	old := *transaction
+	if op.Op == store.BatchUpdate {
+		if err := app.applyTransactionChanges(ctx, transaction, op.Changes); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return transaction, &old, nil
+}
+
+func (app *application) batchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrVersionMismatch):
+		app.preconditionFailed(w, r, err)
+	case errors.Is(err, store.ErrLocked):
+		app.conflict(w, r, err)
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, store.ErrInvalidReference):
+		app.badRequest(w, r, err)
+	default:
+		app.payloadError(w, r, err)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// batchTransactionStore looks transactions up by id, which the batch handler
+// does for every update and delete.
+type batchTransactionStore struct {
+	*MockTransactionStore
+	byID map[int64]*store.Transaction
+}
+
+func (m *batchTransactionStore) GetById(ctx context.Context, id int64) (*store.Transaction, error) {
+	transaction, ok := m.byID[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	copy := *transaction
+	return &copy, nil
+}
+
+type TransactionBatchTestSuite struct {
+	suite.Suite
+	app          *application
+	transactions *batchTransactionStore
+}
+
+func (suite *TransactionBatchTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	suite.transactions = &batchTransactionStore{
+		MockTransactionStore: &MockTransactionStore{},
+		byID: map[int64]*store.Transaction{
+			1: {ID: 1, AccountID: 1, Amount: 50000, Description: "Lunch", Kind: store.KindExpense, Currency: "IDR", Version: 2,
+				CategoryID: sql.NullInt64{Int64: 1, Valid: true}},
+			2: {ID: 2, AccountID: 1, Amount: 186000, Description: "Netflix", Kind: store.KindExpense, Currency: "IDR", Version: 1,
+				CategoryID: sql.NullInt64{Int64: 3, Valid: true}},
+		},
+	}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Transactions: suite.transactions,
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Cash", Currency: "IDR"}},
+	}}
+}
+
+func (suite *TransactionBatchTestSuite) batch(body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(http.MethodPost, "/transactions/batch", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	suite.app.batchTransactionsHandler(rr, req)
+	return rr
+}
+
+func (suite *TransactionBatchTestSuite) TestBatch() {
+	rr := suite.batch(`{"operations": [
+		{"op": "create", "transaction": {"amount": 25000, "description": "Coffee", "date": "2024-03-01", "category_id": 1}},
+		{"op": "update", "id": 1, "version": 2, "changes": {"category_id": 4}},
+		{"op": "delete", "id": 2}
+	]}`)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	operations := suite.transactions.operations
+	if assert.Len(suite.T(), operations, 3) {
+		assert.Equal(suite.T(), store.BatchCreate, operations[0].Action)
+		assert.Equal(suite.T(), "Coffee", operations[0].Transaction.Description)
+		assert.Equal(suite.T(), store.BatchUpdate, operations[1].Action)
+		assert.Equal(suite.T(), int64(4), operations[1].Transaction.CategoryID.Int64)
+		assert.Equal(suite.T(), int64(2), operations[1].Transaction.Version)
+		assert.Equal(suite.T(), store.BatchDelete, operations[2].Action)
+		assert.Equal(suite.T(), int64(2), operations[2].Transaction.ID)
+	}
+
+	var response struct {
+		Data []BatchOperationResult `json:"data"`
+	}
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), response.Data, 3) {
+		assert.Equal(suite.T(), int64(100), response.Data[0].ID)
+		assert.Equal(suite.T(), "Coffee", response.Data[0].Transaction.Description)
+		assert.Equal(suite.T(), int64(1), response.Data[1].ID)
+		assert.Equal(suite.T(), int64(2), response.Data[2].ID)
+		assert.Nil(suite.T(), response.Data[2].Transaction)
+	}
+}
+
+func (suite *TransactionBatchTestSuite) TestBatch_Invalid() {
+	for _, body := range []string{
+		`{"operations": []}`,
+		`{"operations": [{"op": "move", "id": 1}]}`,
+		`{"operations": [{"op": "create"}]}`,
+		`{"operations": [{"op": "update", "changes": {"amount": 1}}]}`,
+		`{"operations": [{"op": "update", "id": 1}]}`,
+		`{"operations": [{"op": "delete", "id": 1}, {"op": "update", "id": 1, "changes": {"amount": 1}}]}`,
+		`{"operations": [{"op": "delete", "id": 2}, {"op": "delete", "id": 9}]}`,
+		`{"operations": [{"op": "create", "transaction": {"amount": 1, "description": "x", "date": "2024-03-01", "category_id": 1, "currency": "USD"}}]}`,
+	} {
+		rr := suite.batch(body)
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+	assert.Empty(suite.T(), suite.transactions.operations)
+}
+
+func (suite *TransactionBatchTestSuite) TestBatch_ErrorNamesOperation() {
+	rr := suite.batch(`{"operations": [{"op": "delete", "id": 2}, {"op": "delete", "id": 9}]}`)
+
+	var response map[string]string
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "operation 1: transaction 9 not found", response["error"])
+}
+
+func (suite *TransactionBatchTestSuite) TestBatch_StaleVersion() {
+	rr := suite.batch(`{"operations": [{"op": "delete", "id": 1, "version": 1}]}`)
+
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, rr.Code)
+	assert.Empty(suite.T(), suite.transactions.operations)
+}
+
+func (suite *TransactionBatchTestSuite) TestBatch_StoreErrors() {
+	for err, status := range map[error]int{
+		&store.BatchError{Index: 0, Err: store.ErrVersionMismatch}:  http.StatusPreconditionFailed,
+		&store.BatchError{Index: 0, Err: store.ErrInvalidReference}: http.StatusBadRequest,
+		fmt.Errorf("database error"):                                http.StatusInternalServerError,
+	} {
+		suite.transactions.err = err
+		rr := suite.batch(`{"operations": [{"op": "update", "id": 1, "changes": {"amount": 60000}}]}`)
+		assert.Equal(suite.T(), status, rr.Code, err.Error())
+	}
+}
+
+func TestTransactionBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(TransactionBatchTestSuite))
+}
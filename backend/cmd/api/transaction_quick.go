@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/quickentry"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// QuickTransactionPayload is a transaction typed as one line, such as
+// "kopi 35k kemarin #jajan @gopay". The parsed transaction is only returned
+// for confirmation unless Create is set. Relative dates such as "kemarin"
+// are read in Timezone, the client's IANA zone, or in config.Timezone when
+// it is not sent.
+type QuickTransactionPayload struct {
+	Text     string `json:"text" validate:"required,max=500"`
+	Create   bool   `json:"create"`
+	Timezone string `json:"timezone" validate:"omitempty,timezone"`
+}
+
+// quickTransactionHandler parses a quick entry line into a transaction. It
+// responds with the transaction as it would be created, or creates it when
+// the payload asks to.
+func (app *application) quickTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	var payload QuickTransactionPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	location, err := app.quickEntryLocation(payload.Timezone)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	entry, err := quickentry.Parse(payload.Text, time.Now().In(location))
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	create, err := app.quickTransactionPayload(ctx, entry)
+	if err != nil {
+		app.payloadError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(create); err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	transaction, err := app.newTransaction(ctx, create)
+	if err != nil {
+		app.payloadError(w, r, err)
+		return
+	}
+
+	if !payload.Create {
+		if err := app.jsonResponse(w, http.StatusOK, transaction); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.store.Transactions.Create(ctx, transaction); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		case errors.Is(err, store.ErrLocked):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.learnTransaction(transaction)
+
+	if err := app.jsonResponse(w, http.StatusCreated, transaction); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// quickEntryLocation is the zone a quick entry is dated in: the client's
+// zone when it sent one, and the configured zone otherwise.
+func (app *application) quickEntryLocation(zone string) (*time.Location, error) {
+	if zone == "" {
+		zone = app.config.Timezone
+	}
+	return time.LoadLocation(zone)
+}
+
+// quickTransactionPayload turns a parsed entry into a create payload,
+// looking up the category and account it names and converting the amount to
+// the minor unit of the account's currency. Income is booked as a
+// negative amount, whether it was written with a "+" or its category is an
+// income category.
+func (app *application) quickTransactionPayload(ctx context.Context, entry *quickentry.Entry) (*CreateTransactionPayload, error) {
+	payload := &CreateTransactionPayload{
+		Description: entry.Description,
+		Date:        entry.Date.Format(time.DateOnly),
+	}
+	if entry.Income {
+		payload.Kind = store.KindIncome
+	}
+
+	if entry.Category != "" {
+		categories, err := app.store.Categories.Index(ctx)
+		if err != nil {
+			return nil, err
+		}
+		category := findByName(categories, entry.Category, func(c store.Category) string { return c.Name })
+		if category == nil {
+			return nil, invalidPayload(fmt.Errorf("category %q not found", entry.Category))
+		}
+		payload.CategoryID = &category.ID
+		if payload.Kind == "" {
+			payload.Kind = category.Kind
+		}
+		if payload.Description == "" {
+			payload.Description = category.Name
+		}
+	}
+
+	var account *store.Account
+	if entry.Account != "" {
+		accounts, err := app.store.Accounts.Index(ctx)
+		if err != nil {
+			return nil, err
+		}
+		account = findByName(accounts, entry.Account, func(a store.Account) string { return a.Name })
+		if account == nil {
+			return nil, invalidPayload(fmt.Errorf("account %q not found", entry.Account))
+		}
+		payload.AccountID = &account.ID
+	} else {
+		var err error
+		if account, err = app.store.Accounts.GetDefault(ctx); err != nil {
+			return nil, accountLookupError(err)
+		}
+	}
+
+	// entries are written in whole units of the account's currency
+	amount, err := store.MinorUnits(entry.Amount, account.Currency)
+	if err != nil {
+		return nil, invalidPayload(err)
+	}
+	payload.Amount = amount
+
+	if payload.Description == "" {
+		return nil, invalidPayload(errors.New("a description or #category is required"))
+	}
+	if payload.Kind == store.KindIncome {
+		payload.Amount = -payload.Amount
+	}
+
+	return payload, nil
+}
+
+// findByName finds the item whose name matches name regardless of case and
+// punctuation, so that "#makan-siang" finds "Makan Siang".
+func findByName[T any](items []T, name string, nameOf func(T) string) *T {
+	want := importer.NormalizeDescription(name)
+	for i := range items {
+		if importer.NormalizeDescription(nameOf(items[i])) == want {
+			return &items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// quickTransactionStore records the transactions created through it.
+type quickTransactionStore struct {
+	*MockTransactionStore
+	created []*store.Transaction
+}
+
+func (m *quickTransactionStore) Create(ctx context.Context, transaction *store.Transaction) error {
+	if m.err != nil {
+		return m.err
+	}
+	transaction.ID = int64(len(m.created) + 1)
+	m.created = append(m.created, transaction)
+	return nil
+}
+
+type QuickTransactionTestSuite struct {
+	suite.Suite
+	app          *application
+	transactions *quickTransactionStore
+}
+
+func (suite *QuickTransactionTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr:     "0.0.0.0",
+		Env:      "test",
+		Timezone: "Asia/Jakarta",
+	}
+	gopay := store.Account{ID: 3, Name: "GoPay", Currency: "IDR"}
+	suite.transactions = &quickTransactionStore{MockTransactionStore: &MockTransactionStore{}}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Transactions: suite.transactions,
+		Accounts: &MockAccountStore{
+			account:  &gopay,
+			accounts: []store.Account{{ID: 2, Name: "BCA", Currency: "IDR"}, gopay, {ID: 4, Name: "Wise", Currency: "USD"}},
+		},
+		Categories: &MockCategoryStore{categories: []store.Category{
+			{ID: 5, Name: "Jajan", Kind: store.KindExpense},
+			{ID: 6, Name: "Makan Siang", Kind: store.KindExpense},
+			{ID: 7, Name: "Gaji", Kind: store.KindIncome},
+		}},
+		CategorizationRules: &MockCategorizationRuleStore{},
+	}}
+}
+
+func (suite *QuickTransactionTestSuite) request(body string) (*httptest.ResponseRecorder, *store.Transaction) {
+	req, err := http.NewRequest(http.MethodPost, "/transactions/quick", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	suite.app.quickTransactionHandler(rr, req)
+
+	var response struct {
+		Data store.Transaction `json:"data"`
+	}
+	if rr.Code == http.StatusOK || rr.Code == http.StatusCreated {
+		err = json.Unmarshal(rr.Body.Bytes(), &response)
+		assert.NoError(suite.T(), err)
+	}
+	return rr, &response.Data
+}
+
+func (suite *QuickTransactionTestSuite) today(zone string) time.Time {
+	location, err := time.LoadLocation(zone)
+	assert.NoError(suite.T(), err)
+	return time.Now().In(location)
+}
+
+func (suite *QuickTransactionTestSuite) TestQuickTransaction_Preview() {
+	rr, transaction := suite.request(`{"text": "kopi 35k kemarin #jajan @gopay"}`)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Empty(suite.T(), suite.transactions.created)
+	assert.Equal(suite.T(), int64(35000), transaction.Amount)
+	assert.Equal(suite.T(), "kopi", transaction.Description)
+	assert.Equal(suite.T(), store.KindExpense, transaction.Kind)
+	assert.Equal(suite.T(), int64(3), transaction.AccountID)
+	assert.Equal(suite.T(), int64(5), transaction.CategoryID.Int64)
+	assert.Equal(suite.T(), suite.today("Asia/Jakarta").AddDate(0, 0, -1).Format(time.DateOnly), transaction.Date)
+}
+
+func (suite *QuickTransactionTestSuite) TestQuickTransaction_Timezone() {
+	// a day apart for most of the day, so the zone decides what today is
+	for _, zone := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago"} {
+		_, transaction := suite.request(`{"text": "kopi 35k", "timezone": "` + zone + `"}`)
+		assert.Equal(suite.T(), suite.today(zone).Format(time.DateOnly), transaction.Date, zone)
+	}
+}
+
+func (suite *QuickTransactionTestSuite) TestQuickTransaction_Create() {
+	rr, transaction := suite.request(`{"text": "nasi padang Rp 30.000 #makan-siang @bca", "create": true}`)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+	if assert.Len(suite.T(), suite.transactions.created, 1) {
+		created := suite.transactions.created[0]
+		assert.Equal(suite.T(), int64(30000), created.Amount)
+		assert.Equal(suite.T(), "nasi padang", created.Description)
+		assert.Equal(suite.T(), int64(6), created.CategoryID.Int64)
+	}
+	assert.Equal(suite.T(), int64(1), transaction.ID)
+}
+
+func (suite *QuickTransactionTestSuite) TestQuickTransaction_Income() {
+	// income is stored as a negative amount, whether marked by "+" or by an
+	// income category, which also stands in for a missing description
+	_, transaction := suite.request(`{"text": "+15jt #gaji"}`)
+	assert.Equal(suite.T(), int64(-15000000), transaction.Amount)
+	assert.Equal(suite.T(), store.KindIncome, transaction.Kind)
+	assert.Equal(suite.T(), "Gaji", transaction.Description)
+
+	_, transaction = suite.request(`{"text": "refund tiket +250rb #jajan"}`)
+	assert.Equal(suite.T(), int64(-250000), transaction.Amount)
+	assert.Equal(suite.T(), store.KindIncome, transaction.Kind)
+}
+
+func (suite *QuickTransactionTestSuite) TestQuickTransaction_MinorUnits() {
+	// entries are whole units, stored in cents for a USD account
+	_, transaction := suite.request(`{"text": "coffee 5 @wise"}`)
+	assert.Equal(suite.T(), int64(500), transaction.Amount)
+}
+
+func (suite *QuickTransactionTestSuite) TestQuickTransaction_Invalid() {
+	for _, body := range []string{
+		`{"text": ""}`,
+		`{"text": "kopi"}`,
+		`{"text": "35k"}`,
+		`{"text": "kopi 35k #kopi"}`,
+		`{"text": "kopi 35k @ovo"}`,
+		`{"text": "kopi 35k 31/2"}`,
+		`{"text": "kopi 35k", "timezone": "Mars/Olympus_Mons"}`,
+	} {
+		rr, _ := suite.request(body)
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, body)
+	}
+	assert.Empty(suite.T(), suite.transactions.created)
+}
+
+func TestQuickTransactionTestSuite(t *testing.T) {
+	suite.Run(t, new(QuickTransactionTestSuite))
+}
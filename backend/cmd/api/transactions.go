@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -13,11 +15,62 @@ import (
 )
 
 type CreateTransactionPayload struct {
-	CategoryID     *int64 `json:"category_id"`
-	Amount         int64  `json:"amount" validate:"required"`
-	RunningBalance *int64 `json:"running_balance"`
-	Description    string `json:"description" validate:"required"`
-	Date           string `json:"date" validate:"required"`
+	AccountID   *int64         `json:"account_id"`
+	CategoryID  *int64         `json:"category_id"`
+	EventID     *int64         `json:"event_id"`
+	PayeeID     *int64         `json:"payee_id"`
+	Amount      int64          `json:"amount" validate:"required"`
+	Description string         `json:"description" validate:"required"`
+	Date        string         `json:"date" validate:"required"`
+	Kind        string         `json:"kind" validate:"omitempty,oneof=expense income transfer adjustment"`
+	Currency    string         `json:"currency" validate:"omitempty,len=3,uppercase"`
+	Tags        []int64        `json:"tags"`
+	Splits      []SplitPayload `json:"splits" validate:"omitempty,dive"`
+}
+
+// SplitPayload is one category line of a split transaction.
+type SplitPayload struct {
+	CategoryID *int64 `json:"category_id"`
+	Amount     int64  `json:"amount" validate:"required"`
+	Note       string `json:"note" validate:"max=255"`
+}
+
+var errSplitsMismatch = errors.New("splits must add up to the transaction amount")
+
+func splitsFromPayload(payload []SplitPayload) []store.Split {
+	splits := make([]store.Split, 0, len(payload))
+	for _, line := range payload {
+		split := store.Split{Amount: line.Amount, Note: line.Note}
+		if line.CategoryID != nil && *line.CategoryID != 0 {
+			split.CategoryID = line.CategoryID
+		}
+		splits = append(splits, split)
+	}
+	return splits
+}
+
+// validateSplits checks that a split transaction's lines add up to its
+// amount. Transactions without splits are always valid.
+func validateSplits(transaction *store.Transaction) error {
+	if len(transaction.Splits) == 0 {
+		return nil
+	}
+	if store.SplitsTotal(transaction.Splits) != transaction.Amount {
+		return errSplitsMismatch
+	}
+	return nil
+}
+
+// checkTransactionDate fails unless the date is a calendar date (YYYY-MM-DD)
+// or an RFC 3339 timestamp.
+func checkTransactionDate(date string) error {
+	if _, err := time.Parse(time.DateOnly, date); err == nil {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, date); err == nil {
+		return nil
+	}
+	return fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", date)
 }
 
 func (app *application) createTransactionHandler(w http.ResponseWriter, r *http.Request) {
@@ -32,100 +85,162 @@ func (app *application) createTransactionHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	ctx := r.Context()
+	transaction, err := app.newTransaction(ctx, &payload)
+	if err != nil {
+		app.payloadError(w, r, err)
+		return
+	}
+
+	if err := app.store.Transactions.Create(ctx, transaction); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		case errors.Is(err, store.ErrLocked):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.learnTransaction(transaction)
+
+	if err := app.jsonResponse(w, http.StatusCreated, transaction); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// newTransaction builds the transaction described by a validated create
+// payload, booked in the currency of its account and categorized by the
+// rules when no category was given.
+func (app *application) newTransaction(ctx context.Context, payload *CreateTransactionPayload) (*store.Transaction, error) {
 	var categoryID sql.NullInt64
-	if *payload.CategoryID != 0 {
+	if payload.CategoryID != nil && *payload.CategoryID != 0 {
 		categoryID = sql.NullInt64{Int64: *payload.CategoryID, Valid: true}
 	} else {
 		categoryID = sql.NullInt64{Valid: false}
 	}
 
-	var runningBalance int64
-	if payload.RunningBalance != nil {
-		runningBalance = *payload.RunningBalance
-	} else {
-		lastTransaction, err := app.store.Transactions.GetLast(r.Context())
-		if err != nil {
-			if errors.Is(err, store.ErrNotFound) {
-				runningBalance = 0 - payload.Amount
-			} else {
-				app.internalServerError(w, r, err)
-				return
-			}
-		} else {
-			runningBalance = lastTransaction.RunningBalance - payload.Amount
-		}
+	if err := checkTransactionDate(payload.Date); err != nil {
+		return nil, invalidPayload(err)
 	}
 
+	account, err := app.resolveAccount(ctx, payload.AccountID)
+	if err != nil {
+		return nil, accountLookupError(err)
+	}
+	if err := checkCurrency(payload.Currency, account.Currency); err != nil {
+		return nil, invalidPayload(err)
+	}
+
+	// the running balance is filled in by the store
 	transaction := &store.Transaction{
-		CategoryID:     categoryID,
-		Amount:         payload.Amount,
-		RunningBalance: runningBalance,
-		Description:    payload.Description,
-		Date:           payload.Date,
+		AccountID:   account.ID,
+		CategoryID:  categoryID,
+		Amount:      payload.Amount,
+		Description: payload.Description,
+		Date:        payload.Date,
+		Kind:        payload.Kind,
+		Tags:        tagsFromIDs(payload.Tags),
+		Splits:      splitsFromPayload(payload.Splits),
+	}
+	if payload.EventID != nil && *payload.EventID != 0 {
+		transaction.EventID = sql.NullInt64{Int64: *payload.EventID, Valid: true}
+	}
+	if payload.PayeeID != nil && *payload.PayeeID != 0 {
+		transaction.PayeeID = sql.NullInt64{Int64: *payload.PayeeID, Valid: true}
 	}
 
-	ctx := r.Context()
-	if err := app.store.Transactions.Create(ctx, transaction); err != nil {
-		app.internalServerError(w, r, err)
-		return
+	if err := validateSplits(transaction); err != nil {
+		return nil, invalidPayload(err)
 	}
 
-	if err := app.jsonResponse(w, http.StatusCreated, transaction); err != nil {
-		app.internalServerError(w, r, err)
-		return
+	if err := app.categorizeTransaction(ctx, transaction); err != nil {
+		return nil, err
 	}
+
+	return transaction, nil
 }
 
 func (app *application) getTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	transaction := getTransactionFromCtx(r)
 
+	w.Header().Set("ETag", versionETag(transaction.Version))
 	if err := app.jsonResponse(w, http.StatusOK, transaction); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
+// kindFromQuery reads the optional "kind" query parameter shared by the
+// analytics endpoints, defaulting to expenses.
+func kindFromQuery(r *http.Request) (string, error) {
+	kind := r.URL.Query().Get("kind")
+	if kind == "" {
+		return store.KindExpense, nil
+	}
+	if !store.IsValidKind(kind) {
+		return "", fmt.Errorf("invalid kind %q", kind)
+	}
+	return kind, nil
+}
+
 func (app *application) getExpensesByMonthHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	date := r.URL.Query().Get("date")
-	amount, err := app.store.Transactions.GetExpensesByMonth(ctx, date)
+	kind, err := kindFromQuery(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	amount, err := app.store.Transactions.GetExpensesByMonth(ctx, kind, date)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
 	type wrapper struct {
-		Amount any `json:"amount"`
+		Amount      any   `json:"amount"`
+		Unconverted int64 `json:"unconverted"`
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, &wrapper{Amount: amount}); err != nil {
+	if err := app.jsonResponse(w, http.StatusOK, &wrapper{Amount: amount.Amount, Unconverted: amount.Unconverted}); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
 type ExpensesByMonthsResponse struct {
-	Date  string `json:"date"`
-	Amount int64  `json:"amount"`
+	Date        string `json:"date"`
+	Amount      int64  `json:"amount"`
+	Unconverted int64  `json:"unconverted"`
 }
 
 func (app *application) getExpensesByMonthsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	date := r.URL.Query().Get("date")
+	kind, err := kindFromQuery(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
 
 	var returnValue []ExpensesByMonthsResponse
 	dates := getBackdate(date)
 
 	for _, monthDate := range dates {
-		amount, err := app.store.Transactions.GetExpensesByMonthRange(ctx, monthDate)
+		amount, err := app.store.Transactions.GetExpensesByMonthRange(ctx, kind, monthDate)
 		if err != nil {
 			app.internalServerError(w, r, err)
 			return
 		}
 
 		response := ExpensesByMonthsResponse{
-			Date:  monthDate,
-			Amount: amount,
+			Date:        monthDate,
+			Amount:      amount.Amount,
+			Unconverted: amount.Unconverted,
 		}
 		returnValue = append(returnValue, response)
 	}
@@ -144,7 +259,13 @@ func (app *application) getExpensesByMonthsHandler(w http.ResponseWriter, r *htt
 func (app *application) getExpensesByMonthCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	date := r.URL.Query().Get("date")
-	transactions, err := app.store.Transactions.GetExpensesByMonthCategory(ctx, date)
+	kind, err := kindFromQuery(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	transactions, err := app.store.Transactions.GetExpensesByMonthCategory(ctx, kind, date)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -158,7 +279,13 @@ func (app *application) getExpensesByMonthCategoryHandler(w http.ResponseWriter,
 
 func (app *application) getExpensesLast30DaysHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	transactions, err := app.store.Transactions.GetExpensesLast30Days(ctx)
+	kind, err := kindFromQuery(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	transactions, err := app.store.Transactions.GetExpensesLast30Days(ctx, kind)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -170,10 +297,66 @@ func (app *application) getExpensesLast30DaysHandler(w http.ResponseWriter, r *h
 	}
 }
 
+// SavingsRateResponse is the savings of a month. Unconverted counts the
+// transactions left out of the totals for lack of an exchange rate.
+type SavingsRateResponse struct {
+	Date        string  `json:"date"`
+	Income      int64   `json:"income"`
+	Expenses    int64   `json:"expenses"`
+	Savings     int64   `json:"savings"`
+	Rate        float64 `json:"rate"`
+	Unconverted int64   `json:"unconverted"`
+}
+
+func (app *application) getSavingsRateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	date := r.URL.Query().Get("date")
+
+	income, err := app.store.Transactions.GetExpensesByMonthRange(ctx, store.KindIncome, date)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	expenses, err := app.store.Transactions.GetExpensesByMonthRange(ctx, store.KindExpense, date)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := SavingsRateResponse{
+		Date:        date,
+		Income:      income.Amount,
+		Expenses:    expenses.Amount,
+		Savings:     income.Amount - expenses.Amount,
+		Unconverted: income.Unconverted + expenses.Unconverted,
+	}
+	if response.Income > 0 {
+		response.Rate = float64(response.Savings) / float64(response.Income)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
 func (app *application) getBalanceByDateHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	date := r.URL.Query().Get("date")
-	amount, err := app.store.Transactions.GetBalanceByDate(ctx, date)
+
+	// without an account the balance covers every account
+	var accountID int64
+	if param := r.URL.Query().Get("account_id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			app.badRequest(w, r, err)
+			return
+		}
+		accountID = id
+	}
+
+	amount, err := app.store.Transactions.GetBalanceByDate(ctx, date, accountID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -192,40 +375,159 @@ func (app *application) getBalanceByDateHandler(w http.ResponseWriter, r *http.R
 func (app *application) indexTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	transactions, err := app.store.Transactions.Index(ctx)
+	filter, err := parseTransactionFilter(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	transactions, next, err := app.store.Transactions.Index(ctx, filter)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, transactions); err != nil {
+	if err := app.paginatedJSONResponse(w, http.StatusOK, transactions, encodeCursor(next)); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
+// parseTransactionFilter reads the listing query parameters: from, to,
+// account_id, payee_id, category_ids (comma separated, 0 for uncategorized),
+// tag_ids, amount_min, amount_max, description, kind, sort (asc|desc), limit
+// and cursor.
+func parseTransactionFilter(r *http.Request) (store.TransactionFilter, error) {
+	query := r.URL.Query()
+	var filter store.TransactionFilter
+
+	for _, param := range []string{"from", "to"} {
+		if value := query.Get(param); value != "" {
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return filter, fmt.Errorf("invalid %s date %q, expected YYYY-MM-DD", param, value)
+			}
+		}
+	}
+	filter.From = query.Get("from")
+	filter.To = query.Get("to")
+
+	if value := query.Get("account_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid account_id %q", value)
+		}
+		filter.AccountID = id
+	}
+	if value := query.Get("payee_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid payee_id %q", value)
+		}
+		filter.PayeeID = id
+	}
+
+	var err error
+	if filter.CategoryIDs, err = parseIDList(query.Get("category_ids")); err != nil {
+		return filter, fmt.Errorf("invalid category_ids: %w", err)
+	}
+	if filter.TagIDs, err = parseIDList(query.Get("tag_ids")); err != nil {
+		return filter, fmt.Errorf("invalid tag_ids: %w", err)
+	}
+
+	if value := query.Get("amount_min"); value != "" {
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid amount_min %q", value)
+		}
+		filter.MinAmount = &amount
+	}
+	if value := query.Get("amount_max"); value != "" {
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid amount_max %q", value)
+		}
+		filter.MaxAmount = &amount
+	}
+
+	filter.Description = query.Get("description")
+
+	if kind := query.Get("kind"); kind != "" {
+		if !store.IsValidKind(kind) {
+			return filter, fmt.Errorf("invalid kind %q", kind)
+		}
+		filter.Kind = kind
+	}
+
+	switch query.Get("sort") {
+	case "", "desc":
+	case "asc":
+		filter.Ascending = true
+	default:
+		return filter, fmt.Errorf("invalid sort %q, expected asc or desc", query.Get("sort"))
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > store.MaxTransactionLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", store.MaxTransactionLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
 func (app *application) deleteTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	transaction := getTransactionFromCtx(r)
+	if !app.checkIfMatch(w, r, transaction.Version) {
+		return
+	}
 
 	ctx := r.Context()
-	if err := app.store.Transactions.Delete(ctx, transaction.ID); err != nil {
-		app.internalServerError(w, r, err)
+	if err := app.store.Transactions.Delete(ctx, transaction.ID, transaction.Version); err != nil {
+		switch {
+		// changed by someone else after it was read above
+		case errors.Is(err, store.ErrVersionMismatch):
+			app.preconditionFailed(w, r, err)
+		case errors.Is(err, store.ErrLocked):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
 		return
 	}
+	app.forgetTransaction(transaction)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 type UpdateTransactionPayload struct {
-	Amount         *int64         `json:"amount" validate:"omitempty"`
-	RunningBalance *int64         `json:"running_balance" validate:"omitempty"`
-	Description    *string        `json:"description" validate:"omitempty"`
-	CategoryID     *NullableInt64 `json:"category_id" validate:"omitempty"`
+	Amount      *int64          `json:"amount" validate:"omitempty"`
+	Description *string         `json:"description" validate:"omitempty"`
+	Date        *string         `json:"date" validate:"omitempty"`
+	AccountID   *int64          `json:"account_id" validate:"omitempty"`
+	CategoryID  *NullableInt64  `json:"category_id" validate:"omitempty"`
+	EventID     *NullableInt64  `json:"event_id"`
+	PayeeID     *NullableInt64  `json:"payee_id"`
+	Kind        *string         `json:"kind" validate:"omitempty,oneof=expense income transfer adjustment"`
+	Currency    *string         `json:"currency" validate:"omitempty,len=3,uppercase"`
+	Tags        *[]int64        `json:"tags"`
+	Splits      *[]SplitPayload `json:"splits" validate:"omitempty,dive"`
 }
 
 func (app *application) updateTransactionHandler(w http.ResponseWriter, r *http.Request) {
 	transaction := getTransactionFromCtx(r)
-	oldAmount := transaction.Amount // Store original amount for cascading
+	if !app.checkIfMatch(w, r, transaction.Version) {
+		return
+	}
 
 	var payload UpdateTransactionPayload
 	if err := readJSON(w, r, &payload); err != nil {
@@ -238,29 +540,93 @@ func (app *application) updateTransactionHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// the classifier unlearns the transaction as it was before the update
+	old := *transaction
+
+	if err := app.applyTransactionChanges(r.Context(), transaction, &payload); err != nil {
+		app.payloadError(w, r, err)
+		return
+	}
+
+	if err := app.store.Transactions.Update(r.Context(), transaction); err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvalidReference):
+			app.badRequest(w, r, err)
+		// changed by someone else after it was read above
+		case errors.Is(err, store.ErrVersionMismatch):
+			app.preconditionFailed(w, r, err)
+		case errors.Is(err, store.ErrLocked):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	app.forgetTransaction(&old)
+	app.learnTransaction(transaction)
+
+	w.Header().Set("ETag", versionETag(transaction.Version))
+	if err := app.jsonResponse(w, http.StatusOK, transaction); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// applyTransactionChanges applies the fields set in a validated update
+// payload to the transaction.
+func (app *application) applyTransactionChanges(ctx context.Context, transaction *store.Transaction, payload *UpdateTransactionPayload) error {
 	if payload.Amount != nil {
-		transaction.RunningBalance = transaction.RunningBalance - (*payload.Amount - transaction.Amount)
 		transaction.Amount = *payload.Amount
 	}
-	if payload.RunningBalance != nil {
-		transaction.RunningBalance = *payload.RunningBalance
-	}
 	if payload.Description != nil {
 		transaction.Description = *payload.Description
 	}
+	if payload.Date != nil {
+		if err := checkTransactionDate(*payload.Date); err != nil {
+			return invalidPayload(err)
+		}
+		transaction.Date = *payload.Date
+	}
+	if payload.AccountID != nil && *payload.AccountID != transaction.AccountID {
+		account, err := app.resolveAccount(ctx, payload.AccountID)
+		if err != nil {
+			return accountLookupError(err)
+		}
+		transaction.AccountID = account.ID
+		transaction.Currency = account.Currency
+	}
+	if payload.Currency != nil {
+		if err := checkCurrency(*payload.Currency, transaction.Currency); err != nil {
+			return invalidPayload(err)
+		}
+	}
 	if payload.CategoryID != nil {
 		transaction.CategoryID = payload.CategoryID.NullInt64
+		// let the store derive the kind from the new category
+		transaction.Kind = ""
 	}
-
-	if err := app.store.Transactions.UpdateWithCascade(r.Context(), transaction, oldAmount); err != nil {
-		app.internalServerError(w, r, err)
-		return
+	if payload.EventID != nil {
+		transaction.EventID = payload.EventID.NullInt64
+	}
+	if payload.PayeeID != nil {
+		transaction.PayeeID = payload.PayeeID.NullInt64
+	}
+	if payload.Kind != nil {
+		transaction.Kind = *payload.Kind
+	}
+	if payload.Tags != nil {
+		transaction.Tags = tagsFromIDs(*payload.Tags)
+	}
+	if payload.Splits != nil {
+		transaction.Splits = splitsFromPayload(*payload.Splits)
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, transaction); err != nil {
-		app.internalServerError(w, r, err)
-		return
+	// an amount change must be matched by the existing splits too
+	if err := validateSplits(transaction); err != nil {
+		return invalidPayload(err)
 	}
+
+	return nil
 }
 
 func (app *application) transactionContextMiddleware(next http.Handler) http.Handler {
@@ -325,3 +691,22 @@ func getBackdate(date string) []string {
 
 	return dates
 }
+
+// parseIDList parses a comma separated list of ids, returning nil for an
+// empty value.
+func parseIDList(value string) ([]int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var ids []int64
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an id", part)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
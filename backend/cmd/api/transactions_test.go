@@ -8,7 +8,9 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/pukuri/expenses/backend/config"
 	"github.com/pukuri/expenses/backend/internal/store"
@@ -20,54 +22,114 @@ type MockTransactionStore struct {
 	transactions            []store.TransactionGet
 	transaction             *store.Transaction
 	transactionList         []*store.Transaction // For tracking multiple transactions in cascading tests
+	openingBalance          int64
+	filter                  store.TransactionFilter
+	nextCursor              *store.TransactionCursor
 	err                     error
 	expensesByMonth         int64
 	expensesByMonthRange    int64
+	incomeByMonthRange      int64
 	balanceByDate           int64
 	expensesByMonthCategory []store.CategoryReturnValue
+	expensesByMonthTag      []store.TagReturnValue
 	expensesLast30Days      []store.AmountDaily
+	fingerprints            []store.TransactionFingerprint
+	exports                 []store.TransactionExport
+	ruleChanges             []store.RuleChange
+	skippedRuleChanges      []int64
+	operations              []store.TransactionOperation
+	createBatchErr          error
 }
 
 func (m *MockTransactionStore) Create(ctx context.Context, transaction *store.Transaction) error {
 	return m.err
 }
 
-func (m *MockTransactionStore) Index(ctx context.Context) ([]store.TransactionGet, error) {
+func (m *MockTransactionStore) CreateBatch(ctx context.Context, transactions []*store.Transaction) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.createBatchErr != nil {
+		return m.createBatchErr
+	}
+	m.transactionList = append(m.transactionList, transactions...)
+	return nil
+}
+
+func (m *MockTransactionStore) Batch(ctx context.Context, operations []store.TransactionOperation) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, op := range operations {
+		if op.Action == store.BatchCreate {
+			op.Transaction.ID = int64(100 + len(m.operations))
+		}
+		m.operations = append(m.operations, op)
+	}
+	return nil
+}
+
+func (m *MockTransactionStore) ApplyRuleChanges(ctx context.Context, changes []store.RuleChange) ([]int64, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
-	return m.transactions, nil
+	m.ruleChanges = append(m.ruleChanges, changes...)
+	return append([]int64{}, m.skippedRuleChanges...), nil
 }
 
-func (m *MockTransactionStore) GetById(ctx context.Context, id int64) (*store.Transaction, error) {
+func (m *MockTransactionStore) GetFingerprints(ctx context.Context, accountID int64, from, to time.Time, externalIDs []string) ([]store.TransactionFingerprint, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
-	return m.transaction, nil
+	return m.fingerprints, nil
+}
+
+func (m *MockTransactionStore) Export(ctx context.Context, filter store.TransactionFilter, fn func(*store.TransactionExport) error) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.filter = filter
+	for i := range m.exports {
+		if err := fn(&m.exports[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockTransactionStore) Index(ctx context.Context, filter store.TransactionFilter) ([]store.TransactionGet, *store.TransactionCursor, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	m.filter = filter
+	return m.transactions, m.nextCursor, nil
 }
 
-func (m *MockTransactionStore) GetLast(ctx context.Context) (*store.Transaction, error) {
+func (m *MockTransactionStore) GetById(ctx context.Context, id int64) (*store.Transaction, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
 	return m.transaction, nil
 }
 
-func (m *MockTransactionStore) GetExpensesByMonth(ctx context.Context, date string) (int64, error) {
+func (m *MockTransactionStore) GetExpensesByMonth(ctx context.Context, kind string, date string) (store.ConvertedTotal, error) {
 	if m.err != nil {
-		return 0, m.err
+		return store.ConvertedTotal{}, m.err
 	}
-	return m.expensesByMonth, nil
+	return store.ConvertedTotal{Amount: m.expensesByMonth}, nil
 }
 
-func (m *MockTransactionStore) GetExpensesByMonthRange(ctx context.Context, date string) (int64, error) {
+func (m *MockTransactionStore) GetExpensesByMonthRange(ctx context.Context, kind string, date string) (store.ConvertedTotal, error) {
 	if m.err != nil {
-		return 0, m.err
+		return store.ConvertedTotal{}, m.err
+	}
+	if kind == store.KindIncome {
+		return store.ConvertedTotal{Amount: m.incomeByMonthRange}, nil
 	}
-	return m.expensesByMonthRange, nil
+	return store.ConvertedTotal{Amount: m.expensesByMonthRange}, nil
 }
 
-func (m *MockTransactionStore) GetExpensesByMonthCategory(ctx context.Context, date string) ([]store.CategoryReturnValue, error) {
+func (m *MockTransactionStore) GetExpensesByMonthCategory(ctx context.Context, kind string, date string) ([]store.CategoryReturnValue, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -75,7 +137,15 @@ func (m *MockTransactionStore) GetExpensesByMonthCategory(ctx context.Context, d
 	return m.expensesByMonthCategory, nil
 }
 
-func (m *MockTransactionStore) GetExpensesLast30Days(ctx context.Context) ([]store.AmountDaily, error) {
+func (m *MockTransactionStore) GetExpensesByMonthTag(ctx context.Context, kind string, date string) ([]store.TagReturnValue, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.expensesByMonthTag, nil
+}
+
+func (m *MockTransactionStore) GetExpensesLast30Days(ctx context.Context, kind string) ([]store.AmountDaily, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -83,18 +153,18 @@ func (m *MockTransactionStore) GetExpensesLast30Days(ctx context.Context) ([]sto
 	return m.expensesLast30Days, nil
 }
 
-func (m *MockTransactionStore) GetBalanceByDate(ctx context.Context, date string) (int64, error) {
+func (m *MockTransactionStore) GetBalanceByDate(ctx context.Context, date string, accountID int64) (int64, error) {
 	if m.err != nil {
 		return 0, m.err
 	}
 	return m.balanceByDate, nil
 }
 
-func (m *MockTransactionStore) Delete(ctx context.Context, id int64) error {
+func (m *MockTransactionStore) Delete(ctx context.Context, id, version int64) error {
 	return m.err
 }
 
-func (m *MockTransactionStore) UpdateWithCascade(ctx context.Context, transaction *store.Transaction, oldAmount int64) error {
+func (m *MockTransactionStore) Update(ctx context.Context, transaction *store.Transaction) error {
 	if m.err != nil {
 		return m.err
 	}
@@ -107,14 +177,19 @@ func (m *MockTransactionStore) UpdateWithCascade(ctx context.Context, transactio
 		}
 	}
 
-	// Update subsequent transactions if amount changed
-	if oldAmount != transaction.Amount {
-		amountDiff := transaction.Amount - oldAmount
-		for _, t := range m.transactionList {
-			if t.ID > transaction.ID {
-				t.RunningBalance = t.RunningBalance - amountDiff
-			}
+	// Recompute running balances in (date, id) order like the ledger does
+	ordered := make([]*store.Transaction, len(m.transactionList))
+	copy(ordered, m.transactionList)
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].Date == ordered[j].Date {
+			return ordered[i].ID < ordered[j].ID
 		}
+		return ordered[i].Date < ordered[j].Date
+	})
+	balance := m.openingBalance
+	for _, t := range ordered {
+		balance -= t.Amount
+		t.RunningBalance = balance
 	}
 
 	return nil
@@ -221,6 +296,110 @@ func (suite *TransactionsTestSuite) TestIndexTransactionHandler_StoreError() {
 	assert.Equal(suite.T(), "the server encountered a problem", response["error"])
 }
 
+func (suite *TransactionsTestSuite) TestIndexTransactionHandler_Filters() {
+	mockStore := &MockTransactionStore{
+		transactions: []store.TransactionGet{},
+		err:          nil,
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/transactions?from=2022-01-01&to=2022-03-31&category_ids=1,0&tag_ids=3,4&amount_min=1000&amount_max=50000&description=tokopedia&kind=expense&sort=asc&limit=20", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	filter := mockStore.filter
+	assert.Equal(suite.T(), "2022-01-01", filter.From)
+	assert.Equal(suite.T(), "2022-03-31", filter.To)
+	assert.Equal(suite.T(), []int64{1, 0}, filter.CategoryIDs)
+	assert.Equal(suite.T(), []int64{3, 4}, filter.TagIDs)
+	assert.Equal(suite.T(), int64(1000), *filter.MinAmount)
+	assert.Equal(suite.T(), int64(50000), *filter.MaxAmount)
+	assert.Equal(suite.T(), "tokopedia", filter.Description)
+	assert.Equal(suite.T(), store.KindExpense, filter.Kind)
+	assert.True(suite.T(), filter.Ascending)
+	assert.Equal(suite.T(), 20, filter.Limit)
+	assert.Nil(suite.T(), filter.After)
+}
+
+func (suite *TransactionsTestSuite) TestIndexTransactionHandler_NextCursor() {
+	cursor := &store.TransactionCursor{Date: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), ID: 42}
+	mockStore := &MockTransactionStore{
+		transactions: []store.TransactionGet{{ID: 42, Description: "Ayam Goreng"}},
+		nextCursor:   cursor,
+		err:          nil,
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/transactions?limit=1", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data       []store.TransactionGet `json:"data"`
+		NextCursor *string                `json:"next_cursor"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data, 1)
+	assert.NotNil(suite.T(), response.NextCursor)
+
+	// the returned cursor is accepted for the next page
+	req, err = http.NewRequest(http.MethodGet, "/transactions?limit=1&cursor="+*response.NextCursor, nil)
+	assert.NoError(suite.T(), err)
+
+	rr = httptest.NewRecorder()
+	suite.app.indexTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), int64(42), mockStore.filter.After.ID)
+	assert.True(suite.T(), cursor.Date.Equal(mockStore.filter.After.Date))
+}
+
+func (suite *TransactionsTestSuite) TestIndexTransactionHandler_InvalidParams() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	for _, query := range []string{
+		"from=01-01-2022",
+		"category_ids=food",
+		"tag_ids=1,,2",
+		"amount_min=lots",
+		"kind=salary",
+		"sort=random",
+		"limit=0",
+		"cursor=not-a-cursor",
+	} {
+		req, err := http.NewRequest(http.MethodGet, "/transactions?"+query, nil)
+		assert.NoError(suite.T(), err)
+
+		rr := httptest.NewRecorder()
+		suite.app.indexTransactionHandler(rr, req)
+
+		assert.Equal(suite.T(), http.StatusBadRequest, rr.Code, query)
+	}
+}
+
 func (suite *TransactionsTestSuite) TestCreateTransactionHandler_Success() {
 	mockStore := &MockTransactionStore{
 		transaction: &store.Transaction{
@@ -236,6 +415,7 @@ func (suite *TransactionsTestSuite) TestCreateTransactionHandler_Success() {
 	originalStore := suite.app.store
 	suite.app.store = store.Storage{
 		Transactions: mockStore,
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
 	}
 	defer func() { suite.app.store = originalStore }()
 
@@ -295,6 +475,158 @@ func (suite *TransactionsTestSuite) TestCreateTransactionHandler_InvalidInput()
 	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
 }
 
+func (suite *TransactionsTestSuite) TestCreateTransactionHandler_InvalidDate() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{},
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	body := `{"amount": 1000, "description": "Lunch", "date": "01/03/2024", "category_id": 1}`
+	req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	suite.app.createTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *TransactionsTestSuite) TestCreateTransactionHandler_ArchivedAccount() {
+	mockStore := &MockTransactionStore{
+		err: nil,
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 2, Name: "Old Wallet", Archived: true}},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	accountID := int64(2)
+	requestBody := CreateTransactionPayload{
+		AccountID:   &accountID,
+		Amount:      1000,
+		Description: "Lunch",
+		Date:        "2023-01-01T10:00:00Z",
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	suite.app.createTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *TransactionsTestSuite) TestCreateTransactionHandler_CurrencyMismatch() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{},
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 3, Name: "Wise", Currency: "USD"}},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	for currency, status := range map[string]int{"USD": http.StatusCreated, "IDR": http.StatusBadRequest} {
+		accountID, categoryID := int64(3), int64(1)
+		requestBody := CreateTransactionPayload{
+			AccountID:   &accountID,
+			CategoryID:  &categoryID,
+			Amount:      1000,
+			Currency:    currency,
+			Description: "Lunch",
+			Date:        "2023-01-01T10:00:00Z",
+		}
+		jsonBody, err := json.Marshal(requestBody)
+		assert.NoError(suite.T(), err)
+
+		req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(jsonBody))
+		assert.NoError(suite.T(), err)
+		req.Header.Set("Content-Type", "application/json")
+
+		rr := httptest.NewRecorder()
+		suite.app.createTransactionHandler(rr, req)
+
+		assert.Equal(suite.T(), status, rr.Code, currency)
+	}
+}
+
+func (suite *TransactionsTestSuite) TestCreateTransactionHandler_Splits() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{},
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	groceries, household := int64(1), int64(2)
+	requestBody := CreateTransactionPayload{
+		Amount:      250000,
+		Description: "Superindo",
+		Date:        "2023-01-01T10:00:00Z",
+		Splits: []SplitPayload{
+			{CategoryID: &groceries, Amount: 180000},
+			{CategoryID: &household, Amount: 50000, Note: "detergent"},
+			{Amount: 20000, Note: "birthday card"},
+		},
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusCreated, rr.Code)
+
+	var response struct {
+		Data store.Transaction `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data.Splits, 3)
+	assert.Equal(suite.T(), int64(2), *response.Data.Splits[1].CategoryID)
+	assert.Nil(suite.T(), response.Data.Splits[2].CategoryID)
+}
+
+func (suite *TransactionsTestSuite) TestCreateTransactionHandler_SplitsMismatch() {
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{},
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	requestBody := CreateTransactionPayload{
+		Amount:      250000,
+		Description: "Superindo",
+		Date:        "2023-01-01T10:00:00Z",
+		Splits: []SplitPayload{
+			{Amount: 180000},
+			{Amount: 50000},
+		},
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.createTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
 func (suite *TransactionsTestSuite) TestCreateTransactionHandler_StoreError() {
 	mockStore := &MockTransactionStore{
 		err: errors.New("database error"),
@@ -303,6 +635,7 @@ func (suite *TransactionsTestSuite) TestCreateTransactionHandler_StoreError() {
 	originalStore := suite.app.store
 	suite.app.store = store.Storage{
 		Transactions: mockStore,
+		Accounts:     &MockAccountStore{account: &store.Account{ID: 1, Name: "Main", Currency: "IDR"}},
 	}
 	defer func() { suite.app.store = originalStore }()
 
@@ -387,6 +720,88 @@ func (suite *TransactionsTestSuite) TestGetExpensesByMonthHandler_StoreError() {
 	assert.Equal(suite.T(), "the server encountered a problem", response["error"])
 }
 
+func (suite *TransactionsTestSuite) TestGetExpensesByMonthHandler_InvalidKind() {
+	mockStore := &MockTransactionStore{
+		expensesByMonth: 5000,
+		err:             nil,
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/expenses-by-month?date=2023-01-01&kind=salary", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.getExpensesByMonthHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+}
+
+func (suite *TransactionsTestSuite) TestGetSavingsRateHandler_Success() {
+	mockStore := &MockTransactionStore{
+		incomeByMonthRange:   10000,
+		expensesByMonthRange: 7500,
+		err:                  nil,
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/savings_rate?date=2023-01-01", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.getSavingsRateHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data SavingsRateResponse `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(10000), response.Data.Income)
+	assert.Equal(suite.T(), int64(7500), response.Data.Expenses)
+	assert.Equal(suite.T(), int64(2500), response.Data.Savings)
+	assert.InDelta(suite.T(), 0.25, response.Data.Rate, 0.0001)
+}
+
+func (suite *TransactionsTestSuite) TestGetSavingsRateHandler_NoIncome() {
+	mockStore := &MockTransactionStore{
+		expensesByMonthRange: 7500,
+		err:                  nil,
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodGet, "/savings_rate?date=2023-01-01", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.getSavingsRateHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data SavingsRateResponse `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(-7500), response.Data.Savings)
+	assert.Equal(suite.T(), float64(0), response.Data.Rate)
+}
+
 func (suite *TransactionsTestSuite) TestGetBalanceByDateHandler_Success() {
 	mockStore := &MockTransactionStore{
 		balanceByDate: 10000,
@@ -418,14 +833,17 @@ func (suite *TransactionsTestSuite) TestGetBalanceByDateHandler_Success() {
 }
 
 func (suite *TransactionsTestSuite) TestGetTransactionHandler_Success() {
+	converted := int64(15500000)
 	mockStore := &MockTransactionStore{
 		transaction: &store.Transaction{
-			ID:             1,
-			Amount:         1000,
-			RunningBalance: 2000,
-			Description:    "Test Transaction",
-			Date:           "2023-01-01T10:00:00Z",
-			CategoryID:     sql.NullInt64{Int64: 1, Valid: true},
+			ID:              1,
+			Amount:          1000,
+			Currency:        "USD",
+			ConvertedAmount: &converted,
+			RunningBalance:  2000,
+			Description:     "Test Transaction",
+			Date:            "2023-01-01T10:00:00Z",
+			CategoryID:      sql.NullInt64{Int64: 1, Valid: true},
 		},
 		err: nil,
 	}
@@ -453,6 +871,7 @@ func (suite *TransactionsTestSuite) TestGetTransactionHandler_Success() {
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), "Test Transaction", response.Data.Description)
 	assert.Equal(suite.T(), int64(1000), response.Data.Amount)
+	assert.Equal(suite.T(), &converted, response.Data.ConvertedAmount)
 }
 
 func (suite *TransactionsTestSuite) TestGetExpensesByMonthCategoryHandler_Success() {
@@ -696,6 +1115,162 @@ func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_Success() {
 	assert.Equal(suite.T(), int64(1500), response.Data.Amount)
 }
 
+func (suite *TransactionsTestSuite) TestGetTransactionHandler_ETag() {
+	transaction := &store.Transaction{ID: 1, Amount: 1000, Version: 3}
+
+	req, err := http.NewRequest(http.MethodGet, "/transactions/1", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, transaction))
+
+	rr := httptest.NewRecorder()
+	suite.app.getTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), `"3"`, rr.Header().Get("ETag"))
+}
+
+func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_IfMatch() {
+	mockStore := &MockTransactionStore{
+		transaction: &store.Transaction{
+			ID:          1,
+			Amount:      1000,
+			Description: "Lunch",
+			Date:        "2023-01-01T10:00:00Z",
+			CategoryID:  sql.NullInt64{Int64: 2, Valid: true},
+			Version:     3,
+		},
+	}
+	mockStore.transactionList = []*store.Transaction{mockStore.transaction}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPatch, "/transactions/1", bytes.NewReader([]byte(`{"amount":1500}`)))
+		assert.NoError(suite.T(), err)
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), transactionCtx, mockStore.transaction))
+
+		rr := httptest.NewRecorder()
+		suite.app.updateTransactionHandler(rr, req)
+		return rr
+	}
+
+	for _, stale := range []string{`"2"`, `W/"3"`, `"4", "5"`} {
+		rr := update(stale)
+		assert.Equal(suite.T(), http.StatusPreconditionFailed, rr.Code, stale)
+	}
+	assert.Equal(suite.T(), int64(1000), mockStore.transaction.Amount)
+
+	rr := update(`"1", "3"`)
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), int64(1500), mockStore.transaction.Amount)
+
+	// someone else saved between reading and writing the transaction
+	mockStore.err = store.ErrVersionMismatch
+	rr = update("*")
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, rr.Code)
+}
+
+func (suite *TransactionsTestSuite) TestDeleteTransactionHandler_IfMatchRequired() {
+	suite.app.config.RequireIfMatch = true
+	transaction := &store.Transaction{ID: 1, Version: 3}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{transaction: transaction},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	deleteTransaction := func(ifMatch string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodDelete, "/transactions/1", nil)
+		assert.NoError(suite.T(), err)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), transactionCtx, transaction))
+
+		rr := httptest.NewRecorder()
+		suite.app.deleteTransactionHandler(rr, req)
+		return rr
+	}
+
+	assert.Equal(suite.T(), http.StatusPreconditionRequired, deleteTransaction("").Code)
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, deleteTransaction(`"2"`).Code)
+	assert.Equal(suite.T(), http.StatusNoContent, deleteTransaction(`"3"`).Code)
+
+	// someone else saved between reading and deleting the transaction
+	suite.app.store.Transactions.(*MockTransactionStore).err = store.ErrVersionMismatch
+	assert.Equal(suite.T(), http.StatusPreconditionFailed, deleteTransaction(`"3"`).Code)
+}
+
+func (suite *TransactionsTestSuite) TestDeleteTransactionHandler_Reconciled() {
+	transaction := &store.Transaction{ID: 1, Version: 3, Status: store.StatusReconciled}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: &MockTransactionStore{transaction: transaction, err: store.ErrLocked},
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodDelete, "/transactions/1", nil)
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, transaction))
+
+	rr := httptest.NewRecorder()
+	suite.app.deleteTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusConflict, rr.Code)
+}
+
+func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_AmountBreaksSplits() {
+	mockStore := &MockTransactionStore{
+		transaction: &store.Transaction{
+			ID:          1,
+			Amount:      250000,
+			Description: "Superindo",
+			Date:        "2023-01-01T10:00:00Z",
+			Splits: []store.Split{
+				{ID: 1, Amount: 200000},
+				{ID: 2, Amount: 50000},
+			},
+		},
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodPatch, "/transactions/1", bytes.NewReader([]byte(`{"amount": 260000}`)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, mockStore.transaction))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+
+	// sending matching splits together with the new amount is accepted
+	body := `{"amount": 260000, "splits": [{"amount": 210000}, {"amount": 50000, "note": "detergent"}]}`
+	req, err = http.NewRequest(http.MethodPatch, "/transactions/1", bytes.NewReader([]byte(body)))
+	assert.NoError(suite.T(), err)
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, mockStore.transaction))
+
+	rr = httptest.NewRecorder()
+	suite.app.updateTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+	assert.Equal(suite.T(), int64(210000), mockStore.transaction.Splits[0].Amount)
+}
+
 func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_InvalidInput() {
 	mockStore := &MockTransactionStore{
 		transaction: &store.Transaction{ID: 1},
@@ -720,6 +1295,30 @@ func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_InvalidInput()
 	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
 }
 
+func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_InvalidDate() {
+	mockStore := &MockTransactionStore{
+		transaction: &store.Transaction{ID: 1, Date: "2023-01-01T10:00:00Z"},
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	req, err := http.NewRequest(http.MethodPatch, "/transactions/1", bytes.NewReader([]byte(`{"date": "2024-13-01"}`)))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, mockStore.transaction))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusBadRequest, rr.Code)
+	assert.Equal(suite.T(), "2023-01-01T10:00:00Z", mockStore.transaction.Date)
+}
+
 func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_StoreError() {
 	mockStore := &MockTransactionStore{
 		transaction: &store.Transaction{ID: 1},
@@ -783,6 +1382,7 @@ func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_CascadingUpdate
 	mockStore := &MockTransactionStore{
 		transaction:     transaction1, // This is the transaction we'll update
 		transactionList: []*store.Transaction{transaction1, transaction2, transaction3},
+		openingBalance:  6000,
 		err:             nil,
 	}
 
@@ -848,6 +1448,7 @@ func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_CascadingUpdate
 	mockStore := &MockTransactionStore{
 		transaction:     transaction1,
 		transactionList: []*store.Transaction{transaction1, transaction2},
+		openingBalance:  6000,
 		err:             nil,
 	}
 
@@ -907,6 +1508,7 @@ func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_CascadingUpdate
 	mockStore := &MockTransactionStore{
 		transaction:     transaction2, // Update the last transaction
 		transactionList: []*store.Transaction{transaction1, transaction2},
+		openingBalance:  6000,
 		err:             nil,
 	}
 
@@ -944,6 +1546,59 @@ func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_CascadingUpdate
 	assert.Equal(suite.T(), int64(4200), mockStore.transactionList[1].RunningBalance)
 }
 
+func (suite *TransactionsTestSuite) TestUpdateTransactionHandler_BackdatedDate() {
+	// Moving the last transaction before the first one reorders the ledger
+	transaction1 := &store.Transaction{
+		ID:             1,
+		Amount:         1000,
+		RunningBalance: 5000,
+		Description:    "First Transaction",
+		Date:           "2023-01-02T10:00:00Z",
+	}
+	transaction2 := &store.Transaction{
+		ID:             2,
+		Amount:         500,
+		RunningBalance: 4500,
+		Description:    "Second Transaction",
+		Date:           "2023-01-03T10:00:00Z",
+	}
+
+	mockStore := &MockTransactionStore{
+		transaction:     transaction2,
+		transactionList: []*store.Transaction{transaction1, transaction2},
+		openingBalance:  6000,
+		err:             nil,
+	}
+
+	originalStore := suite.app.store
+	suite.app.store = store.Storage{
+		Transactions: mockStore,
+	}
+	defer func() { suite.app.store = originalStore }()
+
+	newDate := "2023-01-01T10:00:00Z"
+	requestBody := UpdateTransactionPayload{
+		Date: &newDate,
+	}
+	jsonBody, err := json.Marshal(requestBody)
+	assert.NoError(suite.T(), err)
+
+	req, err := http.NewRequest(http.MethodPatch, "/transactions/2", bytes.NewReader(jsonBody))
+	assert.NoError(suite.T(), err)
+	req.Header.Set("Content-Type", "application/json")
+
+	req = req.WithContext(context.WithValue(req.Context(), transactionCtx, transaction2))
+
+	rr := httptest.NewRecorder()
+	suite.app.updateTransactionHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	assert.Equal(suite.T(), newDate, mockStore.transactionList[1].Date)
+	assert.Equal(suite.T(), int64(5500), mockStore.transactionList[1].RunningBalance)
+	assert.Equal(suite.T(), int64(4500), mockStore.transactionList[0].RunningBalance)
+}
+
 func TestTransactionsTestSuite(t *testing.T) {
 	suite.Run(t, new(TransactionsTestSuite))
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// indexTrashHandler lists the deleted transactions, events and categories
+// that can still be restored, most recently deleted first.
+func (app *application) indexTrashHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := app.store.Trash.Index(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, items); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// restoreTrashItemHandler takes one item out of the trash. The type is the
+// type of a trash item: transaction, event or category.
+func (app *application) restoreTrashItemHandler(w http.ResponseWriter, r *http.Request) {
+	itemType := chi.URLParam(r, "type")
+	switch itemType {
+	case store.TrashTransaction, store.TrashEvent, store.TrashCategory:
+	default:
+		app.notFound(w, r, fmt.Errorf("unknown trash item type %q", itemType))
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.store.Trash.Restore(ctx, itemType, id); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFound(w, r, err)
+		case errors.Is(err, store.ErrConflict):
+			app.conflict(w, r, errors.New("another category already uses this name or color"))
+		case errors.Is(err, store.ErrLocked):
+			app.conflict(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if itemType == store.TrashTransaction {
+		transaction, err := app.store.Transactions.GetById(ctx, id)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		app.learnTransaction(transaction)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockTrashStore struct {
+	items    []store.TrashItem
+	restored []string
+	err      error
+}
+
+func (m *MockTrashStore) Index(ctx context.Context) ([]store.TrashItem, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.items, nil
+}
+
+func (m *MockTrashStore) Restore(ctx context.Context, itemType string, id int64) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, item := range m.items {
+		if item.Type == itemType && item.ID == id {
+			m.restored = append(m.restored, itemType)
+			return nil
+		}
+	}
+	return store.ErrNotFound
+}
+
+func (m *MockTrashStore) Purge(ctx context.Context, before time.Time) (*store.PurgeResult, error) {
+	return &store.PurgeResult{}, m.err
+}
+
+type TrashTestSuite struct {
+	suite.Suite
+	app   *application
+	trash *MockTrashStore
+}
+
+func (suite *TrashTestSuite) SetupTest() {
+	cfg := &config.Config{
+		Addr: "0.0.0.0",
+		Env:  "test",
+	}
+	amount := int64(186000)
+	suite.trash = &MockTrashStore{items: []store.TrashItem{
+		{Type: store.TrashTransaction, ID: 7, Name: "Netflix", Amount: &amount, DeletedAt: "2024-03-02T10:00:00Z"},
+		{Type: store.TrashCategory, ID: 3, Name: "Subscriptions", DeletedAt: "2024-03-01T10:00:00Z"},
+	}}
+	suite.app = &application{config: cfg, store: store.Storage{
+		Trash: suite.trash,
+		Transactions: &MockTransactionStore{transaction: &store.Transaction{
+			ID:          7,
+			Amount:      186000,
+			Description: "Netflix",
+			Kind:        store.KindExpense,
+			CategoryID:  sql.NullInt64{Int64: 3, Valid: true},
+		}},
+	}}
+}
+
+func (suite *TrashTestSuite) restore(itemType, id string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(http.MethodPost, "/trash/"+itemType+"/"+id+"/restore", nil)
+	assert.NoError(suite.T(), err)
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("type", itemType)
+	rctx.URLParams.Add("id", id)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	rr := httptest.NewRecorder()
+	suite.app.restoreTrashItemHandler(rr, req)
+	return rr
+}
+
+func (suite *TrashTestSuite) TestIndexTrashHandler() {
+	req, err := http.NewRequest(http.MethodGet, "/trash", nil)
+	assert.NoError(suite.T(), err)
+
+	rr := httptest.NewRecorder()
+	suite.app.indexTrashHandler(rr, req)
+
+	assert.Equal(suite.T(), http.StatusOK, rr.Code)
+
+	var response struct {
+		Data []store.TrashItem `json:"data"`
+	}
+	err = json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), response.Data, 2)
+	assert.Equal(suite.T(), int64(186000), *response.Data[0].Amount)
+	assert.Nil(suite.T(), response.Data[1].Amount)
+}
+
+func (suite *TrashTestSuite) TestRestoreTrashItemHandler_Transaction() {
+	rr := suite.restore(store.TrashTransaction, "7")
+
+	assert.Equal(suite.T(), http.StatusNoContent, rr.Code)
+	assert.Equal(suite.T(), []string{store.TrashTransaction}, suite.trash.restored)
+	// the restored transaction is learned again
+	suggestions := suite.app.suggestions.Suggest("Netflix", 186000, 1)
+	if assert.Len(suite.T(), suggestions, 1) {
+		assert.Equal(suite.T(), int64(3), suggestions[0].CategoryID)
+	}
+}
+
+func (suite *TrashTestSuite) TestRestoreTrashItemHandler_Errors() {
+	assert.Equal(suite.T(), http.StatusNotFound, suite.restore("tag", "3").Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.restore(store.TrashCategory, "abc").Code)
+	assert.Equal(suite.T(), http.StatusNotFound, suite.restore(store.TrashEvent, "3").Code)
+
+	suite.trash.err = store.ErrConflict
+	assert.Equal(suite.T(), http.StatusConflict, suite.restore(store.TrashCategory, "3").Code)
+	assert.Empty(suite.T(), suite.trash.restored)
+}
+
+func TestTrashTestSuite(t *testing.T) {
+	suite.Run(t, new(TrashTestSuite))
+}
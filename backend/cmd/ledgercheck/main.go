@@ -0,0 +1,95 @@
+// Command ledgercheck recomputes the running balance of every transaction
+// and reports the rows whose stored balance has drifted. With --fix the
+// drifting rows are rewritten in one database transaction and recorded in the
+// audit log, attributed to the user given with --user. It exits with status 1
+// when drifts were found and left in place.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pukuri/expenses/backend/config"
+	"github.com/pukuri/expenses/backend/internal/db"
+	"github.com/pukuri/expenses/backend/internal/store"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "rewrite the drifting running balances")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	userID := flag.Int64("user", 0, "id of the user the fixes are attributed to in the audit log")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("cannot load config:", err)
+	}
+
+	conn, err := db.New(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx := store.WithActor(context.Background(), store.Actor{UserID: *userID})
+	report, err := store.NewStorage(conn).Ledger.Check(ctx, *fix)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		err = json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		err = writeReport(os.Stdout, report)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(report.Drifts) > 0 && !report.Fixed {
+		os.Exit(1)
+	}
+}
+
+// writeReport prints one line per drifting transaction, followed by a
+// summary per account and overall.
+func writeReport(w io.Writer, report *store.LedgerReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	if len(report.Drifts) > 0 {
+		fmt.Fprintln(tw, "TRANSACTION\tACCOUNT\tDATE\tSTORED\tEXPECTED\tDIFFERENCE\t")
+		for _, drift := range report.Drifts {
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%d\t%d\t%+d\t\n",
+				drift.TransactionID, drift.AccountID, drift.Date, drift.Stored, drift.Expected, drift.Expected-drift.Stored)
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, account := range report.Accounts {
+		fmt.Fprintf(w, "account %d: %d drifting from %s, largest difference %d\n",
+			account.AccountID, account.Drifts, account.From, account.LargestDifference)
+	}
+
+	if len(report.Drifts) == 0 {
+		_, err := fmt.Fprintf(w, "checked %d transactions, the ledger is consistent\n", report.Checked)
+		return err
+	}
+
+	outcome := "left as they are, run with --fix to rewrite them"
+	if report.Fixed {
+		outcome = "rewritten"
+	}
+	_, err := fmt.Fprintf(w, "checked %d transactions, %d drifting in %d accounts: %s\n",
+		report.Checked, len(report.Drifts), len(report.Accounts), outcome)
+	return err
+}
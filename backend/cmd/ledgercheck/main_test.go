@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReport(t *testing.T) {
+	report := &store.LedgerReport{
+		Checked: 120,
+		Drifts: []store.LedgerDrift{
+			{TransactionID: 4, AccountID: 1, Date: "2023-01-05T00:00:00Z", Stored: 300, Expected: 310},
+			{TransactionID: 9, AccountID: 2, Date: "2023-02-01T00:00:00Z", Stored: 100, Expected: 50},
+		},
+		Accounts: []store.LedgerAccountSummary{
+			{AccountID: 1, Drifts: 1, From: "2023-01-05T00:00:00Z", LargestDifference: 10},
+			{AccountID: 2, Drifts: 1, From: "2023-02-01T00:00:00Z", LargestDifference: 50},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeReport(&buf, report))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 7) {
+		assert.Equal(t, []string{"TRANSACTION", "ACCOUNT", "DATE", "STORED", "EXPECTED", "DIFFERENCE"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"4", "1", "2023-01-05T00:00:00Z", "300", "310", "+10"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"9", "2", "2023-02-01T00:00:00Z", "100", "50", "-50"}, strings.Fields(lines[2]))
+		assert.Equal(t, "account 2: 1 drifting from 2023-02-01T00:00:00Z, largest difference 50", lines[5])
+		assert.Equal(t, "checked 120 transactions, 2 drifting in 2 accounts: left as they are, run with --fix to rewrite them", lines[6])
+	}
+
+	report.Fixed = true
+	buf.Reset()
+	assert.NoError(t, writeReport(&buf, report))
+	assert.True(t, strings.HasSuffix(buf.String(), "2 drifting in 2 accounts: rewritten\n"))
+}
+
+func TestWriteReport_Consistent(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeReport(&buf, &store.LedgerReport{Checked: 120, Drifts: []store.LedgerDrift{}}))
+
+	assert.Equal(t, "checked 120 transactions, the ledger is consistent\n", buf.String())
+}
@@ -0,0 +1,9 @@
+SET search_path TO public;
+
+DROP INDEX IF EXISTS idx_transactions_kind_date;
+
+ALTER TABLE transactions
+DROP COLUMN kind;
+
+ALTER TABLE categories
+DROP COLUMN kind;
@@ -0,0 +1,19 @@
+SET search_path TO public;
+
+ALTER TABLE categories
+ADD COLUMN kind varchar(20) NOT NULL DEFAULT 'expense'
+  CHECK (kind IN ('expense', 'income', 'transfer', 'adjustment'));
+
+ALTER TABLE transactions
+ADD COLUMN kind varchar(20) NOT NULL DEFAULT 'expense'
+  CHECK (kind IN ('expense', 'income', 'transfer', 'adjustment'));
+
+-- Salary used to be recognised by name only
+UPDATE categories SET kind = 'income' WHERE name = 'Gajian';
+
+UPDATE transactions t
+SET kind = c.kind
+FROM categories c
+WHERE t.category_id = c.id;
+
+CREATE INDEX idx_transactions_kind_date ON transactions(kind, date);
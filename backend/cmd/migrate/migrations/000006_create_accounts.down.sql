@@ -0,0 +1,8 @@
+SET search_path TO public;
+
+DROP INDEX IF EXISTS idx_transactions_account_id_date;
+
+ALTER TABLE transactions
+DROP COLUMN account_id;
+
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,26 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS accounts(
+  id bigserial PRIMARY KEY,
+  name varchar(100) UNIQUE NOT NULL,
+  currency varchar(3) NOT NULL DEFAULT 'IDR',
+  opening_balance BIGINT NOT NULL DEFAULT 0,
+  archived boolean NOT NULL DEFAULT false,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+-- Existing history moves into a single default account whose opening
+-- balance is the balance before the first recorded transaction.
+INSERT INTO accounts (name, opening_balance)
+SELECT 'Main', COALESCE((SELECT running_balance + amount FROM transactions ORDER BY id ASC LIMIT 1), 0);
+
+ALTER TABLE transactions
+ADD COLUMN account_id BIGINT NULL REFERENCES accounts(id) ON DELETE RESTRICT;
+
+UPDATE transactions SET account_id = (SELECT id FROM accounts WHERE name = 'Main');
+
+ALTER TABLE transactions
+ALTER COLUMN account_id SET NOT NULL;
+
+CREATE INDEX idx_transactions_account_id_date ON transactions(account_id, date);
@@ -0,0 +1,3 @@
+SET search_path TO public;
+
+DROP INDEX IF EXISTS idx_transactions_date_id;
@@ -0,0 +1,4 @@
+SET search_path TO public;
+
+-- Keyset pagination and ledger recalculation both walk (date, id)
+CREATE INDEX IF NOT EXISTS idx_transactions_date_id ON transactions(date, id);
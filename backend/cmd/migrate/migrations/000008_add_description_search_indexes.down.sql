@@ -0,0 +1,6 @@
+SET search_path TO public;
+
+DROP INDEX IF EXISTS idx_event_expenses_description_trgm;
+DROP INDEX IF EXISTS idx_event_expenses_description_fts;
+DROP INDEX IF EXISTS idx_transactions_description_trgm;
+DROP INDEX IF EXISTS idx_transactions_description_fts;
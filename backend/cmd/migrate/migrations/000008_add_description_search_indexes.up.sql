@@ -0,0 +1,13 @@
+SET search_path TO public;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_description_fts
+  ON transactions USING GIN (to_tsvector('simple', description));
+CREATE INDEX IF NOT EXISTS idx_transactions_description_trgm
+  ON transactions USING GIN (description gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS idx_event_expenses_description_fts
+  ON event_expenses USING GIN (to_tsvector('simple', description));
+CREATE INDEX IF NOT EXISTS idx_event_expenses_description_trgm
+  ON event_expenses USING GIN (description gin_trgm_ops);
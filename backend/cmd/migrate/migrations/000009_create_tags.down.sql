@@ -0,0 +1,4 @@
+SET search_path TO public;
+
+DROP TABLE IF EXISTS transaction_tags;
+DROP TABLE IF EXISTS tags;
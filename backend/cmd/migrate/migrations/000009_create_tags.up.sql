@@ -0,0 +1,16 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS tags(
+  id bigserial PRIMARY KEY,
+  name varchar(50) UNIQUE NOT NULL,
+  color varchar(20) NOT NULL DEFAULT '#666',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS transaction_tags(
+  transaction_id BIGINT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+  tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  PRIMARY KEY (transaction_id, tag_id)
+);
+
+CREATE INDEX idx_transaction_tags_tag_id ON transaction_tags(tag_id);
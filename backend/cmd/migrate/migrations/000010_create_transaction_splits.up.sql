@@ -0,0 +1,12 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS transaction_splits(
+  id bigserial PRIMARY KEY,
+  transaction_id BIGINT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+  category_id BIGINT NULL REFERENCES categories(id) ON DELETE SET NULL,
+  amount BIGINT NOT NULL,
+  note varchar(255) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_transaction_splits_transaction_id ON transaction_splits(transaction_id);
+CREATE INDEX idx_transaction_splits_category_id ON transaction_splits(category_id);
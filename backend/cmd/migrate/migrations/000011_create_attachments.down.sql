@@ -0,0 +1,3 @@
+SET search_path TO public;
+
+DROP TABLE IF EXISTS attachments;
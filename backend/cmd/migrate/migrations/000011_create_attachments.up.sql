@@ -0,0 +1,17 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS attachments(
+  id bigserial PRIMARY KEY,
+  transaction_id BIGINT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+  event_expense_id BIGINT NULL REFERENCES event_expenses(id) ON DELETE CASCADE,
+  file_name varchar(255) NOT NULL,
+  content_type varchar(100) NOT NULL,
+  size BIGINT NOT NULL,
+  storage_key varchar(255) UNIQUE NOT NULL,
+  thumbnail_key varchar(255) NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  CONSTRAINT attachments_single_owner CHECK (num_nonnulls(transaction_id, event_expense_id) = 1)
+);
+
+CREATE INDEX idx_attachments_transaction_id ON attachments(transaction_id);
+CREATE INDEX idx_attachments_event_expense_id ON attachments(event_expense_id);
@@ -0,0 +1,4 @@
+SET search_path TO public;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS recurring_rule_id;
+DROP TABLE IF EXISTS recurring_rules;
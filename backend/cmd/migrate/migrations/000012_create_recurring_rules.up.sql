@@ -0,0 +1,24 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS recurring_rules(
+  id bigserial PRIMARY KEY,
+  account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE RESTRICT,
+  category_id BIGINT NULL REFERENCES categories(id) ON DELETE SET NULL,
+  amount BIGINT NOT NULL,
+  description varchar(255) NOT NULL,
+  kind varchar(20) NOT NULL DEFAULT '',
+  rrule varchar(255) NOT NULL,
+  start_date timestamp(0) with time zone NOT NULL,
+  end_date timestamp(0) with time zone NULL,
+  next_run timestamp(0) with time zone NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_recurring_rules_next_run ON recurring_rules(next_run) WHERE next_run IS NOT NULL;
+
+ALTER TABLE transactions
+ADD COLUMN recurring_rule_id BIGINT NULL REFERENCES recurring_rules(id) ON DELETE SET NULL;
+
+-- each occurrence of a rule is materialized at most once
+CREATE UNIQUE INDEX idx_transactions_recurring_rule_id_date ON transactions(recurring_rule_id, date) WHERE recurring_rule_id IS NOT NULL;
@@ -0,0 +1,20 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS import_profiles(
+  id bigserial PRIMARY KEY,
+  name varchar(100) UNIQUE NOT NULL,
+  delimiter varchar(1) NOT NULL DEFAULT ',',
+  has_header boolean NOT NULL DEFAULT true,
+  skip_rows int NOT NULL DEFAULT 0,
+  date_column varchar(100) NOT NULL,
+  date_format varchar(50) NOT NULL,
+  description_column varchar(100) NOT NULL,
+  amount_column varchar(100) NOT NULL DEFAULT '',
+  debit_column varchar(100) NOT NULL DEFAULT '',
+  credit_column varchar(100) NOT NULL DEFAULT '',
+  amount_sign varchar(20) NOT NULL CHECK (amount_sign IN ('negative_expense', 'positive_expense', 'debit_credit')),
+  decimal_separator varchar(1) NOT NULL DEFAULT '.',
+  thousand_separator varchar(1) NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+SET search_path TO public;
+
+ALTER TABLE transactions
+ADD COLUMN external_id varchar(255) NULL;
+
+-- a bank's transaction id (OFX FITID) is booked at most once per account
+CREATE UNIQUE INDEX idx_transactions_account_id_external_id ON transactions(account_id, external_id) WHERE external_id IS NOT NULL;
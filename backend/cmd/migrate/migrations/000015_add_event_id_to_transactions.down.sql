@@ -0,0 +1,3 @@
+SET search_path TO public;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS event_id;
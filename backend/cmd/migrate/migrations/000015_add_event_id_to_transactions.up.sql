@@ -0,0 +1,6 @@
+SET search_path TO public;
+
+ALTER TABLE transactions
+ADD COLUMN event_id BIGINT NULL REFERENCES events(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_transactions_event_id ON transactions(event_id) WHERE event_id IS NOT NULL;
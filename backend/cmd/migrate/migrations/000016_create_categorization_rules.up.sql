@@ -0,0 +1,20 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS categorization_rules(
+  id bigserial PRIMARY KEY,
+  name varchar(100) NOT NULL,
+  priority int NOT NULL DEFAULT 0,
+  enabled boolean NOT NULL DEFAULT true,
+  description_contains varchar(255) NOT NULL DEFAULT '',
+  description_pattern varchar(255) NOT NULL DEFAULT '',
+  min_amount BIGINT NULL,
+  max_amount BIGINT NULL,
+  account_id BIGINT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  category_id BIGINT NULL REFERENCES categories(id) ON DELETE SET NULL,
+  tag_ids BIGINT[] NOT NULL DEFAULT '{}',
+  rename_to varchar(255) NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_categorization_rules_priority ON categorization_rules(priority DESC, id ASC) WHERE enabled;
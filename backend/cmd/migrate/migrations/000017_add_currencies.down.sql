@@ -0,0 +1,11 @@
+SET search_path TO public;
+
+DROP FUNCTION IF EXISTS convert_amount(BIGINT, TEXT, TEXT, DATE);
+
+DROP TABLE IF EXISTS exchange_rates;
+
+ALTER TABLE event_expenses
+DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE transactions
+DROP COLUMN IF EXISTS currency;
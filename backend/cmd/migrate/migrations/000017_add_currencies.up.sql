@@ -0,0 +1,52 @@
+SET search_path TO public;
+
+-- a transaction is in the currency of its account
+ALTER TABLE transactions
+ADD COLUMN currency varchar(3) NULL;
+
+UPDATE transactions t SET currency = a.currency FROM accounts a WHERE a.id = t.account_id;
+
+ALTER TABLE transactions
+ALTER COLUMN currency SET NOT NULL;
+
+ALTER TABLE event_expenses
+ADD COLUMN currency varchar(3) NOT NULL DEFAULT 'IDR';
+
+-- one unit of currency is worth rate units of base_currency on date
+CREATE TABLE IF NOT EXISTS exchange_rates(
+  id bigserial PRIMARY KEY,
+  currency varchar(3) NOT NULL,
+  base_currency varchar(3) NOT NULL,
+  date DATE NOT NULL,
+  rate NUMERIC(24, 10) NOT NULL CHECK (rate > 0),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  UNIQUE (currency, base_currency, date),
+  CHECK (currency <> base_currency)
+);
+
+CREATE INDEX idx_exchange_rates_base_currency_date ON exchange_rates(base_currency, currency, date);
+
+-- convert_amount converts an amount with the rate closest to on_date,
+-- preferring the latest rate on or before it, and using a rate quoted the
+-- other way around when that is all there is. It returns NULL when the two
+-- currencies have never been quoted against each other.
+CREATE OR REPLACE FUNCTION convert_amount(amount BIGINT, from_currency TEXT, to_currency TEXT, on_date DATE)
+RETURNS BIGINT
+LANGUAGE sql STABLE
+AS $$
+  SELECT CASE
+    WHEN amount IS NULL THEN NULL
+    WHEN from_currency = to_currency THEN amount
+    ELSE (
+      SELECT ROUND(amount * r.rate)::bigint
+      FROM (
+        SELECT rate, date FROM exchange_rates WHERE currency = from_currency AND base_currency = to_currency
+        UNION ALL
+        SELECT 1 / rate, date FROM exchange_rates WHERE currency = to_currency AND base_currency = from_currency
+      ) r
+      ORDER BY r.date > on_date, ABS(r.date - on_date)
+      LIMIT 1
+    )
+  END
+$$;
@@ -0,0 +1,19 @@
+SET search_path TO public;
+
+-- items still in the trash would come back to life without the column
+DELETE FROM transactions WHERE deleted_at IS NOT NULL;
+DELETE FROM events WHERE deleted_at IS NOT NULL;
+DELETE FROM categories WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_categories_name;
+DROP INDEX IF EXISTS idx_categories_color;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
+ALTER TABLE categories ADD CONSTRAINT categories_color_key UNIQUE (color);
+
+DROP INDEX IF EXISTS idx_transactions_deleted_at;
+DROP INDEX IF EXISTS idx_events_deleted_at;
+DROP INDEX IF EXISTS idx_categories_deleted_at;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE events DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
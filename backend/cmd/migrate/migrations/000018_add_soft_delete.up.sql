@@ -0,0 +1,15 @@
+SET search_path TO public;
+
+ALTER TABLE transactions ADD COLUMN deleted_at timestamp(0) with time zone NULL;
+ALTER TABLE events ADD COLUMN deleted_at timestamp(0) with time zone NULL;
+ALTER TABLE categories ADD COLUMN deleted_at timestamp(0) with time zone NULL;
+
+CREATE INDEX idx_transactions_deleted_at ON transactions(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_events_deleted_at ON events(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_categories_deleted_at ON categories(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- a category in the trash no longer reserves its name and color
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_name_key;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_color_key;
+CREATE UNIQUE INDEX idx_categories_name ON categories(name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_categories_color ON categories(color) WHERE deleted_at IS NULL;
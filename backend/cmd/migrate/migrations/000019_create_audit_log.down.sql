@@ -0,0 +1,5 @@
+SET search_path TO public;
+
+DROP TABLE IF EXISTS audit_log;
+
+DROP FUNCTION IF EXISTS audit_log_append_only();
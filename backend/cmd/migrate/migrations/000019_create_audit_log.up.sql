@@ -0,0 +1,29 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS audit_log(
+  id bigserial PRIMARY KEY,
+  entity varchar(50) NOT NULL,
+  entity_id BIGINT NOT NULL,
+  action varchar(20) NOT NULL,
+  before JSONB NULL,
+  after JSONB NULL,
+  user_id BIGINT NULL,
+  request_id varchar(100) NOT NULL DEFAULT '',
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log(entity, entity_id, id DESC);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_request_id ON audit_log(request_id) WHERE request_id <> '';
+
+-- the audit log is append-only, even for the application itself
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,14 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+  user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  key varchar(255) NOT NULL,
+  request_hash char(64) NOT NULL,
+  status_code INT NULL,
+  content_type varchar(255) NOT NULL DEFAULT '',
+  response_body BYTEA NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_created_at ON idempotency_keys(created_at);
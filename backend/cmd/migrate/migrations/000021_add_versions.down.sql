@@ -0,0 +1,4 @@
+SET search_path TO public;
+
+ALTER TABLE events DROP COLUMN IF EXISTS version;
+ALTER TABLE transactions DROP COLUMN IF EXISTS version;
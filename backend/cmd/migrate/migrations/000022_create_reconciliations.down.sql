@@ -0,0 +1,8 @@
+SET search_path TO public;
+
+DROP INDEX IF EXISTS idx_transactions_unreconciled;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS reconciliation_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS status;
+
+DROP TABLE IF EXISTS reconciliations;
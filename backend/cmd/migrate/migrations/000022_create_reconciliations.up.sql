@@ -0,0 +1,22 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS reconciliations(
+  id bigserial PRIMARY KEY,
+  account_id BIGINT NOT NULL REFERENCES accounts(id),
+  statement_date DATE NOT NULL,
+  statement_balance BIGINT NOT NULL,
+  status varchar(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'completed')),
+  adjustment_transaction_id BIGINT NULL REFERENCES transactions(id),
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  completed_at timestamp(0) with time zone NULL
+);
+
+-- an account is reconciled against one statement at a time
+CREATE UNIQUE INDEX idx_reconciliations_open ON reconciliations(account_id) WHERE status = 'open';
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'uncleared'
+  CHECK (status IN ('uncleared', 'cleared', 'reconciled'));
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reconciliation_id BIGINT NULL REFERENCES reconciliations(id);
+
+CREATE INDEX idx_transactions_unreconciled ON transactions(account_id, date) WHERE status <> 'reconciled';
@@ -0,0 +1,6 @@
+SET search_path TO public;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS payee_id;
+
+DROP TABLE IF EXISTS payee_aliases;
+DROP TABLE IF EXISTS payees;
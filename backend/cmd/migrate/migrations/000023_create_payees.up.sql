@@ -0,0 +1,22 @@
+SET search_path TO public;
+
+CREATE TABLE IF NOT EXISTS payees(
+  id bigserial PRIMARY KEY,
+  name varchar(255) NOT NULL,
+  created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+  updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_payees_name ON payees(lower(name));
+
+-- aliases are kept normalized, as descriptions are matched against them
+CREATE TABLE IF NOT EXISTS payee_aliases(
+  payee_id BIGINT NOT NULL REFERENCES payees(id) ON DELETE CASCADE,
+  alias varchar(255) NOT NULL PRIMARY KEY
+);
+
+CREATE INDEX idx_payee_aliases_payee_id ON payee_aliases(payee_id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS payee_id BIGINT NULL REFERENCES payees(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_transactions_payee_id ON transactions(payee_id) WHERE payee_id IS NOT NULL;
@@ -0,0 +1,3 @@
+SET search_path TO public;
+
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS locked_at;
@@ -0,0 +1,3 @@
+SET search_path TO public;
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS locked_at timestamp(0) with time zone NOT NULL DEFAULT NOW();
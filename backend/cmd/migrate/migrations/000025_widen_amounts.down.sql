@@ -0,0 +1,6 @@
+SET search_path TO public;
+
+ALTER TABLE transactions
+  ALTER COLUMN amount TYPE INT,
+  ALTER COLUMN running_balance TYPE INT;
+ALTER TABLE event_expenses ALTER COLUMN amount TYPE INT;
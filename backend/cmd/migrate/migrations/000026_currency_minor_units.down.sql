@@ -0,0 +1,51 @@
+SET search_path TO public;
+
+-- back to whole units, dropping any fraction of them
+UPDATE accounts
+SET opening_balance = opening_balance / power(10, currency_decimals(currency))::bigint
+WHERE currency_decimals(currency) > 0;
+
+UPDATE reconciliations r
+SET statement_balance = r.statement_balance / power(10, currency_decimals(a.currency))::bigint
+FROM accounts a
+WHERE a.id = r.account_id AND currency_decimals(a.currency) > 0;
+
+UPDATE recurring_rules r
+SET amount = r.amount / power(10, currency_decimals(a.currency))::bigint
+FROM accounts a
+WHERE a.id = r.account_id AND currency_decimals(a.currency) > 0;
+
+UPDATE event_expenses
+SET amount = amount / power(10, currency_decimals(currency))::bigint
+WHERE currency_decimals(currency) > 0;
+
+UPDATE transaction_splits s
+SET amount = s.amount / power(10, currency_decimals(t.currency))::bigint
+FROM transactions t
+WHERE t.id = s.transaction_id AND currency_decimals(t.currency) > 0;
+
+UPDATE transactions
+SET amount = amount / power(10, currency_decimals(currency))::bigint, running_balance = running_balance / power(10, currency_decimals(currency))::bigint
+WHERE currency_decimals(currency) > 0;
+
+CREATE OR REPLACE FUNCTION convert_amount(amount BIGINT, from_currency TEXT, to_currency TEXT, on_date DATE)
+RETURNS BIGINT
+LANGUAGE sql STABLE
+AS $$
+  SELECT CASE
+    WHEN amount IS NULL THEN NULL
+    WHEN from_currency = to_currency THEN amount
+    ELSE (
+      SELECT ROUND(amount * r.rate)::bigint
+      FROM (
+        SELECT rate, date FROM exchange_rates WHERE currency = from_currency AND base_currency = to_currency
+        UNION ALL
+        SELECT 1 / rate, date FROM exchange_rates WHERE currency = to_currency AND base_currency = from_currency
+      ) r
+      ORDER BY r.date > on_date, ABS(r.date - on_date)
+      LIMIT 1
+    )
+  END
+$$;
+
+DROP FUNCTION IF EXISTS currency_decimals(TEXT);
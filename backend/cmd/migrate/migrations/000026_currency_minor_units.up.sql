@@ -0,0 +1,69 @@
+SET search_path TO public;
+
+-- currency_decimals is the number of decimals of the minor unit amounts of a
+-- currency are stored in, as in ISO 4217 except for IDR, which has always
+-- been stored in whole rupiah. It mirrors store.CurrencyDecimals.
+CREATE OR REPLACE FUNCTION currency_decimals(currency TEXT)
+RETURNS INT
+LANGUAGE sql IMMUTABLE
+AS $$
+  SELECT CASE
+    WHEN currency IN (
+      'IDR', 'BIF', 'CLP', 'DJF', 'GNF', 'ISK', 'JPY', 'KMF', 'KRW', 'PYG',
+      'RWF', 'UGX', 'UYI', 'VND', 'VUV', 'XAF', 'XOF', 'XPF'
+    ) THEN 0
+    WHEN currency IN ('BHD', 'IQD', 'JOD', 'KWD', 'LYD', 'OMR', 'TND') THEN 3
+    WHEN currency IN ('CLF', 'UYW') THEN 4
+    ELSE 2
+  END
+$$;
+
+-- rates are quoted between major units, so the converted amount is moved to
+-- the minor unit of to_currency
+CREATE OR REPLACE FUNCTION convert_amount(amount BIGINT, from_currency TEXT, to_currency TEXT, on_date DATE)
+RETURNS BIGINT
+LANGUAGE sql STABLE
+AS $$
+  SELECT CASE
+    WHEN amount IS NULL THEN NULL
+    WHEN from_currency = to_currency THEN amount
+    ELSE (
+      SELECT ROUND(amount * r.rate * power(10::numeric, currency_decimals(to_currency) - currency_decimals(from_currency)))::bigint
+      FROM (
+        SELECT rate, date FROM exchange_rates WHERE currency = from_currency AND base_currency = to_currency
+        UNION ALL
+        SELECT 1 / rate, date FROM exchange_rates WHERE currency = to_currency AND base_currency = from_currency
+      ) r
+      ORDER BY r.date > on_date, ABS(r.date - on_date)
+      LIMIT 1
+    )
+  END
+$$;
+
+-- amounts were whole units of every currency until now
+UPDATE transactions
+SET amount = amount * power(10, currency_decimals(currency))::bigint, running_balance = running_balance * power(10, currency_decimals(currency))::bigint
+WHERE currency_decimals(currency) > 0;
+
+UPDATE transaction_splits s
+SET amount = s.amount * power(10, currency_decimals(t.currency))::bigint
+FROM transactions t
+WHERE t.id = s.transaction_id AND currency_decimals(t.currency) > 0;
+
+UPDATE event_expenses
+SET amount = amount * power(10, currency_decimals(currency))::bigint
+WHERE currency_decimals(currency) > 0;
+
+UPDATE recurring_rules r
+SET amount = r.amount * power(10, currency_decimals(a.currency))::bigint
+FROM accounts a
+WHERE a.id = r.account_id AND currency_decimals(a.currency) > 0;
+
+UPDATE reconciliations r
+SET statement_balance = r.statement_balance * power(10, currency_decimals(a.currency))::bigint
+FROM accounts a
+WHERE a.id = r.account_id AND currency_decimals(a.currency) > 0;
+
+UPDATE accounts
+SET opening_balance = opening_balance * power(10, currency_decimals(currency))::bigint
+WHERE currency_decimals(currency) > 0;
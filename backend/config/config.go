@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
@@ -25,6 +26,11 @@ type GoogleConfig struct {
 	RedirectUri  string `env:"GOOGLE_REDIRECT_URI"`
 }
 
+type AttachmentsConfig struct {
+	Dir     string `env:"ATTACHMENTS_DIR" envDefault:"./data/attachments"`
+	MaxSize int64  `env:"ATTACHMENTS_MAX_SIZE" envDefault:"10485760"`
+}
+
 type Config struct {
 	Addr            string `env:"ADDR" envDefault:"0.0.0.0"`
 	Port            int    `env:"PORT" envDefault:"8080"`
@@ -34,6 +40,30 @@ type Config struct {
 	JwtSecret       string `env:"JWT_SECRET"`
 	AllowedGoogleID string `env:"ALLOWED_GOOGLE_ID"`
 	FrontendURL     string `env:"FRONTEND_URL"`
+	Attachments     AttachmentsConfig
+	// currency that totals over several currencies are converted to
+	BaseCurrency string `env:"BASE_CURRENCY" envDefault:"IDR"`
+	// zone that quick entries are dated in when the client does not send one
+	Timezone string `env:"TIMEZONE" envDefault:"Asia/Jakarta"`
+	// how often due recurring transactions are booked
+	RecurringInterval time.Duration `env:"RECURRING_INTERVAL" envDefault:"1h"`
+	// how often category suggestions are retrained from the full history
+	SuggestRetrainInterval time.Duration `env:"SUGGEST_RETRAIN_INTERVAL" envDefault:"24h"`
+	// how long deleted items stay in the trash before they are purged
+	TrashRetention time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+	// how often the trash is checked for items past their retention
+	TrashPurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL" envDefault:"1h"`
+	// how long a response is kept for replay under its idempotency key
+	IdempotencyKeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+	// how long a request holds its idempotency key before a retry may take
+	// it over, in case the process handling it died; longer than any request
+	// is allowed to run
+	IdempotencyKeyLease time.Duration `env:"IDEMPOTENCY_KEY_LEASE" envDefault:"10m"`
+	// emails of the users allowed to check and fix the ledger over the API;
+	// when empty, the ledger can only be checked with the ledgercheck command
+	AdminEmails []string `env:"ADMIN_EMAILS" envSeparator:","`
+	// whether changing a transaction or event needs an If-Match header
+	RequireIfMatch bool `env:"REQUIRE_IF_MATCH" envDefault:"false"`
 }
 
 func Load() (*Config, error) {
@@ -0,0 +1,21 @@
+// Package blob stores the binary content of attachments outside the
+// database. Objects are addressed by slash-separated keys so that local and
+// S3-compatible backends can share the same layout.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,69 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LocalStoreTestSuite struct {
+	suite.Suite
+	store *LocalStore
+}
+
+func (suite *LocalStoreTestSuite) SetupTest() {
+	store, err := NewLocalStore(suite.T().TempDir())
+	assert.NoError(suite.T(), err)
+	suite.store = store
+}
+
+func (suite *LocalStoreTestSuite) TestPutGetDelete() {
+	ctx := context.Background()
+	key := "transactions/1/receipt.jpg"
+
+	err := suite.store.Put(ctx, key, strings.NewReader("receipt"))
+	assert.NoError(suite.T(), err)
+
+	rc, err := suite.store.Get(ctx, key)
+	assert.NoError(suite.T(), err)
+	content, err := io.ReadAll(rc)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), rc.Close())
+	assert.Equal(suite.T(), "receipt", string(content))
+
+	assert.NoError(suite.T(), suite.store.Delete(ctx, key))
+
+	_, err = suite.store.Get(ctx, key)
+	assert.ErrorIs(suite.T(), err, ErrNotFound)
+	assert.ErrorIs(suite.T(), suite.store.Delete(ctx, key), ErrNotFound)
+}
+
+func (suite *LocalStoreTestSuite) TestPutOverwrites() {
+	ctx := context.Background()
+
+	assert.NoError(suite.T(), suite.store.Put(ctx, "a", strings.NewReader("first")))
+	assert.NoError(suite.T(), suite.store.Put(ctx, "a", strings.NewReader("second")))
+
+	rc, err := suite.store.Get(ctx, "a")
+	assert.NoError(suite.T(), err)
+	defer rc.Close()
+	content, _ := io.ReadAll(rc)
+	assert.Equal(suite.T(), "second", string(content))
+}
+
+func (suite *LocalStoreTestSuite) TestInvalidKeys() {
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../b", "a//b", `a\b`, "."} {
+		err := suite.store.Put(ctx, key, strings.NewReader("x"))
+		assert.ErrorIs(suite.T(), err, ErrInvalidKey, key)
+	}
+}
+
+func TestLocalStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(LocalStoreTestSuite))
+}
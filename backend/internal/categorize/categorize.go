@@ -0,0 +1,142 @@
+// Package categorize applies categorization rules to transactions that
+// arrive without a category.
+package categorize
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var ErrInvalidRule = errors.New("invalid categorization rule")
+
+// Input is what rules match on.
+type Input struct {
+	AccountID   int64
+	Amount      int64
+	Description string
+	Kind        string
+}
+
+// Result is the combined effect of the matching rules. The category and
+// description come from the first matching rule that sets them, and tags are
+// collected from all of them. CategoryID is nil and Description unchanged
+// when no rule sets them.
+type Result struct {
+	RuleIDs     []int64
+	CategoryID  *int64
+	TagIDs      []int64
+	Description string
+}
+
+func (r Result) Matched() bool {
+	return len(r.RuleIDs) > 0
+}
+
+type compiledRule struct {
+	store.CategorizationRule
+	contains string
+	pattern  *regexp.Regexp
+}
+
+// Engine holds rules ready to be applied, in the order given.
+type Engine struct {
+	rules []compiledRule
+}
+
+// New compiles the rules, which are expected in priority order as returned
+// by the store.
+func New(rules []store.CategorizationRule) (*Engine, error) {
+	engine := &Engine{}
+	for _, rule := range rules {
+		compiled := compiledRule{CategorizationRule: rule, contains: strings.ToLower(rule.DescriptionContains)}
+		if rule.DescriptionPattern != "" {
+			pattern, err := regexp.Compile(rule.DescriptionPattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", rule.ID, err)
+			}
+			compiled.pattern = pattern
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+// Apply runs the rules against a transaction. All rules match on the
+// original description, so a rename does not affect later rules. Transfers
+// and adjustments are renamed and tagged but never categorized.
+func (e *Engine) Apply(in Input) Result {
+	result := Result{Description: in.Description}
+	categorizable := in.Kind == "" || in.Kind == store.KindExpense || in.Kind == store.KindIncome
+	renamed := false
+
+	for _, rule := range e.rules {
+		if !rule.matches(in) {
+			continue
+		}
+		result.RuleIDs = append(result.RuleIDs, rule.ID)
+
+		if result.CategoryID == nil && rule.CategoryID != nil && categorizable {
+			categoryID := *rule.CategoryID
+			result.CategoryID = &categoryID
+		}
+		if !renamed && rule.RenameTo != "" {
+			result.Description = rule.RenameTo
+			renamed = true
+		}
+		for _, tagID := range rule.TagIDs {
+			if !slices.Contains(result.TagIDs, tagID) {
+				result.TagIDs = append(result.TagIDs, tagID)
+			}
+		}
+	}
+
+	return result
+}
+
+func (r compiledRule) matches(in Input) bool {
+	if r.contains != "" && !strings.Contains(strings.ToLower(in.Description), r.contains) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(in.Description) {
+		return false
+	}
+
+	amount := in.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	if r.MinAmount != nil && amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && amount > *r.MaxAmount {
+		return false
+	}
+
+	return r.AccountID == nil || *r.AccountID == in.AccountID
+}
+
+// Validate checks that a rule has at least one condition and one action, and
+// that its pattern and amount range are valid.
+func Validate(rule *store.CategorizationRule) error {
+	if rule.DescriptionContains == "" && rule.DescriptionPattern == "" &&
+		rule.MinAmount == nil && rule.MaxAmount == nil && rule.AccountID == nil {
+		return fmt.Errorf("%w: at least one condition is required", ErrInvalidRule)
+	}
+	if rule.CategoryID == nil && len(rule.TagIDs) == 0 && rule.RenameTo == "" {
+		return fmt.Errorf("%w: at least one of category_id, tag_ids or rename_to is required", ErrInvalidRule)
+	}
+	if rule.DescriptionPattern != "" {
+		if _, err := regexp.Compile(rule.DescriptionPattern); err != nil {
+			return fmt.Errorf("%w: description_pattern: %s", ErrInvalidRule, err)
+		}
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return fmt.Errorf("%w: min_amount is larger than max_amount", ErrInvalidRule)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package categorize
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CategorizeTestSuite struct {
+	suite.Suite
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func (suite *CategorizeTestSuite) TestApply() {
+	engine, err := New([]store.CategorizationRule{
+		{ID: 1, DescriptionContains: "grab", MinAmount: int64Ptr(100000), CategoryID: int64Ptr(3), RenameTo: "Grab (large)"},
+		{ID: 2, DescriptionPattern: `(?i)^grab`, AccountID: int64Ptr(7), CategoryID: int64Ptr(4), TagIDs: []int64{1, 2}},
+		{ID: 3, DescriptionContains: "grab", RenameTo: "Grab", TagIDs: []int64{2, 5}},
+	})
+	assert.NoError(suite.T(), err)
+
+	result := engine.Apply(Input{AccountID: 7, Amount: 45000, Description: "GRAB* A-123", Kind: store.KindExpense})
+	assert.Equal(suite.T(), []int64{2, 3}, result.RuleIDs)
+	assert.Equal(suite.T(), int64(4), *result.CategoryID)
+	assert.Equal(suite.T(), []int64{1, 2, 5}, result.TagIDs)
+	assert.Equal(suite.T(), "Grab", result.Description)
+
+	// amounts compare by absolute value, and the highest priority rule wins
+	result = engine.Apply(Input{AccountID: 7, Amount: -150000, Description: "Grab refund", Kind: store.KindIncome})
+	assert.Equal(suite.T(), []int64{1, 2, 3}, result.RuleIDs)
+	assert.Equal(suite.T(), int64(3), *result.CategoryID)
+	assert.Equal(suite.T(), "Grab (large)", result.Description)
+
+	result = engine.Apply(Input{AccountID: 1, Amount: 20000, Description: "Indomaret"})
+	assert.False(suite.T(), result.Matched())
+	assert.Nil(suite.T(), result.CategoryID)
+	assert.Equal(suite.T(), "Indomaret", result.Description)
+}
+
+func (suite *CategorizeTestSuite) TestApply_Transfers() {
+	engine, err := New([]store.CategorizationRule{
+		{ID: 1, DescriptionContains: "top up", CategoryID: int64Ptr(3), RenameTo: "GoPay top up"},
+	})
+	assert.NoError(suite.T(), err)
+
+	result := engine.Apply(Input{Amount: 100000, Description: "TOP UP GOPAY", Kind: store.KindTransfer})
+	assert.True(suite.T(), result.Matched())
+	assert.Nil(suite.T(), result.CategoryID)
+	assert.Equal(suite.T(), "GoPay top up", result.Description)
+}
+
+func (suite *CategorizeTestSuite) TestNew_InvalidPattern() {
+	_, err := New([]store.CategorizationRule{{ID: 1, DescriptionPattern: "("}})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *CategorizeTestSuite) TestValidate() {
+	valid := &store.CategorizationRule{DescriptionContains: "grab", CategoryID: int64Ptr(1)}
+	assert.NoError(suite.T(), Validate(valid))
+
+	for _, rule := range []*store.CategorizationRule{
+		{CategoryID: int64Ptr(1)},
+		{DescriptionContains: "grab"},
+		{DescriptionPattern: "[", RenameTo: "Grab"},
+		{MinAmount: int64Ptr(10), MaxAmount: int64Ptr(5), TagIDs: []int64{1}},
+	} {
+		err := Validate(rule)
+		assert.True(suite.T(), errors.Is(err, ErrInvalidRule), "%+v", rule)
+	}
+}
+
+func TestCategorizeTestSuite(t *testing.T) {
+	suite.Run(t, new(CategorizeTestSuite))
+}
@@ -40,8 +40,15 @@ func Seed(store store.Storage) {
 		}
 	}
 
+	account, err := store.Accounts.GetDefault(ctx)
+	if err != nil {
+		log.Println("Error loading default account", err)
+		return
+	}
+
 	transactions := generateTransactions(20, categories)
 	for _, transaction := range transactions {
+		transaction.AccountID = account.ID
 		if err := store.Transactions.Create(ctx, transaction); err != nil {
 			log.Println("Error creating transaction", err)
 			return
@@ -0,0 +1,50 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	writer := &csvWriter{w: csv.NewWriter(w)}
+	if err := writer.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvWriter) Write(record any, rows ...[]any) error {
+	for _, row := range rows {
+		c.record = c.record[:0]
+		for _, value := range row {
+			c.record = append(c.record, csvCell(value))
+		}
+		if err := c.w.Write(c.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvCell renders a value as a CSV cell. Spreadsheets may run text starting
+// with =, +, -, @, a tab or a carriage return as a formula, so such text is
+// prefixed with a quote to be shown as it is. Numbers are left alone,
+// negative amounts included.
+func csvCell(value any) string {
+	cell := formatValue(value)
+	if _, ok := value.(string); ok && cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
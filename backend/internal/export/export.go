@@ -0,0 +1,80 @@
+// Package export writes records as CSV, XLSX or JSON to a stream without
+// holding the whole export in memory.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+	FormatJSON = "json"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported export format, expected csv, xlsx or json")
+
+// Writer writes the records of one export. JSON exports encode each record
+// as an element of an array; tabular exports write its rows, one value per
+// column, after a header row.
+type Writer interface {
+	Write(record any, rows ...[]any) error
+	Close() error
+}
+
+// NewWriter starts an export in the given format. sheet names the worksheet
+// of an XLSX export.
+func NewWriter(format string, w io.Writer, sheet string, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, sheet, columns)
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatXLSX, FormatJSON:
+		return true
+	}
+	return false
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/json"
+	}
+}
+
+// formatValue renders a cell of a text format. Times use RFC 3339 and nil
+// is an empty cell.
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
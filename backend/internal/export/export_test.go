@@ -0,0 +1,125 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExportTestSuite struct {
+	suite.Suite
+}
+
+type item struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+var exportDate = time.Date(2024, 1, 3, 12, 0, 0, 0, time.FixedZone("WIB", 7*3600))
+
+func (suite *ExportTestSuite) write(format string) []byte {
+	var buf bytes.Buffer
+	writer, err := NewWriter(format, &buf, "Ledger", []string{"id", "name", "date", "note"})
+	assert.NoError(suite.T(), err)
+
+	assert.NoError(suite.T(), writer.Write(item{1, "Rent"}, []any{int64(1), "Rent", exportDate, nil}))
+	assert.NoError(suite.T(), writer.Write(item{2, `Kopi "Tuku", <large>`}, []any{int64(2), `Kopi "Tuku", <large>`, exportDate, "x"}))
+	assert.NoError(suite.T(), writer.Close())
+
+	return buf.Bytes()
+}
+
+func (suite *ExportTestSuite) TestCSV() {
+	assert.Equal(suite.T(), "id,name,date,note\n"+
+		"1,Rent,2024-01-03T12:00:00+07:00,\n"+
+		"2,\"Kopi \"\"Tuku\"\", <large>\",2024-01-03T12:00:00+07:00,x\n", string(suite.write(FormatCSV)))
+}
+
+func (suite *ExportTestSuite) TestCSV_Formulas() {
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatCSV, &buf, "Ledger", []string{"description", "amount"})
+	assert.NoError(suite.T(), err)
+
+	for _, description := range []string{"=HYPERLINK(\"http://x\")", "+62 812", "-refund", "@SUM(A1)", "a=b"} {
+		assert.NoError(suite.T(), writer.Write(nil, []any{description, int64(-15000)}))
+	}
+	assert.NoError(suite.T(), writer.Close())
+
+	assert.Equal(suite.T(), "description,amount\n"+
+		"\"'=HYPERLINK(\"\"http://x\"\")\",-15000\n"+
+		"'+62 812,-15000\n"+
+		"'-refund,-15000\n"+
+		"'@SUM(A1),-15000\n"+
+		"a=b,-15000\n", buf.String())
+}
+
+func (suite *ExportTestSuite) TestJSON() {
+	var items []item
+	err := json.Unmarshal(suite.write(FormatJSON), &items)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []item{{1, "Rent"}, {2, `Kopi "Tuku", <large>`}}, items)
+
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatJSON, &buf, "", nil)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), writer.Close())
+	assert.Equal(suite.T(), "[]\n", buf.String())
+}
+
+func (suite *ExportTestSuite) TestXLSX() {
+	data := suite.write(FormatXLSX)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(suite.T(), err)
+
+	parts := map[string]string{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		assert.NoError(suite.T(), err)
+		content, err := io.ReadAll(rc)
+		assert.NoError(suite.T(), err)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+
+	assert.Contains(suite.T(), parts, "[Content_Types].xml")
+	assert.Contains(suite.T(), parts["xl/workbook.xml"], `<sheet name="Ledger"`)
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	assert.True(suite.T(), strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+	assert.Contains(suite.T(), sheet, `<c r="A1" t="inlineStr" s="2"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(suite.T(), sheet, `<c r="A2"><v>1</v></c>`)
+	assert.Contains(suite.T(), sheet, `<c r="C2" s="1"><v>45294.5</v></c>`)
+	assert.Contains(suite.T(), sheet, `Kopi &#34;Tuku&#34;, &lt;large&gt;`)
+	assert.NotContains(suite.T(), sheet, `r="D2"`)
+}
+
+func (suite *ExportTestSuite) TestUnsupportedFormat() {
+	_, err := NewWriter("pdf", io.Discard, "", nil)
+	assert.ErrorIs(suite.T(), err, ErrUnsupportedFormat)
+}
+
+func (suite *ExportTestSuite) TestColumnName() {
+	assert.Equal(suite.T(), "A", columnName(0))
+	assert.Equal(suite.T(), "Z", columnName(25))
+	assert.Equal(suite.T(), "AA", columnName(26))
+	assert.Equal(suite.T(), "AZ", columnName(51))
+	assert.Equal(suite.T(), "BA", columnName(52))
+}
+
+func (suite *ExportTestSuite) TestSheetName() {
+	assert.Equal(suite.T(), "Q12024", sheetName("Q1/2024"))
+	assert.Equal(suite.T(), "ab", sheetName("a[b]"))
+	assert.Equal(suite.T(), "Sheet1", sheetName("??"))
+}
+
+func TestExportTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportTestSuite))
+}
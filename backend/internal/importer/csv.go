@@ -0,0 +1,256 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var ErrInvalidProfile = errors.New("invalid import profile")
+
+// ParseCSV reads every data row of a CSV export using the profile's column
+// mapping, with amounts in the given currency. Problems with individual rows
+// are reported on the row; an error is only returned when the file or the
+// profile cannot be used at all.
+func ParseCSV(r io.Reader, profile *store.ImportProfile, currency string) ([]Row, error) {
+	m, err := newMapping(profile, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = m.delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	var rows []Row
+	line := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if line <= profile.SkipRows || isBlank(record) {
+			continue
+		}
+
+		if m.columns == nil {
+			if err := m.resolve(record); err != nil {
+				return nil, err
+			}
+			if profile.HasHeader {
+				continue
+			}
+		}
+
+		rows = append(rows, m.row(line, record))
+	}
+
+	return rows, nil
+}
+
+// mapping is a profile resolved against the header of a file.
+type mapping struct {
+	profile    *store.ImportProfile
+	delimiter  rune
+	dateLayout string
+	decimals   int
+	columns    map[string]int
+}
+
+// ValidateProfile reports a profile that cannot be used to parse any file.
+func ValidateProfile(profile *store.ImportProfile) error {
+	if profile.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(profile.Delimiter)
+		if size != len(profile.Delimiter) || r == '"' || r == '\n' {
+			return fmt.Errorf("%w: delimiter must be a single character", ErrInvalidProfile)
+		}
+	}
+
+	if profile.DecimalSeparator != "" && profile.DecimalSeparator == profile.ThousandSeparator {
+		return fmt.Errorf("%w: decimal and thousand separators must differ", ErrInvalidProfile)
+	}
+
+	if profile.DateFormat == "" {
+		return fmt.Errorf("%w: date format is required", ErrInvalidProfile)
+	}
+
+	refs, err := columnRefs(profile)
+	if err != nil {
+		return err
+	}
+	for field, ref := range refs {
+		if ref == "" {
+			return fmt.Errorf("%w: %s column is required", ErrInvalidProfile, field)
+		}
+		n, err := strconv.Atoi(ref)
+		switch {
+		case err == nil && n < 1:
+			return fmt.Errorf("%w: %s column must be a positive position", ErrInvalidProfile, field)
+		case err != nil && !profile.HasHeader:
+			return fmt.Errorf("%w: %s column must be a position when the file has no header", ErrInvalidProfile, field)
+		}
+	}
+
+	return nil
+}
+
+// columnRefs lists the columns the profile's amount sign convention reads.
+func columnRefs(profile *store.ImportProfile) (map[string]string, error) {
+	refs := map[string]string{
+		"date":        profile.DateColumn,
+		"description": profile.DescriptionColumn,
+	}
+	switch profile.AmountSign {
+	case store.AmountSignDebitCredit:
+		refs["debit"] = profile.DebitColumn
+		refs["credit"] = profile.CreditColumn
+	case store.AmountSignNegativeExpense, store.AmountSignPositiveExpense:
+		refs["amount"] = profile.AmountColumn
+	default:
+		return nil, fmt.Errorf("%w: unknown amount sign %q", ErrInvalidProfile, profile.AmountSign)
+	}
+	return refs, nil
+}
+
+func newMapping(profile *store.ImportProfile, currency string) (*mapping, error) {
+	if err := ValidateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	delimiter := ','
+	if profile.Delimiter != "" {
+		delimiter, _ = utf8.DecodeRuneInString(profile.Delimiter)
+	}
+
+	return &mapping{
+		profile:    profile,
+		delimiter:  delimiter,
+		dateLayout: DateLayout(profile.DateFormat),
+		decimals:   store.CurrencyDecimals(currency),
+	}, nil
+}
+
+// resolve maps the profile's column references, either header names or
+// 1-based positions, to record indexes.
+func (m *mapping) resolve(first []string) error {
+	refs, err := columnRefs(m.profile)
+	if err != nil {
+		return err
+	}
+
+	m.columns = map[string]int{}
+	for field, ref := range refs {
+		if n, err := strconv.Atoi(ref); err == nil {
+			m.columns[field] = n - 1
+			continue
+		}
+
+		index := -1
+		for i, name := range first {
+			// spreadsheet exports often start with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+			if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(ref)) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("%w: column %q not found in header", ErrInvalidProfile, ref)
+		}
+		m.columns[field] = index
+	}
+
+	return nil
+}
+
+func (m *mapping) field(record []string, name string) (string, bool) {
+	index := m.columns[name]
+	if index >= len(record) {
+		return "", false
+	}
+	return strings.TrimSpace(record[index]), true
+}
+
+func (m *mapping) row(line int, record []string) Row {
+	row := Row{Line: line}
+
+	if value, ok := m.field(record, "date"); !ok || value == "" {
+		row.fail("date is missing")
+	} else if date, err := time.ParseInLocation(m.dateLayout, value, time.Local); err != nil {
+		row.fail("date %q does not match format %s", value, m.profile.DateFormat)
+	} else {
+		row.Date = date
+	}
+
+	if value, ok := m.field(record, "description"); !ok || value == "" {
+		row.fail("description is missing")
+	} else {
+		row.Description = truncate(value, 255)
+	}
+
+	if amount, err := m.amount(record); err != nil {
+		row.fail("%s", err)
+	} else {
+		row.setAmount(amount)
+	}
+
+	return row
+}
+
+// amount converts the amount columns to the ledger convention.
+func (m *mapping) amount(record []string) (int64, error) {
+	parse := func(name string) (int64, error) {
+		value, ok := m.field(record, name)
+		if !ok {
+			return 0, fmt.Errorf("%s is missing", name)
+		}
+		if value == "" {
+			return 0, nil
+		}
+		amount, err := ParseAmount(value, m.profile.DecimalSeparator, m.profile.ThousandSeparator, m.decimals)
+		if err != nil {
+			return 0, fmt.Errorf("%s %q: %w", name, value, err)
+		}
+		return amount, nil
+	}
+
+	switch m.profile.AmountSign {
+	case store.AmountSignDebitCredit:
+		debit, err := parse("debit")
+		if err != nil {
+			return 0, err
+		}
+		credit, err := parse("credit")
+		if err != nil {
+			return 0, err
+		}
+		return abs(debit) - abs(credit), nil
+	case store.AmountSignNegativeExpense:
+		amount, err := parse("amount")
+		return -amount, err
+	default:
+		return parse("amount")
+	}
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
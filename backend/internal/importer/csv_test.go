@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CSVTestSuite struct {
+	suite.Suite
+}
+
+func (suite *CSVTestSuite) TestParseCSV_BankExport() {
+	profile := &store.ImportProfile{
+		Delimiter:         ";",
+		HasHeader:         true,
+		SkipRows:          1,
+		DateColumn:        "Tanggal",
+		DateFormat:        "DD/MM/YYYY",
+		DescriptionColumn: "Keterangan",
+		AmountColumn:      "Jumlah",
+		AmountSign:        store.AmountSignNegativeExpense,
+		DecimalSeparator:  ",",
+		ThousandSeparator: ".",
+	}
+	file := "Mutasi Rekening 0123456789\n" +
+		"\ufeffTanggal;Keterangan;Jumlah\n" +
+		"03/01/2024;Indomaret;-Rp 45.500,00\n" +
+		"\n" +
+		"05/01/2024;Gaji Januari;12.000.000\n" +
+		"2024-01-06;Grab;-25.000\n" +
+		"07/01/2024;;-10.000,50\n"
+
+	rows, err := ParseCSV(strings.NewReader(file), profile, "IDR")
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), rows, 4) {
+		return
+	}
+
+	assert.True(suite.T(), rows[0].Valid())
+	assert.Equal(suite.T(), 3, rows[0].Line)
+	assert.Equal(suite.T(), time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local), rows[0].Date)
+	assert.Equal(suite.T(), "Indomaret", rows[0].Description)
+	assert.Equal(suite.T(), int64(45500), rows[0].Amount)
+	assert.Equal(suite.T(), store.KindExpense, rows[0].Kind)
+
+	assert.True(suite.T(), rows[1].Valid())
+	assert.Equal(suite.T(), int64(-12000000), rows[1].Amount)
+	assert.Equal(suite.T(), store.KindIncome, rows[1].Kind)
+
+	assert.False(suite.T(), rows[2].Valid())
+	assert.Len(suite.T(), rows[2].Errors, 1)
+
+	// missing description and a fractional amount
+	assert.Len(suite.T(), rows[3].Errors, 2)
+}
+
+func (suite *CSVTestSuite) TestParseCSV_DebitCreditByPosition() {
+	profile := &store.ImportProfile{
+		DateColumn:        "1",
+		DateFormat:        "YYYY-MM-DD",
+		DescriptionColumn: "2",
+		DebitColumn:       "3",
+		CreditColumn:      "4",
+		AmountSign:        store.AmountSignDebitCredit,
+		ThousandSeparator: ",",
+	}
+	file := "2024-02-01,\"Transfer, BCA\",,\"1,500,000\"\n" +
+		"2024-02-02,Coffee,\"32,000\",\n" +
+		"2024-02-03,Short\n"
+
+	rows, err := ParseCSV(strings.NewReader(file), profile, "IDR")
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), rows, 3) {
+		return
+	}
+
+	assert.Equal(suite.T(), "Transfer, BCA", rows[0].Description)
+	assert.Equal(suite.T(), int64(-1500000), rows[0].Amount)
+	assert.Equal(suite.T(), int64(32000), rows[1].Amount)
+	assert.Contains(suite.T(), rows[2].Errors, "debit is missing")
+}
+
+func (suite *CSVTestSuite) TestParseCSV_InvalidProfile() {
+	for name, profile := range map[string]*store.ImportProfile{
+		"missing column":      {HasHeader: true, DateColumn: "Date", DateFormat: "YYYY-MM-DD", DescriptionColumn: "Memo", AmountColumn: "Total", AmountSign: store.AmountSignPositiveExpense},
+		"name without header": {DateColumn: "Date", DateFormat: "YYYY-MM-DD", DescriptionColumn: "2", AmountColumn: "3", AmountSign: store.AmountSignPositiveExpense},
+		"same separators":     {DateColumn: "1", DateFormat: "YYYY-MM-DD", DescriptionColumn: "2", AmountColumn: "3", AmountSign: store.AmountSignPositiveExpense, DecimalSeparator: ".", ThousandSeparator: "."},
+		"unknown sign":        {DateColumn: "1", DateFormat: "YYYY-MM-DD", DescriptionColumn: "2", AmountColumn: "3", AmountSign: "inverted"},
+	} {
+		_, err := ParseCSV(strings.NewReader("Date,Memo,Amount\n2024-01-01,Lunch,50000\n"), profile, "IDR")
+		assert.True(suite.T(), errors.Is(err, ErrInvalidProfile), name)
+	}
+}
+
+func (suite *CSVTestSuite) TestParseAmount() {
+	for _, tc := range []struct {
+		value    string
+		decimal  string
+		thousand string
+		decimals int
+		want     int64
+	}{
+		{"1.250.000", "", ".", 0, 1250000},
+		{"Rp 1.250.000,00", ",", ".", 0, 1250000},
+		{"-45,000", ".", ",", 0, -45000},
+		{"(12.500)", ",", ".", 0, -12500},
+		{"IDR 7500-", "", "", 0, -7500},
+		{"$ 20.00", ".", ",", 0, 20},
+		{"$ 20.50", ".", ",", 2, 2050},
+		{"1.250,5", ",", ".", 2, 125050},
+		{"-3", "", "", 2, -300},
+		{"KWD 0.125", "", "", 3, 125},
+		{".00", "", "", 0, 0},
+	} {
+		got, err := ParseAmount(tc.value, tc.decimal, tc.thousand, tc.decimals)
+		assert.NoError(suite.T(), err, tc.value)
+		assert.Equal(suite.T(), tc.want, got, tc.value)
+	}
+
+	for _, value := range []string{"", "Rp", "12.50", "1,000.000,00", "12#000"} {
+		_, err := ParseAmount(value, ".", ",", 0)
+		assert.Error(suite.T(), err, value)
+	}
+	_, err := ParseAmount("12.505", ".", ",", 2)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *CSVTestSuite) TestDateLayout() {
+	assert.Equal(suite.T(), "02/01/2006", DateLayout("DD/MM/YYYY"))
+	assert.Equal(suite.T(), "2006-01-02 15:04:05", DateLayout("YYYY-MM-DD HH:mm:ss"))
+	assert.Equal(suite.T(), "02 Jan 06", DateLayout("DD MMM YY"))
+	assert.Equal(suite.T(), "Jan 2, 2006", DateLayout("Jan 2, 2006"))
+}
+
+func TestCSVTestSuite(t *testing.T) {
+	suite.Run(t, new(CSVTestSuite))
+}
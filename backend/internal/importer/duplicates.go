@@ -0,0 +1,122 @@
+package importer
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// DateRange returns the window of booked transactions that valid rows can
+// match, padded by a day on both sides for time zone differences.
+func DateRange(rows []Row) (from, to time.Time, ok bool) {
+	for _, row := range rows {
+		if !row.Valid() {
+			continue
+		}
+		if !ok || row.Date.Before(from) {
+			from = row.Date
+		}
+		if !ok || row.Date.After(to) {
+			to = row.Date
+		}
+		ok = true
+	}
+
+	return from.AddDate(0, 0, -1), to.AddDate(0, 0, 2), ok
+}
+
+// ExternalIDs lists the bank transaction ids of valid rows.
+func ExternalIDs(rows []Row) []string {
+	ids := []string{}
+	for _, row := range rows {
+		if row.Valid() && row.ExternalID != "" {
+			ids = append(ids, row.ExternalID)
+		}
+	}
+	return ids
+}
+
+type fingerprintKey struct {
+	day         string
+	amount      int64
+	description string
+}
+
+func newFingerprintKey(date time.Time, amount int64, description string) fingerprintKey {
+	return fingerprintKey{
+		day:         date.In(time.Local).Format(time.DateOnly),
+		amount:      amount,
+		description: NormalizeDescription(description),
+	}
+}
+
+// MarkDuplicates flags valid rows that are already booked. A row matches a
+// transaction with the same external id, or, failing that, a transaction on
+// the same day with the same amount and normalized description. Rows and
+// transactions that both carry an external id only match by it, as they are
+// different bank transactions when the ids differ. Each booked transaction
+// matches at most one row, so repeated purchases on one day are only flagged
+// as often as they were booked. Rows repeating an external id seen earlier in
+// the file are flagged as well.
+func MarkDuplicates(rows []Row, existing []store.TransactionFingerprint) {
+	byExternalID := map[string]int64{}
+	byKey := map[fingerprintKey][]store.TransactionFingerprint{}
+	for _, transaction := range existing {
+		if transaction.ExternalID != "" {
+			byExternalID[transaction.ExternalID] = transaction.ID
+		}
+		key := newFingerprintKey(transaction.Date, transaction.Amount, transaction.Description)
+		byKey[key] = append(byKey[key], transaction)
+	}
+
+	// external ids are matched first, so that a transaction they claim is not
+	// also taken by an earlier row without one
+	matched := map[int64]bool{}
+	seen := map[string]bool{}
+	for i := range rows {
+		row := &rows[i]
+		if !row.Valid() || row.ExternalID == "" {
+			continue
+		}
+
+		if id, ok := byExternalID[row.ExternalID]; ok {
+			row.Duplicate, row.DuplicateOf = true, id
+			matched[id] = true
+			continue
+		}
+		if seen[row.ExternalID] {
+			row.Duplicate = true
+			continue
+		}
+		seen[row.ExternalID] = true
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if !row.Valid() || row.Duplicate {
+			continue
+		}
+
+		key := newFingerprintKey(row.Date, row.Amount, row.Description)
+		for _, transaction := range byKey[key] {
+			if matched[transaction.ID] || (row.ExternalID != "" && transaction.ExternalID != "") {
+				continue
+			}
+			row.Duplicate, row.DuplicateOf = true, transaction.ID
+			matched[transaction.ID] = true
+			break
+		}
+	}
+}
+
+// NormalizeDescription lowercases a description and reduces it to words of
+// letters and digits, so that "POS  INDOMARET-123" and "pos indomaret 123"
+// compare equal.
+func NormalizeDescription(description string) string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,93 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DuplicatesTestSuite struct {
+	suite.Suite
+}
+
+func day(d int) time.Time {
+	return time.Date(2024, 1, d, 0, 0, 0, 0, time.Local)
+}
+
+func (suite *DuplicatesTestSuite) TestMarkDuplicates() {
+	rows := []Row{
+		{Line: 1, Date: day(3), Description: "INDOMARET", Amount: 45000, ExternalID: "A1"},
+		{Line: 2, Date: day(4), Description: "Kopi  Kenangan!", Amount: 28000},
+		{Line: 3, Date: day(4), Description: "kopi kenangan", Amount: 28000},
+		{Line: 4, Date: day(5), Description: "Grab", Amount: 25000, ExternalID: "A3"},
+		{Line: 5, Date: day(5), Description: "Grab", Amount: 25000, ExternalID: "A3"},
+		{Line: 6, Date: day(6), Description: "Bad", Errors: []string{"amount is zero"}},
+	}
+	existing := []store.TransactionFingerprint{
+		{ID: 10, ExternalID: "A1", Date: day(3), Amount: 45000, Description: "Indomaret"},
+		{ID: 11, Date: day(4).Add(15 * time.Hour), Amount: 28000, Description: "Kopi Kenangan"},
+		// a different bank transaction with the same details is not a match
+		{ID: 12, ExternalID: "A2", Date: day(5), Amount: 25000, Description: "Grab"},
+	}
+
+	MarkDuplicates(rows, existing)
+
+	assert.True(suite.T(), rows[0].Duplicate)
+	assert.Equal(suite.T(), int64(10), rows[0].DuplicateOf)
+	// the booked coffee matches one of the two purchases only
+	assert.True(suite.T(), rows[1].Duplicate)
+	assert.Equal(suite.T(), int64(11), rows[1].DuplicateOf)
+	assert.False(suite.T(), rows[2].Duplicate)
+	assert.False(suite.T(), rows[3].Duplicate)
+	assert.True(suite.T(), rows[4].Duplicate)
+	assert.Zero(suite.T(), rows[4].DuplicateOf)
+	assert.False(suite.T(), rows[5].Duplicate)
+}
+
+func (suite *DuplicatesTestSuite) TestMarkDuplicates_ExternalIDs() {
+	rows := []Row{
+		// a row without an id matches a booked transaction that has one
+		{Line: 1, Date: day(3), Description: "Indomaret", Amount: 45000},
+		// the transaction is claimed by its id, even by a later row
+		{Line: 2, Date: day(4), Description: "Grab", Amount: 25000},
+		{Line: 3, Date: day(4), Description: "Grab", Amount: 25000, ExternalID: "B2"},
+	}
+	existing := []store.TransactionFingerprint{
+		{ID: 20, ExternalID: "B1", Date: day(3), Amount: 45000, Description: "INDOMARET"},
+		{ID: 21, ExternalID: "B2", Date: day(4), Amount: 25000, Description: "Grab"},
+	}
+
+	MarkDuplicates(rows, existing)
+
+	assert.True(suite.T(), rows[0].Duplicate)
+	assert.Equal(suite.T(), int64(20), rows[0].DuplicateOf)
+	assert.False(suite.T(), rows[1].Duplicate)
+	assert.True(suite.T(), rows[2].Duplicate)
+	assert.Equal(suite.T(), int64(21), rows[2].DuplicateOf)
+}
+
+func (suite *DuplicatesTestSuite) TestDateRange() {
+	from, to, ok := DateRange([]Row{
+		{Date: day(9)},
+		{Date: day(2)},
+		{Date: day(20), Errors: []string{"description is missing"}},
+	})
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), day(1), from)
+	assert.Equal(suite.T(), day(11), to)
+
+	_, _, ok = DateRange(nil)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *DuplicatesTestSuite) TestNormalizeDescription() {
+	assert.Equal(suite.T(), "pos indomaret 123", NormalizeDescription("POS  INDOMARET-123"))
+	assert.Equal(suite.T(), "café", NormalizeDescription(" Café. "))
+}
+
+func TestDuplicatesTestSuite(t *testing.T) {
+	suite.Run(t, new(DuplicatesTestSuite))
+}
@@ -0,0 +1,188 @@
+// Package importer turns bank and e-wallet exports into transactions.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/pukuri/expenses/backend/internal/suggest"
+)
+
+// Row is one parsed line of an import. Amount follows the ledger convention:
+// positive for money going out, negative for money coming in. Rows with
+// Errors are not imported, and Duplicate rows match a transaction that is
+// already booked (DuplicateOf) or an earlier row of the same file.
+// AccountID, CategoryID and Splits are set by formats that name the account
+// and categories themselves, such as journals, and CategoryID and TagIDs by
+// categorization rules.
+type Row struct {
+	Line        int           `json:"line"`
+	Date        time.Time     `json:"date"`
+	Description string        `json:"description"`
+	Amount      int64         `json:"amount"`
+	Kind        string        `json:"kind"`
+	AccountID   int64         `json:"account_id,omitempty"`
+	CategoryID  *int64        `json:"category_id,omitempty"`
+	PayeeID     *int64        `json:"payee_id,omitempty"`
+	Splits      []store.Split `json:"splits,omitempty"`
+	TagIDs      []int64       `json:"tag_ids,omitempty"`
+	ExternalID  string        `json:"external_id,omitempty"`
+	Duplicate   bool          `json:"duplicate,omitempty"`
+	DuplicateOf int64         `json:"duplicate_of,omitempty"`
+	Errors      []string      `json:"errors,omitempty"`
+	// Suggestions are learned categories offered for a row the rules left
+	// uncategorized; they are not applied on commit.
+	Suggestions []suggest.Suggestion `json:"suggestions,omitempty"`
+}
+
+func (r Row) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+func (r *Row) fail(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// setAmount records an amount in the ledger convention and derives the kind
+// from its sign.
+func (r *Row) setAmount(amount int64) {
+	if amount == 0 {
+		r.fail("amount is zero")
+		return
+	}
+	r.Amount = amount
+	r.Kind = store.KindExpense
+	if amount < 0 {
+		r.Kind = store.KindIncome
+	}
+}
+
+var errTooManyDecimals = errors.New("amount has more decimals than its currency")
+
+// ParseAmount reads a formatted amount such as "Rp 1.250.000,00", "-45,000"
+// or "(12.500)" into the minor unit of a currency with the given number of
+// decimals, so "$ 20.50" is 2050 with two decimals. Currency symbols and
+// spaces are ignored; parentheses and a leading or trailing minus mark
+// negative amounts.
+func ParseAmount(value, decimalSeparator, thousandSeparator string, decimals int) (int64, error) {
+	if decimalSeparator == "" {
+		decimalSeparator = "."
+		if thousandSeparator == "." {
+			decimalSeparator = ","
+		}
+	}
+
+	s := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	var digits strings.Builder
+	fraction := ""
+	seenDecimal := false
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case !seenDecimal && strings.HasPrefix(rest, decimalSeparator):
+			seenDecimal = true
+			i += len(decimalSeparator)
+			continue
+		case thousandSeparator != "" && strings.HasPrefix(rest, thousandSeparator):
+			if seenDecimal {
+				return 0, errors.New("thousand separator after the decimal separator")
+			}
+			i += len(thousandSeparator)
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		switch {
+		case r >= '0' && r <= '9':
+			if seenDecimal {
+				fraction += string(r)
+			} else {
+				digits.WriteRune(r)
+			}
+		case r == '-':
+			negative = !negative
+		case r == '+':
+		case unicode.IsSpace(r) || unicode.IsLetter(r) || unicode.Is(unicode.Sc, r):
+			// currency symbols and codes such as "Rp", "IDR" or "$"
+		default:
+			return 0, fmt.Errorf("unexpected character %q", r)
+		}
+		i += size
+	}
+
+	if digits.Len() == 0 && fraction == "" {
+		return 0, errors.New("no digits")
+	}
+	// trailing zeros beyond the currency's decimals are only formatting
+	if len(fraction) > decimals {
+		if strings.Trim(fraction[decimals:], "0") != "" {
+			return 0, errTooManyDecimals
+		}
+		fraction = fraction[:decimals]
+	}
+	digits.WriteString(fraction)
+	for range decimals - len(fraction) {
+		digits.WriteByte('0')
+	}
+	if digits.Len() == 0 {
+		// ".00" of a currency without decimals
+		digits.WriteByte('0')
+	}
+
+	amount, err := strconv.ParseInt(digits.String(), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
+
+var dateTokens = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MMMM", "January",
+	"MMM", "Jan",
+	"MM", "01",
+	"DD", "02",
+	"HH", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+// DateLayout converts a format such as "DD/MM/YYYY" or "YYYY-MM-DD HH:mm"
+// into a Go time layout. Formats that already are Go layouts pass through.
+func DateLayout(format string) string {
+	if strings.Contains(format, "2006") || strings.Contains(format, "06") {
+		return format
+	}
+	return dateTokens.Replace(format)
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
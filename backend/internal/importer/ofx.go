@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"errors"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var ErrNotOFX = errors.New("file is not an OFX statement")
+
+// ParseOFX reads the bank and credit card transactions of an OFX or QFX
+// statement. Both the SGML flavour (OFX 1.x, leaf elements without closing
+// tags) and the XML flavour (OFX 2.x) are accepted. Amounts are read in the
+// given currency, the one of the account they are booked on.
+func ParseOFX(r io.Reader, currency string) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := string(data)
+
+	start := strings.Index(strings.ToUpper(doc), "<OFX>")
+	if start < 0 {
+		return nil, ErrNotOFX
+	}
+
+	decimals := store.CurrencyDecimals(currency)
+	var rows []Row
+	var fields map[string]string
+	var line int
+	for pos := start; pos < len(doc); {
+		open := strings.IndexByte(doc[pos:], '<')
+		if open < 0 {
+			break
+		}
+		open += pos
+		end := strings.IndexByte(doc[open:], '>')
+		if end < 0 {
+			break
+		}
+		end += open
+
+		tag := strings.ToUpper(strings.TrimSpace(doc[open+1 : end]))
+		next := strings.IndexByte(doc[end+1:], '<')
+		if next < 0 {
+			next = len(doc) - end - 1
+		}
+		text := strings.TrimSpace(doc[end+1 : end+1+next])
+		pos = end + 1 + next
+
+		switch {
+		case tag == "STMTTRN":
+			fields = map[string]string{}
+			line = strings.Count(doc[:open], "\n") + 1
+		case tag == "/STMTTRN":
+			if fields != nil {
+				rows = append(rows, ofxRow(line, fields, decimals))
+			}
+			fields = nil
+		case fields != nil && text != "" && !strings.HasPrefix(tag, "/"):
+			fields[tag] = html.UnescapeString(text)
+		}
+	}
+
+	return rows, nil
+}
+
+func ofxRow(line int, fields map[string]string, decimals int) Row {
+	row := Row{Line: line, ExternalID: truncate(fields["FITID"], 255)}
+
+	if value := fields["DTPOSTED"]; value == "" {
+		row.fail("DTPOSTED is missing")
+	} else if date, err := parseOFXDate(value); err != nil {
+		row.fail("DTPOSTED %q is not a valid date", value)
+	} else {
+		row.Date = date
+	}
+
+	description := fields["NAME"]
+	if description == "" {
+		description = fields["PAYEE"]
+	}
+	if description == "" {
+		description = fields["MEMO"]
+	}
+	if description == "" {
+		row.fail("NAME and MEMO are missing")
+	} else {
+		row.Description = truncate(description, 255)
+	}
+
+	value := fields["TRNAMT"]
+	decimalSeparator := "."
+	if strings.Contains(value, ",") && !strings.Contains(value, ".") {
+		decimalSeparator = ","
+	}
+	if value == "" {
+		row.fail("TRNAMT is missing")
+	} else if amount, err := ParseAmount(value, decimalSeparator, "", decimals); err != nil {
+		row.fail("TRNAMT %q: %s", value, err)
+	} else {
+		// statements use negative amounts for debits
+		row.setAmount(-amount)
+	}
+
+	return row
+}
+
+// parseOFXDate reads YYYYMMDD[HHMMSS[.XXX]][[gmt offset[:tz name]]]. Dates
+// without an offset are in GMT.
+func parseOFXDate(value string) (time.Time, error) {
+	location := time.UTC
+	if i := strings.IndexByte(value, '['); i >= 0 {
+		zone := strings.TrimSuffix(value[i+1:], "]")
+		value = value[:i]
+		if j := strings.IndexByte(zone, ':'); j >= 0 {
+			zone = zone[:j]
+		}
+		hours, err := strconv.ParseFloat(zone, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		location = time.FixedZone("", int(hours*3600))
+	}
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value = value[:i]
+	}
+
+	switch len(value) {
+	case 8:
+		return time.ParseInLocation("20060102", value, location)
+	case 12:
+		return time.ParseInLocation("200601021504", value, location)
+	case 14:
+		return time.ParseInLocation("20060102150405", value, location)
+	default:
+		return time.Time{}, errors.New("unexpected length")
+	}
+}
@@ -0,0 +1,114 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OFXTestSuite struct {
+	suite.Suite
+}
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>IDR
+<BANKTRANLIST>
+<DTSTART>20240101
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240103120000.000[+7:WIB]
+<TRNAMT>-45000.00
+<FITID>202401030001
+<NAME>INDOMARET &amp; CO
+<MEMO>Card 1234
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240125
+<TRNAMT>15000000
+<FITID>202401250002
+<MEMO>Salary
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>2024-01-26
+<TRNAMT>-12.50
+<FITID>202401260003
+<NAME>Coffee
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20240205</DTPOSTED>
+        <TRNAMT>-250000</TRNAMT>
+        <FITID>CC-77</FITID>
+        <NAME>Tokopedia</NAME>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func (suite *OFXTestSuite) TestParseOFX_SGML() {
+	rows, err := ParseOFX(strings.NewReader(sgmlStatement), "IDR")
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), rows, 3) {
+		return
+	}
+
+	assert.True(suite.T(), rows[0].Valid())
+	assert.Equal(suite.T(), 10, rows[0].Line)
+	assert.Equal(suite.T(), "202401030001", rows[0].ExternalID)
+	assert.Equal(suite.T(), "INDOMARET & CO", rows[0].Description)
+	assert.Equal(suite.T(), int64(45000), rows[0].Amount)
+	assert.Equal(suite.T(), store.KindExpense, rows[0].Kind)
+	assert.True(suite.T(), time.Date(2024, 1, 3, 5, 0, 0, 0, time.UTC).Equal(rows[0].Date))
+
+	assert.Equal(suite.T(), "Salary", rows[1].Description)
+	assert.Equal(suite.T(), int64(-15000000), rows[1].Amount)
+	assert.Equal(suite.T(), store.KindIncome, rows[1].Kind)
+
+	// an invalid date and a fractional amount
+	assert.Len(suite.T(), rows[2].Errors, 2)
+}
+
+func (suite *OFXTestSuite) TestParseOFX_XML() {
+	rows, err := ParseOFX(strings.NewReader(xmlStatement), "IDR")
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), rows, 1) {
+		return
+	}
+
+	assert.True(suite.T(), rows[0].Valid())
+	assert.Equal(suite.T(), "CC-77", rows[0].ExternalID)
+	assert.Equal(suite.T(), "Tokopedia", rows[0].Description)
+	assert.Equal(suite.T(), int64(250000), rows[0].Amount)
+}
+
+func (suite *OFXTestSuite) TestParseOFX_NotOFX() {
+	_, err := ParseOFX(strings.NewReader("Date,Description,Amount\n"), "IDR")
+	assert.True(suite.T(), errors.Is(err, ErrNotOFX))
+}
+
+func TestOFXTestSuite(t *testing.T) {
+	suite.Run(t, new(OFXTestSuite))
+}
@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// qifLayouts are the date layouts of US QIF exports, tried when no date
+// format is given. Years after 1999 are often written as '24 or ' 4.
+var qifLayouts = []string{"1/2/2006", "1/2/06", "1-2-2006", "1-2-06"}
+
+// ParseQIF reads the transactions of the bank, cash and credit card sections
+// of a QIF file, with amounts in the given currency. dateFormat uses the
+// tokens of DateLayout and may be empty for month-first dates.
+func ParseQIF(r io.Reader, dateFormat, currency string) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	decimals := store.CurrencyDecimals(currency)
+
+	var rows []Row
+	var fields map[byte]string
+	section := "bank"
+	start, line := 0, 0
+	flush := func() {
+		if fields != nil && isQIFTransactionSection(section) {
+			rows = append(rows, qifRow(start, fields, dateFormat, decimals))
+		}
+		fields = nil
+	}
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		switch text[0] {
+		case '!':
+			flush()
+			header := strings.ToLower(strings.TrimSpace(text[1:]))
+			if strings.HasPrefix(header, "type:") {
+				section = strings.TrimSpace(strings.TrimPrefix(header, "type:"))
+			} else {
+				// !Account, !Option and similar blocks describe the file
+				section = header
+			}
+		case '^':
+			flush()
+		default:
+			if fields == nil {
+				fields = map[byte]string{}
+				start = line
+			}
+			// split lines (S, E, $) only repeat parts of the total
+			if _, ok := fields[text[0]]; !ok {
+				fields[text[0]] = strings.TrimSpace(text[1:])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	return rows, nil
+}
+
+func isQIFTransactionSection(section string) bool {
+	switch section {
+	case "bank", "cash", "ccard", "oth a", "oth l":
+		return true
+	}
+	return false
+}
+
+func qifRow(line int, fields map[byte]string, dateFormat string, decimals int) Row {
+	row := Row{Line: line}
+
+	if value := fields['D']; value == "" {
+		row.fail("date is missing")
+	} else if date, err := parseQIFDate(value, dateFormat); err != nil {
+		row.fail("%s", err)
+	} else {
+		row.Date = date
+	}
+
+	description := fields['P']
+	if description == "" {
+		description = fields['M']
+	}
+	if description == "" {
+		row.fail("payee and memo are missing")
+	} else {
+		row.Description = truncate(description, 255)
+	}
+
+	value := fields['T']
+	if value == "" {
+		value = fields['U']
+	}
+	if value == "" {
+		row.fail("amount is missing")
+	} else if amount, err := ParseAmount(value, ".", ",", decimals); err != nil {
+		row.fail("amount %q: %s", value, err)
+	} else {
+		// QIF uses negative amounts for money going out
+		row.setAmount(-amount)
+	}
+
+	return row
+}
+
+func parseQIFDate(value, dateFormat string) (time.Time, error) {
+	normalized := strings.ReplaceAll(value, "' ", "'0")
+	normalized = strings.ReplaceAll(normalized, "'", "/")
+	normalized = strings.ReplaceAll(normalized, " ", "")
+
+	layouts := qifLayouts
+	if dateFormat != "" {
+		layouts = []string{DateLayout(dateFormat)}
+	}
+	for _, layout := range layouts {
+		if date, err := time.ParseInLocation(layout, normalized, time.Local); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("date %q is not a valid date", value)
+}
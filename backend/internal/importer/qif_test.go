@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type QIFTestSuite struct {
+	suite.Suite
+}
+
+func (suite *QIFTestSuite) TestParseQIF() {
+	file := "!Account\n" +
+		"NChecking\n" +
+		"TBank\n" +
+		"^\n" +
+		"!Type:Bank\n" +
+		"D1/25'24\n" +
+		"T-1,250.00\n" +
+		"PElectricity\n" +
+		"LUtilities\n" +
+		"^\n" +
+		"D2/ 1' 4\n" +
+		"U3,000\n" +
+		"MRefund\n" +
+		"SFood\n" +
+		"$2,000\n" +
+		"SOther\n" +
+		"$1,000\n" +
+		"^\n" +
+		"D13/45/2024\n" +
+		"T-10\n" +
+		"^\n"
+
+	rows, err := ParseQIF(strings.NewReader(file), "", "IDR")
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), rows, 3) {
+		return
+	}
+
+	assert.True(suite.T(), rows[0].Valid())
+	assert.Equal(suite.T(), 6, rows[0].Line)
+	assert.Equal(suite.T(), time.Date(2024, 1, 25, 0, 0, 0, 0, time.Local), rows[0].Date)
+	assert.Equal(suite.T(), "Electricity", rows[0].Description)
+	assert.Equal(suite.T(), int64(1250), rows[0].Amount)
+
+	assert.True(suite.T(), rows[1].Valid())
+	assert.Equal(suite.T(), time.Date(2004, 2, 1, 0, 0, 0, 0, time.Local), rows[1].Date)
+	assert.Equal(suite.T(), "Refund", rows[1].Description)
+	assert.Equal(suite.T(), int64(-3000), rows[1].Amount)
+
+	// an invalid date and no payee
+	assert.Len(suite.T(), rows[2].Errors, 2)
+}
+
+func (suite *QIFTestSuite) TestParseQIF_DateFormat() {
+	file := "!Type:CCard\nD25/01/2024\nT-99,000\nPGrab\n"
+
+	rows, err := ParseQIF(strings.NewReader(file), "DD/MM/YYYY", "IDR")
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), rows, 1) {
+		return
+	}
+	assert.True(suite.T(), rows[0].Valid())
+	assert.Equal(suite.T(), time.Date(2024, 1, 25, 0, 0, 0, 0, time.Local), rows[0].Date)
+	assert.Equal(suite.T(), int64(99000), rows[0].Amount)
+}
+
+func (suite *QIFTestSuite) TestParseQIF_SkipsInvestments() {
+	file := "!Type:Invst\nD1/25/2024\nNBuy\nYACME\nT-500\n^\n"
+
+	rows, err := ParseQIF(strings.NewReader(file), "", "IDR")
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), rows)
+}
+
+func TestQIFTestSuite(t *testing.T) {
+	suite.Run(t, new(QIFTestSuite))
+}
@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var ErrNoRates = errors.New("file contains no exchange rates")
+
+// ParseExchangeRates reads exchange rates from a CSV file with a header row
+// naming the columns date (YYYY-MM-DD), currency and rate, and optionally
+// base_currency, which defaults to baseCurrency. Unlike statements, rates are
+// all or nothing: the first invalid row fails the whole file.
+func ParseExchangeRates(r io.Reader, baseCurrency string) ([]store.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var columns map[string]int
+	rates := []store.ExchangeRate{}
+	line := 0
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if isBlank(record) {
+			continue
+		}
+
+		if columns == nil {
+			if columns, err = rateColumns(record); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		rate, err := exchangeRate(record, columns, baseCurrency)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if len(rates) == 0 {
+		return nil, ErrNoRates
+	}
+	return rates, nil
+}
+
+func rateColumns(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"date", "currency", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header has no %q column", name)
+		}
+	}
+	return columns, nil
+}
+
+func exchangeRate(record []string, columns map[string]int, baseCurrency string) (store.ExchangeRate, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rate := store.ExchangeRate{
+		Currency:     strings.ToUpper(field("currency")),
+		BaseCurrency: strings.ToUpper(field("base_currency")),
+		Date:         field("date"),
+	}
+	if rate.BaseCurrency == "" {
+		rate.BaseCurrency = baseCurrency
+	}
+
+	if _, err := time.Parse(time.DateOnly, rate.Date); err != nil {
+		return rate, fmt.Errorf("invalid date %q", rate.Date)
+	}
+	value, err := strconv.ParseFloat(field("rate"), 64)
+	if err != nil || value <= 0 {
+		return rate, fmt.Errorf("invalid rate %q", field("rate"))
+	}
+	rate.Rate = value
+
+	return rate, ValidateCurrencyPair(rate.Currency, rate.BaseCurrency)
+}
+
+// ValidateCurrencyPair reports a rate between currencies that are not three
+// letter codes or between a currency and itself.
+func ValidateCurrencyPair(currency, baseCurrency string) error {
+	for _, code := range []string{currency, baseCurrency} {
+		if len(code) != 3 || strings.ToUpper(code) != code || strings.ContainsFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) {
+			return fmt.Errorf("invalid currency %q", code)
+		}
+	}
+	if currency == baseCurrency {
+		return fmt.Errorf("rate converts %s to itself", currency)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RatesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *RatesTestSuite) TestParseExchangeRates() {
+	file := "\ufeffDate,Currency,Rate,Base_Currency\n" +
+		"2024-01-02,usd,15500.5,\n" +
+		"\n" +
+		"2024-01-02, SGD, 11600, IDR\n" +
+		"2024-01-02,EUR,1.09,USD\n"
+
+	rates, err := ParseExchangeRates(strings.NewReader(file), "IDR")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []store.ExchangeRate{
+		{Currency: "USD", BaseCurrency: "IDR", Date: "2024-01-02", Rate: 15500.5},
+		{Currency: "SGD", BaseCurrency: "IDR", Date: "2024-01-02", Rate: 11600},
+		{Currency: "EUR", BaseCurrency: "USD", Date: "2024-01-02", Rate: 1.09},
+	}, rates)
+}
+
+func (suite *RatesTestSuite) TestParseExchangeRates_Invalid() {
+	for file, message := range map[string]string{
+		"currency,rate\nUSD,15500\n":             `header has no "date" column`,
+		"date,currency,rate\n02/01/2024,USD,1\n": `line 2: invalid date "02/01/2024"`,
+		"date,currency,rate\n2024-01-02,USD,0\n": `line 2: invalid rate "0"`,
+		"date,currency,rate\n2024-01-02,IDR,1\n": "line 2: rate converts IDR to itself",
+		"date,currency,rate\n2024-01-02,US,1\n":  `line 2: invalid currency "US"`,
+	} {
+		_, err := ParseExchangeRates(strings.NewReader(file), "IDR")
+		assert.EqualError(suite.T(), err, message, file)
+	}
+
+	_, err := ParseExchangeRates(strings.NewReader("date,currency,rate\n"), "IDR")
+	assert.ErrorIs(suite.T(), err, ErrNoRates)
+}
+
+func TestRatesTestSuite(t *testing.T) {
+	suite.Run(t, new(RatesTestSuite))
+}
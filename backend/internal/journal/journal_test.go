@@ -0,0 +1,222 @@
+package journal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JournalTestSuite struct {
+	suite.Suite
+	accounts   []store.Account
+	categories []store.Category
+}
+
+func (suite *JournalTestSuite) SetupTest() {
+	suite.accounts = []store.Account{
+		{ID: 1, Name: "BCA Tahapan", Currency: "IDR", OpeningBalance: 2000000},
+		{ID: 2, Name: "GoPay", Currency: "IDR"},
+	}
+	suite.categories = []store.Category{
+		{ID: 10, Name: "Food & Drinks", Kind: store.KindExpense},
+		{ID: 11, Name: "Salary", Kind: store.KindIncome},
+		{ID: 12, Name: "food  drinks", Kind: store.KindExpense},
+	}
+}
+
+func (suite *JournalTestSuite) transactions() []store.TransactionExport {
+	food, salary, household := int64(10), int64(11), int64(12)
+	return []store.TransactionExport{
+		{
+			ID: 1, Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.Local), AccountID: 1,
+			Description: `Dinner "Bali"`, Kind: store.KindExpense, CategoryID: &food, Amount: 350000,
+			Tags: []string{"holiday", "shared trip"}, ExternalID: "FIT-1",
+		},
+		{
+			ID: 2, Date: time.Date(2024, 1, 25, 0, 0, 0, 0, time.Local), AccountID: 1,
+			Description: "Salary; January", Kind: store.KindIncome, CategoryID: &salary, Amount: -15000000,
+		},
+		{
+			ID: 3, Date: time.Date(2024, 1, 26, 0, 0, 0, 0, time.Local), AccountID: 1,
+			Description: "Supermarket", Kind: store.KindExpense, Amount: 230000,
+			Splits: []store.Split{{CategoryID: &household, Amount: 180000}, {Amount: 50000}},
+		},
+		{
+			ID: 4, Date: time.Date(2024, 1, 27, 0, 0, 0, 0, time.Local), AccountID: 2,
+			Description: "Top up", Kind: store.KindTransfer, Amount: -100000,
+		},
+	}
+}
+
+func (suite *JournalTestSuite) write(format string) string {
+	var b bytes.Buffer
+	writer, err := NewWriter(&b, format, suite.accounts, suite.categories)
+	assert.NoError(suite.T(), err)
+
+	for _, transaction := range suite.transactions() {
+		assert.NoError(suite.T(), writer.WriteTransaction(&transaction))
+	}
+	assert.NoError(suite.T(), writer.Flush())
+	return b.String()
+}
+
+func (suite *JournalTestSuite) TestWriteBeancount() {
+	journal := suite.write(FormatBeancount)
+
+	assert.Contains(suite.T(), journal, "option \"operating_currency\" \"IDR\"\n")
+	assert.Contains(suite.T(), journal, "1970-01-01 open Assets:BCA-Tahapan\n")
+	assert.Contains(suite.T(), journal, "1970-01-01 open Expenses:Food-Drinks\n")
+	// names that collide get the id appended
+	assert.Contains(suite.T(), journal, "1970-01-01 open Expenses:Food-Drinks-12\n")
+	assert.Contains(suite.T(), journal, "1970-01-01 open Income:Salary\n")
+	assert.Contains(suite.T(), journal, "1970-01-01 * \"Opening balance\"\n"+
+		"  Assets:BCA-Tahapan                        2000000 IDR\n"+
+		"  Equity:Opening-Balances                   -2000000 IDR\n")
+	assert.Contains(suite.T(), journal, "2024-01-03 * \"Dinner \\\"Bali\\\"\" #holiday #shared-trip\n"+
+		"  id: 1\n"+
+		"  external_id: \"FIT-1\"\n"+
+		"  Assets:BCA-Tahapan                        -350000 IDR\n"+
+		"  Expenses:Food-Drinks                      350000 IDR\n")
+	assert.Contains(suite.T(), journal, "2024-01-26 * \"Supermarket\"\n"+
+		"  id: 3\n"+
+		"  Assets:BCA-Tahapan                        -230000 IDR\n"+
+		"  Expenses:Food-Drinks-12                   180000 IDR\n"+
+		"  Expenses:Uncategorized                    50000 IDR\n")
+	assert.Contains(suite.T(), journal, "  Equity:Transfers                          -100000 IDR\n")
+}
+
+func (suite *JournalTestSuite) TestWriteLedger() {
+	journal := suite.write(FormatLedger)
+
+	assert.NotContains(suite.T(), journal, "option")
+	assert.Contains(suite.T(), journal, "account Assets:GoPay\n")
+	assert.Contains(suite.T(), journal, "2024-01-03 * Dinner \"Bali\"\n"+
+		"    ; holiday:, shared-trip:\n"+
+		"    ; id: 1\n"+
+		"    ; external_id: FIT-1\n")
+	assert.Contains(suite.T(), journal, "2024-01-25 * Salary, January\n"+
+		"    ; id: 2\n"+
+		"  Assets:BCA-Tahapan                        15000000 IDR\n"+
+		"  Income:Salary                             -15000000 IDR\n")
+}
+
+func (suite *JournalTestSuite) TestNewWriter_InvalidFormat() {
+	_, err := NewWriter(&bytes.Buffer{}, "gnucash", suite.accounts, suite.categories)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *JournalTestSuite) TestRoundTrip() {
+	for _, format := range []string{FormatBeancount, FormatLedger} {
+		rows, err := Import(strings.NewReader(suite.write(format)), suite.accounts, suite.categories)
+		assert.NoError(suite.T(), err, format)
+		if !assert.Len(suite.T(), rows, 4, format) {
+			continue
+		}
+
+		for i, transaction := range suite.transactions() {
+			row := rows[i]
+			assert.True(suite.T(), row.Valid(), "%s %v", format, row.Errors)
+			assert.Equal(suite.T(), transaction.Date, row.Date, format)
+			assert.Equal(suite.T(), transaction.AccountID, row.AccountID, format)
+			assert.Equal(suite.T(), transaction.Amount, row.Amount, format)
+			assert.Equal(suite.T(), transaction.Kind, row.Kind, format)
+			assert.Equal(suite.T(), transaction.CategoryID, row.CategoryID, format)
+			assert.Equal(suite.T(), transaction.Splits, row.Splits, format)
+			assert.Equal(suite.T(), transaction.ExternalID, row.ExternalID, format)
+		}
+		assert.Equal(suite.T(), `Dinner "Bali"`, rows[0].Description, format)
+	}
+}
+
+func (suite *JournalTestSuite) TestImport_HledgerJournal() {
+	file := "; exported by hand\n" +
+		"commodity 1,000 IDR\n" +
+		"account assets:bca tahapan\n" +
+		"P 2024-01-01 USD 15,500 IDR\n" +
+		"\n" +
+		"2024/02/01 * (INV-7) Nasi goreng  ; lunch\n" +
+		"    expenses:food drinks      IDR 45,000\n" +
+		"    assets:bca tahapan\n" +
+		"\n" +
+		"2024/02/02 Top up GoPay\n" +
+		"    assets:gopay\t100,000 IDR\n" +
+		"    assets:bca tahapan    -100,000 IDR\n" +
+		"\n" +
+		"2024-02-03 Books\n" +
+		"    expenses:books    50,000 IDR\n" +
+		"    assets:bca tahapan\n" +
+		"\n" +
+		"2024-02-04 Broken\n" +
+		"    expenses:food drinks    10,000 IDR\n" +
+		"    assets:bca tahapan    -9,000 IDR\n" +
+		"\n" +
+		"2024-02-05 Shares\n" +
+		"    assets:broker    10 ACME @ 5,000 IDR\n" +
+		"    assets:bca tahapan\n"
+
+	rows, err := Import(strings.NewReader(file), suite.accounts, suite.categories)
+	assert.NoError(suite.T(), err)
+	if !assert.Len(suite.T(), rows, 6) {
+		return
+	}
+
+	food := int64(10)
+	assert.True(suite.T(), rows[0].Valid())
+	assert.Equal(suite.T(), 6, rows[0].Line)
+	assert.Equal(suite.T(), time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), rows[0].Date)
+	assert.Equal(suite.T(), "Nasi goreng", rows[0].Description)
+	assert.Equal(suite.T(), int64(1), rows[0].AccountID)
+	assert.Equal(suite.T(), int64(45000), rows[0].Amount)
+	assert.Equal(suite.T(), store.KindExpense, rows[0].Kind)
+	assert.Equal(suite.T(), &food, rows[0].CategoryID)
+
+	// a transfer between accounts is booked on both
+	assert.Equal(suite.T(), int64(2), rows[1].AccountID)
+	assert.Equal(suite.T(), int64(-100000), rows[1].Amount)
+	assert.Equal(suite.T(), store.KindTransfer, rows[1].Kind)
+	assert.Equal(suite.T(), int64(1), rows[2].AccountID)
+	assert.Equal(suite.T(), int64(100000), rows[2].Amount)
+
+	assert.Equal(suite.T(), []string{"unknown category expenses:books"}, rows[3].Errors)
+	assert.Equal(suite.T(), []string{"postings do not balance"}, rows[4].Errors)
+	assert.Equal(suite.T(), []string{"postings with a cost or price are not supported"}, rows[5].Errors)
+}
+
+func (suite *JournalTestSuite) TestMinorUnits() {
+	accounts := []store.Account{{ID: 3, Name: "Wise", Currency: "USD", OpeningBalance: 5}}
+	var b bytes.Buffer
+	writer, err := NewWriter(&b, FormatLedger, accounts, suite.categories)
+	assert.NoError(suite.T(), err)
+	food := int64(10)
+	assert.NoError(suite.T(), writer.WriteTransaction(&store.TransactionExport{
+		ID: 5, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), AccountID: 3,
+		Description: "Coffee", Kind: store.KindExpense, CategoryID: &food, Amount: 1250,
+	}))
+	assert.NoError(suite.T(), writer.Flush())
+
+	// amounts are written with the decimals of the account's currency
+	assert.Contains(suite.T(), b.String(), "  Assets:Wise                               0.05 USD\n")
+	assert.Contains(suite.T(), b.String(), "  Assets:Wise                               -12.50 USD\n"+
+		"  Expenses:Food-Drinks                      12.50 USD\n")
+
+	rows, err := Import(strings.NewReader(b.String()), accounts, suite.categories)
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), rows, 1) {
+		assert.True(suite.T(), rows[0].Valid(), rows[0].Errors)
+		assert.Equal(suite.T(), int64(1250), rows[0].Amount)
+	}
+}
+
+func (suite *JournalTestSuite) TestImport_NoEntries() {
+	_, err := Import(strings.NewReader("2024-01-01 open Assets:Cash\n"), suite.accounts, suite.categories)
+	assert.ErrorIs(suite.T(), err, ErrNoEntries)
+}
+
+func TestJournalTestSuite(t *testing.T) {
+	suite.Run(t, new(JournalTestSuite))
+}
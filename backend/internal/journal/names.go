@@ -0,0 +1,143 @@
+// Package journal converts the ledger to and from plain-text accounting
+// journals in beancount and ledger (hledger) syntax.
+//
+// Accounts become Assets:<name>, categories Expenses:<name> or
+// Income:<name> depending on their kind, and opening balances are booked
+// against Equity:Opening-Balances. Uncategorized transactions use
+// Expenses:Uncategorized or Income:Uncategorized, transfers Equity:Transfers
+// and adjustments Equity:Adjustments.
+package journal
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+const (
+	FormatBeancount = "beancount"
+	FormatLedger    = "ledger"
+)
+
+const (
+	rootAssets   = "Assets"
+	rootExpenses = "Expenses"
+	rootIncome   = "Income"
+	rootEquity   = "Equity"
+
+	openingBalances = "Equity:Opening-Balances"
+	transfers       = "Equity:Transfers"
+	adjustments     = "Equity:Adjustments"
+	uncategorized   = "Uncategorized"
+)
+
+func IsValidFormat(format string) bool {
+	return format == FormatBeancount || format == FormatLedger
+}
+
+// names maps accounts and categories to journal account names. The writer
+// and the importer build it from the same accounts and categories, so a
+// journal written by this package reads back to the same ids.
+type names struct {
+	accounts   map[int64]string
+	categories map[int64]string
+	// lookups by lookupKey of the journal name
+	accountIDs  map[string]int64
+	categoryIDs map[string]int64
+}
+
+func newNames(accounts []store.Account, categories []store.Category) *names {
+	n := &names{
+		accounts:    map[int64]string{},
+		categories:  map[int64]string{},
+		accountIDs:  map[string]int64{},
+		categoryIDs: map[string]int64{},
+	}
+
+	used := map[string]bool{}
+	unique := func(name string, id int64) string {
+		if used[strings.ToLower(name)] {
+			name += "-" + strconv.FormatInt(id, 10)
+		}
+		used[strings.ToLower(name)] = true
+		return name
+	}
+
+	for _, account := range accounts {
+		name := unique(rootAssets+":"+component(account.Name), account.ID)
+		n.accounts[account.ID] = name
+		n.accountIDs[lookupKey(name)] = account.ID
+	}
+	for _, category := range categories {
+		root := rootExpenses
+		if category.Kind == store.KindIncome {
+			root = rootIncome
+		}
+		name := unique(root+":"+component(category.Name), category.ID)
+		n.categories[category.ID] = name
+		n.categoryIDs[lookupKey(name)] = category.ID
+	}
+
+	return n
+}
+
+// root returns the canonical top level account of a journal account name,
+// accepting the lower case and alternative spellings of other tools.
+func root(account string) string {
+	top, _, _ := strings.Cut(account, ":")
+	switch strings.ToLower(top) {
+	case "assets", "asset", "liabilities", "liability":
+		return rootAssets
+	case "expenses", "expense":
+		return rootExpenses
+	case "income", "revenue", "revenues":
+		return rootIncome
+	case "equity":
+		return rootEquity
+	default:
+		return ""
+	}
+}
+
+// lookupKey normalizes a journal account name so that "assets:bca tahapan"
+// finds Assets:BCA-Tahapan.
+func lookupKey(account string) string {
+	_, rest, _ := strings.Cut(account, ":")
+	return strings.ToLower(root(account) + ":" + component(rest))
+}
+
+// counterAccount is the account a transaction's amount is booked against
+// when it has no category of its own.
+func counterAccount(kind string) string {
+	switch kind {
+	case store.KindIncome:
+		return rootIncome + ":" + uncategorized
+	case store.KindTransfer:
+		return transfers
+	case store.KindAdjustment:
+		return adjustments
+	default:
+		return rootExpenses + ":" + uncategorized
+	}
+}
+
+// component turns a name into a valid account name component: words of
+// letters and digits joined by dashes, starting with an upper case letter
+// or a digit.
+func component(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "Unnamed"
+	}
+
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, "-")
+}
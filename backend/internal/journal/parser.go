@@ -0,0 +1,397 @@
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+var ErrNoEntries = errors.New("journal contains no transactions")
+
+// entryHeader matches the first line of a transaction in either syntax:
+// "2024-01-05 * "Lunch" #work", "2024/01/05 ! (1234) Lunch" or
+// "2024-01-05 txn "Lunch"". An entry without a flag is accepted as well.
+var entryHeader = regexp.MustCompile(`^(\d{4}[-/]\d{1,2}[-/]\d{1,2})(?:=\S+)?(?:\s+(?:[*!]|txn))?(?:\s+\([^)]*\))?(?:\s+(.*))?$`)
+
+// amountPattern matches a posting amount with an optional commodity before
+// or after the number.
+var amountPattern = regexp.MustCompile(`^(?:([A-Za-z$€£¥"][^\s\d-]*)\s*)?(-?[\d.,]+)(?:\s*([A-Za-z"][^\s]*))?$`)
+
+// commodityPattern matches a commodity that names a currency.
+var commodityPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type posting struct {
+	account string
+	amount  int64
+	elided  bool
+}
+
+type entry struct {
+	line        int
+	date        time.Time
+	description string
+	externalID  string
+	postings    []posting
+	err         string
+}
+
+// Import reads a beancount or ledger journal into transaction rows, mapping
+// journal accounts back to accounts and categories by name as the writer
+// names them. Lower case names and spaces in place of dashes, as other tools
+// write them, are accepted too. Opening balance entries are skipped, and an
+// entry moving money between two accounts becomes a transfer on each.
+func Import(r io.Reader, accounts []store.Account, categories []store.Category) ([]importer.Row, error) {
+	entries, err := parseEntries(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNoEntries
+	}
+
+	n := newNames(accounts, categories)
+	rows := []importer.Row{}
+	for _, e := range entries {
+		rows = append(rows, n.rows(e)...)
+	}
+	return rows, nil
+}
+
+// parseEntries splits a journal into transaction entries, skipping
+// directives, comments and price declarations.
+func parseEntries(r io.Reader) ([]*entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+
+	entries := []*entry{}
+	var current *entry
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		if text == "" || !isIndented(text) {
+			current = nil
+		}
+		if text == "" {
+			continue
+		}
+
+		if isIndented(text) {
+			if current != nil {
+				current.addLine(strings.TrimSpace(text))
+			}
+			continue
+		}
+
+		match := entryHeader.FindStringSubmatch(text)
+		if match == nil || isDirective(match[2]) {
+			continue
+		}
+
+		current = &entry{line: line}
+		entries = append(entries, current)
+		date, err := parseDate(match[1])
+		if err != nil {
+			current.err = "invalid date " + match[1]
+			continue
+		}
+		current.date = date
+		current.description = parseDescription(match[2])
+	}
+
+	return entries, scanner.Err()
+}
+
+func isIndented(line string) bool {
+	return line[0] == ' ' || line[0] == '\t'
+}
+
+// isDirective reports whether the rest of a dated line is a beancount
+// directive rather than a transaction.
+func isDirective(rest string) bool {
+	keyword, _, _ := strings.Cut(rest, " ")
+	switch keyword {
+	case "open", "close", "commodity", "balance", "pad", "price", "note", "document", "event", "custom", "query":
+		return true
+	}
+	return false
+}
+
+func parseDate(value string) (time.Time, error) {
+	return time.ParseInLocation("2006-1-2", strings.ReplaceAll(value, "/", "-"), time.Local)
+}
+
+// parseDescription reads the beancount payee and narration strings, or the
+// ledger description up to its comment. Beancount tags and links are
+// dropped.
+func parseDescription(rest string) string {
+	if !strings.HasPrefix(rest, `"`) {
+		description, _, _ := strings.Cut(rest, ";")
+		return strings.TrimSpace(description)
+	}
+
+	quoted := []string{}
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != '"' {
+			continue
+		}
+		var b []byte
+		for i++; i < len(rest) && rest[i] != '"'; i++ {
+			if rest[i] == '\\' && i+1 < len(rest) {
+				i++
+			}
+			b = append(b, rest[i])
+		}
+		quoted = append(quoted, string(b))
+	}
+
+	switch len(quoted) {
+	case 0:
+		return ""
+	case 1:
+		return quoted[0]
+	default:
+		// payee and narration
+		if quoted[1] == "" {
+			return quoted[0]
+		}
+		if quoted[0] == "" {
+			return quoted[1]
+		}
+		return quoted[0] + " " + quoted[1]
+	}
+}
+
+// addLine reads an indented line of an entry: metadata, a comment or a
+// posting.
+func (e *entry) addLine(text string) {
+	if strings.HasPrefix(text, ";") || strings.HasPrefix(text, "#") {
+		// ledger keeps metadata in comments
+		if key, value, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(text, ";")), ":"); ok && key == "external_id" {
+			e.externalID = strings.TrimSpace(value)
+		}
+		return
+	}
+
+	account, amount := splitPosting(text)
+	if key, value, ok := strings.Cut(text, ": "); ok && amount == "" && isMetadataKey(key) {
+		if key == "external_id" {
+			e.externalID = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+		return
+	}
+
+	if e.err != "" {
+		return
+	}
+	p := posting{account: account}
+	if amount == "" {
+		p.elided = true
+		e.postings = append(e.postings, p)
+		return
+	}
+
+	if strings.ContainsAny(amount, "{@") {
+		e.err = "postings with a cost or price are not supported"
+		return
+	}
+	match := amountPattern.FindStringSubmatch(amount)
+	if match == nil {
+		e.err = "invalid amount " + amount
+		return
+	}
+	value, err := importer.ParseAmount(match[2], ".", ",", store.CurrencyDecimals(commodity(match[1]+match[3])))
+	if err != nil {
+		e.err = "invalid amount " + amount + ": " + err.Error()
+		return
+	}
+	p.amount = value
+	e.postings = append(e.postings, p)
+}
+
+// commodity returns the currency of a posting amount. Symbols and amounts
+// without a commodity are taken to be in BaseCurrency.
+func commodity(value string) string {
+	value = strings.Trim(value, `"`)
+	if !commodityPattern.MatchString(value) {
+		return store.BaseCurrency
+	}
+	return value
+}
+
+// splitPosting separates the account of a posting from its amount, which
+// follow each other after a tab or at least two spaces.
+func splitPosting(text string) (account, amount string) {
+	text, _, _ = strings.Cut(text, ";")
+	text = strings.TrimSpace(strings.TrimLeft(text, "*! "))
+	if i := strings.IndexAny(text, "\t"); i >= 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+	}
+	if i := strings.Index(text, "  "); i >= 0 {
+		return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+2:])
+	}
+	return text, ""
+}
+
+func isMetadataKey(key string) bool {
+	if key == "" || key[0] < 'a' || key[0] > 'z' {
+		return false
+	}
+	return !strings.ContainsAny(key, " :")
+}
+
+// rows converts an entry to transaction rows.
+func (n *names) rows(e *entry) []importer.Row {
+	row := importer.Row{
+		Line:        e.line,
+		Date:        e.date,
+		Description: e.description,
+		ExternalID:  e.externalID,
+	}
+	if e.err != "" {
+		return []importer.Row{fail(row, e.err)}
+	}
+	if err := balance(e.postings); err != "" {
+		return []importer.Row{fail(row, err)}
+	}
+
+	var assets, counters []posting
+	for _, p := range e.postings {
+		if lookupKey(p.account) == strings.ToLower(openingBalances) {
+			// opening balances are part of the accounts
+			return nil
+		}
+		if root(p.account) == rootAssets {
+			assets = append(assets, p)
+		} else {
+			counters = append(counters, p)
+		}
+	}
+
+	switch {
+	case len(assets) == 2 && len(counters) == 0:
+		// a transfer between two accounts
+		rows := []importer.Row{}
+		for _, p := range assets {
+			transfer := row
+			transfer.Amount = -p.amount
+			transfer.Kind = store.KindTransfer
+			n.setAccount(&transfer, p.account)
+			rows = append(rows, transfer)
+		}
+		return rows
+	case len(assets) != 1 || len(counters) == 0:
+		return []importer.Row{fail(row, "entries need exactly one asset posting and at least one other posting")}
+	}
+
+	row.Amount = -assets[0].amount
+	n.setAccount(&row, assets[0].account)
+	if row.Amount == 0 {
+		row.Errors = append(row.Errors, "amount is zero")
+	}
+
+	row.Kind = n.kind(counters[0].account)
+	if row.Kind == "" {
+		return []importer.Row{fail(row, "unknown account "+counters[0].account)}
+	}
+
+	if len(counters) == 1 {
+		if row.Kind == store.KindExpense || row.Kind == store.KindIncome {
+			row.CategoryID = n.category(&row, counters[0].account)
+		}
+		return []importer.Row{row}
+	}
+
+	for _, p := range counters {
+		kind := n.kind(p.account)
+		if kind != store.KindExpense && kind != store.KindIncome {
+			row.Errors = append(row.Errors, "split postings must use category accounts, got "+p.account)
+			continue
+		}
+		row.Splits = append(row.Splits, store.Split{CategoryID: n.category(&row, p.account), Amount: p.amount})
+	}
+	return []importer.Row{row}
+}
+
+func (n *names) setAccount(row *importer.Row, account string) {
+	id, ok := n.accountIDs[lookupKey(account)]
+	if !ok {
+		row.Errors = append(row.Errors, "unknown account "+account)
+		return
+	}
+	row.AccountID = id
+}
+
+// category returns the category of an Expenses or Income posting, or nil for
+// the uncategorized accounts.
+func (n *names) category(row *importer.Row, account string) *int64 {
+	_, rest, _ := strings.Cut(account, ":")
+	if strings.EqualFold(component(rest), uncategorized) {
+		return nil
+	}
+	id, ok := n.categoryIDs[lookupKey(account)]
+	if !ok {
+		row.Errors = append(row.Errors, "unknown category "+account)
+		return nil
+	}
+	return &id
+}
+
+// kind derives the transaction kind from the account a transaction is booked
+// against.
+func (n *names) kind(account string) string {
+	switch root(account) {
+	case rootExpenses:
+		return store.KindExpense
+	case rootIncome:
+		return store.KindIncome
+	case rootEquity:
+		switch lookupKey(account) {
+		case strings.ToLower(transfers):
+			return store.KindTransfer
+		case strings.ToLower(adjustments):
+			return store.KindAdjustment
+		}
+	}
+	return ""
+}
+
+// balance fills in an elided posting amount and checks that the postings sum
+// to zero.
+func balance(postings []posting) string {
+	var sum int64
+	elided := -1
+	for i, p := range postings {
+		if p.elided {
+			if elided >= 0 {
+				return "only one posting may omit its amount"
+			}
+			elided = i
+			continue
+		}
+		sum += p.amount
+	}
+
+	if elided >= 0 {
+		postings[elided].amount = -sum
+		postings[elided].elided = false
+		return ""
+	}
+	if sum != 0 {
+		return "postings do not balance"
+	}
+	return ""
+}
+
+func fail(row importer.Row, message string) importer.Row {
+	row.Errors = append(row.Errors, message)
+	return row
+}
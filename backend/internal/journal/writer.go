@@ -0,0 +1,213 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// openDate is the date of the account declarations and opening balances,
+// which predate every transaction.
+const openDate = "1970-01-01"
+
+// Writer renders transactions as journal entries. Amounts are written in the
+// account's currency with as many decimals as its minor unit.
+type Writer struct {
+	w          *bufio.Writer
+	format     string
+	names      *names
+	currencies map[int64]string
+}
+
+// NewWriter writes the journal header: the declaration of every account,
+// category and equity account, followed by the opening balances.
+func NewWriter(w io.Writer, format string, accounts []store.Account, categories []store.Category) (*Writer, error) {
+	if !IsValidFormat(format) {
+		return nil, fmt.Errorf("unsupported journal format %q, expected %s or %s", format, FormatBeancount, FormatLedger)
+	}
+
+	writer := &Writer{
+		w:          bufio.NewWriter(w),
+		format:     format,
+		names:      newNames(accounts, categories),
+		currencies: map[int64]string{},
+	}
+
+	currency := ""
+	for _, account := range accounts {
+		writer.currencies[account.ID] = account.Currency
+		if currency == "" {
+			currency = account.Currency
+		}
+	}
+	if format == FormatBeancount && currency != "" {
+		fmt.Fprintf(writer.w, "option \"operating_currency\" %q\n\n", currency)
+	}
+
+	declared := []string{}
+	for _, account := range accounts {
+		declared = append(declared, writer.names.accounts[account.ID])
+	}
+	for _, category := range categories {
+		declared = append(declared, writer.names.categories[category.ID])
+	}
+	declared = append(declared,
+		rootExpenses+":"+uncategorized,
+		rootIncome+":"+uncategorized,
+		openingBalances,
+		transfers,
+		adjustments,
+	)
+	for _, name := range declared {
+		writer.declare(name)
+	}
+
+	for _, account := range accounts {
+		if account.OpeningBalance == 0 {
+			continue
+		}
+		writer.w.WriteString("\n")
+		writer.header(openDate, "Opening balance", nil)
+		writer.posting(writer.names.accounts[account.ID], account.OpeningBalance, account.Currency)
+		writer.posting(openingBalances, -account.OpeningBalance, account.Currency)
+	}
+
+	return writer, writer.err()
+}
+
+// WriteTransaction writes one entry: the account posting followed by one
+// posting per category line. The transaction id and external id are kept as
+// metadata.
+func (j *Writer) WriteTransaction(transaction *store.TransactionExport) error {
+	account, ok := j.names.accounts[transaction.AccountID]
+	if !ok {
+		return fmt.Errorf("transaction %d references unknown account %d", transaction.ID, transaction.AccountID)
+	}
+	currency := j.currencies[transaction.AccountID]
+
+	j.w.WriteString("\n")
+	j.header(transaction.Date.Format("2006-01-02"), transaction.Description, transaction.Tags)
+	j.metadata("id", strconv.FormatInt(transaction.ID, 10), false)
+	if transaction.ExternalID != "" {
+		j.metadata("external_id", transaction.ExternalID, true)
+	}
+
+	// money going out of the account is a positive ledger amount and a
+	// negative posting
+	j.posting(account, -transaction.Amount, currency)
+	if len(transaction.Splits) == 0 {
+		j.posting(j.counterAccount(transaction.CategoryID, transaction.Kind), transaction.Amount, currency)
+	}
+	for _, split := range transaction.Splits {
+		j.posting(j.counterAccount(split.CategoryID, transaction.Kind), split.Amount, currency)
+	}
+
+	return j.err()
+}
+
+// Flush writes any buffered entries.
+func (j *Writer) Flush() error {
+	return j.w.Flush()
+}
+
+func (j *Writer) counterAccount(categoryID *int64, kind string) string {
+	if categoryID != nil {
+		if name, ok := j.names.categories[*categoryID]; ok {
+			return name
+		}
+	}
+	return counterAccount(kind)
+}
+
+func (j *Writer) declare(name string) {
+	if j.format == FormatBeancount {
+		fmt.Fprintf(j.w, "%s open %s\n", openDate, name)
+		return
+	}
+	fmt.Fprintf(j.w, "account %s\n", name)
+}
+
+// header writes the first line of an entry. Beancount quotes the narration
+// and lists tags after it; ledger has no quoting, so a semicolon, which would
+// start a comment, is replaced, and tags go into a comment.
+func (j *Writer) header(date, description string, tags []string) {
+	description = strings.Join(strings.Fields(description), " ")
+
+	if j.format == FormatBeancount {
+		fmt.Fprintf(j.w, "%s * %s", date, quote(description))
+		for _, tag := range tags {
+			j.w.WriteString(" #" + tagName(tag))
+		}
+		j.w.WriteString("\n")
+		return
+	}
+
+	fmt.Fprintf(j.w, "%s * %s\n", date, strings.ReplaceAll(description, ";", ","))
+	if len(tags) > 0 {
+		names := make([]string, len(tags))
+		for i, tag := range tags {
+			names[i] = tagName(tag) + ":"
+		}
+		j.w.WriteString("    ; " + strings.Join(names, ", ") + "\n")
+	}
+}
+
+func (j *Writer) metadata(key, value string, text bool) {
+	if j.format == FormatBeancount {
+		if text {
+			value = quote(value)
+		}
+		fmt.Fprintf(j.w, "  %s: %s\n", key, value)
+		return
+	}
+	fmt.Fprintf(j.w, "    ; %s: %s\n", key, value)
+}
+
+func (j *Writer) posting(account string, amount int64, currency string) {
+	decimals := store.CurrencyDecimals(currency)
+	if currency == "" {
+		decimals = store.CurrencyDecimals(store.BaseCurrency)
+	}
+	fmt.Fprintf(j.w, "  %-40s  %s", account, formatAmount(amount, decimals))
+	if currency != "" {
+		j.w.WriteString(" " + currency)
+	}
+	j.w.WriteString("\n")
+}
+
+// formatAmount writes an amount in minor units as a decimal number.
+func formatAmount(amount int64, decimals int) string {
+	digits := strconv.FormatInt(amount, 10)
+	if decimals == 0 {
+		return digits
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+// err reports the first write error, which bufio.Writer keeps returning.
+func (j *Writer) err() error {
+	_, err := j.w.Write(nil)
+	return err
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// tagName makes a tag usable as a beancount tag or ledger tag name, which
+// may not contain spaces.
+func tagName(tag string) string {
+	return strings.Join(strings.Fields(tag), "-")
+}
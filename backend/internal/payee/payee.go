@@ -0,0 +1,79 @@
+// Package payee recognizes the payee of a transaction from its description.
+package payee
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pukuri/expenses/backend/internal/importer"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// Normalize reduces an alias to the form it is stored and matched in:
+// lowercase words of letters and digits, as descriptions are compared when
+// importing.
+func Normalize(alias string) string {
+	return importer.NormalizeDescription(alias)
+}
+
+type alias struct {
+	payeeID int64
+	words   []string
+}
+
+// Matcher maps descriptions to the payees whose aliases they contain.
+type Matcher struct {
+	aliases []alias
+}
+
+// New builds a matcher for the payees. The normalized name of a payee counts
+// as one of its aliases.
+func New(payees []store.Payee) *Matcher {
+	matcher := &Matcher{}
+	for _, payee := range payees {
+		for _, name := range append([]string{payee.Name}, payee.Aliases...) {
+			if words := strings.Fields(Normalize(name)); len(words) > 0 {
+				matcher.aliases = append(matcher.aliases, alias{payeeID: payee.ID, words: words})
+			}
+		}
+	}
+
+	// longer aliases are more specific, and ties go to the oldest payee
+	slices.SortStableFunc(matcher.aliases, func(a, b alias) int {
+		if len(a.words) != len(b.words) {
+			return len(b.words) - len(a.words)
+		}
+		switch {
+		case a.payeeID < b.payeeID:
+			return -1
+		case a.payeeID > b.payeeID:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return matcher
+}
+
+// Match returns the payee with the longest alias found as whole words in the
+// description, or nil when no alias is found.
+func (m *Matcher) Match(description string) *int64 {
+	words := strings.Fields(Normalize(description))
+	for _, alias := range m.aliases {
+		if containsWords(words, alias.words) {
+			payeeID := alias.payeeID
+			return &payeeID
+		}
+	}
+	return nil
+}
+
+func containsWords(words, sequence []string) bool {
+	for i := 0; i+len(sequence) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(sequence)], sequence) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package payee
+
+import (
+	"testing"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PayeeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PayeeTestSuite) TestNormalize() {
+	assert.Equal(suite.T(), "pos indomaret 123", Normalize("POS  INDOMARET-123"))
+	assert.Equal(suite.T(), "", Normalize(" -- "))
+}
+
+func (suite *PayeeTestSuite) TestMatch() {
+	matcher := New([]store.Payee{
+		{ID: 1, Name: "Indomaret", Aliases: []string{"idm"}},
+		{ID: 2, Name: "Grab", Aliases: []string{"grab food", "GRAB*"}},
+		{ID: 3, Name: "GrabFood", Aliases: []string{"grab food jakarta"}},
+		{ID: 4, Name: "Starbucks"},
+	})
+
+	match := func(description string) int64 {
+		if payeeID := matcher.Match(description); payeeID != nil {
+			return *payeeID
+		}
+		return 0
+	}
+
+	assert.Equal(suite.T(), int64(1), match("POS INDOMARET KEMANG 123"))
+	assert.Equal(suite.T(), int64(1), match("IDM-0042"))
+	assert.Equal(suite.T(), int64(2), match("GRAB* A-123"))
+	// the longest alias wins
+	assert.Equal(suite.T(), int64(3), match("Grab Food Jakarta Selatan"))
+	// aliases only match whole words
+	assert.Equal(suite.T(), int64(0), match("Grabfoodie"))
+	assert.Equal(suite.T(), int64(0), match("Starbucksy"))
+	assert.Equal(suite.T(), int64(0), match(""))
+}
+
+func (suite *PayeeTestSuite) TestMatch_Ties() {
+	matcher := New([]store.Payee{
+		{ID: 7, Name: "Shell Kemang"},
+		{ID: 5, Name: "Shell", Aliases: []string{"spbu kemang"}},
+	})
+
+	assert.Equal(suite.T(), int64(5), *matcher.Match("SPBU KEMANG SHELL KEMANG"))
+	assert.Equal(suite.T(), int64(7), *matcher.Match("shell kemang 2"))
+}
+
+func TestPayeeTestSuite(t *testing.T) {
+	suite.Run(t, new(PayeeTestSuite))
+}
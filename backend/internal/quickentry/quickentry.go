@@ -0,0 +1,352 @@
+// Package quickentry parses one-line transaction entries such as
+// "kopi 35k kemarin #jajan @gopay" into their amount, date, category,
+// account and description.
+//
+// Amounts take the shorthands k/rb/ribu (thousands) and jt/juta (millions),
+// an optional Rp prefix and thousands separators, and a leading "+" marks
+// income. Dates are written as relative words in English or Indonesian
+// ("yesterday", "kemarin", "3 hari lalu", "last monday", "senin"), as
+// YYYY-MM-DD, or day first as D/M or D/M/YYYY. The category follows "#" and
+// the account "@"; whatever is left is the description.
+package quickentry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoAmount    = errors.New("no amount found, expected something like 35000, 35k, 1,5jt or Rp35.000")
+	ErrInvalidDate = errors.New("invalid date")
+)
+
+// Entry is a parsed line. Amount is always positive; Income is set for
+// amounts written with a leading "+". Category and Account are the names
+// given after "#" and "@", and empty when none was given.
+type Entry struct {
+	Amount      int64
+	Income      bool
+	Date        time.Time
+	Description string
+	Category    string
+	Account     string
+}
+
+var (
+	amountPattern    = regexp.MustCompile(`(?i)^(\+)?(rp\.?)?(\d+(?:[.,]\d+)*)(k|rb|ribu|jt|juta)?$`)
+	thousandsPattern = regexp.MustCompile(`^\d{1,3}(?:[.,]\d{3})+$`)
+	dayMonthPattern  = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{4}))?$`)
+)
+
+var multipliers = map[string]int64{
+	"":     1,
+	"k":    1_000,
+	"rb":   1_000,
+	"ribu": 1_000,
+	"jt":   1_000_000,
+	"juta": 1_000_000,
+}
+
+var relativeDays = map[string]int{
+	"today":        0,
+	"hari ini":     0,
+	"yesterday":    -1,
+	"kemarin":      -1,
+	"kmrn":         -1,
+	"kemarin lusa": -2,
+	"tomorrow":     1,
+	"besok":        1,
+	"lusa":         2,
+	"last week":    -7,
+	"minggu lalu":  -7,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+	"minggu":    time.Sunday,
+	"senin":     time.Monday,
+	"selasa":    time.Tuesday,
+	"rabu":      time.Wednesday,
+	"kamis":     time.Thursday,
+	"jumat":     time.Friday,
+	"sabtu":     time.Saturday,
+}
+
+type amount struct {
+	value  int64
+	income bool
+	// marked amounts carry a shorthand, currency, sign or separators and
+	// cannot be mistaken for a number in the description
+	marked bool
+}
+
+// Parse reads the line relative to today, whose date is used when the line
+// names none.
+func Parse(line string, today time.Time) (*Entry, error) {
+	tokens := strings.Fields(line)
+	entry := &Entry{Date: dateOf(today)}
+
+	var words []string
+	var amounts []amount
+	var amountIndexes []int
+	dated := false
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		switch {
+		case len(token) > 1 && token[0] == '#':
+			if entry.Category != "" {
+				return nil, errors.New("more than one #category given")
+			}
+			entry.Category = token[1:]
+			continue
+		case len(token) > 1 && token[0] == '@':
+			if entry.Account != "" {
+				return nil, errors.New("more than one @account given")
+			}
+			entry.Account = token[1:]
+			continue
+		}
+
+		if !dated {
+			date, n, err := parseDate(tokens[i:], today)
+			if err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				entry.Date = date
+				dated = true
+				i += n - 1
+				continue
+			}
+		}
+
+		if a, n, ok := readAmount(tokens[i:]); ok {
+			amounts = append(amounts, a)
+			amountIndexes = append(amountIndexes, len(words))
+			token = strings.Join(tokens[i:i+n], " ")
+			i += n - 1
+		}
+		words = append(words, token)
+	}
+
+	// the first marked amount wins, and otherwise the last number, so that
+	// "kopi 2 gelas 35000" costs 35000
+	chosen := -1
+	for i, a := range amounts {
+		if a.marked {
+			chosen = i
+			break
+		}
+	}
+	if chosen < 0 {
+		chosen = len(amounts) - 1
+	}
+	if chosen < 0 {
+		return nil, ErrNoAmount
+	}
+
+	entry.Amount = amounts[chosen].value
+	entry.Income = amounts[chosen].income
+	index := amountIndexes[chosen]
+	entry.Description = strings.Join(append(words[:index:index], words[index+1:]...), " ")
+
+	return entry, nil
+}
+
+// readAmount reads an amount from the start of tokens and returns how many
+// tokens it took. A separate "Rp" before the number or shorthand after it,
+// as in "Rp 35.000" or "25 ribu", belongs to the amount.
+func readAmount(tokens []string) (amount, int, bool) {
+	start := 1
+	text := tokens[0]
+	if strings.EqualFold(strings.TrimSuffix(text, "."), "rp") && len(tokens) > 1 {
+		text += tokens[1]
+		start = 2
+	}
+
+	if len(tokens) > start {
+		if _, ok := multipliers[strings.ToLower(tokens[start])]; ok {
+			if a, ok := parseAmount(text + tokens[start]); ok {
+				return a, start + 1, true
+			}
+		}
+	}
+	if a, ok := parseAmount(text); ok {
+		return a, start, true
+	}
+	return amount{}, 0, false
+}
+
+// parseAmount reads one token as an amount.
+func parseAmount(token string) (amount, bool) {
+	match := amountPattern.FindStringSubmatch(token)
+	if match == nil {
+		return amount{}, false
+	}
+	sign, currency, number, suffix := match[1], match[2], match[3], strings.ToLower(match[4])
+	result := amount{
+		income: sign == "+",
+		marked: sign != "" || currency != "" || suffix != "",
+	}
+
+	var value int64
+	var err error
+	switch {
+	case suffix != "":
+		// with a shorthand the separator is a decimal one: 1,5jt or 2.25k
+		value, err = scaleDecimal(number, multipliers[suffix])
+	case thousandsPattern.MatchString(number):
+		result.marked = true
+		value, err = strconv.ParseInt(strings.NewReplacer(".", "", ",", "").Replace(number), 10, 64)
+	case strings.ContainsAny(number, ".,"):
+		return amount{}, false
+	default:
+		value, err = strconv.ParseInt(number, 10, 64)
+	}
+	if err != nil || value <= 0 {
+		return amount{}, false
+	}
+
+	result.value = value
+	return result, true
+}
+
+// scaleDecimal multiplies a number with at most one decimal separator by
+// multiplier, failing when the result is not a whole amount.
+func scaleDecimal(number string, multiplier int64) (int64, error) {
+	whole, fraction, _ := strings.Cut(strings.ReplaceAll(number, ",", "."), ".")
+	if strings.Contains(fraction, ".") {
+		return 0, fmt.Errorf("invalid amount %q", number)
+	}
+
+	value, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if value > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("amount %q is too large", number)
+	}
+
+	divisor := int64(1)
+	for range fraction {
+		divisor *= 10
+	}
+	if (value*multiplier)%divisor != 0 {
+		return 0, fmt.Errorf("amount %q is not a whole number", number)
+	}
+
+	return value * multiplier / divisor, nil
+}
+
+// parseDate reads a date from the start of tokens and returns how many
+// tokens it took, which is zero when they do not start with a date.
+func parseDate(tokens []string, today time.Time) (time.Time, int, error) {
+	today = dateOf(today)
+	word := func(i int) string {
+		if i >= len(tokens) {
+			return ""
+		}
+		return strings.Trim(strings.ToLower(tokens[i]), ".,;!?")
+	}
+
+	// longer phrases first, so "kemarin lusa" is not read as "kemarin"
+	for n := 2; n >= 1; n-- {
+		phrase := word(0)
+		if n == 2 {
+			if word(1) == "" {
+				continue
+			}
+			phrase += " " + word(1)
+		}
+		if days, ok := relativeDays[phrase]; ok {
+			return today.AddDate(0, 0, days), n, nil
+		}
+	}
+
+	// "3 days ago", "3 hari lalu" and "3 hari yang lalu"
+	if days, err := strconv.Atoi(word(0)); err == nil && days >= 0 {
+		switch {
+		case (word(1) == "day" || word(1) == "days") && word(2) == "ago":
+			return today.AddDate(0, 0, -days), 3, nil
+		case word(1) == "hari" && word(2) == "lalu":
+			return today.AddDate(0, 0, -days), 3, nil
+		case word(1) == "hari" && word(2) == "yang" && word(3) == "lalu":
+			return today.AddDate(0, 0, -days), 4, nil
+		}
+	}
+
+	// a bare weekday is the latest one up to today, and "last monday" or
+	// "senin lalu" the one before today
+	if word(0) == "last" {
+		if weekday, ok := weekdays[word(1)]; ok {
+			return previousWeekday(today, weekday, true), 2, nil
+		}
+	}
+	if weekday, ok := weekdays[strings.ReplaceAll(word(0), "'", "")]; ok {
+		if word(1) == "lalu" {
+			return previousWeekday(today, weekday, true), 2, nil
+		}
+		return previousWeekday(today, weekday, false), 1, nil
+	}
+
+	if date, err := time.ParseInLocation(time.DateOnly, word(0), today.Location()); err == nil {
+		return date, 1, nil
+	}
+	if match := dayMonthPattern.FindStringSubmatch(word(0)); match != nil {
+		date, err := dayMonth(match, today)
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		return date, 1, nil
+	}
+
+	return time.Time{}, 0, nil
+}
+
+// dayMonth builds the date of a D/M or D/M/YYYY match. Without a year it is
+// the latest such date up to today.
+func dayMonth(match []string, today time.Time) (time.Time, error) {
+	day, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	year := today.Year()
+	if match[3] != "" {
+		year, _ = strconv.Atoi(match[3])
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	// time.Date normalizes 31/2 into March, which is not what was meant
+	if date.Day() != day || int(date.Month()) != month {
+		return time.Time{}, fmt.Errorf("%w %q", ErrInvalidDate, match[0])
+	}
+
+	if match[3] == "" && date.After(today) {
+		date = date.AddDate(-1, 0, 0)
+	}
+	return date, nil
+}
+
+func previousWeekday(today time.Time, weekday time.Weekday, strict bool) time.Time {
+	days := (int(today.Weekday()) - int(weekday) + 7) % 7
+	if days == 0 && strict {
+		days = 7
+	}
+	return today.AddDate(0, 0, -days)
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
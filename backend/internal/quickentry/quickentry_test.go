@@ -0,0 +1,145 @@
+package quickentry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type QuickEntryTestSuite struct {
+	suite.Suite
+	// a Wednesday
+	today time.Time
+}
+
+func (suite *QuickEntryTestSuite) SetupTest() {
+	suite.today = time.Date(2024, time.March, 13, 18, 30, 0, 0, time.UTC)
+}
+
+func (suite *QuickEntryTestSuite) date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *QuickEntryTestSuite) TestParse() {
+	entry, err := Parse("kopi 35k kemarin #jajan @gopay", suite.today)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), &Entry{
+		Amount:      35000,
+		Date:        suite.date(2024, time.March, 12),
+		Description: "kopi",
+		Category:    "jajan",
+		Account:     "gopay",
+	}, entry)
+
+	entry, err = Parse("+15jt Gaji Maret", suite.today)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(15000000), entry.Amount)
+	assert.True(suite.T(), entry.Income)
+	assert.Equal(suite.T(), "Gaji Maret", entry.Description)
+	assert.Equal(suite.T(), suite.date(2024, time.March, 13), entry.Date)
+	assert.Empty(suite.T(), entry.Category)
+	assert.Empty(suite.T(), entry.Account)
+}
+
+func (suite *QuickEntryTestSuite) TestParse_Amounts() {
+	for line, amount := range map[string]int64{
+		"makan 25rb":         25000,
+		"makan 25 ribu":      25000,
+		"sewa 1,5 jt":        1500000,
+		"makan 25RIBU":       25000,
+		"sewa 1,5jt":         1500000,
+		"sewa 2.25juta":      2250000,
+		"parkir 2.5k":        2500,
+		"belanja Rp58.900":   58900,
+		"belanja rp 58.900":  58900,
+		"belanja 1.250.000":  1250000,
+		"kopi 2 gelas 35000": 35000,
+		"2 kopi 35k":         35000,
+		"tol 7000":           7000,
+		"mobil 3000jt":       3000000000,
+	} {
+		entry, err := Parse(line, suite.today)
+		if assert.NoError(suite.T(), err, line) {
+			assert.Equal(suite.T(), amount, entry.Amount, line)
+		}
+	}
+
+	entry, err := Parse("kopi 2 gelas 35k", suite.today)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "kopi 2 gelas", entry.Description)
+
+	entry, err = Parse("belanja Rp 58.900 indomaret", suite.today)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "belanja indomaret", entry.Description)
+
+	entry, err = Parse("makan 25 ribu", suite.today)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "makan", entry.Description)
+
+	for _, line := range []string{"kopi", "kopi 12.5", "kopi 1.2345k", "kopi 0k", "kopi 99999999999999999jt", ""} {
+		_, err := Parse(line, suite.today)
+		assert.ErrorIs(suite.T(), err, ErrNoAmount, line)
+	}
+}
+
+func (suite *QuickEntryTestSuite) TestParse_Dates() {
+	for line, date := range map[string]time.Time{
+		"kopi 35k":                   suite.date(2024, time.March, 13),
+		"kopi 35k today":             suite.date(2024, time.March, 13),
+		"kopi 35k hari ini":          suite.date(2024, time.March, 13),
+		"kopi 35k yesterday":         suite.date(2024, time.March, 12),
+		"kopi 35k kmrn":              suite.date(2024, time.March, 12),
+		"kopi 35k kemarin lusa":      suite.date(2024, time.March, 11),
+		"kopi 35k besok":             suite.date(2024, time.March, 14),
+		"kopi 35k lusa":              suite.date(2024, time.March, 15),
+		"kopi 35k 3 days ago":        suite.date(2024, time.March, 10),
+		"kopi 35k 3 hari lalu":       suite.date(2024, time.March, 10),
+		"kopi 35k 10 hari yang lalu": suite.date(2024, time.March, 3),
+		"kopi 35k last week":         suite.date(2024, time.March, 6),
+		"kopi 35k minggu lalu":       suite.date(2024, time.March, 6),
+		"kopi 35k monday":            suite.date(2024, time.March, 11),
+		"kopi 35k rabu":              suite.date(2024, time.March, 13),
+		"kopi 35k last wednesday":    suite.date(2024, time.March, 6),
+		"kopi 35k rabu lalu":         suite.date(2024, time.March, 6),
+		"kopi 35k jum'at":            suite.date(2024, time.March, 8),
+		"kopi 35k 2024-02-29":        suite.date(2024, time.February, 29),
+		"kopi 35k 5/3":               suite.date(2024, time.March, 5),
+		"kopi 35k 31/12":             suite.date(2023, time.December, 31),
+		"kopi 35k 1/2/2023":          suite.date(2023, time.February, 1),
+		"Kemarin, kopi 35k":          suite.date(2024, time.March, 12),
+	} {
+		entry, err := Parse(line, suite.today)
+		if assert.NoError(suite.T(), err, line) {
+			assert.Equal(suite.T(), date, entry.Date, line)
+			assert.Equal(suite.T(), "kopi", entry.Description, line)
+		}
+	}
+
+	// only the first date counts
+	entry, err := Parse("kado ulang tahun besok 150k kemarin", suite.today)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.date(2024, time.March, 14), entry.Date)
+	assert.Equal(suite.T(), "kado ulang tahun kemarin", entry.Description)
+
+	_, err = Parse("kopi 35k 31/2", suite.today)
+	assert.ErrorIs(suite.T(), err, ErrInvalidDate)
+}
+
+func (suite *QuickEntryTestSuite) TestParse_Names() {
+	entry, err := Parse("#makan-siang @bca nasi padang 30k", suite.today)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "makan-siang", entry.Category)
+	assert.Equal(suite.T(), "bca", entry.Account)
+	assert.Equal(suite.T(), "nasi padang", entry.Description)
+
+	_, err = Parse("kopi 35k #jajan #kopi", suite.today)
+	assert.Error(suite.T(), err)
+	_, err = Parse("kopi 35k @bca @gopay", suite.today)
+	assert.Error(suite.T(), err)
+}
+
+func TestQuickEntryTestSuite(t *testing.T) {
+	suite.Run(t, new(QuickEntryTestSuite))
+}
@@ -0,0 +1,307 @@
+// Package recurring expands recurring transaction schedules and materializes
+// their due occurrences.
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxIterations bounds schedule expansion so a rule that never produces an
+// occurrence (e.g. FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31 starting in
+// February) cannot loop forever.
+const maxIterations = 100_000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// Rule is the supported subset of an RFC 5545 RRULE: FREQ, INTERVAL, BYDAY
+// (weekly rules), BYMONTHDAY (monthly rules, negative values count from the
+// end of the month), COUNT and UNTIL.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      time.Time
+}
+
+// Parse reads a rule such as "FREQ=MONTHLY;BYMONTHDAY=25". A leading
+// "RRULE:" is accepted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		name = strings.ToUpper(name)
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				return nil, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRule, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL must be a positive number", ErrInvalidRule)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(value), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("%w: unsupported BYDAY %q", ErrInvalidRule, day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("%w: BYMONTHDAY must be between 1 and 31 or -31 and -1", ErrInvalidRule)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRule)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: UNTIL %q", ErrInvalidRule, value)
+			}
+			rule.Until = until
+		default:
+			return nil, fmt.Errorf("%w: unsupported part %s", ErrInvalidRule, name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return nil, fmt.Errorf("%w: BYDAY is only supported with FREQ=WEEKLY", ErrInvalidRule)
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return nil, fmt.Errorf("%w: BYMONTHDAY is only supported with FREQ=MONTHLY", ErrInvalidRule)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// a date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("unrecognized date")
+}
+
+// Schedule anchors a rule at its first occurrence. Occurrences keep the time
+// of day and location of Start; End, when set, is an inclusive upper bound.
+type Schedule struct {
+	Rule  *Rule
+	Start time.Time
+	End   *time.Time
+}
+
+// Next returns the first occurrence strictly after t.
+func (s Schedule) Next(t time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	s.each(func(occurrence time.Time) bool {
+		if occurrence.After(t) {
+			next, found = occurrence, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Between returns the occurrences in [from, through].
+func (s Schedule) Between(from, through time.Time) []time.Time {
+	var occurrences []time.Time
+	s.each(func(occurrence time.Time) bool {
+		if occurrence.After(through) {
+			return false
+		}
+		if !occurrence.Before(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+		return true
+	})
+	return occurrences
+}
+
+// Take returns up to n occurrences at or after from.
+func (s Schedule) Take(from time.Time, n int) []time.Time {
+	var occurrences []time.Time
+	if n <= 0 {
+		return occurrences
+	}
+	s.each(func(occurrence time.Time) bool {
+		if !occurrence.Before(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+		return len(occurrences) < n
+	})
+	return occurrences
+}
+
+// each calls fn with every occurrence in order until fn returns false or the
+// schedule ends.
+func (s Schedule) each(fn func(time.Time) bool) {
+	count := 0
+	for period := 0; period < maxIterations; period++ {
+		candidates := s.period(period)
+		for _, occurrence := range candidates {
+			if occurrence.Before(s.Start) {
+				continue
+			}
+			if !s.Rule.Until.IsZero() && occurrence.After(s.Rule.Until) {
+				return
+			}
+			if s.End != nil && occurrence.After(*s.End) {
+				return
+			}
+			if !fn(occurrence) {
+				return
+			}
+			count++
+			if s.Rule.Count > 0 && count >= s.Rule.Count {
+				return
+			}
+		}
+	}
+}
+
+// period returns the sorted candidate occurrences of the n-th period after
+// Start, some of which may precede Start in the first period.
+func (s Schedule) period(n int) []time.Time {
+	start := s.Start
+	step := n * s.Rule.Interval
+	hour, minute, sec := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, sec, 0, start.Location())
+	}
+
+	switch s.Rule.Freq {
+	case Daily:
+		return []time.Time{start.AddDate(0, 0, step)}
+
+	case Weekly:
+		days := s.Rule.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		// weeks start on Monday
+		offset := (int(start.Weekday()) + 6) % 7
+		weekStart := start.AddDate(0, 0, step*7-offset)
+		var candidates []time.Time
+		for _, day := range days {
+			candidates = append(candidates, weekStart.AddDate(0, 0, (int(day)+6)%7))
+		}
+		sortTimes(candidates)
+		return dedupe(candidates)
+
+	case Monthly:
+		first := at(start.Year(), start.Month()+time.Month(step), 1)
+		year, month := first.Year(), first.Month()
+		last := daysIn(year, month)
+		days := s.Rule.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		var candidates []time.Time
+		for _, day := range days {
+			if day < 0 {
+				day = last + day + 1
+			}
+			// months without the day are skipped, as in RFC 5545
+			if day < 1 || day > last {
+				continue
+			}
+			candidates = append(candidates, at(year, month, day))
+		}
+		sortTimes(candidates)
+		return dedupe(candidates)
+
+	case Yearly:
+		year := start.Year() + step
+		if start.Day() > daysIn(year, start.Month()) {
+			return nil
+		}
+		return []time.Time{at(year, start.Month(), start.Day())}
+	}
+
+	return nil
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+}
+
+func dedupe(times []time.Time) []time.Time {
+	out := times[:0]
+	for i, t := range times {
+		if i == 0 || !t.Equal(times[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RuleTestSuite struct {
+	suite.Suite
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func mustSchedule(t *testing.T, rule string, start time.Time) Schedule {
+	parsed, err := Parse(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Schedule{Rule: parsed, Start: start}
+}
+
+func (suite *RuleTestSuite) TestParse() {
+	rule, err := Parse("RRULE:FREQ=weekly;INTERVAL=2;BYDAY=MO,FR;COUNT=10")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), Weekly, rule.Freq)
+	assert.Equal(suite.T(), 2, rule.Interval)
+	assert.Equal(suite.T(), []time.Weekday{time.Monday, time.Friday}, rule.ByDay)
+	assert.Equal(suite.T(), 10, rule.Count)
+
+	rule, err = Parse("FREQ=MONTHLY;UNTIL=20241231")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, rule.Interval)
+	assert.Equal(suite.T(), time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC), rule.Until)
+}
+
+func (suite *RuleTestSuite) TestParse_Invalid() {
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;COUNT=2;UNTIL=20240101",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ",
+	} {
+		_, err := Parse(rule)
+		assert.ErrorIs(suite.T(), err, ErrInvalidRule, rule)
+	}
+}
+
+func (suite *RuleTestSuite) TestMonthlyRent() {
+	schedule := mustSchedule(suite.T(), "FREQ=MONTHLY;BYMONTHDAY=1", date(2024, 1, 15))
+
+	occurrences := schedule.Take(schedule.Start, 3)
+	assert.Equal(suite.T(), []time.Time{date(2024, 2, 1), date(2024, 3, 1), date(2024, 4, 1)}, occurrences)
+}
+
+func (suite *RuleTestSuite) TestMonthlyLastDay() {
+	schedule := mustSchedule(suite.T(), "FREQ=MONTHLY;BYMONTHDAY=-1", date(2024, 1, 1))
+
+	occurrences := schedule.Take(schedule.Start, 3)
+	assert.Equal(suite.T(), []time.Time{date(2024, 1, 31), date(2024, 2, 29), date(2024, 3, 31)}, occurrences)
+}
+
+func (suite *RuleTestSuite) TestMonthlySkipsShortMonths() {
+	schedule := mustSchedule(suite.T(), "FREQ=MONTHLY", date(2024, 1, 31))
+
+	occurrences := schedule.Take(schedule.Start, 3)
+	assert.Equal(suite.T(), []time.Time{date(2024, 1, 31), date(2024, 3, 31), date(2024, 5, 31)}, occurrences)
+}
+
+func (suite *RuleTestSuite) TestWeeklyByDay() {
+	// 2024-01-03 is a Wednesday
+	schedule := mustSchedule(suite.T(), "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", date(2024, 1, 3))
+
+	occurrences := schedule.Take(schedule.Start, 4)
+	assert.Equal(suite.T(), []time.Time{date(2024, 1, 5), date(2024, 1, 15), date(2024, 1, 19), date(2024, 1, 29)}, occurrences)
+}
+
+func (suite *RuleTestSuite) TestYearlyLeapDay() {
+	schedule := mustSchedule(suite.T(), "FREQ=YEARLY", date(2024, 2, 29))
+
+	occurrences := schedule.Take(schedule.Start, 2)
+	assert.Equal(suite.T(), []time.Time{date(2024, 2, 29), date(2028, 2, 29)}, occurrences)
+}
+
+func (suite *RuleTestSuite) TestCountAndEnd() {
+	schedule := mustSchedule(suite.T(), "FREQ=DAILY;COUNT=3", date(2024, 1, 1))
+	assert.Len(suite.T(), schedule.Take(schedule.Start, 10), 3)
+
+	// COUNT includes occurrences before the window
+	assert.Equal(suite.T(), []time.Time{date(2024, 1, 3)}, schedule.Take(date(2024, 1, 2).Add(time.Hour), 10))
+
+	end := date(2024, 1, 2)
+	schedule = mustSchedule(suite.T(), "FREQ=DAILY", date(2024, 1, 1))
+	schedule.End = &end
+	assert.Equal(suite.T(), []time.Time{date(2024, 1, 1), date(2024, 1, 2)}, schedule.Take(schedule.Start, 10))
+
+	_, ok := schedule.Next(end)
+	assert.False(suite.T(), ok)
+}
+
+func (suite *RuleTestSuite) TestNextAndBetween() {
+	schedule := mustSchedule(suite.T(), "FREQ=MONTHLY;BYMONTHDAY=25", date(2024, 1, 1))
+
+	next, ok := schedule.Next(date(2024, 1, 25))
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), date(2024, 2, 25), next)
+
+	between := schedule.Between(date(2024, 1, 25), date(2024, 3, 25))
+	assert.Equal(suite.T(), []time.Time{date(2024, 1, 25), date(2024, 2, 25), date(2024, 3, 25)}, between)
+}
+
+func (suite *RuleTestSuite) TestNeverMatching() {
+	schedule := mustSchedule(suite.T(), "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31", date(2024, 2, 1))
+
+	_, ok := schedule.Next(schedule.Start)
+	assert.False(suite.T(), ok)
+}
+
+func TestRuleTestSuite(t *testing.T) {
+	suite.Run(t, new(RuleTestSuite))
+}
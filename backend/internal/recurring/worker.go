@@ -0,0 +1,124 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// ScheduleFor builds the schedule of a stored rule.
+func ScheduleFor(rule *store.RecurringRule) (Schedule, error) {
+	parsed, err := Parse(rule.RRule)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	return Schedule{Rule: parsed, Start: rule.StartDate, End: rule.EndDate}, nil
+}
+
+// FirstRun returns the first occurrence of the rule at or after from, or nil
+// when the schedule has ended.
+func FirstRun(rule *store.RecurringRule, from time.Time) (*time.Time, error) {
+	schedule, err := ScheduleFor(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := schedule.Take(from, 1)
+	if len(occurrences) == 0 {
+		return nil, nil
+	}
+
+	return &occurrences[0], nil
+}
+
+// Worker periodically turns due occurrences of recurring rules into
+// transactions.
+type Worker struct {
+	store    store.Storage
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewWorker(storage store.Storage, interval time.Duration) *Worker {
+	return &Worker{
+		store:    storage,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Run materializes due occurrences right away and then on every interval
+// until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.RunOnce(ctx); err != nil {
+			log.Printf("recurring: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce materializes every occurrence up to now. A failing rule is logged
+// and retried on the next run without holding back the others.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	now := w.now()
+
+	rules, err := w.store.RecurringRules.Due(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		if err := w.materialize(ctx, &rules[i], now); err != nil {
+			log.Printf("recurring: rule %d: %s", rules[i].ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) materialize(ctx context.Context, rule *store.RecurringRule, now time.Time) error {
+	schedule, err := ScheduleFor(rule)
+	if err != nil {
+		return err
+	}
+
+	for _, occurrence := range schedule.Between(*rule.NextRun, now) {
+		transaction := &store.Transaction{
+			AccountID:       rule.AccountID,
+			Amount:          rule.Amount,
+			Description:     rule.Description,
+			Kind:            rule.Kind,
+			Date:            occurrence.Format(time.RFC3339),
+			RecurringRuleID: sql.NullInt64{Int64: rule.ID, Valid: true},
+		}
+		if rule.CategoryID != nil {
+			transaction.CategoryID = sql.NullInt64{Int64: *rule.CategoryID, Valid: true}
+		}
+
+		// a conflict means an earlier run already booked this occurrence
+		if err := w.store.Transactions.Create(ctx, transaction); err != nil && !errors.Is(err, store.ErrConflict) {
+			return err
+		}
+	}
+
+	var nextRun *time.Time
+	if next, ok := schedule.Next(now); ok {
+		nextRun = &next
+	}
+
+	return w.store.RecurringRules.SetNextRun(ctx, rule.ID, nextRun)
+}
@@ -0,0 +1,143 @@
+package recurring
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// mockTransactions records created transactions and rejects an occurrence
+// that was already booked, like the unique index does.
+type mockTransactions struct {
+	*store.TransactionStore
+	created []store.Transaction
+	booked  map[string]bool
+}
+
+func (m *mockTransactions) Create(ctx context.Context, transaction *store.Transaction) error {
+	if m.booked[transaction.Date] {
+		return store.ErrConflict
+	}
+	m.booked[transaction.Date] = true
+	m.created = append(m.created, *transaction)
+	return nil
+}
+
+type mockRecurringRules struct {
+	*store.RecurringRuleStore
+	rules   []store.RecurringRule
+	nextRun map[int64]*time.Time
+}
+
+func (m *mockRecurringRules) Due(ctx context.Context, now time.Time) ([]store.RecurringRule, error) {
+	var due []store.RecurringRule
+	for _, rule := range m.rules {
+		if rule.NextRun != nil && !rule.NextRun.After(now) {
+			due = append(due, rule)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockRecurringRules) SetNextRun(ctx context.Context, id int64, nextRun *time.Time) error {
+	m.nextRun[id] = nextRun
+	for i := range m.rules {
+		if m.rules[i].ID == id {
+			m.rules[i].NextRun = nextRun
+		}
+	}
+	return nil
+}
+
+type WorkerTestSuite struct {
+	suite.Suite
+	transactions *mockTransactions
+	rules        *mockRecurringRules
+	worker       *Worker
+}
+
+func (suite *WorkerTestSuite) SetupTest() {
+	suite.transactions = &mockTransactions{booked: map[string]bool{}}
+	suite.rules = &mockRecurringRules{nextRun: map[int64]*time.Time{}}
+	suite.worker = NewWorker(store.Storage{
+		Transactions:   suite.transactions,
+		RecurringRules: suite.rules,
+	}, time.Hour)
+}
+
+func (suite *WorkerTestSuite) addRule(rule store.RecurringRule) {
+	first, err := FirstRun(&rule, rule.StartDate)
+	assert.NoError(suite.T(), err)
+	rule.NextRun = first
+	suite.rules.rules = append(suite.rules.rules, rule)
+}
+
+func (suite *WorkerTestSuite) TestRunOnce_CatchesUpAndAdvances() {
+	categoryID := int64(4)
+	suite.addRule(store.RecurringRule{
+		ID:          1,
+		AccountID:   2,
+		CategoryID:  &categoryID,
+		Amount:      3500000,
+		Description: "Rent",
+		RRule:       "FREQ=MONTHLY;BYMONTHDAY=1",
+		StartDate:   date(2024, 1, 1),
+	})
+	suite.worker.now = func() time.Time { return date(2024, 3, 10) }
+
+	err := suite.worker.RunOnce(context.Background())
+	assert.NoError(suite.T(), err)
+
+	assert.Len(suite.T(), suite.transactions.created, 3)
+	created := suite.transactions.created[2]
+	assert.Equal(suite.T(), "2024-03-01T09:00:00Z", created.Date)
+	assert.Equal(suite.T(), int64(2), created.AccountID)
+	assert.Equal(suite.T(), int64(4), created.CategoryID.Int64)
+	assert.Equal(suite.T(), int64(1), created.RecurringRuleID.Int64)
+	assert.Equal(suite.T(), date(2024, 4, 1), *suite.rules.nextRun[1])
+}
+
+func (suite *WorkerTestSuite) TestRunOnce_Idempotent() {
+	suite.addRule(store.RecurringRule{
+		ID:          1,
+		Amount:      55000,
+		Description: "Spotify",
+		RRule:       "FREQ=MONTHLY",
+		StartDate:   date(2024, 1, 20),
+	})
+	suite.worker.now = func() time.Time { return date(2024, 2, 21) }
+
+	// a crash after booking but before advancing next_run repeats the run
+	assert.NoError(suite.T(), suite.worker.RunOnce(context.Background()))
+	suite.rules.rules[0].NextRun = &suite.rules.rules[0].StartDate
+	assert.NoError(suite.T(), suite.worker.RunOnce(context.Background()))
+
+	assert.Len(suite.T(), suite.transactions.created, 2)
+	assert.Equal(suite.T(), date(2024, 3, 20), *suite.rules.nextRun[1])
+}
+
+func (suite *WorkerTestSuite) TestRunOnce_EndsSchedule() {
+	end := date(2024, 1, 3)
+	suite.addRule(store.RecurringRule{
+		ID:          1,
+		Amount:      20000,
+		Description: "Parking",
+		RRule:       "FREQ=DAILY",
+		StartDate:   date(2024, 1, 1),
+		EndDate:     &end,
+	})
+	suite.worker.now = func() time.Time { return date(2024, 1, 10) }
+
+	assert.NoError(suite.T(), suite.worker.RunOnce(context.Background()))
+
+	assert.Len(suite.T(), suite.transactions.created, 3)
+	assert.Nil(suite.T(), suite.rules.nextRun[1])
+}
+
+func TestWorkerTestSuite(t *testing.T) {
+	suite.Run(t, new(WorkerTestSuite))
+}
@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type Account struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	Currency       string `json:"currency"`
+	OpeningBalance int64  `json:"opening_balance"`
+	Archived       bool   `json:"archived"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+// AccountBalance is the current balance of an account. ConvertedBalance is
+// the balance in BaseCurrency at the latest known rate, or nil when no rate
+// is known for the account's currency.
+type AccountBalance struct {
+	ID               int64  `json:"id"`
+	Name             string `json:"name"`
+	Currency         string `json:"currency"`
+	Archived         bool   `json:"archived"`
+	Balance          int64  `json:"balance"`
+	ConvertedBalance *int64 `json:"converted_balance"`
+}
+
+type AccountStore struct {
+	db *sql.DB
+}
+
+func (s *AccountStore) Create(ctx context.Context, account *Account) error {
+	query := `
+		INSERT INTO accounts (name, currency, opening_balance, archived)
+		VALUES ($1::text, $2::text, $3::bigint, $4::boolean) RETURNING id, created_at, updated_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			account.Name,
+			account.Currency,
+			account.OpeningBalance,
+			account.Archived,
+		).Scan(
+			&account.ID,
+			&account.CreatedAt,
+			&account.UpdatedAt,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditAccount, AuditCreate, nil, account.ID)
+	})
+}
+
+func (s *AccountStore) Index(ctx context.Context) ([]Account, error) {
+	query := `
+		SELECT id, name, currency, opening_balance, archived, created_at, updated_at
+		FROM accounts
+		ORDER BY id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []Account
+
+	for rows.Next() {
+		var account Account
+		if err := rows.Scan(
+			&account.ID,
+			&account.Name,
+			&account.Currency,
+			&account.OpeningBalance,
+			&account.Archived,
+			&account.CreatedAt,
+			&account.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func (s *AccountStore) GetByID(ctx context.Context, id int64) (*Account, error) {
+	query := `
+		SELECT id, name, currency, opening_balance, archived, created_at, updated_at
+		FROM accounts
+		WHERE id = $1
+	`
+
+	return s.getOne(ctx, query, id)
+}
+
+// GetDefault returns the oldest active account, used when a transaction
+// does not name one.
+func (s *AccountStore) GetDefault(ctx context.Context) (*Account, error) {
+	query := `
+		SELECT id, name, currency, opening_balance, archived, created_at, updated_at
+		FROM accounts
+		WHERE archived = false
+		ORDER BY id ASC
+		LIMIT 1
+	`
+
+	return s.getOne(ctx, query)
+}
+
+func (s *AccountStore) getOne(ctx context.Context, query string, args ...any) (*Account, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var account Account
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		args...,
+	).Scan(
+		&account.ID,
+		&account.Name,
+		&account.Currency,
+		&account.OpeningBalance,
+		&account.Archived,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &account, nil
+}
+
+// Update saves the account. The currency is fixed once the account has
+// transactions and returns ErrCurrencyInUse, and the opening balance is fixed
+// once a reconciliation of the account was completed and returns ErrLocked.
+func (s *AccountStore) Update(ctx context.Context, account *Account) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditAccount, account.ID)
+		if err != nil {
+			return err
+		}
+
+		// the amounts of the transactions are in the account's currency, so
+		// it is fixed once there are any. FOR UPDATE makes new transactions
+		// wait until the change is committed.
+		var currency string
+		var openingBalance int64
+		var used bool
+		err = tx.QueryRowContext(ctx,
+			`SELECT a.currency, a.opening_balance, EXISTS (SELECT 1 FROM transactions t WHERE t.account_id = a.id) FROM accounts a WHERE a.id = $1 FOR UPDATE`,
+			account.ID,
+		).Scan(&currency, &openingBalance, &used)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if used && currency != account.Currency {
+			return ErrCurrencyInUse
+		}
+
+		// every reconciled balance starts from the opening balance
+		locks := accountLocks{}
+		if err := locks.lock(ctx, tx, account.ID); err != nil {
+			return err
+		}
+		if openingBalance != account.OpeningBalance && locks.reconciled(account.ID) {
+			return ErrLocked
+		}
+
+		updateQuery := `
+			UPDATE accounts
+			SET name = $1::text, currency = $2::text, opening_balance = $3::bigint, archived = $4::boolean, updated_at = NOW()
+			WHERE id = $5::bigint
+			RETURNING updated_at
+		`
+		err = tx.QueryRowContext(ctx, updateQuery,
+			account.Name,
+			account.Currency,
+			account.OpeningBalance,
+			account.Archived,
+			account.ID,
+		).Scan(&account.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			case isUniqueViolation(err):
+				return ErrConflict
+			default:
+				return err
+			}
+		}
+
+		// every balance in the account depends on its starting point
+		if openingBalance != account.OpeningBalance {
+			if err := recalculateBalances(ctx, tx, account.ID, ledgerPoint{}); err != nil {
+				return err
+			}
+		}
+
+		return recordChanges(ctx, tx, AuditAccount, AuditUpdate, before, account.ID)
+	})
+}
+
+func (s *AccountStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM accounts WHERE id = $1`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditAccount, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			// accounts with transactions can only be archived
+			if isForeignKeyViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditAccount, AuditDelete, before, id)
+	})
+}
+
+// GetBalances returns the current balance of every account, falling back to
+// the opening balance for accounts without transactions.
+func (s *AccountStore) GetBalances(ctx context.Context) ([]AccountBalance, error) {
+	query := `
+		SELECT a.id, a.name, a.currency, a.archived, b.balance, convert_amount(b.balance, a.currency, $1, CURRENT_DATE)
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT running_balance
+			FROM transactions
+			WHERE account_id = a.id AND deleted_at IS NULL
+			ORDER BY date DESC, id DESC
+			LIMIT 1
+		) t ON true
+		CROSS JOIN LATERAL (SELECT COALESCE(t.running_balance, a.opening_balance) AS balance) b
+		ORDER BY a.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []AccountBalance
+
+	for rows.Next() {
+		var balance AccountBalance
+		if err := rows.Scan(
+			&balance.ID,
+			&balance.Name,
+			&balance.Currency,
+			&balance.Archived,
+			&balance.Balance,
+			&balance.ConvertedBalance,
+		); err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AccountStoreTestSuite struct {
+	suite.Suite
+}
+
+func (suite *AccountStoreTestSuite) TestAccountStructure() {
+	account := Account{
+		ID:             1,
+		Name:           "Cash",
+		Currency:       "IDR",
+		OpeningBalance: 250000,
+		Archived:       false,
+		CreatedAt:      "2023-01-01T10:00:00Z",
+		UpdatedAt:      "2023-01-01T10:00:00Z",
+	}
+
+	assert.Equal(suite.T(), int64(1), account.ID)
+	assert.Equal(suite.T(), "Cash", account.Name)
+	assert.Equal(suite.T(), "IDR", account.Currency)
+	assert.Equal(suite.T(), int64(250000), account.OpeningBalance)
+	assert.False(suite.T(), account.Archived)
+}
+
+func (suite *AccountStoreTestSuite) TestAccountStoreCreation() {
+	var db *sql.DB
+	store := &AccountStore{db: db}
+
+	assert.NotNil(suite.T(), store)
+	assert.Equal(suite.T(), db, store.db)
+}
+
+func (suite *AccountStoreTestSuite) TestConstraintViolationHelpers() {
+	unique := fmt.Errorf("insert: %w", &pq.Error{Code: "23505"})
+	foreignKey := &pq.Error{Code: "23503"}
+
+	assert.True(suite.T(), isUniqueViolation(unique))
+	assert.False(suite.T(), isUniqueViolation(foreignKey))
+	assert.True(suite.T(), isForeignKeyViolation(foreignKey))
+	assert.False(suite.T(), isForeignKeyViolation(errors.New("boom")))
+}
+
+func TestAccountStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(AccountStoreTestSuite))
+}
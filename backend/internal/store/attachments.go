@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Attachment describes an uploaded receipt or document. The content itself
+// lives in the blob store under StorageKey.
+type Attachment struct {
+	ID             int64          `json:"id"`
+	TransactionID  *int64         `json:"transaction_id,omitempty"`
+	EventExpenseID *int64         `json:"event_expense_id,omitempty"`
+	FileName       string         `json:"file_name"`
+	ContentType    string         `json:"content_type"`
+	Size           int64          `json:"size"`
+	StorageKey     string         `json:"-"`
+	ThumbnailKey   sql.NullString `json:"-"`
+	HasThumbnail   bool           `json:"has_thumbnail"`
+	CreatedAt      string         `json:"created_at"`
+}
+
+type AttachmentStore struct {
+	db *sql.DB
+}
+
+const attachmentColumns = `id, transaction_id, event_expense_id, file_name, content_type, size, storage_key, thumbnail_key, created_at`
+
+func (s *AttachmentStore) Create(ctx context.Context, attachment *Attachment) error {
+	query := `
+		INSERT INTO attachments (transaction_id, event_expense_id, file_name, content_type, size, storage_key, thumbnail_key)
+		VALUES ($1, $2, $3::text, $4::text, $5::bigint, $6::text, $7) RETURNING id, created_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			attachment.TransactionID,
+			attachment.EventExpenseID,
+			attachment.FileName,
+			attachment.ContentType,
+			attachment.Size,
+			attachment.StorageKey,
+			attachment.ThumbnailKey,
+		).Scan(
+			&attachment.ID,
+			&attachment.CreatedAt,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidReference
+			}
+			return err
+		}
+
+		attachment.HasThumbnail = attachment.ThumbnailKey.Valid
+
+		return recordChanges(ctx, tx, AuditAttachment, AuditCreate, nil, attachment.ID)
+	})
+}
+
+func (s *AttachmentStore) GetByID(ctx context.Context, id int64) (*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var attachment Attachment
+	err := s.db.QueryRowContext(ctx, query, id).Scan(attachmentFields(&attachment)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+	attachment.HasThumbnail = attachment.ThumbnailKey.Valid
+
+	return &attachment, nil
+}
+
+func (s *AttachmentStore) IndexByTransaction(ctx context.Context, transactionID int64) ([]Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE transaction_id = $1 ORDER BY id ASC`
+
+	return s.index(ctx, query, transactionID)
+}
+
+func (s *AttachmentStore) IndexByEventExpense(ctx context.Context, eventExpenseID int64) ([]Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE event_expense_id = $1 ORDER BY id ASC`
+
+	return s.index(ctx, query, eventExpenseID)
+}
+
+func (s *AttachmentStore) index(ctx context.Context, query string, args ...any) ([]Attachment, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var attachment Attachment
+		if err := rows.Scan(attachmentFields(&attachment)...); err != nil {
+			return nil, err
+		}
+		attachment.HasThumbnail = attachment.ThumbnailKey.Valid
+		attachments = append(attachments, attachment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (s *AttachmentStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM attachments WHERE id = $1`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditAttachment, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditAttachment, AuditDelete, before, id)
+	})
+}
+
+func attachmentFields(attachment *Attachment) []any {
+	return []any{
+		&attachment.ID,
+		&attachment.TransactionID,
+		&attachment.EventExpenseID,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.StorageKey,
+		&attachment.ThumbnailKey,
+		&attachment.CreatedAt,
+	}
+}
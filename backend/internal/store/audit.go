@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// The audit log is an append-only trail of the changes made through the
+// store. Logins and the scheduler moving the next run of a recurring rule are
+// bookkeeping and are not recorded, nor are rows that only change as a side
+// effect of another change, such as the currency of transactions following
+// their account or the attachments purged together with a transaction.
+const (
+	AuditTransaction        = "transaction"
+	AuditAccount            = "account"
+	AuditCategory           = "category"
+	AuditTag                = "tag"
+	AuditEvent              = "event"
+	AuditEventExpense       = "event_expense"
+	AuditAttachment         = "attachment"
+	AuditRecurringRule      = "recurring_rule"
+	AuditImportProfile      = "import_profile"
+	AuditCategorizationRule = "categorization_rule"
+	AuditExchangeRate       = "exchange_rate"
+	AuditReconciliation     = "reconciliation"
+	AuditPayee              = "payee"
+)
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 500
+)
+
+// auditEntity is where the rows of an audited entity live and how a row,
+// aliased "r", is turned into the JSON kept in the log.
+type auditEntity struct {
+	table    string
+	snapshot string
+}
+
+var auditEntities = map[string]auditEntity{
+	AuditTransaction: {"transactions", `to_jsonb(r) || jsonb_build_object(
+		'tag_ids', COALESCE((SELECT jsonb_agg(tt.tag_id ORDER BY tt.tag_id) FROM transaction_tags tt WHERE tt.transaction_id = r.id), '[]'::jsonb),
+		'splits', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object('category_id', s.category_id, 'amount', s.amount, 'note', s.note) ORDER BY s.id)
+			FROM transaction_splits s
+			WHERE s.transaction_id = r.id
+		), '[]'::jsonb)
+	)`},
+	AuditAccount:            {"accounts", "to_jsonb(r)"},
+	AuditCategory:           {"categories", "to_jsonb(r)"},
+	AuditTag:                {"tags", "to_jsonb(r)"},
+	AuditEvent:              {"events", "to_jsonb(r)"},
+	AuditEventExpense:       {"event_expenses", "to_jsonb(r)"},
+	AuditAttachment:         {"attachments", "to_jsonb(r)"},
+	AuditRecurringRule:      {"recurring_rules", "to_jsonb(r)"},
+	AuditImportProfile:      {"import_profiles", "to_jsonb(r)"},
+	AuditCategorizationRule: {"categorization_rules", "to_jsonb(r)"},
+	AuditExchangeRate:       {"exchange_rates", "to_jsonb(r)"},
+	AuditReconciliation:     {"reconciliations", "to_jsonb(r)"},
+	AuditPayee: {"payees", `to_jsonb(r) || jsonb_build_object(
+		'aliases', COALESCE((SELECT jsonb_agg(a.alias ORDER BY a.alias) FROM payee_aliases a WHERE a.payee_id = r.id), '[]'::jsonb)
+	)`},
+}
+
+// IsAuditEntity reports whether changes to entity are recorded.
+func IsAuditEntity(entity string) bool {
+	_, ok := auditEntities[entity]
+	return ok
+}
+
+// Actor is who a change is attributed to in the audit log. Changes made by
+// background jobs have neither a user nor a request.
+type Actor struct {
+	UserID    int64
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor attributes the changes made with ctx to actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// snapshotRows returns the rows of an entity as a JSON object keyed by id, to
+// be handed to recordChanges as their state before a change.
+func snapshotRows(ctx context.Context, q querier, entity string, ids ...int64) ([]byte, error) {
+	e, ok := auditEntities[entity]
+	if !ok {
+		return nil, fmt.Errorf("unknown audit entity %q", entity)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(jsonb_object_agg(r.id, %s), '{}')
+		FROM %s r
+		WHERE r.id = ANY($1::bigint[])
+	`, e.snapshot, e.table)
+
+	var before []byte
+	err := q.QueryRowContext(ctx, query, pq.Array(ids)).Scan(&before)
+	return before, err
+}
+
+// recordChanges appends one audit log entry per id, with the row's state
+// from before (nil for new rows) and as it is now, which is null for rows
+// that no longer exist. It runs on the same querier as the change itself so
+// that the log never disagrees with the data.
+func recordChanges(ctx context.Context, q querier, entity, action string, before []byte, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	e, ok := auditEntities[entity]
+	if !ok {
+		return fmt.Errorf("unknown audit entity %q", entity)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO audit_log (entity, entity_id, action, before, after, user_id, request_id)
+		SELECT $1::text, ids.id, $2::text, $3::jsonb -> ids.id::text,
+			CASE WHEN r.id IS NULL THEN NULL ELSE %s END,
+			$5, $6::text
+		FROM unnest($4::bigint[]) AS ids(id)
+		LEFT JOIN %s r ON r.id = ids.id
+	`, e.snapshot, e.table)
+
+	// lib/pq would send bytes as bytea, which does not cast to jsonb
+	var beforeArg any
+	if before != nil {
+		beforeArg = string(before)
+	}
+
+	actor := actorFrom(ctx)
+	_, err := q.ExecContext(
+		ctx,
+		query,
+		entity,
+		action,
+		beforeArg,
+		pq.Array(ids),
+		sql.NullInt64{Int64: actor.UserID, Valid: actor.UserID != 0},
+		actor.RequestID,
+	)
+	return err
+}
+
+// AuditEntry is one recorded change. Before is null for created rows and
+// After is null for rows that were deleted for good.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityID  int64           `json:"entity_id"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	UserID    *int64          `json:"user_id"`
+	RequestID string          `json:"request_id"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// AuditFilter narrows down the audit log. Zero values mean "no restriction";
+// From and To are inclusive calendar dates (YYYY-MM-DD) and Before is the id
+// of the last entry of the previous page.
+type AuditFilter struct {
+	Entity    string
+	EntityID  int64
+	Action    string
+	UserID    int64
+	RequestID string
+	From      string
+	To        string
+	Limit     int
+	Before    int64
+}
+
+func (f AuditFilter) limit() int {
+	switch {
+	case f.Limit <= 0:
+		return DefaultAuditLimit
+	case f.Limit > MaxAuditLimit:
+		return MaxAuditLimit
+	default:
+		return f.Limit
+	}
+}
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+// Index returns one page of the entries matching the filter, newest first,
+// and the id to pass as Before for the following page, which is nil on the
+// last page.
+func (s *AuditStore) Index(ctx context.Context, filter AuditFilter) ([]AuditEntry, *int64, error) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Entity != "" {
+		add("entity = $%d::text", filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		add("entity_id = $%d::bigint", filter.EntityID)
+	}
+	if filter.Action != "" {
+		add("action = $%d::text", filter.Action)
+	}
+	if filter.UserID != 0 {
+		add("user_id = $%d::bigint", filter.UserID)
+	}
+	if filter.RequestID != "" {
+		add("request_id = $%d::text", filter.RequestID)
+	}
+	if filter.From != "" {
+		add("created_at >= $%d::date", filter.From)
+	}
+	if filter.To != "" {
+		add("created_at < $%d::date + INTERVAL '1 day'", filter.To)
+	}
+	if filter.Before != 0 {
+		add("id < $%d::bigint", filter.Before)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.limit()
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT id, entity, entity_id, action, before, after, user_id, request_id, created_at
+		FROM audit_log
+		%s
+		ORDER BY id DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var before, after []byte
+		var userID sql.NullInt64
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Entity,
+			&entry.EntityID,
+			&entry.Action,
+			&before,
+			&after,
+			&userID,
+			&entry.RequestID,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, nil, err
+		}
+		entry.Before = rawJSON(before)
+		entry.After = rawJSON(after)
+		if userID.Valid {
+			entry.UserID = &userID.Int64
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// the extra row only tells us another page exists
+	if len(entries) <= limit {
+		return entries, nil, nil
+	}
+
+	entries = entries[:limit]
+	next := entries[limit-1].ID
+
+	return entries, &next, nil
+}
+
+// rawJSON keeps a null column as JSON null rather than an empty message,
+// which would not marshal.
+func rawJSON(b []byte) json.RawMessage {
+	if b == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(b)
+}
@@ -3,12 +3,14 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 )
 
 type Category struct {
 	ID        int64  `json:"id"`
 	Name      string `json:"name"`
 	Color     string `json:"color"`
+	Kind      string `json:"kind"`
 	CreatedAt string `json:"created_at"`
 }
 
@@ -18,33 +20,35 @@ type CategoryStore struct {
 
 func (s *CategoryStore) Create(ctx context.Context, category *Category) error {
 	query := `
-		INSERT INTO categories (name, color)
-		VALUES ($1::text, $2::text) RETURNING id, created_at
+		INSERT INTO categories (name, color, kind)
+		VALUES ($1::text, $2::text, COALESCE(NULLIF($3::text, ''), 'expense')) RETURNING id, kind, created_at
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		category.Name,
-		category.Color,
-	).Scan(
-		&category.ID,
-		&category.CreatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			category.Name,
+			category.Color,
+			category.Kind,
+		).Scan(
+			&category.ID,
+			&category.Kind,
+			&category.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return recordChanges(ctx, tx, AuditCategory, AuditCreate, nil, category.ID)
+	})
 }
 
 func (s *CategoryStore) Index(ctx context.Context) ([]Category, error) {
 	query := `
-		SELECT id, name, color, created_at
+		SELECT id, name, color, kind, created_at
 		FROM categories
+		WHERE deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -63,6 +67,7 @@ func (s *CategoryStore) Index(ctx context.Context) ([]Category, error) {
 			&category.ID,
 			&category.Name,
 			&category.Color,
+			&category.Kind,
 			&category.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -75,3 +80,66 @@ func (s *CategoryStore) Index(ctx context.Context) ([]Category, error) {
 
 	return categories, nil
 }
+
+func (s *CategoryStore) GetByID(ctx context.Context, id int64) (*Category, error) {
+	query := `
+		SELECT id, name, color, kind, created_at
+		FROM categories
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var category Category
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&category.ID,
+		&category.Name,
+		&category.Color,
+		&category.Kind,
+		&category.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &category, nil
+}
+
+// Delete moves the category to the trash. Its transactions keep pointing at
+// it but are reported as uncategorized until the category is restored.
+func (s *CategoryStore) Delete(ctx context.Context, id int64) error {
+	query := `UPDATE categories SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditCategory, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditCategory, AuditDelete, before, id)
+	})
+}
@@ -0,0 +1,331 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CategorizationRule fills in transactions that arrive without a category.
+// A rule matches when all of its set conditions hold: the description
+// contains DescriptionContains (case-insensitively) and matches
+// DescriptionPattern, the absolute amount lies within MinAmount and
+// MaxAmount, and the transaction is on AccountID. Its actions set the
+// category, add tags and replace the description with RenameTo. Rules run by
+// descending priority.
+type CategorizationRule struct {
+	ID                  int64   `json:"id"`
+	Name                string  `json:"name"`
+	Priority            int     `json:"priority"`
+	Enabled             bool    `json:"enabled"`
+	DescriptionContains string  `json:"description_contains"`
+	DescriptionPattern  string  `json:"description_pattern"`
+	MinAmount           *int64  `json:"min_amount"`
+	MaxAmount           *int64  `json:"max_amount"`
+	AccountID           *int64  `json:"account_id"`
+	CategoryID          *int64  `json:"category_id"`
+	TagIDs              []int64 `json:"tag_ids"`
+	RenameTo            string  `json:"rename_to"`
+	CreatedAt           string  `json:"created_at"`
+	UpdatedAt           string  `json:"updated_at"`
+}
+
+// RuleChange is the effect of re-running the rules on a booked transaction.
+// Version is the version of the transaction the change was worked out from.
+type RuleChange struct {
+	TransactionID  int64     `json:"transaction_id"`
+	Version        int64     `json:"version"`
+	Date           time.Time `json:"date"`
+	RuleIDs        []int64   `json:"rule_ids"`
+	OldCategoryID  *int64    `json:"old_category_id"`
+	CategoryID     *int64    `json:"category_id"`
+	OldDescription string    `json:"old_description"`
+	Description    string    `json:"description"`
+	AddedTagIDs    []int64   `json:"added_tag_ids"`
+}
+
+type CategorizationRuleStore struct {
+	db *sql.DB
+}
+
+const categorizationRuleColumns = `id, name, priority, enabled, description_contains, description_pattern, min_amount, max_amount,
+	account_id, category_id, tag_ids, rename_to, created_at, updated_at`
+
+func (s *CategorizationRuleStore) Create(ctx context.Context, rule *CategorizationRule) error {
+	query := `
+		INSERT INTO categorization_rules (name, priority, enabled, description_contains, description_pattern, min_amount, max_amount,
+			account_id, category_id, tag_ids, rename_to)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10::bigint[], '{}'), $11) RETURNING id, created_at, updated_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			rule.Name,
+			rule.Priority,
+			rule.Enabled,
+			rule.DescriptionContains,
+			rule.DescriptionPattern,
+			rule.MinAmount,
+			rule.MaxAmount,
+			rule.AccountID,
+			rule.CategoryID,
+			pq.Array(rule.TagIDs),
+			rule.RenameTo,
+		).Scan(
+			&rule.ID,
+			&rule.CreatedAt,
+			&rule.UpdatedAt,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidReference
+			}
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditCategorizationRule, AuditCreate, nil, rule.ID)
+	})
+}
+
+// Index returns all rules in the order they are applied.
+func (s *CategorizationRuleStore) Index(ctx context.Context) ([]CategorizationRule, error) {
+	query := `SELECT ` + categorizationRuleColumns + ` FROM categorization_rules ORDER BY priority DESC, id ASC`
+
+	return s.index(ctx, query)
+}
+
+// Enabled returns the rules to apply, in the order they are applied.
+func (s *CategorizationRuleStore) Enabled(ctx context.Context) ([]CategorizationRule, error) {
+	query := `SELECT ` + categorizationRuleColumns + ` FROM categorization_rules WHERE enabled ORDER BY priority DESC, id ASC`
+
+	return s.index(ctx, query)
+}
+
+func (s *CategorizationRuleStore) index(ctx context.Context, query string, args ...any) ([]CategorizationRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []CategorizationRule
+	for rows.Next() {
+		var rule CategorizationRule
+		if err := rows.Scan(categorizationRuleFields(&rule)...); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (s *CategorizationRuleStore) GetByID(ctx context.Context, id int64) (*CategorizationRule, error) {
+	query := `SELECT ` + categorizationRuleColumns + ` FROM categorization_rules WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rule CategorizationRule
+	err := s.db.QueryRowContext(ctx, query, id).Scan(categorizationRuleFields(&rule)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rule, nil
+}
+
+func (s *CategorizationRuleStore) Update(ctx context.Context, rule *CategorizationRule) error {
+	query := `
+		UPDATE categorization_rules
+		SET name = $1, priority = $2, enabled = $3, description_contains = $4, description_pattern = $5, min_amount = $6,
+			max_amount = $7, account_id = $8, category_id = $9, tag_ids = COALESCE($10::bigint[], '{}'), rename_to = $11, updated_at = NOW()
+		WHERE id = $12
+		RETURNING updated_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditCategorizationRule, rule.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			rule.Name,
+			rule.Priority,
+			rule.Enabled,
+			rule.DescriptionContains,
+			rule.DescriptionPattern,
+			rule.MinAmount,
+			rule.MaxAmount,
+			rule.AccountID,
+			rule.CategoryID,
+			pq.Array(rule.TagIDs),
+			rule.RenameTo,
+			rule.ID,
+		).Scan(&rule.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			case isForeignKeyViolation(err):
+				return ErrInvalidReference
+			default:
+				return err
+			}
+		}
+
+		return recordChanges(ctx, tx, AuditCategorizationRule, AuditUpdate, before, rule.ID)
+	})
+}
+
+func (s *CategorizationRuleStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM categorization_rules WHERE id = $1`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditCategorizationRule, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditCategorizationRule, AuditDelete, before, id)
+	})
+}
+
+// ApplyRuleChanges books the changes of a retroactive rule run in one
+// database transaction and returns the ids of the transactions it skipped.
+// A transaction is skipped when it moved on from the version the change was
+// worked out from, or was reconciled or trashed since. A transaction moved to
+// another category takes the kind of that category, as when it is edited.
+// Categories, kinds and descriptions do not affect balances, so running
+// balances are left alone. A run may cover a long date range, so the database
+// transaction is bounded by BatchTimeoutDuration.
+func (s *TransactionStore) ApplyRuleChanges(ctx context.Context, changes []RuleChange) ([]int64, error) {
+	ids := make([]int64, len(changes))
+	versions := make([]int64, len(changes))
+	categoryIDs := make([]sql.NullInt64, len(changes))
+	descriptions := make([]string, len(changes))
+	var tagTransactionIDs, tagIDs []int64
+	for i, change := range changes {
+		ids[i] = change.TransactionID
+		versions[i] = change.Version
+		if change.CategoryID != nil {
+			categoryIDs[i] = sql.NullInt64{Int64: *change.CategoryID, Valid: true}
+		}
+		descriptions[i] = change.Description
+		for _, tagID := range change.AddedTagIDs {
+			tagTransactionIDs = append(tagTransactionIDs, change.TransactionID)
+			tagIDs = append(tagIDs, tagID)
+		}
+	}
+
+	var skipped []int64
+	err := withTxTimeout(ctx, s.db, BatchTimeoutDuration, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditTransaction, ids...)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE transactions t
+			SET category_id = c.category_id, description = c.description, updated_at = NOW(), version = t.version + 1,
+				kind = CASE WHEN t.category_id IS DISTINCT FROM c.category_id THEN COALESCE((SELECT kind FROM categories WHERE id = c.category_id), 'expense') ELSE t.kind END
+			FROM unnest($1::bigint[], $2::bigint[], $3::bigint[], $4::text[]) AS c(id, version, category_id, description)
+			WHERE t.id = c.id AND t.version = c.version AND t.status <> $5 AND t.deleted_at IS NULL
+			RETURNING t.id
+		`, pq.Array(ids), pq.Array(versions), pq.Array(categoryIDs), pq.Array(descriptions), StatusReconciled)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidReference
+			}
+			return err
+		}
+		applied, err := scanIDs(rows)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO transaction_tags (transaction_id, tag_id)
+			SELECT tt.transaction_id, tt.tag_id
+			FROM unnest($1::bigint[], $2::bigint[]) AS tt(transaction_id, tag_id)
+			WHERE tt.transaction_id = ANY($3::bigint[])
+			ON CONFLICT DO NOTHING
+		`, pq.Array(tagTransactionIDs), pq.Array(tagIDs), pq.Array(applied))
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidReference
+			}
+			return err
+		}
+
+		done := map[int64]bool{}
+		for _, id := range applied {
+			done[id] = true
+		}
+		skipped = []int64{}
+		for _, id := range ids {
+			if !done[id] {
+				skipped = append(skipped, id)
+			}
+		}
+
+		return recordChanges(ctx, tx, AuditTransaction, AuditUpdate, before, applied...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return skipped, nil
+}
+
+func categorizationRuleFields(rule *CategorizationRule) []any {
+	return []any{
+		&rule.ID,
+		&rule.Name,
+		&rule.Priority,
+		&rule.Enabled,
+		&rule.DescriptionContains,
+		&rule.DescriptionPattern,
+		&rule.MinAmount,
+		&rule.MaxAmount,
+		&rule.AccountID,
+		&rule.CategoryID,
+		pq.Array(&rule.TagIDs),
+		&rule.RenameTo,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	}
+}
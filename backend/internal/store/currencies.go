@@ -0,0 +1,66 @@
+package store
+
+import (
+	"fmt"
+	"math"
+)
+
+// Amounts are stored as integers in the minor unit of their currency, such as
+// cents for USD. Currencies have two decimals unless listed here, as in ISO
+// 4217. IDR is the exception: rupiah amounts have always been stored whole,
+// as sen are no longer in use. The currency_decimals function of the database
+// mirrors this list.
+var currencyDecimals = map[string]int{
+	"IDR": 0,
+
+	"BIF": 0,
+	"CLP": 0,
+	"DJF": 0,
+	"GNF": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KMF": 0,
+	"KRW": 0,
+	"PYG": 0,
+	"RWF": 0,
+	"UGX": 0,
+	"UYI": 0,
+	"VND": 0,
+	"VUV": 0,
+	"XAF": 0,
+	"XOF": 0,
+	"XPF": 0,
+
+	"BHD": 3,
+	"IQD": 3,
+	"JOD": 3,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"TND": 3,
+
+	"CLF": 4,
+	"UYW": 4,
+}
+
+// CurrencyDecimals returns the number of decimals of the currency's minor
+// unit.
+func CurrencyDecimals(currency string) int {
+	if decimals, ok := currencyDecimals[currency]; ok {
+		return decimals
+	}
+	return 2
+}
+
+// MinorUnits converts a whole amount of the currency to its minor unit.
+func MinorUnits(amount int64, currency string) (int64, error) {
+	scale := int64(1)
+	for range CurrencyDecimals(currency) {
+		scale *= 10
+	}
+
+	if amount > math.MaxInt64/scale || amount < math.MinInt64/scale {
+		return 0, fmt.Errorf("amount %d %s is too large", amount, currency)
+	}
+	return amount * scale, nil
+}
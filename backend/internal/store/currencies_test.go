@@ -0,0 +1,39 @@
+package store
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CurrenciesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *CurrenciesTestSuite) TestCurrencyDecimals() {
+	assert.Equal(suite.T(), 0, CurrencyDecimals("IDR"))
+	assert.Equal(suite.T(), 0, CurrencyDecimals("JPY"))
+	assert.Equal(suite.T(), 2, CurrencyDecimals("USD"))
+	assert.Equal(suite.T(), 3, CurrencyDecimals("KWD"))
+	// unlisted currencies have cents
+	assert.Equal(suite.T(), 2, CurrencyDecimals("SGD"))
+}
+
+func (suite *CurrenciesTestSuite) TestMinorUnits() {
+	amount, err := MinorUnits(35000, "IDR")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(35000), amount)
+
+	amount, err = MinorUnits(-12, "USD")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(-1200), amount)
+
+	_, err = MinorUnits(math.MaxInt64/10, "USD")
+	assert.Error(suite.T(), err)
+}
+
+func TestCurrenciesTestSuite(t *testing.T) {
+	suite.Run(t, new(CurrenciesTestSuite))
+}
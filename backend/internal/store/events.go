@@ -13,23 +13,32 @@ type Event struct {
 	Date        string `json:"date"`
 	CreatedAt   string `json:"created_at"`
 	UpdatedAt   string `json:"updated_at"`
+	Version     int64  `json:"version"`
 }
 
+// EventSummary is an event with the total of its expenses in BaseCurrency at
+// the rates of the event's date. Expenses without a known rate are left out
+// of the total and counted in Unconverted.
 type EventSummary struct {
-	ID            int64 `json:"id"`
+	ID            int64  `json:"id"`
 	Name          string `json:"name"`
 	Description   string `json:"description"`
 	Date          string `json:"date"`
-	TotalExpenses int64 `json:"totalExpenses"`
+	TotalExpenses int64  `json:"totalExpenses"`
+	Unconverted   int64  `json:"unconverted"`
 }
 
+// EventExpense is one expense of an event. ConvertedAmount is the amount in
+// BaseCurrency at the rate of the event's date, or nil when no rate is known.
 type EventExpense struct {
-	ID          int64  `json:"id"`
-	EventID     int64  `json:"eventId"`
-	Amount      int64  `json:"amount"`
-	Description string `json:"description"`
-	CreatedAt   string `json:"created_at"`
-	UpdatedAt   string `json:"updated_at"`
+	ID              int64  `json:"id"`
+	EventID         int64  `json:"eventId"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	ConvertedAmount *int64 `json:"convertedAmount"`
+	Description     string `json:"description"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
 }
 
 type EventStore struct {
@@ -39,28 +48,28 @@ type EventStore struct {
 func (s *EventStore) Create(ctx context.Context, event *Event) error {
 	query := `
 		INSERT INTO events (name, description, date)
-		VALUES ($1::text, $2::text, $3::date) RETURNING id, created_at, updated_at
+		VALUES ($1::text, $2::text, $3::date) RETURNING id, created_at, updated_at, version
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		event.Name,
-		event.Description,
-		event.Date,
-	).Scan(
-		&event.ID,
-		&event.CreatedAt,
-		&event.UpdatedAt,
-	)
-	if err != nil {
-		return err
-	}
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			event.Name,
+			event.Description,
+			event.Date,
+		).Scan(
+			&event.ID,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+			&event.Version,
+		)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return recordChanges(ctx, tx, AuditEvent, AuditCreate, nil, event.ID)
+	})
 }
 
 func (s *EventStore) GetAll(ctx context.Context) ([]EventSummary, error) {
@@ -70,9 +79,12 @@ func (s *EventStore) GetAll(ctx context.Context) ([]EventSummary, error) {
 			e.name, 
 			e.description, 
 			e.date,
-			COALESCE(SUM(ee.amount), 0) as total_expenses
+			COALESCE(SUM(cv.amount), 0) as total_expenses,
+			COUNT(ee.id) FILTER (WHERE cv.amount IS NULL) as unconverted
 		FROM events e
 		LEFT JOIN event_expenses ee ON e.id = ee.event_id
+		LEFT JOIN LATERAL (SELECT convert_amount(ee.amount, ee.currency, $1, e.date) AS amount) cv ON true
+		WHERE e.deleted_at IS NULL
 		GROUP BY e.id, e.name, e.description, e.date
 		ORDER BY e.date DESC
 	`
@@ -80,7 +92,7 @@ func (s *EventStore) GetAll(ctx context.Context) ([]EventSummary, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, BaseCurrency)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +106,7 @@ func (s *EventStore) GetAll(ctx context.Context) ([]EventSummary, error) {
 			&event.Description,
 			&event.Date,
 			&event.TotalExpenses,
+			&event.Unconverted,
 		); err != nil {
 			return nil, err
 		}
@@ -108,9 +121,9 @@ func (s *EventStore) GetAll(ctx context.Context) ([]EventSummary, error) {
 
 func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 	query := `
-		SELECT id, name, description, date, created_at, updated_at
+		SELECT id, name, description, date, created_at, updated_at, version
 		FROM events
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -128,6 +141,7 @@ func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 		&event.Date,
 		&event.CreatedAt,
 		&event.UpdatedAt,
+		&event.Version,
 	)
 
 	if err != nil {
@@ -144,16 +158,18 @@ func (s *EventStore) GetByID(ctx context.Context, id int64) (*Event, error) {
 
 func (s *EventStore) GetEventExpenses(ctx context.Context, eventID int64) ([]EventExpense, error) {
 	query := `
-		SELECT id, event_id, amount, description, created_at, updated_at
-		FROM event_expenses
-		WHERE event_id = $1
-		ORDER BY id DESC
+		SELECT ee.id, ee.event_id, ee.amount, ee.currency, convert_amount(ee.amount, ee.currency, $2, e.date),
+			ee.description, ee.created_at, ee.updated_at
+		FROM event_expenses ee
+		JOIN events e ON e.id = ee.event_id
+		WHERE ee.event_id = $1
+		ORDER BY ee.id DESC
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, eventID)
+	rows, err := s.db.QueryContext(ctx, query, eventID, BaseCurrency)
 	if err != nil {
 		return nil, err
 	}
@@ -165,6 +181,8 @@ func (s *EventStore) GetEventExpenses(ctx context.Context, eventID int64) ([]Eve
 			&expense.ID,
 			&expense.EventID,
 			&expense.Amount,
+			&expense.Currency,
+			&expense.ConvertedAmount,
 			&expense.Description,
 			&expense.CreatedAt,
 			&expense.UpdatedAt,
@@ -180,52 +198,136 @@ func (s *EventStore) GetEventExpenses(ctx context.Context, eventID int64) ([]Eve
 	return expenses, nil
 }
 
-func (s *EventStore) CreateExpense(ctx context.Context, expense *EventExpense) error {
+func (s *EventStore) GetExpenseByID(ctx context.Context, id int64) (*EventExpense, error) {
 	query := `
-		INSERT INTO event_expenses (event_id, amount, description)
-		VALUES ($1::bigint, $2::bigint, $3::text) RETURNING id, created_at, updated_at
+		SELECT ee.id, ee.event_id, ee.amount, ee.currency, convert_amount(ee.amount, ee.currency, $2, e.date),
+			ee.description, ee.created_at, ee.updated_at
+		FROM event_expenses ee
+		JOIN events e ON e.id = ee.event_id
+		WHERE ee.id = $1 AND e.deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
+	var expense EventExpense
 	err := s.db.QueryRowContext(
 		ctx,
 		query,
-		expense.EventID,
-		expense.Amount,
-		expense.Description,
+		id,
+		BaseCurrency,
 	).Scan(
 		&expense.ID,
+		&expense.EventID,
+		&expense.Amount,
+		&expense.Currency,
+		&expense.ConvertedAmount,
+		&expense.Description,
 		&expense.CreatedAt,
 		&expense.UpdatedAt,
 	)
+
 	if err != nil {
-		return err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
 	}
 
-	return nil
+	return &expense, nil
 }
 
-func (s *EventStore) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM events WHERE id = $1`
+// CreateExpense adds an expense to the event. An expense changes its event,
+// so the event moves on to a new version. ErrNotFound is returned when the
+// event is in the trash.
+func (s *EventStore) CreateExpense(ctx context.Context, expense *EventExpense) error {
+	query := `
+		INSERT INTO event_expenses (event_id, amount, description, currency)
+		VALUES ($1::bigint, $2::bigint, $3::text, $4::text)
+		RETURNING id, convert_amount(amount, currency, $5, (SELECT date FROM events WHERE id = $1::bigint)), created_at, updated_at
+	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditEvent, expense.EventID)
+		if err != nil {
+			return err
+		}
 
-	res, err := s.db.ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
+		res, err := tx.ExecContext(ctx,
+			`UPDATE events SET version = version + 1, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL`,
+			expense.EventID,
+		)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrNotFound
+		}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			expense.EventID,
+			expense.Amount,
+			expense.Description,
+			expense.Currency,
+			BaseCurrency,
+		).Scan(
+			&expense.ID,
+			&expense.ConvertedAmount,
+			&expense.CreatedAt,
+			&expense.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
 
-	if rows == 0 {
-		return ErrNotFound
-	}
+		if err := recordChanges(ctx, tx, AuditEvent, AuditUpdate, before, expense.EventID); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditEventExpense, AuditCreate, nil, expense.ID)
+	})
+}
+
+// Delete moves the event to the trash. Its expenses stay with it and come
+// back when the event is restored. ErrVersionMismatch is returned when the
+// event moved on from version since it was read.
+func (s *EventStore) Delete(ctx context.Context, id, version int64) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var current int64
+		err := tx.QueryRowContext(ctx,
+			`SELECT version FROM events WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+			id,
+		).Scan(&current)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+		if current != version {
+			return ErrVersionMismatch
+		}
+
+		before, err := snapshotRows(ctx, tx, AuditEvent, id)
+		if err != nil {
+			return err
+		}
 
-	return nil
-}
\ No newline at end of file
+		// the row is locked above, so it is still there to update
+		if _, err := tx.ExecContext(ctx, `UPDATE events SET deleted_at = NOW() WHERE id = $1`, id); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditEvent, AuditDelete, before, id)
+	})
+}
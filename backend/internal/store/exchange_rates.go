@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExchangeRate is the price of one unit of Currency in BaseCurrency on Date.
+// Amounts are converted with the rate closest to their date, preferring the
+// latest one on or before it, and a rate quoted the other way around is used
+// inverted.
+type ExchangeRate struct {
+	ID           int64   `json:"id"`
+	Currency     string  `json:"currency"`
+	BaseCurrency string  `json:"base_currency"`
+	Date         string  `json:"date"`
+	Rate         float64 `json:"rate"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+}
+
+// ExchangeRateFilter narrows an exchange rate listing. Empty fields match
+// everything; From and To are inclusive dates.
+type ExchangeRateFilter struct {
+	Currency     string
+	BaseCurrency string
+	From         string
+	To           string
+}
+
+type ExchangeRateStore struct {
+	db *sql.DB
+}
+
+// Upsert saves the rates in one database transaction, replacing the rate of
+// a currency pair already known for the same date.
+func (s *ExchangeRateStore) Upsert(ctx context.Context, rates []ExchangeRate) error {
+	// the CTE sees the table as it was before the insert, which tells a new
+	// rate apart from a replaced one for the audit log
+	query := `
+		WITH old AS (
+			SELECT to_jsonb(r) AS row
+			FROM exchange_rates r
+			WHERE r.currency = $1::text AND r.base_currency = $2::text AND r.date = $3::date
+		)
+		INSERT INTO exchange_rates (currency, base_currency, date, rate)
+		VALUES ($1::text, $2::text, $3::date, $4)
+		ON CONFLICT (currency, base_currency, date) DO UPDATE SET rate = EXCLUDED.rate, updated_at = NOW()
+		RETURNING id, created_at, updated_at, (SELECT row FROM old)
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var created, replaced []int64
+		before := map[int64]json.RawMessage{}
+		for i := range rates {
+			rate := &rates[i]
+			var old []byte
+			err := tx.QueryRowContext(
+				ctx,
+				query,
+				rate.Currency,
+				rate.BaseCurrency,
+				rate.Date,
+				rate.Rate,
+			).Scan(
+				&rate.ID,
+				&rate.CreatedAt,
+				&rate.UpdatedAt,
+				&old,
+			)
+			if err != nil {
+				return err
+			}
+
+			if old == nil {
+				created = append(created, rate.ID)
+			} else {
+				replaced = append(replaced, rate.ID)
+				before[rate.ID] = old
+			}
+		}
+
+		if err := recordChanges(ctx, tx, AuditExchangeRate, AuditCreate, nil, created...); err != nil {
+			return err
+		}
+
+		previous, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditExchangeRate, AuditUpdate, previous, replaced...)
+	})
+}
+
+// Index returns the rates matching the filter, newest first.
+func (s *ExchangeRateStore) Index(ctx context.Context, filter ExchangeRateFilter) ([]ExchangeRate, error) {
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.Currency != "" {
+		add("currency = $%d::text", filter.Currency)
+	}
+	if filter.BaseCurrency != "" {
+		add("base_currency = $%d::text", filter.BaseCurrency)
+	}
+	if filter.From != "" {
+		add("date >= $%d::date", filter.From)
+	}
+	if filter.To != "" {
+		add("date <= $%d::date", filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := `
+		SELECT id, currency, base_currency, date, rate, created_at, updated_at
+		FROM exchange_rates
+		` + where + `
+		ORDER BY date DESC, currency ASC, base_currency ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []ExchangeRate
+	for rows.Next() {
+		var rate ExchangeRate
+		if err := rows.Scan(exchangeRateFields(&rate)...); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func (s *ExchangeRateStore) GetByID(ctx context.Context, id int64) (*ExchangeRate, error) {
+	query := `
+		SELECT id, currency, base_currency, date, rate, created_at, updated_at
+		FROM exchange_rates
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rate ExchangeRate
+	err := s.db.QueryRowContext(ctx, query, id).Scan(exchangeRateFields(&rate)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rate, nil
+}
+
+func (s *ExchangeRateStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM exchange_rates WHERE id = $1`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditExchangeRate, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditExchangeRate, AuditDelete, before, id)
+	})
+}
+
+func exchangeRateFields(rate *ExchangeRate) []any {
+	return []any{
+		&rate.ID,
+		&rate.Currency,
+		&rate.BaseCurrency,
+		&rate.Date,
+		&rate.Rate,
+		&rate.CreatedAt,
+		&rate.UpdatedAt,
+	}
+}
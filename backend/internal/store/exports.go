@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// TransactionExport is one transaction of a ledger export. CategoryName is
+// empty for uncategorized and split transactions, and ConvertedAmount is nil
+// when no rate to BaseCurrency is known.
+type TransactionExport struct {
+	ID              int64     `json:"id"`
+	Date            time.Time `json:"date"`
+	AccountID       int64     `json:"account_id"`
+	AccountName     string    `json:"account_name"`
+	Description     string    `json:"description"`
+	Kind            string    `json:"kind"`
+	Status          string    `json:"status"`
+	CategoryID      *int64    `json:"category_id"`
+	CategoryName    string    `json:"category_name"`
+	Amount          int64     `json:"amount"`
+	Currency        string    `json:"currency"`
+	ConvertedAmount *int64    `json:"converted_amount"`
+	RunningBalance  int64     `json:"running_balance"`
+	Tags            []string  `json:"tags"`
+	Splits          []Split   `json:"splits"`
+	EventID         *int64    `json:"event_id"`
+	EventName       string    `json:"event_name"`
+	ExternalID      string    `json:"external_id,omitempty"`
+	Version         int64     `json:"version"`
+}
+
+// EventExport is an event together with all of its expenses.
+type EventExport struct {
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Date        string         `json:"date"`
+	Expenses    []EventExpense `json:"expenses"`
+}
+
+// Export calls fn for every transaction matching the filter, ignoring its
+// limit and cursor. Rows are read one at a time so that the full ledger is
+// never held in memory; the query is bounded by ctx only, as a large export
+// may take longer than QueryTimeoutDuration.
+func (s *TransactionStore) Export(ctx context.Context, filter TransactionFilter, fn func(*TransactionExport) error) error {
+	filter.After = nil
+	where, args := filter.whereClause()
+	args = append(args, BaseCurrency)
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.date, t.account_id, a.name, t.description, t.kind, t.status, c.id, COALESCE(c.name, ''), t.amount,
+			t.currency, convert_amount(t.amount, t.currency, $%d, t.date::date), t.running_balance,
+			ARRAY(
+				SELECT tg.name FROM transaction_tags tt JOIN tags tg ON tg.id = tt.tag_id
+				WHERE tt.transaction_id = t.id ORDER BY tg.name
+			),
+			%s,
+			e.id, COALESCE(e.name, ''), COALESCE(t.external_id, ''), t.version
+		FROM transactions t
+		JOIN accounts a
+			ON t.account_id = a.id
+		LEFT JOIN categories c
+			ON t.category_id = c.id AND c.deleted_at IS NULL
+		LEFT JOIN events e
+			ON t.event_id = e.id AND e.deleted_at IS NULL
+		%s
+		%s
+	`, len(args), transactionSplitsColumn, where, filter.orderClause())
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transaction TransactionExport
+		var categoryID, eventID sql.NullInt64
+		var splits []byte
+		if err := rows.Scan(
+			&transaction.ID,
+			&transaction.Date,
+			&transaction.AccountID,
+			&transaction.AccountName,
+			&transaction.Description,
+			&transaction.Kind,
+			&transaction.Status,
+			&categoryID,
+			&transaction.CategoryName,
+			&transaction.Amount,
+			&transaction.Currency,
+			&transaction.ConvertedAmount,
+			&transaction.RunningBalance,
+			pq.Array(&transaction.Tags),
+			&splits,
+			&eventID,
+			&transaction.EventName,
+			&transaction.ExternalID,
+			&transaction.Version,
+		); err != nil {
+			return err
+		}
+		if categoryID.Valid {
+			transaction.CategoryID = &categoryID.Int64
+		}
+		if eventID.Valid {
+			transaction.EventID = &eventID.Int64
+		}
+		if transaction.Splits, err = decodeSplits(splits); err != nil {
+			return err
+		}
+
+		if err := fn(&transaction); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Export calls fn for every event outside the trash, oldest first, with its
+// expenses.
+func (s *EventStore) Export(ctx context.Context, fn func(*EventExport) error) error {
+	query := `
+		SELECT e.id, e.name, e.description, e.date,
+			ee.id, ee.amount, ee.currency, convert_amount(ee.amount, ee.currency, $1, e.date), ee.description, ee.created_at, ee.updated_at
+		FROM events e
+		LEFT JOIN event_expenses ee ON e.id = ee.event_id
+		WHERE e.deleted_at IS NULL
+		ORDER BY e.date ASC, e.id ASC, ee.id ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, BaseCurrency)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// rows of one event are consecutive; an event is handed to fn once the
+	// next one starts
+	var current *EventExport
+	for rows.Next() {
+		var event EventExport
+		var expenseID, amount, convertedAmount sql.NullInt64
+		var currency, description, createdAt, updatedAt sql.NullString
+		if err := rows.Scan(
+			&event.ID,
+			&event.Name,
+			&event.Description,
+			&event.Date,
+			&expenseID,
+			&amount,
+			&currency,
+			&convertedAmount,
+			&description,
+			&createdAt,
+			&updatedAt,
+		); err != nil {
+			return err
+		}
+
+		if current == nil || current.ID != event.ID {
+			if current != nil {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+			event.Expenses = []EventExpense{}
+			current = &event
+		}
+
+		if expenseID.Valid {
+			expense := EventExpense{
+				ID:          expenseID.Int64,
+				EventID:     current.ID,
+				Amount:      amount.Int64,
+				Currency:    currency.String,
+				Description: description.String,
+				CreatedAt:   createdAt.String,
+				UpdatedAt:   updatedAt.String,
+			}
+			if convertedAmount.Valid {
+				expense.ConvertedAmount = &convertedAmount.Int64
+			}
+			current.Expenses = append(current.Expenses, expense)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if current != nil {
+		return fn(current)
+	}
+
+	return nil
+}
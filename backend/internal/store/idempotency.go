@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IdempotentResponse is the response saved for an idempotency key, which is
+// replayed when the same request is sent again.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type IdempotencyStore struct {
+	db *sql.DB
+}
+
+// Begin claims key for a request identified by requestHash and returns nil
+// when the request should be handled. When the key was already used for the
+// same request the saved response is returned instead, or ErrConflict while
+// that request is still being handled; ErrKeyReused means the key was used
+// for a different request. A claim without a response that is older than
+// lease was left by a request that never finished and is taken over. Keys
+// are forgotten once they are older than ttl.
+func (s *IdempotencyStore) Begin(ctx context.Context, userID int64, key, requestHash string, ttl, lease time.Duration) (*IdempotentResponse, error) {
+	var saved *IdempotentResponse
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < $1`, time.Now().Add(-ttl))
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO idempotency_keys (user_id, key, request_hash)
+			VALUES ($1, $2::text, $3::text)
+			ON CONFLICT (user_id, key) DO UPDATE
+			SET locked_at = NOW()
+			WHERE idempotency_keys.status_code IS NULL
+				AND idempotency_keys.request_hash = EXCLUDED.request_hash
+				AND idempotency_keys.locked_at < $4
+		`, userID, key, requestHash, time.Now().Add(-lease))
+		if err != nil {
+			return err
+		}
+
+		claimed, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if claimed == 1 {
+			return nil
+		}
+
+		var hash string
+		var statusCode sql.NullInt64
+		var response IdempotentResponse
+		err = tx.QueryRowContext(ctx, `
+			SELECT request_hash, status_code, content_type, response_body
+			FROM idempotency_keys
+			WHERE user_id = $1 AND key = $2::text
+		`, userID, key).Scan(&hash, &statusCode, &response.ContentType, &response.Body)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case hash != requestHash:
+			return ErrKeyReused
+		case !statusCode.Valid:
+			return ErrConflict
+		}
+
+		response.StatusCode = int(statusCode.Int64)
+		saved = &response
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// Complete saves the response of the request that claimed key.
+func (s *IdempotencyStore) Complete(ctx context.Context, userID int64, key string, response IdempotentResponse) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $1, content_type = $2::text, response_body = $3
+		WHERE user_id = $4 AND key = $5::text
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, response.StatusCode, response.ContentType, response.Body, userID, key)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Release gives up a claimed key without saving a response, so that the
+// request can be retried.
+func (s *IdempotencyStore) Release(ctx context.Context, userID int64, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2::text AND status_code IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, key)
+	return err
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Amount sign conventions of an import file. Banks either export one signed
+// amount column, with expenses negative or positive, or separate debit and
+// credit columns.
+const (
+	AmountSignNegativeExpense = "negative_expense"
+	AmountSignPositiveExpense = "positive_expense"
+	AmountSignDebitCredit     = "debit_credit"
+)
+
+// ImportProfile is a saved column mapping for the exports of one bank or
+// e-wallet. Columns are referenced by header name or by 1-based position.
+type ImportProfile struct {
+	ID                int64  `json:"id"`
+	Name              string `json:"name"`
+	Delimiter         string `json:"delimiter"`
+	HasHeader         bool   `json:"has_header"`
+	SkipRows          int    `json:"skip_rows"`
+	DateColumn        string `json:"date_column"`
+	DateFormat        string `json:"date_format"`
+	DescriptionColumn string `json:"description_column"`
+	AmountColumn      string `json:"amount_column"`
+	DebitColumn       string `json:"debit_column"`
+	CreditColumn      string `json:"credit_column"`
+	AmountSign        string `json:"amount_sign"`
+	DecimalSeparator  string `json:"decimal_separator"`
+	ThousandSeparator string `json:"thousand_separator"`
+	CreatedAt         string `json:"created_at"`
+	UpdatedAt         string `json:"updated_at"`
+}
+
+type ImportProfileStore struct {
+	db *sql.DB
+}
+
+const importProfileColumns = `id, name, delimiter, has_header, skip_rows, date_column, date_format, description_column,
+	amount_column, debit_column, credit_column, amount_sign, decimal_separator, thousand_separator, created_at, updated_at`
+
+func (s *ImportProfileStore) Create(ctx context.Context, profile *ImportProfile) error {
+	query := `
+		INSERT INTO import_profiles (name, delimiter, has_header, skip_rows, date_column, date_format, description_column,
+			amount_column, debit_column, credit_column, amount_sign, decimal_separator, thousand_separator)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id, created_at, updated_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			profile.Name,
+			profile.Delimiter,
+			profile.HasHeader,
+			profile.SkipRows,
+			profile.DateColumn,
+			profile.DateFormat,
+			profile.DescriptionColumn,
+			profile.AmountColumn,
+			profile.DebitColumn,
+			profile.CreditColumn,
+			profile.AmountSign,
+			profile.DecimalSeparator,
+			profile.ThousandSeparator,
+		).Scan(
+			&profile.ID,
+			&profile.CreatedAt,
+			&profile.UpdatedAt,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditImportProfile, AuditCreate, nil, profile.ID)
+	})
+}
+
+func (s *ImportProfileStore) Index(ctx context.Context) ([]ImportProfile, error) {
+	query := `SELECT ` + importProfileColumns + ` FROM import_profiles ORDER BY name ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []ImportProfile
+	for rows.Next() {
+		var profile ImportProfile
+		if err := rows.Scan(importProfileFields(&profile)...); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+func (s *ImportProfileStore) GetByID(ctx context.Context, id int64) (*ImportProfile, error) {
+	query := `SELECT ` + importProfileColumns + ` FROM import_profiles WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var profile ImportProfile
+	err := s.db.QueryRowContext(ctx, query, id).Scan(importProfileFields(&profile)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &profile, nil
+}
+
+func (s *ImportProfileStore) Update(ctx context.Context, profile *ImportProfile) error {
+	query := `
+		UPDATE import_profiles
+		SET name = $1, delimiter = $2, has_header = $3, skip_rows = $4, date_column = $5, date_format = $6,
+			description_column = $7, amount_column = $8, debit_column = $9, credit_column = $10, amount_sign = $11,
+			decimal_separator = $12, thousand_separator = $13, updated_at = NOW()
+		WHERE id = $14
+		RETURNING updated_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditImportProfile, profile.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			profile.Name,
+			profile.Delimiter,
+			profile.HasHeader,
+			profile.SkipRows,
+			profile.DateColumn,
+			profile.DateFormat,
+			profile.DescriptionColumn,
+			profile.AmountColumn,
+			profile.DebitColumn,
+			profile.CreditColumn,
+			profile.AmountSign,
+			profile.DecimalSeparator,
+			profile.ThousandSeparator,
+			profile.ID,
+		).Scan(&profile.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			case isUniqueViolation(err):
+				return ErrConflict
+			default:
+				return err
+			}
+		}
+
+		return recordChanges(ctx, tx, AuditImportProfile, AuditUpdate, before, profile.ID)
+	})
+}
+
+func (s *ImportProfileStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM import_profiles WHERE id = $1`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditImportProfile, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditImportProfile, AuditDelete, before, id)
+	})
+}
+
+func importProfileFields(profile *ImportProfile) []any {
+	return []any{
+		&profile.ID,
+		&profile.Name,
+		&profile.Delimiter,
+		&profile.HasHeader,
+		&profile.SkipRows,
+		&profile.DateColumn,
+		&profile.DateFormat,
+		&profile.DescriptionColumn,
+		&profile.AmountColumn,
+		&profile.DebitColumn,
+		&profile.CreditColumn,
+		&profile.AmountSign,
+		&profile.DecimalSeparator,
+		&profile.ThousandSeparator,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+	}
+}
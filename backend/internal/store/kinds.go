@@ -0,0 +1,27 @@
+package store
+
+// Kinds classify money movement independently of the category name.
+// Income is stored as a negative amount because running balances are
+// computed as previous balance minus amount.
+const (
+	KindExpense    = "expense"
+	KindIncome     = "income"
+	KindTransfer   = "transfer"
+	KindAdjustment = "adjustment"
+)
+
+func IsValidKind(kind string) bool {
+	switch kind {
+	case KindExpense, KindIncome, KindTransfer, KindAdjustment:
+		return true
+	}
+	return false
+}
+
+// kindSign flips income totals so every report returns positive amounts.
+func kindSign(kind string) int64 {
+	if kind == KindIncome {
+		return -1
+	}
+	return 1
+}
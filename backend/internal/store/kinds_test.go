@@ -0,0 +1,32 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type KindsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *KindsTestSuite) TestIsValidKind() {
+	for _, kind := range []string{KindExpense, KindIncome, KindTransfer, KindAdjustment} {
+		assert.True(suite.T(), IsValidKind(kind), kind)
+	}
+
+	assert.False(suite.T(), IsValidKind(""))
+	assert.False(suite.T(), IsValidKind("Gajian"))
+}
+
+func (suite *KindsTestSuite) TestKindSign() {
+	assert.Equal(suite.T(), int64(1), kindSign(KindExpense))
+	assert.Equal(suite.T(), int64(-1), kindSign(KindIncome))
+	assert.Equal(suite.T(), int64(1), kindSign(KindTransfer))
+	assert.Equal(suite.T(), int64(1), kindSign(KindAdjustment))
+}
+
+func TestKindsTestSuite(t *testing.T) {
+	suite.Run(t, new(KindsTestSuite))
+}
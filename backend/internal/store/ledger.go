@@ -0,0 +1,380 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx so ledger helpers can
+// run inside or outside a transaction.
+type querier interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+// withTx runs fn inside a database transaction bounded by QueryTimeoutDuration,
+// committing only when fn succeeds.
+func withTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	return withTxTimeout(ctx, db, QueryTimeoutDuration, fn)
+}
+
+// withTxTimeout is withTx bounded by the given timeout.
+func withTxTimeout(ctx context.Context, db *sql.DB, timeout time.Duration, fn func(*sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ledgerPoint is the position in an account's history where a change took
+// place. Running balances are ordered by (date, id).
+type ledgerPoint struct {
+	Date time.Time
+	ID   int64
+}
+
+func (p ledgerPoint) before(other ledgerPoint) bool {
+	if p.Date.Equal(other.Date) {
+		return p.ID < other.ID
+	}
+	return p.Date.Before(other.Date)
+}
+
+// ledgerChanges collects the earliest changed point per account so that a
+// set of mutations only triggers one recalculation per account.
+type ledgerChanges map[int64]ledgerPoint
+
+func (c ledgerChanges) add(accountID int64, date time.Time, id int64) {
+	point := ledgerPoint{Date: date, ID: id}
+	if current, ok := c[accountID]; ok && !point.before(current) {
+		return
+	}
+	c[accountID] = point
+}
+
+// apply recalculates the changed accounts, locking any of them that locks
+// does not hold yet. Two writers recomputing the same account would each miss
+// the other's uncommitted rows. Under READ COMMITTED the recalculation then
+// runs on a snapshot taken after the lock, which includes the rows of the
+// writer that held it.
+func (c ledgerChanges) apply(ctx context.Context, q querier, locks accountLocks) error {
+	if len(c) == 0 {
+		return nil
+	}
+
+	accountIDs := make([]int64, 0, len(c))
+	for accountID := range c {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+	if err := locks.lock(ctx, q, accountIDs...); err != nil {
+		return err
+	}
+
+	for _, accountID := range accountIDs {
+		if err := recalculateBalances(ctx, q, accountID, c[accountID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accountLocks holds the accounts locked by a database transaction, each with
+// the end of its reconciled period. Transactions dated before the end belong
+// to a completed reconciliation, so none can be added, changed or removed
+// there. The end is zero for accounts that were never reconciled.
+type accountLocks map[int64]time.Time
+
+// lock locks the given accounts that are not held yet. Accounts are always
+// locked before any of their transactions, and in id order so that moves
+// between two accounts cannot deadlock. NO KEY UPDATE leaves the key share
+// locks of foreign key checks alone, so concurrent inserts are not blocked
+// until the lock is taken. Accounts that do not exist are skipped and left for
+// the foreign keys to reject.
+func (l accountLocks) lock(ctx context.Context, q querier, accountIDs ...int64) error {
+	var missing []int64
+	for _, accountID := range accountIDs {
+		if _, ok := l[accountID]; !ok {
+			missing = append(missing, accountID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT id FROM accounts WHERE id = ANY($1::bigint[]) ORDER BY id FOR NO KEY UPDATE`,
+		pq.Array(missing),
+	)
+	if err != nil {
+		return err
+	}
+	locked, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+	for _, accountID := range locked {
+		l[accountID] = time.Time{}
+	}
+
+	// read in a statement of its own, whose snapshot is taken after the
+	// lock, so that a reconciliation completed while waiting is seen
+	rows, err = q.QueryContext(ctx, `
+		SELECT account_id, (MAX(statement_date) + INTERVAL '1 day')::timestamptz
+		FROM reconciliations
+		WHERE account_id = ANY($1::bigint[]) AND status = $2
+		GROUP BY account_id
+	`, pq.Array(locked), ReconciliationCompleted)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var accountID int64
+		var until time.Time
+		if err := rows.Scan(&accountID, &until); err != nil {
+			return err
+		}
+		l[accountID] = until
+	}
+
+	return rows.Err()
+}
+
+// reconciled tells whether the account has a completed reconciliation.
+func (l accountLocks) reconciled(accountID int64) bool {
+	return !l[accountID].IsZero()
+}
+
+// check returns ErrLocked when the date falls in the reconciled period of the
+// account.
+func (l accountLocks) check(accountID int64, date time.Time) error {
+	if date.Before(l[accountID]) {
+		return ErrLocked
+	}
+	return nil
+}
+
+// recalculateBalances recomputes the running balance of every transaction in
+// the account at or after the given point. The starting balance is taken from
+// the last row before the point, or from the account's opening balance.
+// Transactions in the trash are skipped and keep their last balance.
+func recalculateBalances(ctx context.Context, q querier, accountID int64, from ledgerPoint) error {
+	query := `
+		WITH base AS (
+			SELECT COALESCE(
+				(
+					SELECT running_balance
+					FROM transactions
+					WHERE account_id = $1 AND (date, id) < ($2::timestamptz, $3::bigint) AND deleted_at IS NULL
+					ORDER BY date DESC, id DESC
+					LIMIT 1
+				),
+				(SELECT opening_balance FROM accounts WHERE id = $1)
+			) AS balance
+		), ordered AS (
+			SELECT t.id, (SELECT balance FROM base) - SUM(t.amount) OVER (ORDER BY t.date, t.id) AS balance
+			FROM transactions t
+			WHERE t.account_id = $1 AND (t.date, t.id) >= ($2::timestamptz, $3::bigint) AND t.deleted_at IS NULL
+		)
+		UPDATE transactions t
+		SET running_balance = o.balance
+		FROM ordered o
+		WHERE t.id = o.id AND t.running_balance <> o.balance
+	`
+
+	_, err := q.ExecContext(ctx, query, accountID, from.Date, from.ID)
+	return err
+}
+
+// LedgerDrift is a transaction whose stored running balance disagrees with
+// the balance recomputed from its account's opening balance and every earlier
+// transaction.
+type LedgerDrift struct {
+	TransactionID int64  `json:"transaction_id"`
+	AccountID     int64  `json:"account_id"`
+	Date          string `json:"date"`
+	Stored        int64  `json:"stored_balance"`
+	Expected      int64  `json:"expected_balance"`
+}
+
+// LedgerAccountSummary sums up the drifts found in one account. From is the
+// date of the earliest drifting transaction.
+type LedgerAccountSummary struct {
+	AccountID         int64  `json:"account_id"`
+	Drifts            int    `json:"drifts"`
+	From              string `json:"from"`
+	LargestDifference int64  `json:"largest_difference"`
+}
+
+// LedgerReport is the outcome of a ledger check. Checked counts the
+// transactions walked, and Fixed tells whether the drifts were rewritten.
+type LedgerReport struct {
+	Checked  int64                  `json:"checked"`
+	Drifts   []LedgerDrift          `json:"drifts"`
+	Accounts []LedgerAccountSummary `json:"accounts"`
+	Fixed    bool                   `json:"fixed"`
+}
+
+// summarize orders the drifts by account and position and fills in the
+// summary of each account.
+func (r *LedgerReport) summarize() {
+	sort.Slice(r.Drifts, func(i, j int) bool {
+		a, b := r.Drifts[i], r.Drifts[j]
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.TransactionID < b.TransactionID
+	})
+
+	r.Accounts = []LedgerAccountSummary{}
+	for _, drift := range r.Drifts {
+		n := len(r.Accounts)
+		if n == 0 || r.Accounts[n-1].AccountID != drift.AccountID {
+			r.Accounts = append(r.Accounts, LedgerAccountSummary{AccountID: drift.AccountID, From: drift.Date})
+			n++
+		}
+		summary := &r.Accounts[n-1]
+		summary.Drifts++
+		difference := drift.Expected - drift.Stored
+		if difference < 0 {
+			difference = -difference
+		}
+		summary.LargestDifference = max(summary.LargestDifference, difference)
+	}
+}
+
+type LedgerStore struct {
+	db *sql.DB
+}
+
+// ledgerBalances recomputes the running balance of every transaction that is
+// not in the trash by walking each account in (date, id) order, the same
+// order recalculateBalances keeps them in.
+const ledgerBalances = `
+	WITH expected AS (
+		SELECT t.id, t.account_id, t.date, t.running_balance AS stored,
+			a.opening_balance - SUM(t.amount) OVER (PARTITION BY t.account_id ORDER BY t.date, t.id) AS balance
+		FROM transactions t
+		JOIN accounts a
+			ON a.id = t.account_id
+		WHERE t.deleted_at IS NULL
+	)
+`
+
+// Check compares the stored running balance of every transaction with the
+// recomputed one and reports the rows that disagree. With fix, those rows are
+// rewritten and recorded in the audit log in the same database transaction,
+// while writes to transactions are blocked. A full walk of the ledger may take
+// longer than QueryTimeoutDuration, so it is bounded by ctx only.
+func (s *LedgerStore) Check(ctx context.Context, fix bool) (*LedgerReport, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if fix {
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE transactions IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &LedgerReport{Fixed: fix}
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM transactions WHERE deleted_at IS NULL`).Scan(&report.Checked)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, ledgerBalances+`
+		SELECT id, account_id, date, stored, balance
+		FROM expected
+		WHERE stored <> balance
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.Drifts = []LedgerDrift{}
+	for rows.Next() {
+		var drift LedgerDrift
+		var date time.Time
+		if err := rows.Scan(
+			&drift.TransactionID,
+			&drift.AccountID,
+			&date,
+			&drift.Stored,
+			&drift.Expected,
+		); err != nil {
+			return nil, err
+		}
+		drift.Date = date.Format(time.RFC3339)
+		report.Drifts = append(report.Drifts, drift)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if fix && len(report.Drifts) > 0 {
+		if err := fixDrifts(ctx, tx, report.Drifts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	report.summarize()
+	return report, nil
+}
+
+// fixDrifts rewrites the running balance of the drifting transactions with
+// the expected one. The table is locked, so the drifts are still current.
+func fixDrifts(ctx context.Context, tx *sql.Tx, drifts []LedgerDrift) error {
+	ids := make([]int64, len(drifts))
+	balances := make([]int64, len(drifts))
+	for i, drift := range drifts {
+		ids[i] = drift.TransactionID
+		balances[i] = drift.Expected
+	}
+
+	before, err := snapshotRows(ctx, tx, AuditTransaction, ids...)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE transactions t
+		SET running_balance = d.balance
+		FROM unnest($1::bigint[], $2::bigint[]) AS d(id, balance)
+		WHERE t.id = d.id
+	`, pq.Array(ids), pq.Array(balances))
+	if err != nil {
+		return err
+	}
+
+	return recordChanges(ctx, tx, AuditTransaction, AuditUpdate, before, ids...)
+}
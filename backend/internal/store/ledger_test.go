@@ -0,0 +1,75 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LedgerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *LedgerTestSuite) TestLedgerPointOrdering() {
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(suite.T(), ledgerPoint{Date: day, ID: 5}.before(ledgerPoint{Date: day.AddDate(0, 0, 1), ID: 1}))
+	assert.True(suite.T(), ledgerPoint{Date: day, ID: 1}.before(ledgerPoint{Date: day, ID: 2}))
+	assert.False(suite.T(), ledgerPoint{Date: day, ID: 2}.before(ledgerPoint{Date: day, ID: 2}))
+}
+
+func (suite *LedgerTestSuite) TestLedgerChangesKeepEarliestPointPerAccount() {
+	day := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	changes := ledgerChanges{}
+
+	changes.add(1, day, 10)
+	changes.add(1, day.AddDate(0, 0, -3), 12)
+	changes.add(1, day.AddDate(0, 0, 2), 3)
+	changes.add(2, day, 7)
+
+	assert.Len(suite.T(), changes, 2)
+	assert.Equal(suite.T(), ledgerPoint{Date: day.AddDate(0, 0, -3), ID: 12}, changes[1])
+	assert.Equal(suite.T(), ledgerPoint{Date: day, ID: 7}, changes[2])
+}
+
+func (suite *LedgerTestSuite) TestAccountLocksRejectReconciledPeriod() {
+	// reconciled up to a statement dated 2023-01-31
+	until := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	locks := accountLocks{1: until, 2: {}}
+
+	assert.ErrorIs(suite.T(), locks.check(1, until.Add(-time.Second)), ErrLocked)
+	assert.ErrorIs(suite.T(), locks.check(1, until.AddDate(0, -6, 0)), ErrLocked)
+	assert.NoError(suite.T(), locks.check(1, until))
+	assert.NoError(suite.T(), locks.check(1, until.AddDate(0, 0, 1)))
+
+	assert.NoError(suite.T(), locks.check(2, until.AddDate(-10, 0, 0)))
+	assert.True(suite.T(), locks.reconciled(1))
+	assert.False(suite.T(), locks.reconciled(2))
+}
+
+func (suite *LedgerTestSuite) TestLedgerReportSummary() {
+	report := &LedgerReport{Drifts: []LedgerDrift{
+		{TransactionID: 9, AccountID: 2, Date: "2023-02-01T00:00:00Z", Stored: 100, Expected: 50},
+		{TransactionID: 7, AccountID: 1, Date: "2023-01-05T00:00:00Z", Stored: 100, Expected: 120},
+		{TransactionID: 4, AccountID: 1, Date: "2023-01-05T00:00:00Z", Stored: 300, Expected: 310},
+		{TransactionID: 3, AccountID: 1, Date: "2023-01-09T00:00:00Z", Stored: 0, Expected: 10},
+	}}
+
+	report.summarize()
+
+	ids := []int64{}
+	for _, drift := range report.Drifts {
+		ids = append(ids, drift.TransactionID)
+	}
+	assert.Equal(suite.T(), []int64{4, 7, 3, 9}, ids)
+	assert.Equal(suite.T(), []LedgerAccountSummary{
+		{AccountID: 1, Drifts: 3, From: "2023-01-05T00:00:00Z", LargestDifference: 20},
+		{AccountID: 2, Drifts: 1, From: "2023-02-01T00:00:00Z", LargestDifference: 50},
+	}, report.Accounts)
+}
+
+func TestLedgerTestSuite(t *testing.T) {
+	suite.Run(t, new(LedgerTestSuite))
+}
@@ -0,0 +1,369 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DefaultPayeeReportLimit = 10
+	MaxPayeeReportLimit     = 100
+)
+
+// Payee is the shop or person on the other side of a transaction. Aliases
+// are normalized descriptions that stand for the payee, such as "indomaret"
+// for "INDOMARET 123" and "Indomaret Kemang".
+type Payee struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	Aliases   []string `json:"aliases"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// PayeeTotal is what was booked with one payee over a report's date range,
+// in BaseCurrency. Unconverted counts the transactions left out of Amount
+// because no exchange rate is known for their currency.
+type PayeeTotal struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	Transactions int64  `json:"transactions"`
+	Amount       int64  `json:"amount"`
+	Unconverted  int64  `json:"unconverted"`
+}
+
+// PayeeReportFilter selects the transactions of a top payees report. From and
+// To are inclusive dates and may be empty.
+type PayeeReportFilter struct {
+	From  string
+	To    string
+	Kind  string
+	Limit int
+}
+
+type PayeeStore struct {
+	db *sql.DB
+}
+
+const payeeColumns = `p.id, p.name, ARRAY(SELECT a.alias FROM payee_aliases a WHERE a.payee_id = p.id ORDER BY a.alias), p.created_at, p.updated_at`
+
+// Create saves the payee and its aliases. ErrConflict is returned when the
+// name or one of the aliases is already taken.
+func (s *PayeeStore) Create(ctx context.Context, payee *Payee) error {
+	query := `INSERT INTO payees (name) VALUES ($1::text) RETURNING id, created_at, updated_at`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, query, payee.Name).Scan(
+			&payee.ID,
+			&payee.CreatedAt,
+			&payee.UpdatedAt,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		if err := setPayeeAliases(ctx, tx, payee); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditPayee, AuditCreate, nil, payee.ID)
+	})
+}
+
+func (s *PayeeStore) Index(ctx context.Context) ([]Payee, error) {
+	query := `SELECT ` + payeeColumns + ` FROM payees p ORDER BY p.name ASC`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payees []Payee
+	for rows.Next() {
+		var payee Payee
+		if err := rows.Scan(payeeFields(&payee)...); err != nil {
+			return nil, err
+		}
+		payees = append(payees, payee)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payees, nil
+}
+
+func (s *PayeeStore) GetByID(ctx context.Context, id int64) (*Payee, error) {
+	query := `SELECT ` + payeeColumns + ` FROM payees p WHERE p.id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var payee Payee
+	err := s.db.QueryRowContext(ctx, query, id).Scan(payeeFields(&payee)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &payee, nil
+}
+
+// Update saves the name and replaces the aliases of the payee.
+func (s *PayeeStore) Update(ctx context.Context, payee *Payee) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditPayee, payee.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := updatePayee(ctx, tx, payee); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditPayee, AuditUpdate, before, payee.ID)
+	})
+}
+
+// Delete removes the payee and its aliases. Its transactions, including
+// those in the trash, are kept without a payee; they get a new version and
+// are audited like any other change. ErrLocked is returned when any of them
+// is reconciled, as clearing their payee would change them.
+func (s *PayeeStore) Delete(ctx context.Context, id int64) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditPayee, id)
+		if err != nil {
+			return err
+		}
+
+		if err := movePayeeTransactions(ctx, tx, []int64{id}, nil); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM payees WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditPayee, AuditDelete, before, id)
+	})
+}
+
+// Merge moves the transactions of the source payees to the target and
+// deletes the sources, then saves the target, whose aliases are expected to
+// already include those of the sources. The moved transactions, including
+// those in the trash so that they come back with the target when restored,
+// get a new version and are audited like any other change. ErrNotFound is
+// returned when any of the payees does not exist, and ErrLocked when any of
+// the transactions to move is reconciled.
+func (s *PayeeStore) Merge(ctx context.Context, target *Payee, sourceIDs []int64) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditPayee, append([]int64{target.ID}, sourceIDs...)...)
+		if err != nil {
+			return err
+		}
+
+		if err := movePayeeTransactions(ctx, tx, sourceIDs, &target.ID); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `DELETE FROM payees WHERE id = ANY($1::bigint[]) AND id <> $2 RETURNING id`, pq.Array(sourceIDs), target.ID)
+		if err != nil {
+			return err
+		}
+		deleted, err := scanIDs(rows)
+		if err != nil {
+			return err
+		}
+
+		unique := map[int64]bool{}
+		for _, id := range sourceIDs {
+			unique[id] = true
+		}
+		if len(deleted) != len(unique) {
+			return ErrNotFound
+		}
+
+		if err := updatePayee(ctx, tx, target); err != nil {
+			return err
+		}
+
+		if err := recordChanges(ctx, tx, AuditPayee, AuditDelete, before, deleted...); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditPayee, AuditUpdate, before, target.ID)
+	})
+}
+
+// movePayeeTransactions points every transaction of the given payees at
+// payeeID, or at no payee when it is nil, giving each a new version and an
+// audit entry. ErrLocked is returned when any of them is reconciled.
+func movePayeeTransactions(ctx context.Context, tx *sql.Tx, payeeIDs []int64, payeeID *int64) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, status FROM transactions WHERE payee_id = ANY($1::bigint[]) ORDER BY id FOR UPDATE`,
+		pq.Array(payeeIDs),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var moved []int64
+	for rows.Next() {
+		var id int64
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return err
+		}
+		if status == StatusReconciled {
+			return ErrLocked
+		}
+		moved = append(moved, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	before, err := snapshotRows(ctx, tx, AuditTransaction, moved...)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE transactions SET payee_id = $1, updated_at = NOW(), version = version + 1 WHERE id = ANY($2::bigint[])`,
+		payeeID, pq.Array(moved),
+	)
+	if err != nil {
+		return err
+	}
+
+	return recordChanges(ctx, tx, AuditTransaction, AuditUpdate, before, moved...)
+}
+
+// Top returns the payees with the largest totals of the given kind over the
+// filter's date range, largest first.
+func (s *PayeeStore) Top(ctx context.Context, filter PayeeReportFilter) ([]PayeeTotal, error) {
+	query := `
+		SELECT p.id, p.name, COUNT(*), COALESCE(SUM(cv.amount), 0) * $3::bigint AS amount, COUNT(*) FILTER (WHERE cv.amount IS NULL)
+		FROM transactions t
+		` + convertedAmountJoin(4) + `
+		JOIN payees p
+			ON p.id = t.payee_id
+		WHERE t.kind = $1::text
+			AND t.deleted_at IS NULL
+			AND ($5::text = '' OR t.date >= $5::date)
+			AND ($6::text = '' OR t.date < $6::date + INTERVAL '1 day')
+		GROUP BY p.id, p.name
+		ORDER BY amount DESC, p.id ASC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, filter.Kind, filter.Limit, kindSign(filter.Kind), BaseCurrency, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []PayeeTotal{}
+	for rows.Next() {
+		var total PayeeTotal
+		if err := rows.Scan(
+			&total.ID,
+			&total.Name,
+			&total.Transactions,
+			&total.Amount,
+			&total.Unconverted,
+		); err != nil {
+			return nil, err
+		}
+		totals = append(totals, total)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+func updatePayee(ctx context.Context, tx *sql.Tx, payee *Payee) error {
+	err := tx.QueryRowContext(ctx,
+		`UPDATE payees SET name = $1::text, updated_at = NOW() WHERE id = $2 RETURNING updated_at`,
+		payee.Name, payee.ID,
+	).Scan(&payee.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		case isUniqueViolation(err):
+			return ErrConflict
+		default:
+			return err
+		}
+	}
+
+	return setPayeeAliases(ctx, tx, payee)
+}
+
+// setPayeeAliases replaces the aliases of the payee with payee.Aliases.
+func setPayeeAliases(ctx context.Context, tx *sql.Tx, payee *Payee) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM payee_aliases WHERE payee_id = $1`, payee.ID); err != nil {
+		return err
+	}
+
+	if payee.Aliases == nil {
+		payee.Aliases = []string{}
+	}
+	if len(payee.Aliases) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO payee_aliases (payee_id, alias) SELECT $1, unnest($2::text[])`,
+		payee.ID, pq.Array(payee.Aliases),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+func payeeFields(payee *Payee) []any {
+	return []any{
+		&payee.ID,
+		&payee.Name,
+		pq.Array(&payee.Aliases),
+		&payee.CreatedAt,
+		&payee.UpdatedAt,
+	}
+}
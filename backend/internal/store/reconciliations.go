@@ -0,0 +1,437 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// A transaction is cleared once it shows up on a bank statement, and
+// reconciled once a reconciliation covering it is completed. Reconciled
+// transactions can no longer be changed or deleted.
+const (
+	StatusUncleared  = "uncleared"
+	StatusCleared    = "cleared"
+	StatusReconciled = "reconciled"
+)
+
+const (
+	ReconciliationOpen      = "open"
+	ReconciliationCompleted = "completed"
+)
+
+// Reconciliation checks the ledger of an account against a bank statement
+// ending on StatementDate. AdjustmentTransactionID is the transaction booked
+// on completion to make up for a difference, if there was one.
+type Reconciliation struct {
+	ID                      int64   `json:"id"`
+	AccountID               int64   `json:"account_id"`
+	StatementDate           string  `json:"statement_date"`
+	StatementBalance        int64   `json:"statement_balance"`
+	Status                  string  `json:"status"`
+	AdjustmentTransactionID *int64  `json:"adjustment_transaction_id"`
+	CreatedAt               string  `json:"created_at"`
+	UpdatedAt               string  `json:"updated_at"`
+	CompletedAt             *string `json:"completed_at"`
+}
+
+// ReconciliationItem is a transaction of the reconciled account up to the
+// statement date that is not reconciled yet.
+type ReconciliationItem struct {
+	ID          int64  `json:"id"`
+	Date        string `json:"date"`
+	Description string `json:"description"`
+	Kind        string `json:"kind"`
+	Amount      int64  `json:"amount"`
+	Status      string `json:"status"`
+}
+
+// BalanceDifferenceError is returned when a reconciliation is completed while
+// the ledger balance at the statement date differs from the statement
+// balance and no adjustment was asked for. Difference is what the statement
+// balance is above the ledger balance.
+type BalanceDifferenceError struct {
+	Difference int64
+}
+
+func (e *BalanceDifferenceError) Error() string {
+	return fmt.Sprintf("the statement balance differs from the ledger balance by %d", e.Difference)
+}
+
+type ReconciliationStore struct {
+	db *sql.DB
+}
+
+const reconciliationColumns = `id, account_id, statement_date, statement_balance, status, adjustment_transaction_id, created_at, updated_at, completed_at`
+
+// Create starts a reconciliation. ErrConflict is returned when the account
+// already has an open one.
+func (s *ReconciliationStore) Create(ctx context.Context, reconciliation *Reconciliation) error {
+	query := `
+		INSERT INTO reconciliations (account_id, statement_date, statement_balance)
+		VALUES ($1::bigint, $2::date, $3::bigint) RETURNING ` + reconciliationColumns
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			reconciliation.AccountID,
+			reconciliation.StatementDate,
+			reconciliation.StatementBalance,
+		).Scan(reconciliationFields(reconciliation)...)
+		if err != nil {
+			switch {
+			case isForeignKeyViolation(err):
+				return ErrInvalidReference
+			case isUniqueViolation(err):
+				return ErrConflict
+			default:
+				return err
+			}
+		}
+
+		return recordChanges(ctx, tx, AuditReconciliation, AuditCreate, nil, reconciliation.ID)
+	})
+}
+
+// Index returns the reconciliations of one account, or of all accounts when
+// accountID is 0, latest statement first.
+func (s *ReconciliationStore) Index(ctx context.Context, accountID int64) ([]Reconciliation, error) {
+	query := `
+		SELECT ` + reconciliationColumns + `
+		FROM reconciliations
+		WHERE $1::bigint = 0 OR account_id = $1::bigint
+		ORDER BY statement_date DESC, id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reconciliations []Reconciliation
+	for rows.Next() {
+		var reconciliation Reconciliation
+		if err := rows.Scan(reconciliationFields(&reconciliation)...); err != nil {
+			return nil, err
+		}
+		reconciliations = append(reconciliations, reconciliation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reconciliations, nil
+}
+
+func (s *ReconciliationStore) GetByID(ctx context.Context, id int64) (*Reconciliation, error) {
+	query := `SELECT ` + reconciliationColumns + ` FROM reconciliations WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var reconciliation Reconciliation
+	err := s.db.QueryRowContext(ctx, query, id).Scan(reconciliationFields(&reconciliation)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &reconciliation, nil
+}
+
+// Items returns the transactions of the reconciled account dated up to the
+// statement date that are not reconciled yet, oldest first.
+func (s *ReconciliationStore) Items(ctx context.Context, reconciliation *Reconciliation) ([]ReconciliationItem, error) {
+	query := `
+		SELECT id, date, description, kind, amount, status
+		FROM transactions
+		WHERE account_id = $1 AND date < $2::date + INTERVAL '1 day' AND status <> $3 AND deleted_at IS NULL
+		ORDER BY date ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, reconciliation.AccountID, reconciliation.StatementDate, StatusReconciled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []ReconciliationItem{}
+	for rows.Next() {
+		var item ReconciliationItem
+		var date time.Time
+		if err := rows.Scan(
+			&item.ID,
+			&date,
+			&item.Description,
+			&item.Kind,
+			&item.Amount,
+			&item.Status,
+		); err != nil {
+			return nil, err
+		}
+		item.Date = date.Format(time.RFC3339)
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// SetCleared marks transactions as cleared or back as uncleared. Every
+// transaction must be one of the items of the reconciliation, otherwise
+// ErrInvalidReference is returned and nothing changes. ErrConflict is
+// returned when the reconciliation is no longer open. The status is part of
+// the transaction, so its version moves on and edits made on an earlier read
+// fail.
+func (s *ReconciliationStore) SetCleared(ctx context.Context, reconciliation *Reconciliation, ids []int64, cleared bool) error {
+	status := StatusUncleared
+	if cleared {
+		status = StatusCleared
+	}
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockOpenReconciliation(ctx, tx, reconciliation.ID); err != nil {
+			return err
+		}
+
+		before, err := snapshotRows(ctx, tx, AuditTransaction, ids...)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			UPDATE transactions
+			SET status = $1, updated_at = NOW(), version = version + 1
+			WHERE id = ANY($2::bigint[]) AND account_id = $3 AND date < $4::date + INTERVAL '1 day' AND status <> $5 AND deleted_at IS NULL
+			RETURNING id
+		`, status, pq.Array(ids), reconciliation.AccountID, reconciliation.StatementDate, StatusReconciled)
+		if err != nil {
+			return err
+		}
+		updated, err := scanIDs(rows)
+		if err != nil {
+			return err
+		}
+
+		want := map[int64]bool{}
+		for _, id := range ids {
+			want[id] = true
+		}
+		if len(updated) != len(want) {
+			return ErrInvalidReference
+		}
+
+		return recordChanges(ctx, tx, AuditTransaction, AuditUpdate, before, updated...)
+	})
+}
+
+// Complete reconciles the cleared transactions of the account up to the
+// statement date and closes the reconciliation. The ledger balance is compared
+// with the statement balance once the account is locked, so no other write
+// can move it before the reconciliation commits. When they differ, adjustment
+// is booked with the amount that makes up the difference and reconciled along
+// with the transactions, or a *BalanceDifferenceError is returned when
+// adjustment is nil.
+func (s *ReconciliationStore) Complete(ctx context.Context, reconciliation *Reconciliation, adjustment *Transaction) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockOpenReconciliation(ctx, tx, reconciliation.ID); err != nil {
+			return err
+		}
+
+		// the account is locked before its transactions, as every writer
+		// does, so the balance read below is final until commit
+		locks := accountLocks{}
+		if err := locks.lock(ctx, tx, reconciliation.AccountID); err != nil {
+			return err
+		}
+
+		balance, err := balanceByDate(ctx, tx, reconciliation.StatementDate, reconciliation.AccountID)
+		if err != nil {
+			return err
+		}
+
+		var adjustmentID *int64
+		if difference := reconciliation.StatementBalance - balance; difference != 0 {
+			if adjustment == nil {
+				return &BalanceDifferenceError{Difference: difference}
+			}
+
+			// running balances subtract amounts, so raising the balance to
+			// the statement takes a negative amount
+			adjustment.Amount = -difference
+
+			changes := ledgerChanges{}
+			if err := insertTransaction(ctx, tx, adjustment, changes, locks); err != nil {
+				return err
+			}
+			if err := changes.apply(ctx, tx, locks); err != nil {
+				return err
+			}
+			if err := recordChanges(ctx, tx, AuditTransaction, AuditCreate, nil, adjustment.ID); err != nil {
+				return err
+			}
+			adjustmentID = &adjustment.ID
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id
+			FROM transactions
+			WHERE account_id = $1 AND date < $2::date + INTERVAL '1 day' AND (status = $3 OR id = $4) AND deleted_at IS NULL
+			ORDER BY id
+			FOR UPDATE
+		`, reconciliation.AccountID, reconciliation.StatementDate, StatusCleared, adjustmentID)
+		if err != nil {
+			return err
+		}
+		ids, err := scanIDs(rows)
+		if err != nil {
+			return err
+		}
+
+		before, err := snapshotRows(ctx, tx, AuditTransaction, ids...)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE transactions SET status = $1, reconciliation_id = $2, updated_at = NOW(), version = version + 1 WHERE id = ANY($3::bigint[])`,
+			StatusReconciled, reconciliation.ID, pq.Array(ids),
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := recordChanges(ctx, tx, AuditTransaction, AuditUpdate, before, ids...); err != nil {
+			return err
+		}
+
+		before, err = snapshotRows(ctx, tx, AuditReconciliation, reconciliation.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			UPDATE reconciliations
+			SET status = $1, adjustment_transaction_id = $2, completed_at = NOW(), updated_at = NOW()
+			WHERE id = $3
+			RETURNING `+reconciliationColumns,
+			ReconciliationCompleted, adjustmentID, reconciliation.ID,
+		).Scan(reconciliationFields(reconciliation)...)
+		if err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditReconciliation, AuditUpdate, before, reconciliation.ID)
+	})
+}
+
+// Delete abandons an open reconciliation. Cleared transactions stay cleared,
+// and completed reconciliations cannot be deleted and return ErrConflict.
+func (s *ReconciliationStore) Delete(ctx context.Context, id int64) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := lockOpenReconciliation(ctx, tx, id); err != nil {
+			return err
+		}
+
+		before, err := snapshotRows(ctx, tx, AuditReconciliation, id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM reconciliations WHERE id = $1`, id); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditReconciliation, AuditDelete, before, id)
+	})
+}
+
+// lockOpenReconciliation locks the reconciliation for the rest of the
+// database transaction, returning ErrConflict when it is already completed.
+func lockOpenReconciliation(ctx context.Context, tx *sql.Tx, id int64) error {
+	var status string
+	err := tx.QueryRowContext(ctx, `SELECT status FROM reconciliations WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	if status != ReconciliationOpen {
+		return ErrConflict
+	}
+	return nil
+}
+
+// checkUnlocked locks the transaction for the rest of the database
+// transaction and returns ErrLocked when it is reconciled.
+func checkUnlocked(ctx context.Context, tx *sql.Tx, id int64) error {
+	var status string
+	err := tx.QueryRowContext(ctx,
+		`SELECT status FROM transactions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		id,
+	).Scan(&status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	if status == StatusReconciled {
+		return ErrLocked
+	}
+	return nil
+}
+
+func scanIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func reconciliationFields(reconciliation *Reconciliation) []any {
+	return []any{
+		&reconciliation.ID,
+		&reconciliation.AccountID,
+		&reconciliation.StatementDate,
+		&reconciliation.StatementBalance,
+		&reconciliation.Status,
+		&reconciliation.AdjustmentTransactionID,
+		&reconciliation.CreatedAt,
+		&reconciliation.UpdatedAt,
+		&reconciliation.CompletedAt,
+	}
+}
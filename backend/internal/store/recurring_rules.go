@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RecurringRule describes a transaction that repeats on a schedule. NextRun
+// is the next occurrence still to be materialized and is nil once the
+// schedule has ended.
+type RecurringRule struct {
+	ID          int64      `json:"id"`
+	AccountID   int64      `json:"account_id"`
+	CategoryID  *int64     `json:"category_id"`
+	Amount      int64      `json:"amount"`
+	Description string     `json:"description"`
+	Kind        string     `json:"kind"`
+	RRule       string     `json:"rrule"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+	NextRun     *time.Time `json:"next_run"`
+	CreatedAt   string     `json:"created_at"`
+	UpdatedAt   string     `json:"updated_at"`
+}
+
+type RecurringRuleStore struct {
+	db *sql.DB
+}
+
+const recurringRuleColumns = `id, account_id, category_id, amount, description, kind, rrule, start_date, end_date, next_run, created_at, updated_at`
+
+func (s *RecurringRuleStore) Create(ctx context.Context, rule *RecurringRule) error {
+	query := `
+		INSERT INTO recurring_rules (account_id, category_id, amount, description, kind, rrule, start_date, end_date, next_run)
+		VALUES ($1::bigint, $2, $3::bigint, $4::text, $5::text, $6::text, $7::timestamptz, $8, $9) RETURNING id, created_at, updated_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			rule.AccountID,
+			rule.CategoryID,
+			rule.Amount,
+			rule.Description,
+			rule.Kind,
+			rule.RRule,
+			rule.StartDate,
+			rule.EndDate,
+			rule.NextRun,
+		).Scan(
+			&rule.ID,
+			&rule.CreatedAt,
+			&rule.UpdatedAt,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidReference
+			}
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditRecurringRule, AuditCreate, nil, rule.ID)
+	})
+}
+
+func (s *RecurringRuleStore) Index(ctx context.Context) ([]RecurringRule, error) {
+	query := `SELECT ` + recurringRuleColumns + ` FROM recurring_rules ORDER BY id ASC`
+
+	return s.index(ctx, query)
+}
+
+// Due returns the rules with an occurrence at or before now.
+func (s *RecurringRuleStore) Due(ctx context.Context, now time.Time) ([]RecurringRule, error) {
+	query := `SELECT ` + recurringRuleColumns + ` FROM recurring_rules WHERE next_run <= $1 ORDER BY next_run ASC, id ASC`
+
+	return s.index(ctx, query, now)
+}
+
+func (s *RecurringRuleStore) index(ctx context.Context, query string, args ...any) ([]RecurringRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []RecurringRule
+	for rows.Next() {
+		var rule RecurringRule
+		if err := rows.Scan(recurringRuleFields(&rule)...); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (s *RecurringRuleStore) GetByID(ctx context.Context, id int64) (*RecurringRule, error) {
+	query := `SELECT ` + recurringRuleColumns + ` FROM recurring_rules WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var rule RecurringRule
+	err := s.db.QueryRowContext(ctx, query, id).Scan(recurringRuleFields(&rule)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rule, nil
+}
+
+func (s *RecurringRuleStore) Update(ctx context.Context, rule *RecurringRule) error {
+	query := `
+		UPDATE recurring_rules
+		SET account_id = $1::bigint, category_id = $2, amount = $3::bigint, description = $4::text, kind = $5::text,
+			rrule = $6::text, start_date = $7::timestamptz, end_date = $8, next_run = $9, updated_at = NOW()
+		WHERE id = $10::bigint
+		RETURNING updated_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditRecurringRule, rule.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(
+			ctx,
+			query,
+			rule.AccountID,
+			rule.CategoryID,
+			rule.Amount,
+			rule.Description,
+			rule.Kind,
+			rule.RRule,
+			rule.StartDate,
+			rule.EndDate,
+			rule.NextRun,
+			rule.ID,
+		).Scan(&rule.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			case isForeignKeyViolation(err):
+				return ErrInvalidReference
+			default:
+				return err
+			}
+		}
+
+		return recordChanges(ctx, tx, AuditRecurringRule, AuditUpdate, before, rule.ID)
+	})
+}
+
+// SetNextRun advances a rule after its due occurrences were materialized.
+func (s *RecurringRuleStore) SetNextRun(ctx context.Context, id int64, nextRun *time.Time) error {
+	query := `UPDATE recurring_rules SET next_run = $1 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, nextRun, id)
+	return err
+}
+
+func (s *RecurringRuleStore) Delete(ctx context.Context, id int64) error {
+	query := `DELETE FROM recurring_rules WHERE id = $1`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditRecurringRule, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditRecurringRule, AuditDelete, before, id)
+	})
+}
+
+func recurringRuleFields(rule *RecurringRule) []any {
+	return []any{
+		&rule.ID,
+		&rule.AccountID,
+		&rule.CategoryID,
+		&rule.Amount,
+		&rule.Description,
+		&rule.Kind,
+		&rule.RRule,
+		&rule.StartDate,
+		&rule.EndDate,
+		&rule.NextRun,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	}
+}
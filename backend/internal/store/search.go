@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"time"
+)
+
+const (
+	SearchHitTransaction  = "transaction"
+	SearchHitEventExpense = "event_expense"
+)
+
+// SearchHit is a single ranked match. EventID and EventName are only set for
+// event expenses. Snippet is the description escaped as HTML, with matched
+// words wrapped in <mark> tags.
+type SearchHit struct {
+	Type        string  `json:"type"`
+	ID          int64   `json:"id"`
+	EventID     int64   `json:"event_id,omitempty"`
+	EventName   string  `json:"event_name,omitempty"`
+	Description string  `json:"description"`
+	Snippet     string  `json:"snippet"`
+	Amount      int64   `json:"amount"`
+	Date        string  `json:"date"`
+	Rank        float64 `json:"rank"`
+}
+
+// ts_headline does not escape the text around its selections, so matches
+// are marked with private use characters and the snippet is escaped before
+// the marks become <mark> tags.
+const (
+	headlineStart   = "\uE000"
+	headlineStop    = "\uE001"
+	headlineOptions = `StartSel="` + headlineStart + `", StopSel="` + headlineStop + `", HighlightAll=true`
+)
+
+var headlineMarks = strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>")
+
+// highlight turns a ts_headline snippet into HTML.
+func highlight(snippet string) string {
+	return headlineMarks.Replace(html.EscapeString(snippet))
+}
+
+type SearchStore struct {
+	db *sql.DB
+}
+
+// Find runs a full-text search over transaction and event expense
+// descriptions, falling back to trigram word similarity for typos and
+// partial words.
+func (s *SearchStore) Find(ctx context.Context, term string, limit int) ([]SearchHit, error) {
+	query := `
+		WITH q AS (
+			SELECT websearch_to_tsquery('simple', $1) AS tsq
+		)
+		SELECT type, id, event_id, event_name, description, snippet, amount, date, rank
+		FROM (
+			SELECT
+				'transaction' AS type,
+				t.id,
+				0::bigint AS event_id,
+				'' AS event_name,
+				t.description,
+				ts_headline('simple', t.description, q.tsq, $3) AS snippet,
+				t.amount::bigint AS amount,
+				t.date,
+				GREATEST(ts_rank(to_tsvector('simple', t.description), q.tsq), word_similarity($1, t.description)) AS rank
+			FROM transactions t, q
+			WHERE (to_tsvector('simple', t.description) @@ q.tsq OR $1 <% t.description)
+				AND t.deleted_at IS NULL
+
+			UNION ALL
+
+			SELECT
+				'event_expense' AS type,
+				ee.id,
+				ee.event_id,
+				e.name AS event_name,
+				ee.description,
+				ts_headline('simple', ee.description, q.tsq, $3) AS snippet,
+				ee.amount::bigint AS amount,
+				e.date::timestamptz AS date,
+				GREATEST(ts_rank(to_tsvector('simple', ee.description), q.tsq), word_similarity($1, ee.description)) AS rank
+			FROM event_expenses ee
+			JOIN events e ON e.id = ee.event_id, q
+			WHERE (to_tsvector('simple', ee.description) @@ q.tsq OR $1 <% ee.description)
+				AND e.deleted_at IS NULL
+		) hits
+		ORDER BY rank DESC, date DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, term, limit, headlineOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+
+	for rows.Next() {
+		var hit SearchHit
+		var date time.Time
+		if err := rows.Scan(
+			&hit.Type,
+			&hit.ID,
+			&hit.EventID,
+			&hit.EventName,
+			&hit.Description,
+			&hit.Snippet,
+			&hit.Amount,
+			&date,
+			&hit.Rank,
+		); err != nil {
+			return nil, err
+		}
+		hit.Date = date.Format(time.RFC3339)
+		hit.Snippet = highlight(hit.Snippet)
+		hits = append(hits, hit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
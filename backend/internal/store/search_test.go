@@ -0,0 +1,23 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SearchTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SearchTestSuite) TestHighlight() {
+	snippet := headlineStart + "Tokopedia" + headlineStop + ` <img src=x onerror="alert(1)"> & co`
+
+	assert.Equal(suite.T(), `<mark>Tokopedia</mark> &lt;img src=x onerror=&#34;alert(1)&#34;&gt; &amp; co`, highlight(snippet))
+	assert.Equal(suite.T(), "plain", highlight("plain"))
+}
+
+func TestSearchTestSuite(t *testing.T) {
+	suite.Run(t, new(SearchTestSuite))
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// Split is one line of a transaction that spans several categories. The
+// amounts of all splits add up to the parent transaction's amount.
+type Split struct {
+	ID         int64  `json:"id"`
+	CategoryID *int64 `json:"category_id"`
+	Amount     int64  `json:"amount"`
+	Note       string `json:"note"`
+}
+
+// transactionLines yields one row per category line: the splits of split
+// transactions and the transaction itself otherwise. It exposes the columns
+// of the transactions table that category breakdowns need, so it can stand in
+// for "transactions t", and leaves out transactions in the trash.
+const transactionLines = `(
+		SELECT t.id, t.account_id, t.date, t.kind, t.amount, t.currency, t.category_id
+		FROM transactions t
+		WHERE NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+			AND t.deleted_at IS NULL
+		UNION ALL
+		SELECT t.id, t.account_id, t.date, t.kind, s.amount, t.currency, s.category_id
+		FROM transactions t
+		JOIN transaction_splits s
+			ON s.transaction_id = t.id
+		WHERE t.deleted_at IS NULL
+	)`
+
+const transactionSplitsColumn = `
+	COALESCE((
+		SELECT json_agg(json_build_object('id', s.id, 'category_id', s.category_id, 'amount', s.amount, 'note', s.note) ORDER BY s.id)
+		FROM transaction_splits s
+		WHERE s.transaction_id = t.id
+	), '[]')
+`
+
+func decodeSplits(raw []byte) ([]Split, error) {
+	splits := []Split{}
+	if err := json.Unmarshal(raw, &splits); err != nil {
+		return nil, err
+	}
+	return splits, nil
+}
+
+// SplitsTotal returns the sum of the split amounts.
+func SplitsTotal(splits []Split) int64 {
+	var total int64
+	for _, split := range splits {
+		total += split.Amount
+	}
+	return total
+}
+
+func setTransactionSplits(ctx context.Context, tx *sql.Tx, transaction *Transaction) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transaction_splits WHERE transaction_id = $1`, transaction.ID); err != nil {
+		return err
+	}
+
+	for i := range transaction.Splits {
+		split := &transaction.Splits[i]
+		// like the category of the transaction, a split category cannot be
+		// in the trash
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO transaction_splits (transaction_id, category_id, amount, note)
+			SELECT $1::bigint, $2::bigint, $3::bigint, $4::text
+			WHERE NOT EXISTS (SELECT 1 FROM categories WHERE id = $2::bigint AND deleted_at IS NOT NULL)
+			RETURNING id
+		`, transaction.ID, split.CategoryID, split.Amount, split.Note,
+		).Scan(&split.ID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || isForeignKeyViolation(err) {
+				return ErrInvalidReference
+			}
+			return err
+		}
+	}
+
+	if transaction.Splits == nil {
+		transaction.Splits = []Split{}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SplitTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SplitTestSuite) TestSplitsTotal() {
+	groceries := int64(1)
+	splits := []Split{
+		{CategoryID: &groceries, Amount: 180000},
+		{Amount: 50000, Note: "detergent"},
+	}
+
+	assert.Equal(suite.T(), int64(230000), SplitsTotal(splits))
+	assert.Zero(suite.T(), SplitsTotal(nil))
+}
+
+func (suite *SplitTestSuite) TestDecodeSplits() {
+	splits, err := decodeSplits([]byte(`[{"id":1,"category_id":3,"amount":180000,"note":""},{"id":2,"category_id":null,"amount":20000,"note":"card"}]`))
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), splits, 2)
+	assert.Equal(suite.T(), int64(3), *splits[0].CategoryID)
+	assert.Nil(suite.T(), splits[1].CategoryID)
+	assert.Equal(suite.T(), "card", splits[1].Note)
+}
+
+func (suite *SplitTestSuite) TestTransactionLinesColumns() {
+	// breakdown queries alias the lines as "t" in place of the table
+	for _, column := range []string{"t.id", "t.date", "t.kind", "t.currency", "s.amount", "s.category_id"} {
+		assert.Contains(suite.T(), transactionLines, column)
+	}
+}
+
+func TestSplitTestSuite(t *testing.T) {
+	suite.Run(t, new(SplitTestSuite))
+}
@@ -5,30 +5,107 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 var (
 	ErrNotFound          = errors.New("resource not found")
+	ErrConflict          = errors.New("resource conflicts with existing data")
+	ErrInvalidReference  = errors.New("referenced resource does not exist")
+	ErrKeyReused         = errors.New("idempotency key was already used for a different request")
+	ErrVersionMismatch   = errors.New("resource was changed since it was read")
+	ErrLocked            = errors.New("resource is reconciled and can no longer be changed")
+	ErrCurrencyInUse     = errors.New("the currency of an account with transactions cannot be changed")
 	QueryTimeoutDuration = time.Second * 5
+	// BatchTimeoutDuration bounds the database transactions that write many
+	// rows at once, such as imports.
+	BatchTimeoutDuration = time.Minute * 2
+	// BaseCurrency is the currency that aggregates over several currencies
+	// are converted to.
+	BaseCurrency = "IDR"
 )
 
 type Storage struct {
 	Transactions interface {
 		GetById(context.Context, int64) (*Transaction, error)
-		GetLast(context.Context) (*Transaction, error)
-		GetExpensesByMonth(context.Context, string) (int64, error)
-		GetExpensesByMonthRange(context.Context, string) (int64, error)
-		GetExpensesByMonthCategory(context.Context, string) ([]CategoryReturnValue, error)
-		GetExpensesLast30Days(context.Context) ([]AmountDaily, error)
-		GetBalanceByDate(context.Context, string) (int64, error)
-		Index(context.Context) ([]TransactionGet, error)
+		GetExpensesByMonth(context.Context, string, string) (ConvertedTotal, error)
+		GetExpensesByMonthRange(context.Context, string, string) (ConvertedTotal, error)
+		GetExpensesByMonthCategory(context.Context, string, string) ([]CategoryReturnValue, error)
+		GetExpensesByMonthTag(context.Context, string, string) ([]TagReturnValue, error)
+		GetExpensesLast30Days(context.Context, string) ([]AmountDaily, error)
+		GetBalanceByDate(context.Context, string, int64) (int64, error)
+		GetFingerprints(context.Context, int64, time.Time, time.Time, []string) ([]TransactionFingerprint, error)
+		Index(context.Context, TransactionFilter) ([]TransactionGet, *TransactionCursor, error)
+		Export(context.Context, TransactionFilter, func(*TransactionExport) error) error
 		Create(context.Context, *Transaction) error
+		CreateBatch(context.Context, []*Transaction) error
+		Batch(context.Context, []TransactionOperation) error
+		ApplyRuleChanges(context.Context, []RuleChange) ([]int64, error)
+		Delete(context.Context, int64, int64) error
+		Update(context.Context, *Transaction) error
+	}
+	Accounts interface {
+		Create(context.Context, *Account) error
+		Index(context.Context) ([]Account, error)
+		GetByID(context.Context, int64) (*Account, error)
+		GetDefault(context.Context) (*Account, error)
+		Update(context.Context, *Account) error
 		Delete(context.Context, int64) error
-		UpdateWithCascade(context.Context, *Transaction, int64) error
+		GetBalances(context.Context) ([]AccountBalance, error)
 	}
 	Categories interface {
 		Create(context.Context, *Category) error
 		Index(context.Context) ([]Category, error)
+		GetByID(context.Context, int64) (*Category, error)
+		Delete(context.Context, int64) error
+	}
+	Search interface {
+		Find(context.Context, string, int) ([]SearchHit, error)
+	}
+	Tags interface {
+		Create(context.Context, *Tag) error
+		Index(context.Context) ([]Tag, error)
+		GetByID(context.Context, int64) (*Tag, error)
+		Update(context.Context, *Tag) error
+		Delete(context.Context, int64) error
+	}
+	Attachments interface {
+		Create(context.Context, *Attachment) error
+		GetByID(context.Context, int64) (*Attachment, error)
+		IndexByTransaction(context.Context, int64) ([]Attachment, error)
+		IndexByEventExpense(context.Context, int64) ([]Attachment, error)
+		Delete(context.Context, int64) error
+	}
+	RecurringRules interface {
+		Create(context.Context, *RecurringRule) error
+		Index(context.Context) ([]RecurringRule, error)
+		Due(context.Context, time.Time) ([]RecurringRule, error)
+		GetByID(context.Context, int64) (*RecurringRule, error)
+		Update(context.Context, *RecurringRule) error
+		SetNextRun(context.Context, int64, *time.Time) error
+		Delete(context.Context, int64) error
+	}
+	ImportProfiles interface {
+		Create(context.Context, *ImportProfile) error
+		Index(context.Context) ([]ImportProfile, error)
+		GetByID(context.Context, int64) (*ImportProfile, error)
+		Update(context.Context, *ImportProfile) error
+		Delete(context.Context, int64) error
+	}
+	CategorizationRules interface {
+		Create(context.Context, *CategorizationRule) error
+		Index(context.Context) ([]CategorizationRule, error)
+		Enabled(context.Context) ([]CategorizationRule, error)
+		GetByID(context.Context, int64) (*CategorizationRule, error)
+		Update(context.Context, *CategorizationRule) error
+		Delete(context.Context, int64) error
+	}
+	ExchangeRates interface {
+		Upsert(context.Context, []ExchangeRate) error
+		Index(context.Context, ExchangeRateFilter) ([]ExchangeRate, error)
+		GetByID(context.Context, int64) (*ExchangeRate, error)
+		Delete(context.Context, int64) error
 	}
 	Users interface {
 		Upsert(context.Context, *User) error
@@ -39,16 +116,76 @@ type Storage struct {
 		GetAll(context.Context) ([]EventSummary, error)
 		GetByID(context.Context, int64) (*Event, error)
 		GetEventExpenses(context.Context, int64) ([]EventExpense, error)
+		GetExpenseByID(context.Context, int64) (*EventExpense, error)
 		CreateExpense(context.Context, *EventExpense) error
+		Export(context.Context, func(*EventExport) error) error
+		Delete(context.Context, int64, int64) error
+	}
+	Trash interface {
+		Index(context.Context) ([]TrashItem, error)
+		Restore(context.Context, string, int64) error
+		Purge(context.Context, time.Time) (*PurgeResult, error)
+	}
+	Audit interface {
+		Index(context.Context, AuditFilter) ([]AuditEntry, *int64, error)
+	}
+	Ledger interface {
+		Check(context.Context, bool) (*LedgerReport, error)
+	}
+	Reconciliations interface {
+		Create(context.Context, *Reconciliation) error
+		Index(context.Context, int64) ([]Reconciliation, error)
+		GetByID(context.Context, int64) (*Reconciliation, error)
+		Items(context.Context, *Reconciliation) ([]ReconciliationItem, error)
+		SetCleared(context.Context, *Reconciliation, []int64, bool) error
+		Complete(context.Context, *Reconciliation, *Transaction) error
 		Delete(context.Context, int64) error
 	}
+	Payees interface {
+		Create(context.Context, *Payee) error
+		Index(context.Context) ([]Payee, error)
+		GetByID(context.Context, int64) (*Payee, error)
+		Update(context.Context, *Payee) error
+		Delete(context.Context, int64) error
+		Merge(context.Context, *Payee, []int64) error
+		Top(context.Context, PayeeReportFilter) ([]PayeeTotal, error)
+	}
+	Idempotency interface {
+		Begin(context.Context, int64, string, string, time.Duration, time.Duration) (*IdempotentResponse, error)
+		Complete(context.Context, int64, string, IdempotentResponse) error
+		Release(context.Context, int64, string) error
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Transactions: &TransactionStore{db},
-		Categories:   &CategoryStore{db},
-		Users:        &UserStore{db},
-		Events:       &EventStore{db},
+		Transactions:        &TransactionStore{db},
+		Accounts:            &AccountStore{db},
+		Categories:          &CategoryStore{db},
+		Tags:                &TagStore{db},
+		Attachments:         &AttachmentStore{db},
+		RecurringRules:      &RecurringRuleStore{db},
+		ImportProfiles:      &ImportProfileStore{db},
+		CategorizationRules: &CategorizationRuleStore{db},
+		ExchangeRates:       &ExchangeRateStore{db},
+		Users:               &UserStore{db},
+		Search:              &SearchStore{db},
+		Events:              &EventStore{db},
+		Trash:               &TrashStore{db},
+		Audit:               &AuditStore{db},
+		Ledger:              &LedgerStore{db},
+		Reconciliations:     &ReconciliationStore{db},
+		Payees:              &PayeeStore{db},
+		Idempotency:         &IdempotencyStore{db},
 	}
 }
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
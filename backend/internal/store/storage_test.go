@@ -24,6 +24,48 @@ func (suite *StorageTestSuite) TestNewStorage() {
 	_, ok := storage.Transactions.(*TransactionStore)
 	assert.True(suite.T(), ok, "Transactions should be of type *TransactionStore")
 	
+	_, ok = storage.Accounts.(*AccountStore)
+	assert.True(suite.T(), ok, "Accounts should be of type *AccountStore")
+
+	_, ok = storage.Search.(*SearchStore)
+	assert.True(suite.T(), ok, "Search should be of type *SearchStore")
+
+	_, ok = storage.Tags.(*TagStore)
+	assert.True(suite.T(), ok, "Tags should be of type *TagStore")
+
+	_, ok = storage.Attachments.(*AttachmentStore)
+	assert.True(suite.T(), ok, "Attachments should be of type *AttachmentStore")
+
+	_, ok = storage.RecurringRules.(*RecurringRuleStore)
+	assert.True(suite.T(), ok, "RecurringRules should be of type *RecurringRuleStore")
+
+	_, ok = storage.ImportProfiles.(*ImportProfileStore)
+	assert.True(suite.T(), ok, "ImportProfiles should be of type *ImportProfileStore")
+
+	_, ok = storage.CategorizationRules.(*CategorizationRuleStore)
+	assert.True(suite.T(), ok, "CategorizationRules should be of type *CategorizationRuleStore")
+
+	_, ok = storage.ExchangeRates.(*ExchangeRateStore)
+	assert.True(suite.T(), ok, "ExchangeRates should be of type *ExchangeRateStore")
+
+	_, ok = storage.Trash.(*TrashStore)
+	assert.True(suite.T(), ok, "Trash should be of type *TrashStore")
+
+	_, ok = storage.Audit.(*AuditStore)
+	assert.True(suite.T(), ok, "Audit should be of type *AuditStore")
+
+	_, ok = storage.Ledger.(*LedgerStore)
+	assert.True(suite.T(), ok, "Ledger should be of type *LedgerStore")
+
+	_, ok = storage.Reconciliations.(*ReconciliationStore)
+	assert.True(suite.T(), ok, "Reconciliations should be of type *ReconciliationStore")
+
+	_, ok = storage.Payees.(*PayeeStore)
+	assert.True(suite.T(), ok, "Payees should be of type *PayeeStore")
+
+	_, ok = storage.Idempotency.(*IdempotencyStore)
+	assert.True(suite.T(), ok, "Idempotency should be of type *IdempotencyStore")
+
 	_, ok = storage.Categories.(*CategoryStore)
 	assert.True(suite.T(), ok, "Categories should be of type *CategoryStore")
 	
@@ -34,6 +76,7 @@ func (suite *StorageTestSuite) TestNewStorage() {
 func (suite *StorageTestSuite) TestErrorConstants() {
 	assert.Equal(suite.T(), "resource not found", ErrNotFound.Error())
 	assert.NotZero(suite.T(), QueryTimeoutDuration)
+	assert.Greater(suite.T(), BatchTimeoutDuration, QueryTimeoutDuration)
 }
 
 func TestStorageTestSuite(t *testing.T) {
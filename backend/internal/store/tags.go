@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+type Tag struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	CreatedAt string `json:"created_at,omitempty"`
+}
+
+type TagReturnValue struct {
+	Amount      int64  `json:"amount"`
+	Unconverted int64  `json:"unconverted"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	ID          int64  `json:"id"`
+}
+
+type TagStore struct {
+	db *sql.DB
+}
+
+func (s *TagStore) Create(ctx context.Context, tag *Tag) error {
+	query := `
+		INSERT INTO tags (name, color)
+		VALUES ($1::text, COALESCE(NULLIF($2::text, ''), '#666')) RETURNING id, color, created_at
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			tag.Name,
+			tag.Color,
+		).Scan(
+			&tag.ID,
+			&tag.Color,
+			&tag.CreatedAt,
+		)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditTag, AuditCreate, nil, tag.ID)
+	})
+}
+
+func (s *TagStore) Index(ctx context.Context) ([]Tag, error) {
+	query := `
+		SELECT id, name, color, created_at
+		FROM tags
+		ORDER BY name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []Tag
+
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(
+			&tag.ID,
+			&tag.Name,
+			&tag.Color,
+			&tag.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (s *TagStore) GetByID(ctx context.Context, id int64) (*Tag, error) {
+	query := `
+		SELECT id, name, color, created_at
+		FROM tags
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var tag Tag
+	err := s.db.QueryRowContext(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&tag.ID,
+		&tag.Name,
+		&tag.Color,
+		&tag.CreatedAt,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &tag, nil
+}
+
+func (s *TagStore) Update(ctx context.Context, tag *Tag) error {
+	query := `
+		UPDATE tags
+		SET name = $1::text, color = $2::text
+		WHERE id = $3::bigint
+	`
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditTag, tag.ID)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, tag.Name, tag.Color, tag.ID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, AuditTag, AuditUpdate, before, tag.ID)
+	})
+}
+
+// Delete removes the tag together with its use in categorization rules,
+// which hold tag ids in an array without a foreign key.
+func (s *TagStore) Delete(ctx context.Context, id int64) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditTag, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		query := `UPDATE categorization_rules SET tag_ids = array_remove(tag_ids, $1) WHERE $1 = ANY(tag_ids)`
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditTag, AuditDelete, before, id)
+	})
+}
+
+// transactionTagsColumn selects the tags of transaction "t" as a JSON array.
+const transactionTagsColumn = `
+	COALESCE((
+		SELECT json_agg(json_build_object('id', tg.id, 'name', tg.name, 'color', tg.color) ORDER BY tg.name)
+		FROM transaction_tags tt
+		JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tt.transaction_id = t.id
+	), '[]')
+`
+
+func decodeTags(raw []byte) ([]Tag, error) {
+	tags := []Tag{}
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// setTransactionTags replaces the tags of a transaction with the given ones
+// and reloads their names and colors.
+func setTransactionTags(ctx context.Context, tx *sql.Tx, transaction *Transaction) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transaction_tags WHERE transaction_id = $1`, transaction.ID); err != nil {
+		return err
+	}
+
+	for _, tag := range transaction.Tags {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO transaction_tags (transaction_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			transaction.ID, tag.ID,
+		)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return ErrInvalidReference
+			}
+			return err
+		}
+	}
+
+	var raw []byte
+	query := `SELECT ` + transactionTagsColumn + ` FROM transactions t WHERE t.id = $1`
+	if err := tx.QueryRowContext(ctx, query, transaction.ID).Scan(&raw); err != nil {
+		return err
+	}
+
+	tags, err := decodeTags(raw)
+	if err != nil {
+		return err
+	}
+	transaction.Tags = tags
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TagStoreTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TagStoreTestSuite) TestTagStoreCreation() {
+	var db *sql.DB
+	store := &TagStore{db: db}
+
+	assert.NotNil(suite.T(), store)
+}
+
+func (suite *TagStoreTestSuite) TestDecodeTags() {
+	tags, err := decodeTags([]byte(`[{"id":1,"name":"bali-trip","color":"#0ea5e9"},{"id":2,"name":"reimbursable","color":"#666"}]`))
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), tags, 2)
+	assert.Equal(suite.T(), "bali-trip", tags[0].Name)
+	assert.Equal(suite.T(), int64(2), tags[1].ID)
+
+	// transactions without tags encode as an empty array, never null
+	tags, err = decodeTags([]byte(`[]`))
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), tags)
+	assert.Empty(suite.T(), tags)
+}
+
+func TestTagStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(TagStoreTestSuite))
+}
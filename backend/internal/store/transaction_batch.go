@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// TransactionOperation is one step of a batch. Updates and deletes refer to
+// an existing transaction by ID and only go ahead while it is still at
+// Version.
+type TransactionOperation struct {
+	Action      string
+	Transaction *Transaction
+}
+
+// BatchError tells which operation made a batch fail.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch runs the operations in order in one database transaction, so either
+// all of them are saved or none is, and recomputes the running balances of
+// each affected account once at the end. A failing operation is reported as
+// a *BatchError. Large batches take longer than QueryTimeoutDuration, so the
+// database transaction is bounded by BatchTimeoutDuration.
+func (s *TransactionStore) Batch(ctx context.Context, operations []TransactionOperation) error {
+	return withTxTimeout(ctx, s.db, BatchTimeoutDuration, func(tx *sql.Tx) error {
+		var existing, accountIDs []int64
+		for _, op := range operations {
+			if op.Action != BatchCreate {
+				existing = append(existing, op.Transaction.ID)
+			}
+			if op.Action != BatchDelete {
+				accountIDs = append(accountIDs, op.Transaction.AccountID)
+			}
+		}
+
+		// every account is locked before any transaction, in one go so that
+		// they are taken in id order
+		current, err := currentAccounts(ctx, tx, existing)
+		if err != nil {
+			return err
+		}
+		locks := accountLocks{}
+		if err := locks.lock(ctx, tx, append(accountIDs, current...)...); err != nil {
+			return err
+		}
+
+		versions, err := lockVersions(ctx, tx, existing)
+		if err != nil {
+			return err
+		}
+
+		before, err := snapshotRows(ctx, tx, AuditTransaction, existing...)
+		if err != nil {
+			return err
+		}
+
+		changes := ledgerChanges{}
+		ids := map[string][]int64{}
+		for i, op := range operations {
+			transaction := op.Transaction
+			if op.Action != BatchCreate {
+				version, ok := versions[transaction.ID]
+				switch {
+				case !ok:
+					return &BatchError{Index: i, Err: ErrNotFound}
+				case version != transaction.Version:
+					return &BatchError{Index: i, Err: ErrVersionMismatch}
+				}
+			}
+
+			switch op.Action {
+			case BatchCreate:
+				err = insertTransaction(ctx, tx, transaction, changes, locks)
+			case BatchUpdate:
+				err = updateTransaction(ctx, tx, transaction, changes, locks)
+			case BatchDelete:
+				err = deleteTransaction(ctx, tx, transaction.ID, transaction.Version, changes, locks)
+			default:
+				err = fmt.Errorf("unknown batch action %q", op.Action)
+			}
+			if err != nil {
+				return &BatchError{Index: i, Err: err}
+			}
+
+			ids[op.Action] = append(ids[op.Action], transaction.ID)
+		}
+
+		if err := changes.apply(ctx, tx, locks); err != nil {
+			return err
+		}
+
+		for _, record := range []struct {
+			action string
+			ids    []int64
+		}{
+			{AuditCreate, ids[BatchCreate]},
+			{AuditUpdate, ids[BatchUpdate]},
+			{AuditDelete, ids[BatchDelete]},
+		} {
+			if err := recordChanges(ctx, tx, AuditTransaction, record.action, before, record.ids...); err != nil {
+				return err
+			}
+		}
+
+		for _, op := range operations {
+			if op.Action == BatchDelete {
+				continue
+			}
+			if err := readRunningBalance(ctx, tx, op.Transaction); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// currentAccounts returns the accounts the given transactions are in, read
+// without a lock.
+func currentAccounts(ctx context.Context, tx *sql.Tx, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT DISTINCT account_id FROM transactions WHERE id = ANY($1::bigint[]) AND deleted_at IS NULL`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanIDs(rows)
+}
+
+// lockVersions locks the given transactions for the rest of the database
+// transaction and returns their versions. Transactions that do not exist or
+// are in the trash are missing from the result.
+func lockVersions(ctx context.Context, tx *sql.Tx, ids []int64) (map[int64]int64, error) {
+	versions := map[int64]int64{}
+	if len(ids) == 0 {
+		return versions, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, version
+		FROM transactions
+		WHERE id = ANY($1::bigint[]) AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, version int64
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, err
+		}
+		versions[id] = version
+	}
+
+	return versions, rows.Err()
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DefaultTransactionLimit = 100
+	MaxTransactionLimit     = 500
+)
+
+// TransactionFilter narrows down transaction listings. Zero values mean
+// "no restriction"; From and To are inclusive calendar dates (YYYY-MM-DD).
+type TransactionFilter struct {
+	From        string
+	To          string
+	AccountID   int64
+	PayeeID     int64
+	CategoryIDs []int64
+	TagIDs      []int64
+	MinAmount   *int64
+	MaxAmount   *int64
+	Description string
+	Kind        string
+	Ascending   bool
+	Limit       int
+	After       *TransactionCursor
+}
+
+// TransactionCursor is the (date, id) position of the last row of a page.
+type TransactionCursor struct {
+	Date time.Time
+	ID   int64
+}
+
+// whereClause renders the filter as SQL conditions on the "t" alias together
+// with their positional arguments. Transactions in the trash never match.
+func (f TransactionFilter) whereClause() (string, []any) {
+	conditions := []string{"t.deleted_at IS NULL"}
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.From != "" {
+		add("t.date >= $%d::date", f.From)
+	}
+	if f.To != "" {
+		add("t.date < $%d::date + INTERVAL '1 day'", f.To)
+	}
+	if f.AccountID != 0 {
+		add("t.account_id = $%d::bigint", f.AccountID)
+	}
+	if f.PayeeID != 0 {
+		add("t.payee_id = $%d::bigint", f.PayeeID)
+	}
+	if len(f.CategoryIDs) > 0 {
+		// category id 0 selects uncategorized transactions; split
+		// transactions match on any of their lines
+		args = append(args, pq.Array(f.CategoryIDs))
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM %s l WHERE l.id = t.id AND (l.category_id = ANY($%d::bigint[]) OR (l.category_id IS NULL AND 0 = ANY($%d::bigint[]))))",
+			transactionLines, n, n,
+		))
+	}
+	if len(f.TagIDs) > 0 {
+		add("EXISTS (SELECT 1 FROM transaction_tags tt WHERE tt.transaction_id = t.id AND tt.tag_id = ANY($%d::bigint[]))", pq.Array(f.TagIDs))
+	}
+	if f.MinAmount != nil {
+		add("t.amount >= $%d::bigint", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		add("t.amount <= $%d::bigint", *f.MaxAmount)
+	}
+	if f.Description != "" {
+		add("t.description ILIKE '%%' || $%d::text || '%%'", escapeLike(f.Description))
+	}
+	if f.Kind != "" {
+		add("t.kind = $%d::text", f.Kind)
+	}
+	if f.After != nil {
+		comparison := "<"
+		if f.Ascending {
+			comparison = ">"
+		}
+		args = append(args, f.After.Date, f.After.ID)
+		conditions = append(conditions, fmt.Sprintf(
+			"(t.date, t.id) %s ($%d::timestamptz, $%d::bigint)", comparison, len(args)-1, len(args),
+		))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (f TransactionFilter) orderClause() string {
+	if f.Ascending {
+		return "ORDER BY t.date ASC, t.id ASC"
+	}
+	return "ORDER BY t.date DESC, t.id DESC"
+}
+
+func (f TransactionFilter) limit() int {
+	switch {
+	case f.Limit <= 0:
+		return DefaultTransactionLimit
+	case f.Limit > MaxTransactionLimit:
+		return MaxTransactionLimit
+	default:
+		return f.Limit
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
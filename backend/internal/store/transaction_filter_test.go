@@ -0,0 +1,63 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TransactionFilterTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TransactionFilterTestSuite) TestEmptyFilter() {
+	where, args := TransactionFilter{}.whereClause()
+
+	assert.Equal(suite.T(), "WHERE t.deleted_at IS NULL", where)
+	assert.Empty(suite.T(), args)
+}
+
+func (suite *TransactionFilterTestSuite) TestWhereClausePlaceholders() {
+	minAmount := int64(1000)
+	filter := TransactionFilter{
+		From:        "2023-01-01",
+		CategoryIDs: []int64{3},
+		MinAmount:   &minAmount,
+		Description: "50%_off",
+		After:       &TransactionCursor{Date: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), ID: 9},
+	}
+
+	where, args := filter.whereClause()
+
+	assert.Contains(suite.T(), where, "t.date >= $1::date")
+	assert.Contains(suite.T(), where, "l.category_id = ANY($2::bigint[])")
+	assert.Contains(suite.T(), where, "t.amount >= $3::bigint")
+	assert.Contains(suite.T(), where, "t.description ILIKE '%' || $4::text || '%'")
+	assert.Contains(suite.T(), where, "(t.date, t.id) < ($5::timestamptz, $6::bigint)")
+	assert.Len(suite.T(), args, 6)
+	assert.Equal(suite.T(), `50\%\_off`, args[3])
+}
+
+func (suite *TransactionFilterTestSuite) TestAscendingCursorAndOrder() {
+	filter := TransactionFilter{
+		Ascending: true,
+		After:     &TransactionCursor{ID: 1},
+	}
+
+	where, _ := filter.whereClause()
+
+	assert.Contains(suite.T(), where, "(t.date, t.id) > ($1::timestamptz, $2::bigint)")
+	assert.Equal(suite.T(), "ORDER BY t.date ASC, t.id ASC", filter.orderClause())
+}
+
+func (suite *TransactionFilterTestSuite) TestLimitBounds() {
+	assert.Equal(suite.T(), DefaultTransactionLimit, TransactionFilter{}.limit())
+	assert.Equal(suite.T(), 20, TransactionFilter{Limit: 20}.limit())
+	assert.Equal(suite.T(), MaxTransactionLimit, TransactionFilter{Limit: 10000}.limit())
+}
+
+func TestTransactionFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(TransactionFilterTestSuite))
+}
@@ -4,127 +4,343 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
+// TransactionGet is a transaction as listed. ConvertedAmount is the amount in
+// BaseCurrency, or nil when no rate is known for the transaction's currency.
 type TransactionGet struct {
-	ID             int64          `json:"id"`
-	Amount         int64          `json:"amount"`
-	RunningBalance int64          `json:"running_balance"`
-	Description    string         `json:"description"`
-	CategoryName   sql.NullString `json:"category_name,omitempty"`
-	CategoryColor  sql.NullString `json:"category_color,omitempty"`
-	Date           string         `json:"date"`
+	ID              int64          `json:"id"`
+	AccountID       int64          `json:"account_id"`
+	AccountName     string         `json:"account_name"`
+	Amount          int64          `json:"amount"`
+	Currency        string         `json:"currency"`
+	ConvertedAmount *int64         `json:"converted_amount"`
+	RunningBalance  int64          `json:"running_balance"`
+	Description     string         `json:"description"`
+	CategoryName    sql.NullString `json:"category_name,omitempty"`
+	CategoryColor   sql.NullString `json:"category_color,omitempty"`
+	PayeeID         sql.NullInt64  `json:"payee_id,omitempty"`
+	PayeeName       sql.NullString `json:"payee_name,omitempty"`
+	Kind            string         `json:"kind"`
+	Status          string         `json:"status"`
+	Date            string         `json:"date"`
+	Tags            []Tag          `json:"tags"`
+	Splits          []Split        `json:"splits"`
 }
+
+// Transaction is a single transaction as read and written by the store.
+// ConvertedAmount is the amount in BaseCurrency at the rate of its date, or
+// nil when no rate is known for the transaction's currency.
 type Transaction struct {
-	ID             int64         `json:"id"`
-	Amount         int64         `json:"amount"`
-	RunningBalance int64         `json:"running_balance"`
-	Description    string        `json:"description"`
-	Date           string        `json:"date"`
-	CreatedAt      string        `json:"created_at"`
-	UpdatedAt      string        `json:"updated_at"`
-	CategoryID     sql.NullInt64 `json:"category_id,omitempty"`
+	ID              int64          `json:"id"`
+	AccountID       int64          `json:"account_id"`
+	Amount          int64          `json:"amount"`
+	Currency        string         `json:"currency"`
+	ConvertedAmount *int64         `json:"converted_amount"`
+	RunningBalance  int64          `json:"running_balance"`
+	Description     string         `json:"description"`
+	Kind            string         `json:"kind"`
+	Date            string         `json:"date"`
+	CreatedAt       string         `json:"created_at"`
+	UpdatedAt       string         `json:"updated_at"`
+	Version         int64          `json:"version"`
+	Status          string         `json:"status"`
+	CategoryID      sql.NullInt64  `json:"category_id,omitempty"`
+	EventID         sql.NullInt64  `json:"event_id,omitempty"`
+	RecurringRuleID sql.NullInt64  `json:"recurring_rule_id,omitempty"`
+	PayeeID         sql.NullInt64  `json:"payee_id,omitempty"`
+	ExternalID      sql.NullString `json:"external_id,omitempty"`
+	Tags            []Tag          `json:"tags"`
+	Splits          []Split        `json:"splits"`
 }
 
 type TransactionStore struct {
 	db *sql.DB
 }
 
+// Create inserts the transaction and recomputes the running balances of its
+// account from the transaction's date onwards.
 func (s *TransactionStore) Create(ctx context.Context, transaction *Transaction) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		changes, locks := ledgerChanges{}, accountLocks{}
+		if err := insertTransaction(ctx, tx, transaction, changes, locks); err != nil {
+			return err
+		}
+
+		if err := changes.apply(ctx, tx, locks); err != nil {
+			return err
+		}
+
+		if err := recordChanges(ctx, tx, AuditTransaction, AuditCreate, nil, transaction.ID); err != nil {
+			return err
+		}
+
+		return readRunningBalance(ctx, tx, transaction)
+	})
+}
+
+// CreateBatch inserts all transactions in one database transaction and
+// recomputes the running balances of each affected account once. Large
+// imports take longer than QueryTimeoutDuration, so the database transaction
+// is bounded by BatchTimeoutDuration.
+func (s *TransactionStore) CreateBatch(ctx context.Context, transactions []*Transaction) error {
+	return withTxTimeout(ctx, s.db, BatchTimeoutDuration, func(tx *sql.Tx) error {
+		changes, locks := ledgerChanges{}, accountLocks{}
+		for _, transaction := range transactions {
+			if err := insertTransaction(ctx, tx, transaction, changes, locks); err != nil {
+				return err
+			}
+		}
+
+		if err := changes.apply(ctx, tx, locks); err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(transactions))
+		for i, transaction := range transactions {
+			ids[i] = transaction.ID
+		}
+		if err := recordChanges(ctx, tx, AuditTransaction, AuditCreate, nil, ids...); err != nil {
+			return err
+		}
+
+		for _, transaction := range transactions {
+			if err := readRunningBalance(ctx, tx, transaction); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// insertTransaction books the transaction, returning ErrLocked when it is
+// dated in the reconciled period of its account and ErrInvalidReference when
+// its category or event does not exist or is in the trash.
+func insertTransaction(ctx context.Context, tx *sql.Tx, transaction *Transaction, changes ledgerChanges, locks accountLocks) error {
+	if err := locks.lock(ctx, tx, transaction.AccountID); err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO transactions (category_id, amount, running_balance, description, date)	
-		VALUES ($1, $2::bigint, $3::bigint, $4::text, $5::timestamp) RETURNING id, created_at, updated_at
+		INSERT INTO transactions (category_id, amount, running_balance, description, date, kind, account_id, recurring_rule_id, external_id, event_id, payee_id, currency)
+		SELECT
+			$1::bigint, $2::bigint, 0, $3::text, $4::timestamptz,
+			COALESCE(NULLIF($5::text, ''), (SELECT kind FROM categories WHERE id = $1::bigint), 'expense'),
+			$6::bigint, $7::bigint, $8::text, $9::bigint, $10::bigint,
+			(SELECT currency FROM accounts WHERE id = $6::bigint)
+		WHERE NOT ` + referencesTrashed(1, 9) + `
+		RETURNING id, kind, date, currency, convert_amount(amount, currency, $11, date::date), created_at, updated_at, version, status
 	`
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := s.db.QueryRowContext(
+	var date time.Time
+	err := tx.QueryRowContext(
 		ctx,
 		query,
 		transaction.CategoryID,
 		transaction.Amount,
-		transaction.RunningBalance,
 		transaction.Description,
 		transaction.Date,
+		transaction.Kind,
+		transaction.AccountID,
+		transaction.RecurringRuleID,
+		transaction.ExternalID,
+		transaction.EventID,
+		transaction.PayeeID,
+		BaseCurrency,
 	).Scan(
 		&transaction.ID,
+		&transaction.Kind,
+		&date,
+		&transaction.Currency,
+		&transaction.ConvertedAmount,
 		&transaction.CreatedAt,
 		&transaction.UpdatedAt,
+		&transaction.Version,
+		&transaction.Status,
 	)
 	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows), isForeignKeyViolation(err):
+			return ErrInvalidReference
+		case isUniqueViolation(err):
+			// the occurrence of a recurring rule was already materialized or
+			// the bank transaction was already imported
+			return ErrConflict
+		default:
+			return err
+		}
+	}
+
+	if err := locks.check(transaction.AccountID, date); err != nil {
 		return err
 	}
 
-	return nil
+	transaction.Date = date.Format(time.RFC3339)
+	changes.add(transaction.AccountID, date, transaction.ID)
+
+	// a new transaction has no splits or tags to replace, so most imported
+	// rows need no further statements
+	if len(transaction.Splits) == 0 {
+		transaction.Splits = []Split{}
+	} else if err := setTransactionSplits(ctx, tx, transaction); err != nil {
+		return err
+	}
+
+	if len(transaction.Tags) == 0 {
+		transaction.Tags = []Tag{}
+		return nil
+	}
+	return setTransactionTags(ctx, tx, transaction)
 }
 
-func (s *TransactionStore) Index(ctx context.Context) ([]TransactionGet, error) {
-	query := `
-		SELECT t.id, c.name, c.color, t.amount, t.running_balance, t.description, t.date
+// referencesTrashed is true when the category or the event of a transaction,
+// passed as the given parameters, is in the trash. The foreign keys only
+// reject those deleted for good.
+func referencesTrashed(categoryParam, eventParam int) string {
+	return fmt.Sprintf(`(
+		EXISTS (SELECT 1 FROM categories WHERE id = $%d::bigint AND deleted_at IS NOT NULL)
+		OR EXISTS (SELECT 1 FROM events WHERE id = $%d::bigint AND deleted_at IS NOT NULL)
+	)`, categoryParam, eventParam)
+}
+
+func readRunningBalance(ctx context.Context, q querier, transaction *Transaction) error {
+	query := `SELECT running_balance FROM transactions WHERE id = $1`
+
+	return q.QueryRowContext(ctx, query, transaction.ID).Scan(&transaction.RunningBalance)
+}
+
+// Index returns one page of transactions matching the filter and the cursor
+// of the following page, which is nil on the last page.
+func (s *TransactionStore) Index(ctx context.Context, filter TransactionFilter) ([]TransactionGet, *TransactionCursor, error) {
+	where, args := filter.whereClause()
+	limit := filter.limit()
+	args = append(args, BaseCurrency, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.account_id, a.name, c.name, c.color, t.payee_id, p.name, t.amount, t.currency, convert_amount(t.amount, t.currency, $%d, t.date::date),
+			t.running_balance, t.description, t.kind, t.status, t.date,
+			%s,
+			%s
 		FROM transactions t
+		JOIN accounts a
+			ON t.account_id = a.id
 		LEFT JOIN categories c
-			ON t.category_id = c.id
-		WHERE t.date > CURRENT_DATE - INTERVAL '3 months'
-		ORDER BY id DESC
-	`
+			ON t.category_id = c.id AND c.deleted_at IS NULL
+		LEFT JOIN payees p
+			ON t.payee_id = p.id
+		%s
+		%s
+		LIMIT $%d
+	`, len(args)-1, transactionTagsColumn, transactionSplitsColumn, where, filter.orderClause(), len(args))
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer rows.Close()
 
 	var transactions []TransactionGet
+	var dates []time.Time
 
 	for rows.Next() {
 		var transaction TransactionGet
+		var date time.Time
+		var tags, splits []byte
 		if err := rows.Scan(
 			&transaction.ID,
+			&transaction.AccountID,
+			&transaction.AccountName,
 			&transaction.CategoryName,
 			&transaction.CategoryColor,
+			&transaction.PayeeID,
+			&transaction.PayeeName,
 			&transaction.Amount,
+			&transaction.Currency,
+			&transaction.ConvertedAmount,
 			&transaction.RunningBalance,
 			&transaction.Description,
-			&transaction.Date,
+			&transaction.Kind,
+			&transaction.Status,
+			&date,
+			&tags,
+			&splits,
 		); err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		transaction.Date = date.Format(time.RFC3339)
+		if transaction.Tags, err = decodeTags(tags); err != nil {
+			return nil, nil, err
+		}
+		if transaction.Splits, err = decodeSplits(splits); err != nil {
+			return nil, nil, err
 		}
 		transactions = append(transactions, transaction)
+		dates = append(dates, date)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return transactions, nil
+	// the extra row only tells us another page exists
+	if len(transactions) <= limit {
+		return transactions, nil, nil
+	}
+
+	transactions = transactions[:limit]
+	next := &TransactionCursor{Date: dates[limit-1], ID: transactions[limit-1].ID}
+
+	return transactions, next, nil
 }
 
 func (s *TransactionStore) GetById(ctx context.Context, id int64) (*Transaction, error) {
 	query := `
-		SELECT id, category_id, amount, running_balance, description, created_at, updated_at, date
-		FROM transactions
-		WHERE id = $1
+		SELECT t.id, t.account_id, t.category_id, t.event_id, t.recurring_rule_id, t.payee_id, t.external_id, t.amount, t.currency, cv.amount, t.running_balance, t.description, t.kind, t.created_at, t.updated_at, t.version, t.status, t.date,
+			` + transactionTagsColumn + `,
+			` + transactionSplitsColumn + `
+		FROM transactions t
+		` + convertedAmountJoin(2) + `
+		WHERE t.id = $1 AND t.deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
 	var transaction Transaction
+	var tags, splits []byte
 	err := s.db.QueryRowContext(
 		ctx,
 		query,
 		id,
+		BaseCurrency,
 	).Scan(
 		&transaction.ID,
+		&transaction.AccountID,
 		&transaction.CategoryID,
+		&transaction.EventID,
+		&transaction.RecurringRuleID,
+		&transaction.PayeeID,
+		&transaction.ExternalID,
 		&transaction.Amount,
+		&transaction.Currency,
+		&transaction.ConvertedAmount,
 		&transaction.RunningBalance,
 		&transaction.Description,
+		&transaction.Kind,
 		&transaction.CreatedAt,
 		&transaction.UpdatedAt,
+		&transaction.Version,
+		&transaction.Status,
 		&transaction.Date,
+		&tags,
+		&splits,
 	)
 
 	if err != nil {
@@ -136,126 +352,125 @@ func (s *TransactionStore) GetById(ctx context.Context, id int64) (*Transaction,
 		}
 	}
 
+	if transaction.Tags, err = decodeTags(tags); err != nil {
+		return nil, err
+	}
+	if transaction.Splits, err = decodeSplits(splits); err != nil {
+		return nil, err
+	}
+
 	return &transaction, nil
 }
 
-func (s *TransactionStore) GetLast(ctx context.Context) (*Transaction, error) {
-	query := `
-		SELECT id, category_id, amount, running_balance, description, created_at, updated_at, date
-		FROM transactions
-		ORDER BY id DESC
-		LIMIT 1
-	`
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	var transaction Transaction
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-	).Scan(
-		&transaction.ID,
-		&transaction.CategoryID,
-		&transaction.Amount,
-		&transaction.RunningBalance,
-		&transaction.Description,
-		&transaction.CreatedAt,
-		&transaction.UpdatedAt,
-		&transaction.Date,
-	)
-
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return nil, ErrNotFound
-		default:
-			return nil, err
-		}
-	}
+// ConvertedTotal is a sum in BaseCurrency. Unconverted counts the
+// transactions left out of Amount because no exchange rate is known for their
+// currency at their date.
+type ConvertedTotal struct {
+	Amount      int64 `json:"amount"`
+	Unconverted int64 `json:"unconverted"`
+}
 
-	return &transaction, nil
+// convertedAmountJoin converts the amount of each row of "t" to BaseCurrency,
+// passed as the given parameter, into cv.amount, which is NULL when no rate
+// is known.
+func convertedAmountJoin(param int) string {
+	return fmt.Sprintf(`CROSS JOIN LATERAL (SELECT convert_amount(t.amount, t.currency, $%d, t.date::date) AS amount) cv`, param)
 }
 
-func (s *TransactionStore) GetExpensesByMonth(ctx context.Context, date string) (int64, error) {
+func (s *TransactionStore) GetExpensesByMonth(ctx context.Context, kind string, date string) (ConvertedTotal, error) {
 	query := `
-		SELECT COALESCE(SUM(t.amount), 0)
+		SELECT COALESCE(SUM(cv.amount), 0) * $3::bigint, COUNT(*) FILTER (WHERE cv.amount IS NULL)
 		FROM transactions t
-		LEFT JOIN categories c ON t.category_id = c.id
+		` + convertedAmountJoin(4) + `
 		WHERE t.date <= date_trunc('day', $1::date)
 			AND t.date > date_trunc('day', $1::date) - INTERVAL '31 days'
-			AND c.name <> 'Gajian'
+			AND t.kind = $2::text
+			AND t.deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	var returnValue int64
+	var total ConvertedTotal
 	err := s.db.QueryRowContext(
 		ctx,
 		query,
 		date,
+		kind,
+		kindSign(kind),
+		BaseCurrency,
 	).Scan(
-		&returnValue,
+		&total.Amount,
+		&total.Unconverted,
 	)
 	if err != nil {
-		return 0, err
+		return ConvertedTotal{}, err
 	}
 
-	return returnValue, nil
+	return total, nil
 }
 
-func (s *TransactionStore) GetExpensesByMonthRange(ctx context.Context, date string) (int64, error) {
+func (s *TransactionStore) GetExpensesByMonthRange(ctx context.Context, kind string, date string) (ConvertedTotal, error) {
 	query := `
-		SELECT COALESCE(SUM(t.amount), 0)
+		SELECT COALESCE(SUM(cv.amount), 0) * $3::bigint, COUNT(*) FILTER (WHERE cv.amount IS NULL)
 		FROM transactions t
-		LEFT JOIN categories c ON t.category_id = c.id
+		` + convertedAmountJoin(4) + `
 		WHERE t.date >= date_trunc('month', $1::date)
 			AND t.date < date_trunc('month', $1::date) + INTERVAL '1 month'
-			AND c.name <> 'Gajian'
+			AND t.kind = $2::text
+			AND t.deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	var returnValue int64
+	var total ConvertedTotal
 	err := s.db.QueryRowContext(
 		ctx,
 		query,
 		date,
+		kind,
+		kindSign(kind),
+		BaseCurrency,
 	).Scan(
-		&returnValue,
+		&total.Amount,
+		&total.Unconverted,
 	)
 	if err != nil {
-		return 0, err
+		return ConvertedTotal{}, err
 	}
 
-	return returnValue, nil
+	return total, nil
 }
 
 type CategoryReturnValue struct {
-	Amount int64  `json:"amount"`
-	Name   string `json:"name"`
-	Color  string `json:"color"`
-	ID     int64  `json:"id"`
+	Amount      int64  `json:"amount"`
+	Unconverted int64  `json:"unconverted"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	ID          int64  `json:"id"`
 }
 
-func (s *TransactionStore) GetExpensesByMonthCategory(ctx context.Context, date string) ([]CategoryReturnValue, error) {
+// GetExpensesByMonthCategory totals the 31 days up to date per category,
+// counting each split line under its own category.
+func (s *TransactionStore) GetExpensesByMonthCategory(ctx context.Context, kind string, date string) ([]CategoryReturnValue, error) {
 	query := `
-		SELECT COALESCE(SUM(t.amount), 0) as amount, COALESCE(NULLIF(c.name, ''), 'Uncategorized') as name, COALESCE(NULLIF(c.color, ''), '#666') as color, COALESCE(c.id, 0) as id
-		FROM transactions t
+		SELECT COALESCE(SUM(cv.amount), 0) * $3::bigint as amount, COUNT(*) FILTER (WHERE cv.amount IS NULL) as unconverted,
+			COALESCE(NULLIF(c.name, ''), 'Uncategorized') as name, COALESCE(NULLIF(c.color, ''), '#666') as color, COALESCE(c.id, 0) as id
+		FROM ` + transactionLines + ` t
+		` + convertedAmountJoin(4) + `
 		LEFT JOIN categories c
-			ON t.category_id = c.id
+			ON t.category_id = c.id AND c.deleted_at IS NULL
 		WHERE t.date <= date_trunc('day', $1::date)
 			AND t.date > date_trunc('day', $1::date) - INTERVAL '31 days'
-			AND c.name <> 'Gajian'
-		GROUP BY 2,3,4
+			AND t.kind = $2::text
+		GROUP BY 3,4,5
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, date)
+	rows, err := s.db.QueryContext(ctx, query, date, kind, kindSign(kind), BaseCurrency)
 	if err != nil {
 		return nil, err
 	}
@@ -266,6 +481,7 @@ func (s *TransactionStore) GetExpensesByMonthCategory(ctx context.Context, date
 		var transaction CategoryReturnValue
 		err := rows.Scan(
 			&transaction.Amount,
+			&transaction.Unconverted,
 			&transaction.Name,
 			&transaction.Color,
 			&transaction.ID,
@@ -279,27 +495,79 @@ func (s *TransactionStore) GetExpensesByMonthCategory(ctx context.Context, date
 	return transactions, nil
 }
 
+func (s *TransactionStore) GetExpensesByMonthTag(ctx context.Context, kind string, date string) ([]TagReturnValue, error) {
+	query := `
+		SELECT COALESCE(SUM(cv.amount), 0) * $3::bigint as amount, COUNT(*) FILTER (WHERE cv.amount IS NULL) as unconverted, tg.name, tg.color, tg.id
+		FROM transactions t
+		` + convertedAmountJoin(4) + `
+		JOIN transaction_tags tt
+			ON tt.transaction_id = t.id
+		JOIN tags tg
+			ON tg.id = tt.tag_id
+		WHERE t.date <= date_trunc('day', $1::date)
+			AND t.date > date_trunc('day', $1::date) - INTERVAL '31 days'
+			AND t.kind = $2::text
+			AND t.deleted_at IS NULL
+		GROUP BY 3,4,5
+		ORDER BY 1 DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, date, kind, kindSign(kind), BaseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []TagReturnValue
+
+	for rows.Next() {
+		var tag TagReturnValue
+		err := rows.Scan(
+			&tag.Amount,
+			&tag.Unconverted,
+			&tag.Name,
+			&tag.Color,
+			&tag.ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
 type AmountDaily struct {
-	Amount int64  `json:"amount"`
-	Date   string `json:"date"`
+	Amount      int64  `json:"amount"`
+	Unconverted int64  `json:"unconverted"`
+	Date        string `json:"date"`
 }
 
-func (s *TransactionStore) GetExpensesLast30Days(ctx context.Context) ([]AmountDaily, error) {
+func (s *TransactionStore) GetExpensesLast30Days(ctx context.Context, kind string) ([]AmountDaily, error) {
 	query := `
-		SELECT COALESCE(SUM(t.amount)) as amount, cast(t.date::timestamp::date as varchar) as date
+		SELECT COALESCE(SUM(cv.amount), 0) * $2::bigint as amount, COUNT(*) FILTER (WHERE cv.amount IS NULL) as unconverted,
+			cast(t.date::timestamp::date as varchar) as date
 		FROM transactions t
-		LEFT JOIN categories c ON t.category_id = c.id
+		` + convertedAmountJoin(3) + `
 		WHERE t.date <= date_trunc('day', now())
 			AND t.date > date_trunc('day', now()) - INTERVAL '31 days'
-			AND c.name <> 'Gajian'
-		GROUP BY 2
-		ORDER BY 2 ASC
+			AND t.kind = $1::text
+			AND t.deleted_at IS NULL
+		GROUP BY 3
+		ORDER BY 3 ASC
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, kind, kindSign(kind), BaseCurrency)
 	if err != nil {
 		return nil, err
 	}
@@ -310,6 +578,7 @@ func (s *TransactionStore) GetExpensesLast30Days(ctx context.Context) ([]AmountD
 		var transaction AmountDaily
 		err := rows.Scan(
 			&transaction.Amount,
+			&transaction.Unconverted,
 			&transaction.Date,
 		)
 		if err != nil {
@@ -321,23 +590,37 @@ func (s *TransactionStore) GetExpensesLast30Days(ctx context.Context) ([]AmountD
 	return transactions, nil
 }
 
-func (s *TransactionStore) GetBalanceByDate(ctx context.Context, date string) (int64, error) {
-	query := `
-		SELECT COALESCE(running_balance, 0)
-		FROM transactions
-		WHERE date <= $1::date
-		ORDER BY date DESC
-		LIMIT 1
-	`
-
+// GetBalanceByDate returns the balance of one account at the end of the
+// given date, or the sum over all accounts when accountID is 0, in
+// BaseCurrency at the rates of that date.
+func (s *TransactionStore) GetBalanceByDate(ctx context.Context, date string, accountID int64) (int64, error) {
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
+	return balanceByDate(ctx, s.db, date, accountID)
+}
+
+func balanceByDate(ctx context.Context, q querier, date string, accountID int64) (int64, error) {
+	query := `
+		SELECT COALESCE(SUM(convert_amount(COALESCE(t.running_balance, a.opening_balance), a.currency, $3, $1::date)), 0)
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT running_balance
+			FROM transactions
+			WHERE account_id = a.id AND date < $1::date + INTERVAL '1 day' AND deleted_at IS NULL
+			ORDER BY date DESC, id DESC
+			LIMIT 1
+		) t ON true
+		WHERE $2::bigint = 0 OR a.id = $2::bigint
+	`
+
 	var returnValue int64
-	err := s.db.QueryRowContext(
+	err := q.QueryRowContext(
 		ctx,
 		query,
 		date,
+		accountID,
+		BaseCurrency,
 	).Scan(
 		&returnValue,
 	)
@@ -353,66 +636,272 @@ func (s *TransactionStore) GetBalanceByDate(ctx context.Context, date string) (i
 	return returnValue, nil
 }
 
-func (s *TransactionStore) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM transactions WHERE id = $1`
+// Delete moves the transaction to the trash and closes the gap it leaves in
+// the running balances of its account. ErrVersionMismatch is returned when
+// the transaction moved on from version since it was read. Reconciled
+// transactions and those dated in the reconciled period of their account are
+// locked and return ErrLocked.
+func (s *TransactionStore) Delete(ctx context.Context, id, version int64) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditTransaction, id)
+		if err != nil {
+			return err
+		}
 
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
+		changes, locks := ledgerChanges{}, accountLocks{}
+		if err := deleteTransaction(ctx, tx, id, version, changes, locks); err != nil {
+			return err
+		}
+
+		if err := changes.apply(ctx, tx, locks); err != nil {
+			return err
+		}
+
+		return recordChanges(ctx, tx, AuditTransaction, AuditDelete, before, id)
+	})
+}
 
-	res, err := s.db.ExecContext(ctx, query, id)
+func deleteTransaction(ctx context.Context, tx *sql.Tx, id, version int64, changes ledgerChanges, locks accountLocks) error {
+	accountID, err := transactionAccount(ctx, tx, id, false)
 	if err != nil {
 		return err
 	}
+	if err := locks.lock(ctx, tx, accountID); err != nil {
+		return err
+	}
 
-	rows, err := res.RowsAffected()
-	if err != nil {
+	if err := checkUnlocked(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// the row is locked by checkUnlocked
+	var current int64
+	if err := tx.QueryRowContext(ctx, `SELECT version FROM transactions WHERE id = $1`, id).Scan(&current); err != nil {
 		return err
 	}
+	if current != version {
+		return ErrVersionMismatch
+	}
+
+	query := `
+		UPDATE transactions
+		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING date
+	`
 
-	if rows == 0 {
-		return ErrNotFound
+	// the version is unchanged, so the transaction is still in the account
+	// locked above
+	var date time.Time
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&date); err != nil {
+		return err
+	}
+	if err := locks.check(accountID, date); err != nil {
+		return err
 	}
 
+	changes.add(accountID, date, id)
+
 	return nil
 }
 
-func (s *TransactionStore) UpdateWithCascade(ctx context.Context, transaction *Transaction, oldAmount int64) error {
-	tx, err := s.db.BeginTx(ctx, nil)
+// transactionAccount returns the account of the transaction, looking in the
+// trash when deleted is set, so that the account can be locked before the
+// transaction itself. The row is read without a lock, and callers find out
+// from its version or trash state whether it moved in the meantime.
+func transactionAccount(ctx context.Context, tx *sql.Tx, id int64, deleted bool) (int64, error) {
+	var accountID int64
+	err := tx.QueryRowContext(ctx,
+		`SELECT account_id FROM transactions WHERE id = $1 AND (deleted_at IS NOT NULL) = $2`,
+		id, deleted,
+	).Scan(&accountID)
 	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return accountID, nil
+}
+
+// Update saves the transaction and recomputes running balances from the
+// earlier of its old and new position, in both accounts when it moved. The
+// version counts edits to the transaction itself, not balance changes caused
+// by other transactions, and ErrVersionMismatch is returned when it moved on
+// since the transaction was read. Reconciled transactions and those moving
+// into or out of the reconciled period of an account are locked and return
+// ErrLocked. A cleared transaction whose amount, date or account changes is
+// no longer what the statement showed and goes back to uncleared.
+func (s *TransactionStore) Update(ctx context.Context, transaction *Transaction) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, AuditTransaction, transaction.ID)
+		if err != nil {
+			return err
+		}
+
+		changes, locks := ledgerChanges{}, accountLocks{}
+		if err := updateTransaction(ctx, tx, transaction, changes, locks); err != nil {
+			return err
+		}
+
+		if err := changes.apply(ctx, tx, locks); err != nil {
+			return err
+		}
+
+		if err := recordChanges(ctx, tx, AuditTransaction, AuditUpdate, before, transaction.ID); err != nil {
+			return err
+		}
+
+		return readRunningBalance(ctx, tx, transaction)
+	})
+}
+
+func updateTransaction(ctx context.Context, tx *sql.Tx, transaction *Transaction, changes ledgerChanges, locks accountLocks) error {
+	oldAccountID, err := transactionAccount(ctx, tx, transaction.ID, false)
+	if err != nil {
+		return err
+	}
+	if err := locks.lock(ctx, tx, oldAccountID, transaction.AccountID); err != nil {
+		return err
+	}
+
+	var version int64
+	var oldDate time.Time
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT date, version, status FROM transactions WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`,
+		transaction.ID,
+	).Scan(&oldDate, &version, &status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	if status == StatusReconciled {
+		return ErrLocked
+	}
+	// the version is unchanged, so the transaction is still in the account
+	// locked above
+	if version != transaction.Version {
+		return ErrVersionMismatch
+	}
+	if err := locks.check(oldAccountID, oldDate); err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	// Update the main transaction
 	updateQuery := `
 		UPDATE transactions
-		SET amount = $1::bigint, running_balance = $2::bigint, description = $3::text, category_id = $4
-		WHERE id = $5::bigint
+		SET amount = $1::bigint, description = $2::text, category_id = $3, account_id = $4::bigint, date = $5::timestamptz,
+			kind = COALESCE(NULLIF($6::text, ''), (SELECT kind FROM categories WHERE id = $3), 'expense'), event_id = $8, payee_id = $9,
+			currency = (SELECT currency FROM accounts WHERE id = $4::bigint), updated_at = NOW(), version = version + 1,
+			status = CASE
+				WHEN status = $10 AND (amount <> $1::bigint OR account_id <> $4::bigint OR date <> $5::timestamptz) THEN $11
+				ELSE status
+			END
+		WHERE id = $7::bigint AND NOT ` + referencesTrashed(3, 8) + `
+		RETURNING kind, date, currency, convert_amount(amount, currency, $12, date::date), updated_at, version, status
 	`
-	_, err = tx.ExecContext(ctx, updateQuery,
+	var date time.Time
+	err = tx.QueryRowContext(ctx, updateQuery,
 		transaction.Amount,
-		transaction.RunningBalance,
 		transaction.Description,
 		transaction.CategoryID,
+		transaction.AccountID,
+		transaction.Date,
+		transaction.Kind,
 		transaction.ID,
-	)
+		transaction.EventID,
+		transaction.PayeeID,
+		StatusCleared,
+		StatusUncleared,
+		BaseCurrency,
+	).Scan(&transaction.Kind, &date, &transaction.Currency, &transaction.ConvertedAmount, &transaction.UpdatedAt, &transaction.Version, &transaction.Status)
 	if err != nil {
+		// the transaction is locked, so no row means a reference in the trash
+		if errors.Is(err, sql.ErrNoRows) || isForeignKeyViolation(err) {
+			return ErrInvalidReference
+		}
 		return err
 	}
 
-	// Update subsequent transactions if amount changed
-	if oldAmount != transaction.Amount {
-		amountDiff := transaction.Amount - oldAmount
-		cascadeQuery := `
-			UPDATE transactions 
-			SET running_balance = running_balance - $1::bigint 
-			WHERE id > $2::bigint
-		`
-		_, err = tx.ExecContext(ctx, cascadeQuery, amountDiff, transaction.ID)
-		if err != nil {
-			return err
+	if err := locks.check(transaction.AccountID, date); err != nil {
+		return err
+	}
+
+	transaction.Date = date.Format(time.RFC3339)
+	changes.add(oldAccountID, oldDate, transaction.ID)
+	changes.add(transaction.AccountID, date, transaction.ID)
+
+	if err := setTransactionSplits(ctx, tx, transaction); err != nil {
+		return err
+	}
+
+	return setTransactionTags(ctx, tx, transaction)
+}
+
+// TransactionFingerprint identifies a booked transaction when matching
+// imported statement rows against the ledger.
+type TransactionFingerprint struct {
+	ID          int64
+	ExternalID  string
+	Date        time.Time
+	Amount      int64
+	Description string
+}
+
+// GetFingerprints returns the transactions of an account dated between from
+// and to, together with any transaction carrying one of the external ids.
+// Transactions in the trash are included, so that importing a statement again
+// does not bring back rows that were deleted on purpose.
+func (s *TransactionStore) GetFingerprints(ctx context.Context, accountID int64, from, to time.Time, externalIDs []string) ([]TransactionFingerprint, error) {
+	query := `
+		SELECT id, COALESCE(external_id, ''), date, amount, description
+		FROM transactions
+		WHERE account_id = $1
+			AND ((date >= $2::timestamptz AND date < $3::timestamptz) OR external_id = ANY($4::text[]))
+		ORDER BY date ASC, id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query,
+		accountID,
+		from,
+		to,
+		pq.Array(externalIDs),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fingerprints []TransactionFingerprint
+	for rows.Next() {
+		var fingerprint TransactionFingerprint
+		if err := rows.Scan(
+			&fingerprint.ID,
+			&fingerprint.ExternalID,
+			&fingerprint.Date,
+			&fingerprint.Amount,
+			&fingerprint.Description,
+		); err != nil {
+			return nil, err
 		}
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return tx.Commit()
+	return fingerprints, nil
 }
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	TrashTransaction = "transaction"
+	TrashEvent       = "event"
+	TrashCategory    = "category"
+)
+
+// TrashItem is a deleted transaction, event or category that can still be
+// restored. Name is the description of a transaction, and Amount is only set
+// for transactions.
+type TrashItem struct {
+	Type      string `json:"type"`
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Amount    *int64 `json:"amount,omitempty"`
+	Date      string `json:"date,omitempty"`
+	DeletedAt string `json:"deleted_at"`
+}
+
+// PurgeResult counts the items a purge removed for good. BlobKeys are the
+// storage and thumbnail keys of the attachments removed along with them,
+// whose content is left for the caller to delete.
+type PurgeResult struct {
+	Transactions int64
+	Events       int64
+	Categories   int64
+	BlobKeys     []string
+}
+
+type TrashStore struct {
+	db *sql.DB
+}
+
+// Index returns everything in the trash, most recently deleted first.
+func (s *TrashStore) Index(ctx context.Context) ([]TrashItem, error) {
+	query := `
+		SELECT type, id, name, amount, date, deleted_at
+		FROM (
+			SELECT 'transaction' AS type, id, description AS name, amount, date, deleted_at
+			FROM transactions
+			WHERE deleted_at IS NOT NULL
+
+			UNION ALL
+
+			SELECT 'event', id, name, NULL::bigint, date::timestamptz, deleted_at
+			FROM events
+			WHERE deleted_at IS NOT NULL
+
+			UNION ALL
+
+			SELECT 'category', id, name, NULL::bigint, NULL::timestamptz, deleted_at
+			FROM categories
+			WHERE deleted_at IS NOT NULL
+		) trash
+		ORDER BY deleted_at DESC, type ASC, id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []TrashItem{}
+	for rows.Next() {
+		var item TrashItem
+		var amount sql.NullInt64
+		var date sql.NullTime
+		if err := rows.Scan(
+			&item.Type,
+			&item.ID,
+			&item.Name,
+			&amount,
+			&date,
+			&item.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		if amount.Valid {
+			item.Amount = &amount.Int64
+		}
+		if date.Valid {
+			item.Date = date.Time.Format(time.RFC3339)
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Restore takes an item of the given type out of the trash. A restored
+// transaction is put back into the running balances of its account, unless it
+// is dated in the reconciled period of the account, which returns ErrLocked. A
+// category whose name or color has been reused since cannot be restored.
+func (s *TrashStore) Restore(ctx context.Context, itemType string, id int64) error {
+	switch itemType {
+	case TrashTransaction:
+		return withTx(ctx, s.db, func(tx *sql.Tx) error {
+			before, err := snapshotRows(ctx, tx, AuditTransaction, id)
+			if err != nil {
+				return err
+			}
+
+			changes, locks := ledgerChanges{}, accountLocks{}
+			if err := restoreTransaction(ctx, tx, id, changes, locks); err != nil {
+				return err
+			}
+
+			if err := changes.apply(ctx, tx, locks); err != nil {
+				return err
+			}
+
+			return recordChanges(ctx, tx, AuditTransaction, AuditRestore, before, id)
+		})
+	case TrashEvent:
+		return s.restore(ctx, AuditEvent, `UPDATE events SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	case TrashCategory:
+		return s.restore(ctx, AuditCategory, `UPDATE categories SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	default:
+		return ErrNotFound
+	}
+}
+
+func restoreTransaction(ctx context.Context, tx *sql.Tx, id int64, changes ledgerChanges, locks accountLocks) error {
+	// transactions in the trash cannot be moved, so the account stays the
+	// same once it is locked
+	accountID, err := transactionAccount(ctx, tx, id, true)
+	if err != nil {
+		return err
+	}
+	if err := locks.lock(ctx, tx, accountID); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE transactions
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING date
+	`
+
+	var date time.Time
+	err = tx.QueryRowContext(ctx, query, id).Scan(&date)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	if err := locks.check(accountID, date); err != nil {
+		return err
+	}
+
+	changes.add(accountID, date, id)
+
+	return nil
+}
+
+func (s *TrashStore) restore(ctx context.Context, entity, query string, id int64) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := snapshotRows(ctx, tx, entity, id)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return ErrNotFound
+		}
+
+		return recordChanges(ctx, tx, entity, AuditRestore, before, id)
+	})
+}
+
+// Purge permanently removes every item deleted before the given time in one
+// database transaction. Running balances are not touched, as deleted
+// transactions were already taken out of them.
+func (s *TrashStore) Purge(ctx context.Context, before time.Time) (*PurgeResult, error) {
+	result := &PurgeResult{}
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		purges := []struct {
+			entity string
+			table  string
+			count  *int64
+			ids    []int64
+		}{
+			{entity: AuditTransaction, table: "transactions", count: &result.Transactions},
+			{entity: AuditEvent, table: "events", count: &result.Events},
+			{entity: AuditCategory, table: "categories", count: &result.Categories},
+		}
+
+		// the rows are locked first so that none is restored in the
+		// meantime, and only the attachments of locked rows are purged
+		for i := range purges {
+			query := fmt.Sprintf(`
+				WITH purged AS (SELECT id FROM %s WHERE deleted_at < $1 FOR UPDATE)
+				SELECT COALESCE(array_agg(id), '{}') FROM purged
+			`, purges[i].table)
+			if err := tx.QueryRowContext(ctx, query, before).Scan(pq.Array(&purges[i].ids)); err != nil {
+				return err
+			}
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT storage_key, thumbnail_key
+			FROM attachments
+			WHERE transaction_id = ANY($1::bigint[])
+				OR event_expense_id IN (SELECT id FROM event_expenses WHERE event_id = ANY($2::bigint[]))
+		`, pq.Array(purges[0].ids), pq.Array(purges[1].ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var storageKey string
+			var thumbnailKey sql.NullString
+			if err := rows.Scan(&storageKey, &thumbnailKey); err != nil {
+				return err
+			}
+			result.BlobKeys = append(result.BlobKeys, storageKey)
+			if thumbnailKey.Valid {
+				result.BlobKeys = append(result.BlobKeys, thumbnailKey.String)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, purge := range purges {
+			snapshot, err := snapshotRows(ctx, tx, purge.entity, purge.ids...)
+			if err != nil {
+				return err
+			}
+
+			query := fmt.Sprintf(`DELETE FROM %s WHERE id = ANY($1::bigint[])`, purge.table)
+			res, err := tx.ExecContext(ctx, query, pq.Array(purge.ids))
+			if err != nil {
+				return err
+			}
+			if *purge.count, err = res.RowsAffected(); err != nil {
+				return err
+			}
+
+			if err := recordChanges(ctx, tx, purge.entity, AuditPurge, snapshot, purge.ids...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,205 @@
+// Package suggest learns which category a transaction belongs to from the
+// categorized transactions booked so far.
+package suggest
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultLimit is the number of suggestions returned when no limit is given.
+const DefaultLimit = 3
+
+// Example is a categorized transaction to learn from.
+type Example struct {
+	Description string
+	Amount      int64
+	CategoryID  int64
+}
+
+// Suggestion is a candidate category with the classifier's confidence in
+// it, between 0 and 1.
+type Suggestion struct {
+	CategoryID int64   `json:"category_id"`
+	Confidence float64 `json:"confidence"`
+}
+
+type class struct {
+	examples int
+	features map[string]int
+	total    int
+}
+
+// Classifier is a multinomial naive Bayes classifier over the words of a
+// description and the order of magnitude of the amount. The zero value is
+// an empty classifier ready to use, and it is safe for concurrent use.
+type Classifier struct {
+	mu       sync.RWMutex
+	classes  map[int64]*class
+	features map[string]int
+	examples int
+}
+
+// Train replaces everything learned so far with the given examples. The new
+// model is built before the lock is taken, so suggestions keep being served
+// from the old one in the meantime.
+func (c *Classifier) Train(examples []Example) {
+	model := &Classifier{}
+	for _, example := range examples {
+		model.add(example, 1)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.classes, c.features, c.examples = model.classes, model.features, model.examples
+}
+
+// Learn adds one example.
+func (c *Classifier) Learn(example Example) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(example, 1)
+}
+
+// Forget removes an example learned earlier, such as the old state of an
+// updated transaction.
+func (c *Classifier) Forget(example Example) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(example, -1)
+}
+
+func (c *Classifier) add(example Example, delta int) {
+	if c.classes == nil {
+		c.classes = map[int64]*class{}
+		c.features = map[string]int{}
+	}
+
+	cl, ok := c.classes[example.CategoryID]
+	if !ok {
+		if delta < 0 {
+			return
+		}
+		cl = &class{features: map[string]int{}}
+		c.classes[example.CategoryID] = cl
+	}
+
+	cl.examples += delta
+	c.examples += delta
+	for _, feature := range Features(example.Description, example.Amount) {
+		cl.features[feature] += delta
+		cl.total += delta
+		c.features[feature] += delta
+		if cl.features[feature] <= 0 {
+			delete(cl.features, feature)
+		}
+		if c.features[feature] <= 0 {
+			delete(c.features, feature)
+		}
+	}
+	if cl.examples <= 0 {
+		delete(c.classes, example.CategoryID)
+	}
+}
+
+// Suggest ranks the categories for a transaction, most likely first, and
+// returns at most limit of them. Nothing is suggested when none of the
+// transaction's words has been seen before.
+func (c *Classifier) Suggest(description string, amount int64, limit int) []Suggestion {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	features := Features(description, amount)
+	known := false
+	for _, feature := range features {
+		if c.features[feature] > 0 && !strings.HasPrefix(feature, amountPrefix) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return []Suggestion{}
+	}
+
+	vocabulary := float64(len(c.features))
+	scores := make([]Suggestion, 0, len(c.classes))
+	best := math.Inf(-1)
+	for categoryID, cl := range c.classes {
+		score := math.Log(float64(cl.examples) / float64(c.examples))
+		for _, feature := range features {
+			score += math.Log((float64(cl.features[feature]) + 1) / (float64(cl.total) + vocabulary))
+		}
+		scores = append(scores, Suggestion{CategoryID: categoryID, Confidence: score})
+		best = max(best, score)
+	}
+
+	// normalize the log likelihoods to probabilities
+	var sum float64
+	for i := range scores {
+		scores[i].Confidence = math.Exp(scores[i].Confidence - best)
+		sum += scores[i].Confidence
+	}
+	for i := range scores {
+		scores[i].Confidence /= sum
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Confidence == scores[j].Confidence {
+			return scores[i].CategoryID < scores[j].CategoryID
+		}
+		return scores[i].Confidence > scores[j].Confidence
+	})
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	return scores
+}
+
+const amountPrefix = "amount:"
+
+// Features lists the distinct words of a description, ignoring numbers and
+// single letters, and a bucket for the amount per half order of magnitude,
+// so that 45,000 and 60,000 fall together but 450,000 does not. Income
+// amounts have buckets of their own.
+func Features(description string, amount int64) []string {
+	features := []string{}
+	seen := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if len([]rune(word)) < 2 || isNumber(word) || seen[word] {
+			continue
+		}
+		seen[word] = true
+		features = append(features, word)
+	}
+
+	if amount != 0 {
+		bucket := int(math.Floor(2 * math.Log10(math.Abs(float64(amount)))))
+		sign := ""
+		if amount < 0 {
+			sign = "-"
+		}
+		features = append(features, amountPrefix+sign+strconv.Itoa(bucket))
+	}
+
+	return features
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package suggest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	food      = int64(1)
+	transport = int64(2)
+	salary    = int64(3)
+)
+
+type mockTransactions struct {
+	*store.TransactionStore
+	exports []store.TransactionExport
+}
+
+func (m *mockTransactions) Export(ctx context.Context, filter store.TransactionFilter, fn func(*store.TransactionExport) error) error {
+	for i := range m.exports {
+		if err := fn(&m.exports[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type SuggestTestSuite struct {
+	suite.Suite
+	classifier *Classifier
+}
+
+func (suite *SuggestTestSuite) SetupTest() {
+	suite.classifier = &Classifier{}
+	suite.classifier.Train([]Example{
+		{Description: "GrabFood Nasi Goreng", Amount: 45000, CategoryID: food},
+		{Description: "GRABFOOD martabak", Amount: 60000, CategoryID: food},
+		{Description: "Starbucks Kota Kasablanka", Amount: 55000, CategoryID: food},
+		{Description: "Grab bike to office", Amount: 25000, CategoryID: transport},
+		{Description: "Grab car airport", Amount: 180000, CategoryID: transport},
+		{Description: "Salary January", Amount: -15000000, CategoryID: salary},
+	})
+}
+
+func (suite *SuggestTestSuite) TestFeatures() {
+	assert.Equal(suite.T(), []string{"grabfood", "nasi", "goreng", "amount:9"}, Features("GRABFOOD nasi-goreng nasi 1234 x", 45000))
+	assert.Equal(suite.T(), []string{"salary", "amount:-14"}, Features("Salary", -15000000))
+	assert.Equal(suite.T(), []string{}, Features("", 0))
+}
+
+func (suite *SuggestTestSuite) TestSuggest() {
+	suggestions := suite.classifier.Suggest("grabfood ayam geprek", 50000, 0)
+	if !assert.Len(suite.T(), suggestions, DefaultLimit) {
+		return
+	}
+	assert.Equal(suite.T(), food, suggestions[0].CategoryID)
+	assert.Greater(suite.T(), suggestions[0].Confidence, 0.5)
+
+	var sum float64
+	for _, suggestion := range suggestions {
+		sum += suggestion.Confidence
+	}
+	assert.InDelta(suite.T(), 1, sum, 1e-9)
+
+	// the amount tells a grab ride from a grab meal
+	suggestions = suite.classifier.Suggest("Grab", 30000, 1)
+	assert.Equal(suite.T(), []int64{transport}, categoryIDs(suggestions))
+}
+
+func (suite *SuggestTestSuite) TestSuggest_UnknownWords() {
+	assert.Empty(suite.T(), suite.classifier.Suggest("pharmacy", 45000, 3))
+	assert.Empty(suite.T(), (&Classifier{}).Suggest("grabfood", 45000, 3))
+}
+
+func (suite *SuggestTestSuite) TestLearnAndForget() {
+	pharmacy := []Example{
+		{Description: "Apotek Kimia Farma", Amount: 80000, CategoryID: 4},
+		{Description: "Apotek Century", Amount: 120000, CategoryID: 4},
+	}
+	for _, example := range pharmacy {
+		suite.classifier.Learn(example)
+	}
+	suggestions := suite.classifier.Suggest("apotek", 75000, 1)
+	assert.Equal(suite.T(), []int64{4}, categoryIDs(suggestions))
+
+	for _, example := range pharmacy {
+		suite.classifier.Forget(example)
+	}
+	assert.Empty(suite.T(), suite.classifier.Suggest("apotek", 75000, 1))
+
+	// forgetting what was never learned changes nothing
+	suite.classifier.Forget(Example{Description: "Apotek", Amount: 80000, CategoryID: 5})
+	assert.Equal(suite.T(), food, suite.classifier.Suggest("grabfood", 45000, 1)[0].CategoryID)
+}
+
+func (suite *SuggestTestSuite) TestTrainer_RunOnce() {
+	household, groceries := int64(5), int64(6)
+	transactions := &mockTransactions{exports: []store.TransactionExport{
+		{Date: time.Now(), Kind: store.KindExpense, Description: "Superindo", Amount: 230000, CategoryID: &groceries},
+		{Date: time.Now(), Kind: store.KindExpense, Description: "IKEA", Amount: 900000, CategoryID: &household},
+		{Date: time.Now(), Kind: store.KindExpense, Description: "IKEA", Amount: 400000, Splits: []store.Split{{CategoryID: &household, Amount: 400000}}},
+		{Date: time.Now(), Kind: store.KindTransfer, Description: "Superindo", Amount: 100000, CategoryID: &household},
+		{Date: time.Now(), Kind: store.KindExpense, Description: "Superindo", Amount: 50000},
+	}}
+
+	classifier := &Classifier{}
+	trainer := NewTrainer(store.Storage{Transactions: transactions}, classifier, time.Hour)
+	assert.NoError(suite.T(), trainer.RunOnce(context.Background()))
+
+	assert.Equal(suite.T(), []int64{groceries, household}, categoryIDs(classifier.Suggest("superindo", 200000, 5)))
+	assert.Equal(suite.T(), 2, classifier.examples)
+}
+
+func categoryIDs(suggestions []Suggestion) []int64 {
+	ids := []int64{}
+	for _, suggestion := range suggestions {
+		ids = append(ids, suggestion.CategoryID)
+	}
+	return ids
+}
+
+func TestSuggestTestSuite(t *testing.T) {
+	suite.Run(t, new(SuggestTestSuite))
+}
@@ -0,0 +1,76 @@
+package suggest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// ExampleOf returns the example a booked transaction teaches, and false when
+// it teaches nothing: transactions without a category, split transactions,
+// transfers and adjustments.
+func ExampleOf(kind, description string, amount int64, categoryID *int64, split bool) (Example, bool) {
+	if categoryID == nil || split || (kind != store.KindExpense && kind != store.KindIncome) {
+		return Example{}, false
+	}
+
+	return Example{Description: description, Amount: amount, CategoryID: *categoryID}, true
+}
+
+// Trainer periodically retrains a classifier from the full transaction
+// history. Between runs the API keeps the classifier current by learning and
+// forgetting single transactions as they change; retraining corrects for
+// changes made outside of it, such as deleted categories.
+type Trainer struct {
+	store      store.Storage
+	classifier *Classifier
+	interval   time.Duration
+}
+
+func NewTrainer(storage store.Storage, classifier *Classifier, interval time.Duration) *Trainer {
+	return &Trainer{
+		store:      storage,
+		classifier: classifier,
+		interval:   interval,
+	}
+}
+
+// Run trains the classifier right away and then on every interval until ctx
+// is cancelled.
+func (t *Trainer) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.RunOnce(ctx); err != nil {
+			log.Printf("suggest: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce trains the classifier from every categorized transaction. The
+// classifier keeps its previous model when loading fails.
+func (t *Trainer) RunOnce(ctx context.Context) error {
+	examples := []Example{}
+	err := t.store.Transactions.Export(ctx, store.TransactionFilter{Ascending: true}, func(transaction *store.TransactionExport) error {
+		example, ok := ExampleOf(transaction.Kind, transaction.Description, transaction.Amount, transaction.CategoryID, len(transaction.Splits) > 0)
+		if ok {
+			examples = append(examples, example)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	t.classifier.Train(examples)
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package trash permanently removes deleted transactions, events and
+// categories once they have been in the trash for longer than the retention
+// period.
+package trash
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/blob"
+	"github.com/pukuri/expenses/backend/internal/store"
+)
+
+// Purger periodically empties the trash of items older than its retention,
+// together with the stored content of their attachments.
+type Purger struct {
+	store     store.Storage
+	blobs     blob.Store
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+func NewPurger(storage store.Storage, blobs blob.Store, retention, interval time.Duration) *Purger {
+	return &Purger{
+		store:     storage,
+		blobs:     blobs,
+		retention: retention,
+		interval:  interval,
+		now:       time.Now,
+	}
+}
+
+// Run purges right away and then on every interval until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.RunOnce(ctx); err != nil {
+			log.Printf("trash: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce purges every item deleted more than the retention ago. Blobs are
+// only deleted once the database rows are gone, so a failure leaves orphaned
+// files rather than attachments without content.
+func (p *Purger) RunOnce(ctx context.Context) error {
+	result, err := p.store.Trash.Purge(ctx, p.now().Add(-p.retention))
+	if err != nil {
+		return err
+	}
+
+	for _, key := range result.BlobKeys {
+		if err := p.blobs.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			log.Printf("trash: delete blob %s: %s", key, err)
+		}
+	}
+
+	if result.Transactions+result.Events+result.Categories > 0 {
+		log.Printf("trash: purged %d transactions, %d events and %d categories",
+			result.Transactions, result.Events, result.Categories)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pukuri/expenses/backend/internal/blob"
+	"github.com/pukuri/expenses/backend/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type mockTrash struct {
+	*store.TrashStore
+	before time.Time
+	result store.PurgeResult
+	err    error
+}
+
+func (m *mockTrash) Purge(ctx context.Context, before time.Time) (*store.PurgeResult, error) {
+	m.before = before
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &m.result, nil
+}
+
+// mockBlobs records deleted keys and reports the ones it never stored as
+// missing.
+type mockBlobs struct {
+	stored  map[string]bool
+	deleted []string
+}
+
+func (m *mockBlobs) Put(ctx context.Context, key string, r io.Reader) error {
+	m.stored[key] = true
+	return nil
+}
+
+func (m *mockBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, blob.ErrNotFound
+}
+
+func (m *mockBlobs) Delete(ctx context.Context, key string) error {
+	if !m.stored[key] {
+		return blob.ErrNotFound
+	}
+	delete(m.stored, key)
+	m.deleted = append(m.deleted, key)
+	return nil
+}
+
+type PurgerTestSuite struct {
+	suite.Suite
+	trash  *mockTrash
+	blobs  *mockBlobs
+	purger *Purger
+}
+
+func (suite *PurgerTestSuite) SetupTest() {
+	suite.trash = &mockTrash{}
+	suite.blobs = &mockBlobs{stored: map[string]bool{}}
+	suite.purger = NewPurger(store.Storage{Trash: suite.trash}, suite.blobs, 30*24*time.Hour, time.Hour)
+	suite.purger.now = func() time.Time { return time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC) }
+}
+
+func (suite *PurgerTestSuite) TestRunOnce_PurgesBeforeRetention() {
+	suite.blobs.stored["attachments/a.jpg"] = true
+	suite.blobs.stored["thumbnails/a.jpg"] = true
+	suite.trash.result = store.PurgeResult{
+		Transactions: 2,
+		BlobKeys:     []string{"attachments/a.jpg", "thumbnails/a.jpg", "attachments/gone.pdf"},
+	}
+
+	err := suite.purger.RunOnce(context.Background())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), suite.trash.before)
+	assert.Equal(suite.T(), []string{"attachments/a.jpg", "thumbnails/a.jpg"}, suite.blobs.deleted)
+	assert.Empty(suite.T(), suite.blobs.stored)
+}
+
+func (suite *PurgerTestSuite) TestRunOnce_StoreError() {
+	suite.blobs.stored["attachments/a.jpg"] = true
+	suite.trash.err = errors.New("database error")
+
+	err := suite.purger.RunOnce(context.Background())
+
+	assert.Error(suite.T(), err)
+	assert.Empty(suite.T(), suite.blobs.deleted)
+}
+
+func TestPurgerTestSuite(t *testing.T) {
+	suite.Run(t, new(PurgerTestSuite))
+}